@@ -0,0 +1,946 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/model"
+)
+
+const Venue = "binance"
+
+// ErrUnknownInstrument 交易所没有该产品
+var ErrUnknownInstrument = errors.New("binance: unknown instrument")
+
+// Adapter 将币安现货与U本位合约接口转换为与交易所无关的领域类型
+// 现货产品标识为BASE-QUOTE 永续为BASE-QUOTE-SWAP 交割合约为BASE-QUOTE-YYMMDD
+type Adapter struct {
+	Rest    *RestClient
+	Spot    *StreamClient
+	Futures *StreamClient
+	Markets []Market     // 启用的市场 默认现货与U本位合约
+	Logger  *slog.Logger // 为nil时使用slog.Default()
+
+	instLock    sync.RWMutex
+	instruments map[string]model.Instrument
+	symbols     map[Market]map[string]string // 原始symbol到统一标识
+
+	lock       sync.Mutex
+	orderCb    func(order *model.Order)
+	fillCb     func(fill *model.Fill)
+	balanceCb  func(balance *model.Balance)
+	positionCb func(position *model.Position)
+	userData   map[Market]*common.Shared
+}
+
+// NewAdapter REST与WS客户端共用同一组Option
+func NewAdapter(ctx context.Context, opts ...Option) (*Adapter, error) {
+	o := newOptions(opts)
+	endpoints := o.resolveEndpoints()
+	rest, err := NewRestClientWithCustom(ctx, o.keyConfig, endpoints, o.proxy)
+	if err != nil {
+		return nil, err
+	}
+	proxyURL := rest.client.Transport.(*http.Transport).Proxy
+	a := &Adapter{
+		Rest:        rest,
+		Spot:        NewStreamClient(ctx, endpoints.SpotWs, proxyURL),
+		Futures:     NewStreamClient(ctx, endpoints.FuturesWs, proxyURL),
+		Markets:     o.markets,
+		instruments: map[string]model.Instrument{},
+		symbols:     map[Market]map[string]string{},
+		userData: map[Market]*common.Shared{
+			SpotMarket:    {},
+			FuturesMarket: {},
+		},
+	}
+	rest.Tracer = o.tracer
+	a.Spot.Tracer = o.tracer
+	a.Futures.Tracer = o.tracer
+	if o.logger != nil {
+		a.SetLogger(o.logger)
+	}
+	return a, nil
+}
+
+func (a *Adapter) Name() string {
+	return Venue
+}
+
+func (a *Adapter) SetLog(logger common.ILogger) {
+	a.SetLogger(common.NewSlogLogger(logger))
+}
+
+// SetLogger 结构化日志 密钥与listenKey会被脱敏
+func (a *Adapter) SetLogger(logger *slog.Logger) {
+	a.Logger = logger
+	a.Spot.Logger = logger
+	a.Futures.Logger = logger
+}
+
+func (a *Adapter) logger() *slog.Logger {
+	return common.ResolveLogger(a.Logger, nil)
+}
+
+func (a *Adapter) SetReadMonitor(f func(arg common.Arg)) {
+	a.Spot.ReadMonitor = f
+	a.Futures.ReadMonitor = f
+}
+
+// Close 关闭WS连接与REST客户端 之后的订阅与请求返回错误
+func (a *Adapter) Close() {
+	a.Spot.Close()
+	a.Futures.Close()
+	a.Rest.Close()
+}
+
+func (a *Adapter) stream(market Market) *StreamClient {
+	if market == FuturesMarket {
+		return a.Futures
+	}
+	return a.Spot
+}
+
+//-------------------------- 产品 --------------------------
+
+// LoadInstruments 加载全部启用市场的产品 首次按统一标识访问时自动调用
+func (a *Adapter) LoadInstruments(ctx context.Context) error {
+	instruments := map[string]model.Instrument{}
+	symbols := map[Market]map[string]string{}
+	for _, market := range a.Markets {
+		info, err := a.Rest.ExchangeInfo(ctx, market)
+		if err != nil {
+			return err
+		}
+		symbols[market] = map[string]string{}
+		for _, s := range info.Symbols {
+			inst, ok := toInstrument(market, s)
+			if !ok {
+				continue
+			}
+			instruments[inst.InstId] = inst
+			symbols[market][s.Symbol] = inst.InstId
+		}
+	}
+	a.instLock.Lock()
+	defer a.instLock.Unlock()
+
+	a.instruments, a.symbols = instruments, symbols
+	return nil
+}
+
+// Instrument 按统一标识查找产品
+func (a *Adapter) Instrument(ctx context.Context, instId string) (model.Instrument, error) {
+	inst, ok, loaded := a.lookupInstrument(instId)
+	if !ok && !loaded {
+		// 只加载一次 没有启用的市场时加载后仍为空
+		if err := a.LoadInstruments(ctx); err != nil {
+			return model.Instrument{}, err
+		}
+		inst, ok, _ = a.lookupInstrument(instId)
+	}
+	if !ok {
+		return model.Instrument{}, fmt.Errorf("%w: %s", ErrUnknownInstrument, instId)
+	}
+	return inst, nil
+}
+
+func (a *Adapter) lookupInstrument(instId string) (inst model.Instrument, ok, loaded bool) {
+	a.instLock.RLock()
+	defer a.instLock.RUnlock()
+
+	inst, ok = a.instruments[instId]
+	return inst, ok, len(a.symbols) != 0
+}
+
+// instId 原始symbol转换为统一标识 未知时返回symbol本身
+func (a *Adapter) instId(market Market, symbol string) string {
+	a.instLock.RLock()
+	defer a.instLock.RUnlock()
+
+	if instId, ok := a.symbols[market][symbol]; ok {
+		return instId
+	}
+	return symbol
+}
+
+func marketOf(inst model.Instrument) Market {
+	if inst.Type == model.Spot {
+		return SpotMarket
+	}
+	return FuturesMarket
+}
+
+//-------------------------- REST --------------------------
+
+// GetInstruments 获取产品列表
+func (a *Adapter) GetInstruments(ctx context.Context, typ model.InstType) ([]model.Instrument, error) {
+	if err := a.LoadInstruments(ctx); err != nil {
+		return nil, err
+	}
+	a.instLock.RLock()
+	defer a.instLock.RUnlock()
+
+	var instruments []model.Instrument
+	for _, inst := range a.instruments {
+		if typ == "" || inst.Type == typ {
+			instruments = append(instruments, inst)
+		}
+	}
+	return instruments, nil
+}
+
+// GetTicker 获取最新行情
+func (a *Adapter) GetTicker(ctx context.Context, instId string) (*model.Ticker, error) {
+	inst, err := a.Instrument(ctx, instId)
+	if err != nil {
+		return nil, err
+	}
+	if marketOf(inst) == SpotMarket {
+		t, err := a.Rest.Ticker24hr(ctx, inst.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		return &model.Ticker{
+			Venue:  Venue,
+			InstId: instId,
+			Last:   t.LastPrice,
+			BidPx:  t.BidPrice,
+			BidSz:  t.BidQty,
+			AskPx:  t.AskPrice,
+			AskSz:  t.AskQty,
+			Ts:     t.CloseTime,
+		}, nil
+	}
+	book, err := a.Rest.BookTicker(ctx, inst.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	price, err := a.Rest.TickerPrice(ctx, inst.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Ticker{
+		Venue:  Venue,
+		InstId: instId,
+		Last:   price.Price,
+		BidPx:  book.BidPrice,
+		BidSz:  book.BidQty,
+		AskPx:  book.AskPrice,
+		AskSz:  book.AskQty,
+		Ts:     book.Time,
+	}, nil
+}
+
+// GetBalances 各启用市场账户的余额
+func (a *Adapter) GetBalances(ctx context.Context) ([]model.Balance, error) {
+	var balances []model.Balance
+	for _, market := range a.Markets {
+		switch market {
+		case SpotMarket:
+			account, err := a.Rest.SpotAccount(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range account.Balances {
+				balances = append(balances, spotBalance(b.Asset, b.Free, b.Locked, account.UpdateTime))
+			}
+		case FuturesMarket:
+			rp, err := a.Rest.FuturesBalance(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range *rp {
+				balances = append(balances, model.Balance{
+					Venue:   Venue,
+					Account: string(FuturesMarket),
+					Ccy:     b.Asset,
+					Total:   b.Balance,
+					Avail:   b.AvailableBalance,
+					Frozen:  sub(b.Balance, b.AvailableBalance),
+					UTime:   b.UpdateTime,
+				})
+			}
+		}
+	}
+	return balances, nil
+}
+
+// GetPositions 合约持仓 不含空仓位
+func (a *Adapter) GetPositions(ctx context.Context) ([]model.Position, error) {
+	if !a.enabled(FuturesMarket) {
+		return nil, nil
+	}
+	rp, err := a.Rest.PositionRisk(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var positions []model.Position
+	for _, p := range *rp {
+		if isZero(p.PositionAmt) {
+			continue
+		}
+		positions = append(positions, model.Position{
+			Venue:   Venue,
+			InstId:  a.instId(FuturesMarket, p.Symbol),
+			PosSide: toPosSide(p.PositionSide),
+			Pos:     p.PositionAmt,
+			AvgPx:   p.EntryPrice,
+			MarkPx:  p.MarkPrice,
+			Upl:     p.UnRealizedProfit,
+			Lever:   p.Leverage,
+			UTime:   p.UpdateTime,
+		})
+	}
+	return positions, nil
+}
+
+// PlaceOrder 下单
+func (a *Adapter) PlaceOrder(ctx context.Context, req model.OrderRequest) (*model.Order, error) {
+	inst, err := a.Instrument(ctx, req.InstId)
+	if err != nil {
+		return nil, err
+	}
+	market := marketOf(inst)
+	o, err := a.Rest.NewOrder(ctx, market, toOrderParams(market, inst.Symbol, req))
+	if err != nil {
+		return nil, err
+	}
+	return toOrder(req.InstId, o), nil
+}
+
+// CancelOrder 按clOrdId撤单
+func (a *Adapter) CancelOrder(ctx context.Context, instId, clOrdId string) error {
+	inst, err := a.Instrument(ctx, instId)
+	if err != nil {
+		return err
+	}
+	_, err = a.Rest.CancelOrder(ctx, marketOf(inst), inst.Symbol, clOrdId)
+	return err
+}
+
+// GetOrder 按clOrdId查询订单
+func (a *Adapter) GetOrder(ctx context.Context, instId, clOrdId string) (*model.Order, error) {
+	inst, err := a.Instrument(ctx, instId)
+	if err != nil {
+		return nil, err
+	}
+	o, err := a.Rest.QueryOrder(ctx, marketOf(inst), inst.Symbol, clOrdId)
+	if err != nil {
+		return nil, err
+	}
+	return toOrder(instId, o), nil
+}
+
+// GetOpenOrders 未完成订单 instId为空时返回全部启用市场的挂单
+func (a *Adapter) GetOpenOrders(ctx context.Context, instId string) ([]model.Order, error) {
+	markets, symbol := a.Markets, ""
+	if instId != "" {
+		inst, err := a.Instrument(ctx, instId)
+		if err != nil {
+			return nil, err
+		}
+		markets, symbol = []Market{marketOf(inst)}, inst.Symbol
+	}
+	var orders []model.Order
+	for _, market := range markets {
+		rp, err := a.Rest.OpenOrders(ctx, market, symbol)
+		if err != nil {
+			return nil, err
+		}
+		for i := range *rp {
+			o := &(*rp)[i]
+			orders = append(orders, *toOrder(a.instId(market, o.Symbol), o))
+		}
+	}
+	return orders, nil
+}
+
+func (a *Adapter) enabled(market Market) bool {
+	for _, m := range a.Markets {
+		if m == market {
+			return true
+		}
+	}
+	return false
+}
+
+//-------------------------- WS --------------------------
+
+// Tickers 最优挂单推送 现货推送不含最新价与时间
+func (a *Adapter) Tickers(ctx context.Context, instId string, callback func(ticker *model.Ticker)) error {
+	inst, err := a.Instrument(ctx, instId)
+	if err != nil {
+		return err
+	}
+	stream := strings.ToLower(inst.Symbol) + "@bookTicker"
+	return a.stream(marketOf(inst)).Subscribe(ctx, stream, func(data json.RawMessage) {
+		var t WsBookTicker
+		if err := json.Unmarshal(data, &t); err != nil {
+			a.logger().Error("binance decode", "stream", stream, "err", err)
+			return
+		}
+		callback(&model.Ticker{
+			Venue:  Venue,
+			InstId: instId,
+			BidPx:  t.BidPrice,
+			BidSz:  t.BidQty,
+			AskPx:  t.AskPrice,
+			AskSz:  t.AskQty,
+			Ts:     t.TradeTime,
+		})
+	})
+}
+
+// Books 五档深度推送 每次推送均为快照
+func (a *Adapter) Books(ctx context.Context, instId string, callback func(book *model.BookUpdate)) error {
+	inst, err := a.Instrument(ctx, instId)
+	if err != nil {
+		return err
+	}
+	stream := strings.ToLower(inst.Symbol) + "@depth5@100ms"
+	return a.stream(marketOf(inst)).Subscribe(ctx, stream, func(data json.RawMessage) {
+		var d WsDepth
+		if err := json.Unmarshal(data, &d); err != nil {
+			a.logger().Error("binance decode", "stream", stream, "err", err)
+			return
+		}
+		book := &model.BookUpdate{Venue: Venue, InstId: instId, Snapshot: true}
+		if d.Event == "" {
+			book.Bids, book.Asks, book.SeqId = toLevels(d.Bids), toLevels(d.Asks), d.LastUpdateId
+		} else {
+			book.Bids, book.Asks, book.SeqId, book.Ts = toLevels(d.B), toLevels(d.A), d.LastId, d.TradeTime
+		}
+		callback(book)
+	})
+}
+
+// Candles k线推送 bar使用OKX格式 如1m、1H、1D 未完结k线同样推送
+func (a *Adapter) Candles(ctx context.Context, instId, bar string, callback func(candle *model.Candle)) error {
+	interval, err := toInterval(bar)
+	if err != nil {
+		return err
+	}
+	inst, err := a.Instrument(ctx, instId)
+	if err != nil {
+		return err
+	}
+	stream := strings.ToLower(inst.Symbol) + "@kline_" + interval
+	return a.stream(marketOf(inst)).Subscribe(ctx, stream, func(data json.RawMessage) {
+		var k WsKline
+		if err := json.Unmarshal(data, &k); err != nil {
+			a.logger().Error("binance decode", "stream", stream, "err", err)
+			return
+		}
+		callback(&model.Candle{
+			Venue:   Venue,
+			InstId:  instId,
+			Bar:     bar,
+			O:       k.K.Open,
+			H:       k.K.High,
+			L:       k.K.Low,
+			C:       k.K.Close,
+			Vol:     k.K.Volume,
+			Confirm: k.K.Closed,
+			Ts:      k.K.StartTime,
+		})
+	})
+}
+
+// MarkPrice 标记价格推送 每秒一次 现货不支持
+func (a *Adapter) MarkPrice(ctx context.Context, instId string, callback func(mp *model.MarkPrice)) error {
+	inst, err := a.Instrument(ctx, instId)
+	if err != nil {
+		return err
+	}
+	if marketOf(inst) != FuturesMarket {
+		return model.ErrNotSupported
+	}
+	stream := strings.ToLower(inst.Symbol) + "@markPrice@1s"
+	return a.stream(FuturesMarket).Subscribe(ctx, stream, func(data json.RawMessage) {
+		var mp WsMarkPrice
+		if err := json.Unmarshal(data, &mp); err != nil {
+			a.logger().Error("binance decode", "stream", stream, "err", err)
+			return
+		}
+		callback(&model.MarkPrice{Venue: Venue, InstId: instId, MarkPx: mp.MarkPrice, Ts: mp.EventTime})
+	})
+}
+
+// Orders 订单推送 来自各启用市场的用户数据流
+func (a *Adapter) Orders(ctx context.Context, callback func(order *model.Order)) error {
+	return a.watchUserData(ctx, a.Markets, func() { a.orderCb = callback }, func() { a.orderCb = nil })
+}
+
+// Fills 成交推送
+func (a *Adapter) Fills(ctx context.Context, callback func(fill *model.Fill)) error {
+	return a.watchUserData(ctx, a.Markets, func() { a.fillCb = callback }, func() { a.fillCb = nil })
+}
+
+// Account 余额推送 每个币种回调一次
+func (a *Adapter) Account(ctx context.Context, callback func(balance *model.Balance)) error {
+	return a.watchUserData(ctx, a.Markets, func() { a.balanceCb = callback }, func() { a.balanceCb = nil })
+}
+
+// Positions 合约持仓推送
+func (a *Adapter) Positions(ctx context.Context, callback func(position *model.Position)) error {
+	if !a.enabled(FuturesMarket) {
+		return model.ErrNotSupported
+	}
+	return a.watchUserData(ctx, []Market{FuturesMarket}, func() { a.positionCb = callback }, func() { a.positionCb = nil })
+}
+
+// watchUserData 多个订阅共享各市场的用户数据流 阻塞至ctx结束或任一数据流异常
+func (a *Adapter) watchUserData(ctx context.Context, markets []Market, set, unset func()) error {
+	a.lock.Lock()
+	set()
+	a.lock.Unlock()
+	defer func() {
+		a.lock.Lock()
+		unset()
+		a.lock.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, len(markets))
+	for _, market := range markets {
+		market := market
+		go func() {
+			errCh <- a.userData[market].Join(ctx, func(ctx context.Context) error {
+				return a.runUserData(ctx, market)
+			})
+		}()
+	}
+	var errs []error
+	for range markets {
+		if err := <-errCh; err != nil {
+			errs = append(errs, err)
+			cancel()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runUserData 创建listenKey并订阅 期间定时延长有效期
+func (a *Adapter) runUserData(ctx context.Context, market Market) error {
+	key, err := a.Rest.NewListenKey(ctx, market)
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(30 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.Rest.KeepaliveListenKey(ctx, market, key.ListenKey); err != nil {
+					a.logger().Warn("binance keepalive listenKey", "market", market, "err", err)
+				}
+			}
+		}
+	}()
+	return a.stream(market).Subscribe(ctx, key.ListenKey, func(data json.RawMessage) {
+		a.onUserData(market, data)
+	})
+}
+
+func (a *Adapter) onUserData(market Market, data json.RawMessage) {
+	var e WsEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		a.logger().Error("binance decode", "market", market, "err", err)
+		return
+	}
+	a.lock.Lock()
+	orderCb, fillCb, balanceCb, positionCb := a.orderCb, a.fillCb, a.balanceCb, a.positionCb
+	a.lock.Unlock()
+
+	switch e.Event {
+	case "executionReport":
+		var r WsExecutionReport
+		if err := json.Unmarshal(data, &r); err != nil {
+			a.logger().Error("binance decode", "market", market, "event", e.Event, "err", err)
+			return
+		}
+		order, fill := a.fromExecutionReport(&r)
+		if orderCb != nil {
+			orderCb(order)
+		}
+		if fill != nil && fillCb != nil {
+			fillCb(fill)
+		}
+	case "ORDER_TRADE_UPDATE":
+		var r WsOrderTradeUpdate
+		if err := json.Unmarshal(data, &r); err != nil {
+			a.logger().Error("binance decode", "market", market, "event", e.Event, "err", err)
+			return
+		}
+		order, fill := a.fromOrderTradeUpdate(&r)
+		if orderCb != nil {
+			orderCb(order)
+		}
+		if fill != nil && fillCb != nil {
+			fillCb(fill)
+		}
+	case "outboundAccountPosition":
+		var r WsOutboundAccountPosition
+		if err := json.Unmarshal(data, &r); err != nil {
+			a.logger().Error("binance decode", "market", market, "event", e.Event, "err", err)
+			return
+		}
+		if balanceCb == nil {
+			return
+		}
+		for _, b := range r.Balances {
+			bal := spotBalance(b.Asset, b.Free, b.Locked, r.UpdateTime)
+			balanceCb(&bal)
+		}
+	case "ACCOUNT_UPDATE":
+		var r WsAccountUpdate
+		if err := json.Unmarshal(data, &r); err != nil {
+			a.logger().Error("binance decode", "market", market, "event", e.Event, "err", err)
+			return
+		}
+		if balanceCb != nil {
+			for _, b := range r.Account.Balances {
+				balanceCb(&model.Balance{
+					Venue:   Venue,
+					Account: string(FuturesMarket),
+					Ccy:     b.Asset,
+					Total:   b.WalletBalance,
+					UTime:   r.Time,
+				})
+			}
+		}
+		if positionCb != nil {
+			for _, p := range r.Account.Positions {
+				positionCb(&model.Position{
+					Venue:   Venue,
+					InstId:  a.instId(FuturesMarket, p.Symbol),
+					PosSide: toPosSide(p.PositionSide),
+					Pos:     p.PositionAmt,
+					AvgPx:   p.EntryPrice,
+					Upl:     p.Upl,
+					UTime:   r.Time,
+				})
+			}
+		}
+	}
+}
+
+func (a *Adapter) fromExecutionReport(r *WsExecutionReport) (*model.Order, *model.Fill) {
+	instId := a.instId(SpotMarket, r.Symbol)
+	clOrdId := r.ClientOrderId
+	if r.OrigClientOrderId != "" {
+		clOrdId = r.OrigClientOrderId
+	}
+	order := &model.Order{
+		Venue:    Venue,
+		InstId:   instId,
+		OrdId:    strconv.FormatInt(r.OrderId, 10),
+		ClOrdId:  clOrdId,
+		Side:     toSide(r.Side),
+		Type:     toOrderType(r.Type, r.TimeInForce),
+		Status:   toOrderStatus(r.Status),
+		Px:       r.Price,
+		Sz:       r.Qty,
+		AvgPx:    avgPx(r.CumQuoteQty, r.CumQty),
+		FilledSz: r.CumQty,
+		CTime:    r.CreateTime,
+		UTime:    r.EventTime,
+	}
+	if r.ExecType != "TRADE" {
+		return order, nil
+	}
+	return order, &model.Fill{
+		Venue:   Venue,
+		InstId:  instId,
+		OrdId:   order.OrdId,
+		ClOrdId: clOrdId,
+		TradeId: strconv.FormatInt(r.TradeId, 10),
+		Side:    order.Side,
+		Px:      r.LastPrice,
+		Sz:      r.LastQty,
+		Fee:     neg(r.Commission),
+		FeeCcy:  r.CommissionAsset,
+		Maker:   r.Maker,
+		Ts:      r.TradeTime,
+	}
+}
+
+func (a *Adapter) fromOrderTradeUpdate(r *WsOrderTradeUpdate) (*model.Order, *model.Fill) {
+	o := r.Order
+	instId := a.instId(FuturesMarket, o.Symbol)
+	order := &model.Order{
+		Venue:    Venue,
+		InstId:   instId,
+		OrdId:    strconv.FormatInt(o.OrderId, 10),
+		ClOrdId:  o.ClientOrderId,
+		Side:     toSide(o.Side),
+		Type:     toOrderType(o.Type, o.TimeInForce),
+		Status:   toOrderStatus(o.Status),
+		Px:       o.Price,
+		Sz:       o.Qty,
+		AvgPx:    o.AvgPrice,
+		FilledSz: o.CumQty,
+		UTime:    r.Time,
+	}
+	if o.ExecType != "TRADE" {
+		return order, nil
+	}
+	return order, &model.Fill{
+		Venue:   Venue,
+		InstId:  instId,
+		OrdId:   order.OrdId,
+		ClOrdId: o.ClientOrderId,
+		TradeId: strconv.FormatInt(o.TradeId, 10),
+		Side:    order.Side,
+		Px:      o.LastPrice,
+		Sz:      o.LastQty,
+		Fee:     neg(o.Commission),
+		FeeCcy:  o.CommissionAsset,
+		Maker:   o.Maker,
+		Ts:      o.TradeTime,
+	}
+}
+
+//-------------------------- 转换 --------------------------
+
+func toInstrument(market Market, s SymbolInfo) (model.Instrument, bool) {
+	inst := model.Instrument{
+		Venue:  Venue,
+		Symbol: s.Symbol,
+		Base:   s.BaseAsset,
+		Quote:  s.QuoteAsset,
+		Live:   s.Status == "TRADING",
+	}
+	switch {
+	case market == SpotMarket:
+		inst.Type = model.Spot
+		inst.InstId = model.MakeInstId(s.BaseAsset, s.QuoteAsset, model.Spot)
+	case s.ContractType == "PERPETUAL":
+		inst.Type = model.Swap
+		inst.Settle = s.MarginAsset
+		inst.InstId = model.MakeInstId(s.BaseAsset, s.QuoteAsset, model.Swap)
+	case strings.Contains(s.Symbol, "_") && !strings.HasSuffix(s.Symbol, "_"):
+		inst.Type = model.Futures
+		inst.Settle = s.MarginAsset
+		inst.InstId = model.MakeInstId(s.BaseAsset, s.QuoteAsset, model.Futures, s.Symbol[strings.LastIndex(s.Symbol, "_")+1:])
+	default:
+		return inst, false
+	}
+	for _, f := range s.Filters {
+		switch f.FilterType {
+		case "PRICE_FILTER":
+			inst.TickSz = f.TickSize
+		case "LOT_SIZE":
+			inst.LotSz, inst.MinSz = f.StepSize, f.MinQty
+		}
+	}
+	return inst, true
+}
+
+func toOrderParams(market Market, symbol string, req model.OrderRequest) url.Values {
+	params := url.Values{
+		"symbol":   {symbol},
+		"side":     {strings.ToUpper(string(req.Side))},
+		"quantity": {req.Sz},
+	}
+	if req.ClOrdId != "" {
+		params.Set("newClientOrderId", req.ClOrdId)
+	}
+	switch req.Type {
+	case model.Market:
+		params.Set("type", "MARKET")
+	case model.PostOnly:
+		if market == SpotMarket {
+			params.Set("type", "LIMIT_MAKER")
+		} else {
+			params.Set("type", "LIMIT")
+			params.Set("timeInForce", "GTX")
+		}
+	case model.IOC, model.FOK:
+		params.Set("type", "LIMIT")
+		params.Set("timeInForce", strings.ToUpper(string(req.Type)))
+	default:
+		params.Set("type", "LIMIT")
+		params.Set("timeInForce", "GTC")
+	}
+	if req.Type != model.Market {
+		params.Set("price", req.Px)
+	}
+	if market == FuturesMarket {
+		if req.PosSide == model.Long || req.PosSide == model.Short {
+			params.Set("positionSide", strings.ToUpper(string(req.PosSide)))
+		} else if req.ReduceOnly {
+			// 双向持仓模式下不接受reduceOnly
+			params.Set("reduceOnly", "true")
+		}
+	}
+	return params
+}
+
+func toOrder(instId string, o *Order) *model.Order {
+	order := &model.Order{
+		Venue:    Venue,
+		InstId:   instId,
+		OrdId:    strconv.FormatInt(o.OrderId, 10),
+		ClOrdId:  o.ClientOrderId,
+		Side:     toSide(o.Side),
+		Type:     toOrderType(o.Type, o.TimeInForce),
+		Status:   toOrderStatus(o.Status),
+		Px:       o.Price,
+		Sz:       o.OrigQty,
+		AvgPx:    o.AvgPrice,
+		FilledSz: o.ExecutedQty,
+		CTime:    o.Time,
+		UTime:    o.UpdateTime,
+	}
+	if order.CTime == 0 {
+		order.CTime = o.TransactTime
+	}
+	if order.UTime == 0 {
+		order.UTime = order.CTime
+	}
+	if order.AvgPx == "" {
+		order.AvgPx = avgPx(o.CummulativeQuoteQty, o.ExecutedQty)
+	}
+	return order
+}
+
+// toInterval OKX格式的k线周期转换为币安格式 1m与1M分别为分钟与月 utc后缀忽略
+func toInterval(bar string) (string, error) {
+	s := strings.TrimSuffix(strings.TrimSuffix(bar, "utc"), "UTC")
+	if len(s) < 2 {
+		return "", fmt.Errorf("%w: bar %q", model.ErrNotSupported, bar)
+	}
+	n, unit := s[:len(s)-1], s[len(s)-1]
+	switch unit {
+	case 'H', 'D', 'W':
+		unit += 'a' - 'A'
+	case 'm', 'M':
+	default:
+		return "", fmt.Errorf("%w: bar %q", model.ErrNotSupported, bar)
+	}
+	interval := n + string(unit)
+	switch interval {
+	case "1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M":
+		return interval, nil
+	}
+	return "", fmt.Errorf("%w: bar %q", model.ErrNotSupported, bar)
+}
+
+func toSide(side string) model.Side {
+	return model.Side(strings.ToLower(side))
+}
+
+func toPosSide(side string) model.PosSide {
+	if side == "BOTH" || side == "" {
+		return model.Net
+	}
+	return model.PosSide(strings.ToLower(side))
+}
+
+func toOrderType(typ, timeInForce string) model.OrderType {
+	switch typ {
+	case "MARKET":
+		return model.Market
+	case "LIMIT_MAKER":
+		return model.PostOnly
+	}
+	switch timeInForce {
+	case "GTX":
+		return model.PostOnly
+	case "IOC":
+		return model.IOC
+	case "FOK":
+		return model.FOK
+	}
+	return model.Limit
+}
+
+func toOrderStatus(status string) model.OrderStatus {
+	switch status {
+	case "NEW", "PENDING_NEW", "PENDING_CANCEL":
+		return model.StatusNew
+	case "PARTIALLY_FILLED":
+		return model.StatusPartiallyFilled
+	case "FILLED":
+		return model.StatusFilled
+	case "CANCELED", "EXPIRED", "EXPIRED_IN_MATCH":
+		return model.StatusCanceled
+	}
+	return model.StatusRejected
+}
+
+func toLevels(rows [][]string) []model.Level {
+	levels := make([]model.Level, 0, len(rows))
+	for _, row := range rows {
+		if len(row) >= 2 {
+			levels = append(levels, model.Level{Px: row[0], Sz: row[1]})
+		}
+	}
+	return levels
+}
+
+func spotBalance(asset, free, locked string, uTime int64) model.Balance {
+	return model.Balance{
+		Venue:   Venue,
+		Account: string(SpotMarket),
+		Ccy:     asset,
+		Total:   add(free, locked),
+		Avail:   free,
+		Frozen:  locked,
+		UTime:   uTime,
+	}
+}
+
+// avgPx 由累计成交额与累计成交量计算均价
+func avgPx(quoteQty, qty string) string {
+	q, _ := strconv.ParseFloat(quoteQty, 64)
+	n, _ := strconv.ParseFloat(qty, 64)
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(q/n, 'f', -1, 64)
+}
+
+func add(a, b string) string {
+	x, _ := strconv.ParseFloat(a, 64)
+	y, _ := strconv.ParseFloat(b, 64)
+	return strconv.FormatFloat(x+y, 'f', -1, 64)
+}
+
+func sub(a, b string) string {
+	x, _ := strconv.ParseFloat(a, 64)
+	y, _ := strconv.ParseFloat(b, 64)
+	return strconv.FormatFloat(x-y, 'f', -1, 64)
+}
+
+// neg 币安手续费为正数 统一为负数表示支出
+func neg(fee string) string {
+	if fee == "" || isZero(fee) {
+		return "0"
+	}
+	if strings.HasPrefix(fee, "-") {
+		return fee[1:]
+	}
+	return "-" + fee
+}
+
+func isZero(v string) bool {
+	f, err := strconv.ParseFloat(v, 64)
+	return err == nil && f == 0
+}
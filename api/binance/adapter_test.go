@@ -0,0 +1,523 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/model"
+)
+
+var testKey = KeyConfig{Apikey: "key", Secretkey: "secret"}
+
+// fixtureServer 以testdata中的录制响应模拟币安REST与组合流
+type fixtureServer struct {
+	t        *testing.T
+	srv      *httptest.Server
+	lock     sync.Mutex
+	requests map[string]url.Values
+	conns    map[string]*fixtureConn // 市场到当前连接
+}
+
+type fixtureConn struct {
+	lock    sync.Mutex
+	conn    *websocket.Conn
+	streams map[string]bool
+}
+
+var routes = map[string]string{
+	"GET /api/v3/exchangeInfo":       "spot_exchange_info.json",
+	"GET /fapi/v1/exchangeInfo":      "futures_exchange_info.json",
+	"GET /api/v3/ticker/24hr":        "spot_ticker_24hr.json",
+	"GET /fapi/v1/ticker/bookTicker": "futures_book_ticker.json",
+	"GET /fapi/v1/ticker/price":      "futures_ticker_price.json",
+	"GET /api/v3/account":            "spot_account.json",
+	"GET /fapi/v2/balance":           "futures_balance.json",
+	"GET /fapi/v2/positionRisk":      "futures_position_risk.json",
+	"POST /api/v3/order":             "spot_order.json",
+	"POST /fapi/v1/order":            "futures_order.json",
+	"GET /api/v3/openOrders":         "spot_open_orders.json",
+	"GET /fapi/v1/openOrders":        "futures_open_orders.json",
+	"POST /api/v3/userDataStream":    "listen_key.json",
+	"POST /fapi/v1/listenKey":        "listen_key.json",
+}
+
+var streamFixtures = map[string]string{
+	"btcusdt@bookTicker":   "ws_spot_book_ticker.json",
+	"btcusdt@depth5@100ms": "ws_futures_depth.json",
+	"btcusdt@kline_1h":     "ws_spot_kline.json",
+	"btcusdt@markPrice@1s": "ws_futures_mark_price.json",
+}
+
+func fixture(t *testing.T, name string) []byte {
+	bs, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
+
+func newFixtureServer(t *testing.T) *fixtureServer {
+	fs := &fixtureServer{t: t, requests: map[string]url.Values{}, conns: map[string]*fixtureConn{}}
+	fs.srv = httptest.NewServer(http.HandlerFunc(fs.serve))
+	t.Cleanup(fs.srv.Close)
+	return fs
+}
+
+func (fs *fixtureServer) endpoints() Endpoints {
+	wsUrl := "ws" + strings.TrimPrefix(fs.srv.URL, "http")
+	return Endpoints{
+		SpotRest:    common.BaseURL(fs.srv.URL),
+		FuturesRest: common.BaseURL(fs.srv.URL),
+		SpotWs:      common.BaseURL(wsUrl + "/spot/stream"),
+		FuturesWs:   common.BaseURL(wsUrl + "/futures/stream"),
+	}
+}
+
+func (fs *fixtureServer) serve(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/stream") {
+		fs.serveStream(w, r, strings.Split(r.URL.Path, "/")[1])
+		return
+	}
+	route := r.Method + " " + r.URL.Path
+	if sig := r.URL.Query().Get("signature"); sig != "" {
+		raw := r.URL.RawQuery[:strings.Index(r.URL.RawQuery, "&signature=")]
+		if sig != testKey.MakeSign(raw) || r.Header.Get("X-MBX-APIKEY") != testKey.Apikey {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":-1022,"msg":"Signature for this request is not valid."}`))
+			return
+		}
+	}
+	fs.lock.Lock()
+	fs.requests[route] = r.URL.Query()
+	fs.lock.Unlock()
+	name, ok := routes[route]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":-5000,"msg":"Path not found"}`))
+		return
+	}
+	_, _ = w.Write(fixture(fs.t, name))
+}
+
+func (fs *fixtureServer) serveStream(w http.ResponseWriter, r *http.Request, market string) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		fs.t.Error(err)
+		return
+	}
+	defer conn.Close()
+	fc := &fixtureConn{conn: conn, streams: map[string]bool{}}
+	fs.lock.Lock()
+	fs.conns[market] = fc
+	fs.lock.Unlock()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req struct {
+			Method string   `json:"method"`
+			Params []string `json:"params"`
+			Id     uint64   `json:"id"`
+		}
+		if err := json.Unmarshal(data, &req); err != nil {
+			continue
+		}
+		fc.lock.Lock()
+		_ = conn.WriteJSON(map[string]any{"result": nil, "id": req.Id})
+		for _, stream := range req.Params {
+			fc.streams[stream] = req.Method == "SUBSCRIBE"
+			if name, ok := streamFixtures[stream]; ok && req.Method == "SUBSCRIBE" {
+				_ = conn.WriteMessage(websocket.TextMessage, fixture(fs.t, name))
+			}
+		}
+		fc.lock.Unlock()
+	}
+}
+
+// subscribed 市场连接上已订阅的流
+func (fs *fixtureServer) subscribed(market, stream string) bool {
+	fs.lock.Lock()
+	fc := fs.conns[market]
+	fs.lock.Unlock()
+	if fc == nil {
+		return false
+	}
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	return fc.streams[stream]
+}
+
+// push 向市场连接推送用户数据 stream替换为已订阅的listenKey
+func (fs *fixtureServer) push(market, listenKey, name string) {
+	var env map[string]json.RawMessage
+	_ = json.Unmarshal(fixture(fs.t, name), &env)
+	env["stream"], _ = json.Marshal(listenKey)
+	fs.lock.Lock()
+	fc := fs.conns[market]
+	fs.lock.Unlock()
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	_ = fc.conn.WriteJSON(env)
+}
+
+func (fs *fixtureServer) request(route string) url.Values {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	return fs.requests[route]
+}
+
+func newTestAdapter(t *testing.T) (*Adapter, *fixtureServer) {
+	fs := newFixtureServer(t)
+	a, err := NewAdapter(context.Background(), WithKeyConfig(testKey), WithEndpoints(fs.endpoints()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetLog(nopLogger{})
+	return a, fs
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+func (nopLogger) Panicf(string, ...interface{}) {}
+
+func TestAdapterInstruments(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	ctx := context.Background()
+
+	spot, err := a.GetInstruments(ctx, model.Spot)
+	assert.NoError(t, err)
+	assert.Len(t, spot, 2)
+
+	inst, err := a.Instrument(ctx, "BTC-USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, "BTCUSDT", inst.Symbol)
+	assert.Equal(t, "0.01000000", inst.TickSz)
+	assert.Equal(t, "0.00001000", inst.LotSz)
+	assert.True(t, inst.Live)
+
+	swap, err := a.Instrument(ctx, "BTC-USDT-SWAP")
+	assert.NoError(t, err)
+	assert.Equal(t, model.Swap, swap.Type)
+	assert.Equal(t, "USDT", swap.Settle)
+	assert.Equal(t, "0.10", swap.TickSz)
+
+	futures, err := a.Instrument(ctx, "BTC-USDT-240628")
+	assert.NoError(t, err)
+	assert.Equal(t, "BTCUSDT_240628", futures.Symbol)
+	assert.Equal(t, model.Futures, model.ParseInstType(futures.InstId))
+
+	eth, _ := a.Instrument(ctx, "ETH-BTC")
+	assert.False(t, eth.Live)
+
+	_, err = a.Instrument(ctx, "DOGE-USDT")
+	assert.ErrorIs(t, err, ErrUnknownInstrument)
+}
+
+func TestAdapterNoMarkets(t *testing.T) {
+	fs := newFixtureServer(t)
+	a, err := NewAdapter(context.Background(), WithKeyConfig(testKey), WithEndpoints(fs.endpoints()), WithMarkets())
+	assert.NoError(t, err)
+	// 没有启用的市场时加载一次后返回未知产品
+	_, err = a.Instrument(context.Background(), "BTC-USDT")
+	assert.ErrorIs(t, err, ErrUnknownInstrument)
+}
+
+func TestAdapterClose(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Tickers(ctx, "BTC-USDT", func(ticker *model.Ticker) {})
+	}()
+	assert.Eventually(t, a.Spot.Alive, 2*time.Second, 10*time.Millisecond)
+	a.Close()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrStreamClosed)
+	case <-ctx.Done():
+		t.Fatal("subscription not closed")
+	}
+	assert.ErrorIs(t, a.Tickers(ctx, "BTC-USDT", func(ticker *model.Ticker) {}), ErrStreamClosed)
+	_, err := a.GetTicker(ctx, "BTC-USDT")
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+func TestAdapterTracing(t *testing.T) {
+	fs := newFixtureServer(t)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	a, err := NewAdapter(context.Background(), WithKeyConfig(testKey), WithEndpoints(fs.endpoints()), WithTracerProvider(tp))
+	assert.NoError(t, err)
+	defer a.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	ctx, parent := tp.Tracer("test").Start(ctx, "caller")
+	_, err = a.GetTicker(ctx, "BTC-USDT")
+	assert.NoError(t, err)
+	subCtx, stop := context.WithCancel(ctx)
+	got := make(chan struct{}, 1)
+	go func() {
+		_ = a.Tickers(subCtx, "BTC-USDT", func(ticker *model.Ticker) {
+			select {
+			case got <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	select {
+	case <-got:
+	case <-ctx.Done():
+		t.Fatal("no ticker")
+	}
+	parent.End()
+	// 推送可能先于SUBSCRIBE的响应到达
+	spans := map[string]tracetest.SpanStub{}
+	assert.Eventually(t, func() bool {
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+		}
+		_, ok := spans["binance.ws SUBSCRIBE"]
+		return ok
+	}, 2*time.Second, 10*time.Millisecond)
+	stop()
+	for _, name := range []string{"binance.rest /api/v3/ticker/24hr", "binance.ws SUBSCRIBE"} {
+		span, ok := spans[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID(), name)
+		}
+	}
+}
+
+func TestAdapterRest(t *testing.T) {
+	a, fs := newTestAdapter(t)
+	ctx := context.Background()
+
+	ticker, err := a.GetTicker(ctx, "BTC-USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, "67010.50000000", ticker.Last)
+	assert.Equal(t, "67010.49000000", ticker.BidPx)
+
+	ticker, err = a.GetTicker(ctx, "BTC-USDT-SWAP")
+	assert.NoError(t, err)
+	assert.Equal(t, "67001.10", ticker.Last)
+	assert.Equal(t, "67001.20", ticker.AskPx)
+	assert.Equal(t, int64(1718000000123), ticker.Ts)
+
+	balances, err := a.GetBalances(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	assert.Equal(t, model.Balance{Venue: Venue, Account: "spot", Ccy: "BTC", Total: "0.75", Avail: "0.50000000", Frozen: "0.25000000", UTime: 1718000000000}, balances[0])
+	assert.Equal(t, "usdm", balances[2].Account)
+	assert.Equal(t, "23.72469206", balances[2].Avail)
+
+	positions, err := a.GetPositions(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, positions, 1)
+	assert.Equal(t, "BTC-USDT-SWAP", positions[0].InstId)
+	assert.Equal(t, model.Net, positions[0].PosSide)
+	assert.Equal(t, "-0.010", positions[0].Pos)
+
+	order, err := a.PlaceOrder(ctx, model.OrderRequest{InstId: "BTC-USDT", ClOrdId: "s1", Side: model.Buy, Type: model.PostOnly, Px: "60000", Sz: "0.01"})
+	assert.NoError(t, err)
+	assert.Equal(t, "28", order.OrdId)
+	assert.Equal(t, model.PostOnly, order.Type)
+	assert.Equal(t, model.StatusNew, order.Status)
+	q := fs.request("POST /api/v3/order")
+	assert.Equal(t, "LIMIT_MAKER", q.Get("type"))
+	assert.Equal(t, "BUY", q.Get("side"))
+	assert.Equal(t, "s1", q.Get("newClientOrderId"))
+	assert.Empty(t, q.Get("timeInForce"))
+
+	order, err = a.PlaceOrder(ctx, model.OrderRequest{InstId: "BTC-USDT-SWAP", ClOrdId: "f1", Side: model.Sell, Type: model.IOC, Px: "67100", Sz: "0.01", ReduceOnly: true})
+	assert.NoError(t, err)
+	assert.Equal(t, model.StatusPartiallyFilled, order.Status)
+	assert.Equal(t, "67001.20", order.AvgPx)
+	q = fs.request("POST /fapi/v1/order")
+	assert.Equal(t, "LIMIT", q.Get("type"))
+	assert.Equal(t, "IOC", q.Get("timeInForce"))
+	assert.Equal(t, "true", q.Get("reduceOnly"))
+
+	_, _ = a.PlaceOrder(ctx, model.OrderRequest{InstId: "BTC-USDT-SWAP", Side: model.Buy, Type: model.PostOnly, Px: "60000", Sz: "0.01", PosSide: model.Long})
+	q = fs.request("POST /fapi/v1/order")
+	assert.Equal(t, "GTX", q.Get("timeInForce"))
+	assert.Equal(t, "LONG", q.Get("positionSide"))
+	assert.Empty(t, q.Get("reduceOnly"))
+
+	orders, err := a.GetOpenOrders(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, orders, 2)
+	assert.Equal(t, "BTC-USDT", orders[0].InstId)
+	assert.Equal(t, "59000", orders[0].AvgPx)
+	assert.Equal(t, "BTC-USDT-SWAP", orders[1].InstId)
+	assert.Equal(t, model.PostOnly, orders[1].Type)
+}
+
+func TestAdapterSignatureRejected(t *testing.T) {
+	fs := newFixtureServer(t)
+	a, err := NewAdapter(context.Background(), WithKeyConfig(KeyConfig{Apikey: "key", Secretkey: "wrong"}), WithEndpoints(fs.endpoints()))
+	assert.NoError(t, err)
+
+	_, err = a.GetBalances(context.Background())
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, -1022, apiErr.Code)
+}
+
+func TestAdapterMarketStreams(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tickers := make(chan *model.Ticker, 1)
+	go func() {
+		_ = a.Tickers(ctx, "BTC-USDT", func(ticker *model.Ticker) { tickers <- ticker })
+	}()
+	books := make(chan *model.BookUpdate, 1)
+	go func() {
+		_ = a.Books(ctx, "BTC-USDT-SWAP", func(book *model.BookUpdate) { books <- book })
+	}()
+
+	select {
+	case ticker := <-tickers:
+		assert.Equal(t, "BTC-USDT", ticker.InstId)
+		assert.Equal(t, "67010.49000000", ticker.BidPx)
+		assert.Equal(t, "0.35000000", ticker.AskSz)
+	case <-ctx.Done():
+		t.Fatal("no ticker")
+	}
+	select {
+	case book := <-books:
+		assert.True(t, book.Snapshot)
+		assert.Equal(t, int64(390497878), book.SeqId)
+		assert.Equal(t, []model.Level{{Px: "67001.10", Sz: "3.512"}, {Px: "67001.00", Sz: "0.200"}}, book.Bids)
+		assert.Equal(t, "67001.20", book.Asks[0].Px)
+	case <-ctx.Done():
+		t.Fatal("no book")
+	}
+}
+
+func TestAdapterCandlesMarkPrice(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	candles := make(chan *model.Candle, 1)
+	go func() {
+		_ = a.Candles(ctx, "BTC-USDT", "1H", func(candle *model.Candle) { candles <- candle })
+	}()
+	marks := make(chan *model.MarkPrice, 1)
+	go func() {
+		_ = a.MarkPrice(ctx, "BTC-USDT-SWAP", func(mp *model.MarkPrice) { marks <- mp })
+	}()
+
+	select {
+	case c := <-candles:
+		assert.Equal(t, model.Candle{
+			Venue: Venue, InstId: "BTC-USDT", Bar: "1H", O: "67000.00000000", H: "67050.00000000", L: "66990.00000000",
+			C: "67010.50000000", Vol: "12.50000000", Ts: 1717999200000,
+		}, *c)
+	case <-ctx.Done():
+		t.Fatal("no candle")
+	}
+	select {
+	case mp := <-marks:
+		assert.Equal(t, model.MarkPrice{Venue: Venue, InstId: "BTC-USDT-SWAP", MarkPx: "67002.10000000", Ts: 1718000002000}, *mp)
+	case <-ctx.Done():
+		t.Fatal("no mark price")
+	}
+
+	assert.ErrorIs(t, a.MarkPrice(ctx, "BTC-USDT", func(mp *model.MarkPrice) {}), model.ErrNotSupported)
+	assert.ErrorIs(t, a.Candles(ctx, "BTC-USDT", "2D", func(candle *model.Candle) {}), model.ErrNotSupported)
+}
+
+func TestInterval(t *testing.T) {
+	for bar, want := range map[string]string{"1m": "1m", "15m": "15m", "4H": "4h", "1Dutc": "1d", "1W": "1w", "1M": "1M"} {
+		got, err := toInterval(bar)
+		assert.NoError(t, err, bar)
+		assert.Equal(t, want, got, bar)
+	}
+	_, err := toInterval("1s")
+	assert.ErrorIs(t, err, model.ErrNotSupported)
+}
+
+func TestAdapterUserData(t *testing.T) {
+	a, fs := newTestAdapter(t)
+	assert.NoError(t, a.LoadInstruments(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	listenKey := "pqia91ma19a5s61cv6a81va65sdf19v8a65a1a5s61cv6a81va65sdf19v8a65a1"
+
+	orders := make(chan *model.Order, 4)
+	fills := make(chan *model.Fill, 4)
+	balances := make(chan *model.Balance, 4)
+	positions := make(chan *model.Position, 4)
+	go func() { _ = a.Orders(ctx, func(order *model.Order) { orders <- order }) }()
+	go func() { _ = a.Fills(ctx, func(fill *model.Fill) { fills <- fill }) }()
+	go func() { _ = a.Account(ctx, func(balance *model.Balance) { balances <- balance }) }()
+	go func() { _ = a.Positions(ctx, func(position *model.Position) { positions <- position }) }()
+	assert.Eventually(t, func() bool {
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		return a.orderCb != nil && a.fillCb != nil && a.balanceCb != nil && a.positionCb != nil &&
+			fs.subscribed("spot", listenKey) && fs.subscribed("futures", listenKey)
+	}, 2*time.Second, 5*time.Millisecond)
+
+	fs.push("spot", listenKey, "ws_execution_report.json")
+	order := <-orders
+	assert.Equal(t, model.Order{
+		Venue: Venue, InstId: "BTC-USDT", OrdId: "28", ClOrdId: "s1", Side: model.Buy, Type: model.PostOnly,
+		Status: model.StatusPartiallyFilled, Px: "60000.00000000", Sz: "0.01000000", AvgPx: "60000",
+		FilledSz: "0.00400000", CTime: 1718000000500, UTime: 1718000001000,
+	}, *order)
+	fill := <-fills
+	assert.Equal(t, "12345", fill.TradeId)
+	assert.Equal(t, "-0.00000400", fill.Fee)
+	assert.True(t, fill.Maker)
+
+	fs.push("futures", listenKey, "ws_order_trade_update.json")
+	order = <-orders
+	assert.Equal(t, "BTC-USDT-SWAP", order.InstId)
+	assert.Equal(t, model.IOC, order.Type)
+	fill = <-fills
+	assert.Equal(t, "67001.20", fill.Px)
+	assert.Equal(t, "0.004", fill.Sz)
+	assert.Equal(t, "-0.10720192", fill.Fee)
+	assert.False(t, fill.Maker)
+
+	fs.push("futures", listenKey, "ws_account_update.json")
+	balance := <-balances
+	assert.Equal(t, "122624.12345678", balance.Total)
+	position := <-positions
+	assert.Equal(t, "BTC-USDT-SWAP", position.InstId)
+	assert.Equal(t, "-0.014", position.Pos)
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	assert.Len(t, fs.conns, 2)
+}
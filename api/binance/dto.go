@@ -0,0 +1,272 @@
+package binance
+
+import "encoding/json"
+
+// 币安推送使用单字母字段且大小写含义不同 encoding/json匹配时忽略大小写
+// 因此声明某个字段时须同时声明与其仅大小写不同的字段 避免被覆盖
+
+type ExchangeInfo struct {
+	Symbols []SymbolInfo `json:"symbols"`
+}
+
+type SymbolInfo struct {
+	Symbol       string         `json:"symbol"`
+	Status       string         `json:"status"`
+	BaseAsset    string         `json:"baseAsset"`
+	QuoteAsset   string         `json:"quoteAsset"`
+	MarginAsset  string         `json:"marginAsset"`
+	ContractType string         `json:"contractType"` // 仅合约 PERPETUAL、CURRENT_QUARTER等
+	Filters      []SymbolFilter `json:"filters"`
+}
+
+type SymbolFilter struct {
+	FilterType string `json:"filterType"`
+	TickSize   string `json:"tickSize"`
+	StepSize   string `json:"stepSize"`
+	MinQty     string `json:"minQty"`
+}
+
+type Ticker24hr struct {
+	Symbol    string `json:"symbol"`
+	LastPrice string `json:"lastPrice"`
+	BidPrice  string `json:"bidPrice"`
+	BidQty    string `json:"bidQty"`
+	AskPrice  string `json:"askPrice"`
+	AskQty    string `json:"askQty"`
+	CloseTime int64  `json:"closeTime"`
+}
+
+type BookTicker struct {
+	Symbol   string `json:"symbol"`
+	BidPrice string `json:"bidPrice"`
+	BidQty   string `json:"bidQty"`
+	AskPrice string `json:"askPrice"`
+	AskQty   string `json:"askQty"`
+	Time     int64  `json:"time"`
+}
+
+type TickerPrice struct {
+	Symbol string `json:"symbol"`
+	Price  string `json:"price"`
+	Time   int64  `json:"time"`
+}
+
+type SpotAccount struct {
+	UpdateTime int64 `json:"updateTime"`
+	Balances   []struct {
+		Asset  string `json:"asset"`
+		Free   string `json:"free"`
+		Locked string `json:"locked"`
+	} `json:"balances"`
+}
+
+type FuturesBalance struct {
+	Asset            string `json:"asset"`
+	Balance          string `json:"balance"`
+	AvailableBalance string `json:"availableBalance"`
+	UpdateTime       int64  `json:"updateTime"`
+}
+
+type PositionRisk struct {
+	Symbol           string `json:"symbol"`
+	PositionAmt      string `json:"positionAmt"`
+	EntryPrice       string `json:"entryPrice"`
+	MarkPrice        string `json:"markPrice"`
+	UnRealizedProfit string `json:"unRealizedProfit"`
+	Leverage         string `json:"leverage"`
+	PositionSide     string `json:"positionSide"`
+	UpdateTime       int64  `json:"updateTime"`
+}
+
+// Order 下单、查询订单的响应 现货与合约字段取并集
+type Order struct {
+	Symbol              string `json:"symbol"`
+	OrderId             int64  `json:"orderId"`
+	ClientOrderId       string `json:"clientOrderId"`
+	Price               string `json:"price"`
+	OrigQty             string `json:"origQty"`
+	ExecutedQty         string `json:"executedQty"`
+	CummulativeQuoteQty string `json:"cummulativeQuoteQty"` // 仅现货
+	AvgPrice            string `json:"avgPrice"`            // 仅合约
+	Status              string `json:"status"`
+	TimeInForce         string `json:"timeInForce"`
+	Type                string `json:"type"`
+	Side                string `json:"side"`
+	PositionSide        string `json:"positionSide"`
+	Time                int64  `json:"time"`
+	TransactTime        int64  `json:"transactTime"`
+	UpdateTime          int64  `json:"updateTime"`
+}
+
+type ListenKey struct {
+	ListenKey string `json:"listenKey"`
+}
+
+// StreamEnvelope 组合流上的一帧 订阅响应带id 行情带stream
+type StreamEnvelope struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+	Id     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *APIError       `json:"error"`
+}
+
+// WsBookTicker 最优挂单推送 现货没有事件类型与时间字段
+type WsBookTicker struct {
+	Event     string `json:"e"`
+	EventTime int64  `json:"E"`
+	Symbol    string `json:"s"`
+	BidPrice  string `json:"b"`
+	BidQty    string `json:"B"`
+	AskPrice  string `json:"a"`
+	AskQty    string `json:"A"`
+	TradeTime int64  `json:"T"`
+}
+
+// WsDepth 有限档深度推送 现货为bids/asks 合约为b/a
+type WsDepth struct {
+	Event        string     `json:"e"`
+	EventTime    int64      `json:"E"`
+	TradeTime    int64      `json:"T"`
+	FirstId      int64      `json:"U"`
+	LastId       int64      `json:"u"`
+	LastUpdateId int64      `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+	B            [][]string `json:"b"`
+	A            [][]string `json:"a"`
+}
+
+// WsKline k线推送
+type WsKline struct {
+	Event     string `json:"e"`
+	EventTime int64  `json:"E"`
+	Symbol    string `json:"s"`
+	K         struct {
+		StartTime int64  `json:"t"`
+		CloseTime int64  `json:"T"`
+		Interval  string `json:"i"`
+		Open      string `json:"o"`
+		Close     string `json:"c"`
+		High      string `json:"h"`
+		Low       string `json:"l"`
+		Volume    string `json:"v"`
+		Closed    bool   `json:"x"`
+		// 大小写不同的字段需显式声明 否则会按大小写不敏感匹配覆盖上面的字段
+		LastTradeId int64  `json:"L"`
+		TakerVolume string `json:"V"`
+	} `json:"k"`
+}
+
+// WsMarkPrice 合约标记价格推送
+type WsMarkPrice struct {
+	Event       string `json:"e"`
+	EventTime   int64  `json:"E"`
+	Symbol      string `json:"s"`
+	MarkPrice   string `json:"p"`
+	SettlePrice string `json:"P"` // 预估结算价
+	IndexPrice  string `json:"i"`
+	FundingRate string `json:"r"`
+	FundingTime int64  `json:"T"`
+}
+
+type WsEvent struct {
+	Event     string `json:"e"`
+	EventTime int64  `json:"E"`
+}
+
+// WsExecutionReport 现货订单推送
+type WsExecutionReport struct {
+	Event             string `json:"e"`
+	EventTime         int64  `json:"E"`
+	Symbol            string `json:"s"`
+	Side              string `json:"S"`
+	ClientOrderId     string `json:"c"`
+	OrigClientOrderId string `json:"C"` // 撤单时为原始clientOrderId
+	Type              string `json:"o"`
+	CreateTime        int64  `json:"O"`
+	TimeInForce       string `json:"f"`
+	IcebergQty        string `json:"F"`
+	Qty               string `json:"q"`
+	QuoteOrderQty     string `json:"Q"`
+	Price             string `json:"p"`
+	StopPrice         string `json:"P"`
+	ExecType          string `json:"x"`
+	Status            string `json:"X"`
+	OrderId           int64  `json:"i"`
+	Ignore            int64  `json:"I"`
+	LastQty           string `json:"l"`
+	LastPrice         string `json:"L"`
+	CumQty            string `json:"z"`
+	CumQuoteQty       string `json:"Z"`
+	Commission        string `json:"n"`
+	CommissionAsset   string `json:"N"`
+	TradeId           int64  `json:"t"`
+	TradeTime         int64  `json:"T"`
+	Maker             bool   `json:"m"`
+	IgnoreM           bool   `json:"M"`
+}
+
+// WsOrderTradeUpdate 合约订单推送
+type WsOrderTradeUpdate struct {
+	Event     string `json:"e"`
+	EventTime int64  `json:"E"`
+	Time      int64  `json:"T"`
+	Order     struct {
+		Symbol          string `json:"s"`
+		Side            string `json:"S"`
+		ClientOrderId   string `json:"c"`
+		Type            string `json:"o"`
+		TimeInForce     string `json:"f"`
+		Qty             string `json:"q"`
+		Price           string `json:"p"`
+		AvgPrice        string `json:"ap"`
+		ActivatePrice   string `json:"AP"`
+		ExecType        string `json:"x"`
+		Status          string `json:"X"`
+		OrderId         int64  `json:"i"`
+		LastQty         string `json:"l"`
+		LastPrice       string `json:"L"`
+		CumQty          string `json:"z"`
+		Commission      string `json:"n"`
+		CommissionAsset string `json:"N"`
+		TradeId         int64  `json:"t"`
+		TradeTime       int64  `json:"T"`
+		Maker           bool   `json:"m"`
+		PositionSide    string `json:"ps"`
+	} `json:"o"`
+}
+
+// WsOutboundAccountPosition 现货余额推送
+type WsOutboundAccountPosition struct {
+	Event      string `json:"e"`
+	EventTime  int64  `json:"E"`
+	UpdateTime int64  `json:"u"`
+	Balances   []struct {
+		Asset  string `json:"a"`
+		Free   string `json:"f"`
+		Locked string `json:"l"`
+	} `json:"B"`
+}
+
+// WsAccountUpdate 合约余额与持仓推送
+type WsAccountUpdate struct {
+	Event     string `json:"e"`
+	EventTime int64  `json:"E"`
+	Time      int64  `json:"T"`
+	Account   struct {
+		Reason   string `json:"m"`
+		Balances []struct {
+			Asset         string `json:"a"`
+			WalletBalance string `json:"wb"`
+			CrossWallet   string `json:"cw"`
+		} `json:"B"`
+		Positions []struct {
+			Symbol       string `json:"s"`
+			PositionAmt  string `json:"pa"`
+			EntryPrice   string `json:"ep"`
+			Upl          string `json:"up"`
+			PositionSide string `json:"ps"`
+		} `json:"P"`
+	} `json:"a"`
+}
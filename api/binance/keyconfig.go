@@ -0,0 +1,29 @@
+package binance
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+type KeyConfig struct {
+	Apikey    string
+	Secretkey string
+}
+
+// MakeHeader 鉴权请求头 公共接口无需密钥时为空
+func (c KeyConfig) MakeHeader() http.Header {
+	header := http.Header{}
+	if c.Apikey != "" {
+		header.Set("X-MBX-APIKEY", c.Apikey)
+	}
+	return header
+}
+
+// MakeSign 对完整查询串做HMAC-SHA256签名
+func (c KeyConfig) MakeSign(query string) string {
+	hash := hmac.New(sha256.New, []byte(c.Secretkey))
+	hash.Write([]byte(query))
+	return hex.EncodeToString(hash.Sum(nil))
+}
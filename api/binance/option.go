@@ -0,0 +1,100 @@
+package binance
+
+import (
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// options 适配器构造参数 由Option修改
+type options struct {
+	env       common.Destination
+	keyConfig KeyConfig
+	endpoints *Endpoints
+	proxy     string
+	logger    *slog.Logger
+	markets   []Market
+	tracer    trace.Tracer
+}
+
+type Option func(o *options)
+
+// WithEnv 服务器环境 默认NormalServer 未知环境使用NormalServer
+func WithEnv(env common.Destination) Option {
+	return func(o *options) {
+		o.env = env
+	}
+}
+
+// WithKeyConfig 使用密钥签名
+func WithKeyConfig(keyConfig KeyConfig) Option {
+	return func(o *options) {
+		o.keyConfig = keyConfig
+	}
+}
+
+// WithEndpoints 覆盖全部REST与WS地址 此时忽略WithEnv
+func WithEndpoints(endpoints Endpoints) Option {
+	return func(o *options) {
+		o.endpoints = &endpoints
+	}
+}
+
+// WithProxy 代理地址 为空时使用环境变量
+func WithProxy(proxy string) Option {
+	return func(o *options) {
+		o.proxy = proxy
+	}
+}
+
+// WithSlog 结构化日志 为nil时使用slog.Default() 密钥与listenKey会被脱敏
+func WithSlog(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithLogger 兼容ILogger 等价于WithSlog(common.NewSlogLogger(logger))
+func WithLogger(logger common.ILogger) Option {
+	return func(o *options) {
+		o.logger = common.NewSlogLogger(logger)
+	}
+}
+
+// WithMarkets 启用的市场 默认现货与U本位合约
+func WithMarkets(markets ...Market) Option {
+	return func(o *options) {
+		o.markets = markets
+	}
+}
+
+// WithTracerProvider 为REST请求与WS订阅请求创建span
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = common.NewTracer(provider)
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		env:     common.NormalServer,
+		markets: []Market{SpotMarket, FuturesMarket},
+		tracer:  common.NewTracer(nil),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) resolveEndpoints() Endpoints {
+	if o.endpoints != nil {
+		return *o.endpoints
+	}
+	if endpoints, ok := DefaultEndpoints[o.env]; ok {
+		return endpoints
+	}
+	return DefaultEndpoints[common.NormalServer]
+}
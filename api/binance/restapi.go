@@ -0,0 +1,108 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// paths 现货与合约对应的接口路径
+var paths = map[Market]map[string]string{
+	SpotMarket: {
+		"exchangeInfo": "/api/v3/exchangeInfo",
+		"order":        "/api/v3/order",
+		"openOrders":   "/api/v3/openOrders",
+		"listenKey":    "/api/v3/userDataStream",
+	},
+	FuturesMarket: {
+		"exchangeInfo": "/fapi/v1/exchangeInfo",
+		"order":        "/fapi/v1/order",
+		"openOrders":   "/fapi/v1/openOrders",
+		"listenKey":    "/fapi/v1/listenKey",
+	},
+}
+
+// ExchangeInfo 交易规则与交易对
+func (c *RestClient) ExchangeInfo(ctx context.Context, market Market) (*ExchangeInfo, error) {
+	return Do[ExchangeInfo](c, ctx, market, http.MethodGet, paths[market]["exchangeInfo"], nil, false)
+}
+
+// Ticker24hr 现货24小时行情 含最新价与最优挂单
+func (c *RestClient) Ticker24hr(ctx context.Context, symbol string) (*Ticker24hr, error) {
+	return Do[Ticker24hr](c, ctx, SpotMarket, http.MethodGet, "/api/v3/ticker/24hr", url.Values{"symbol": {symbol}}, false)
+}
+
+// BookTicker 合约最优挂单
+func (c *RestClient) BookTicker(ctx context.Context, symbol string) (*BookTicker, error) {
+	return Do[BookTicker](c, ctx, FuturesMarket, http.MethodGet, "/fapi/v1/ticker/bookTicker", url.Values{"symbol": {symbol}}, false)
+}
+
+// TickerPrice 合约最新价
+func (c *RestClient) TickerPrice(ctx context.Context, symbol string) (*TickerPrice, error) {
+	return Do[TickerPrice](c, ctx, FuturesMarket, http.MethodGet, "/fapi/v1/ticker/price", url.Values{"symbol": {symbol}}, false)
+}
+
+// SpotAccount 现货账户余额
+func (c *RestClient) SpotAccount(ctx context.Context) (*SpotAccount, error) {
+	return Do[SpotAccount](c, ctx, SpotMarket, http.MethodGet, "/api/v3/account", url.Values{"omitZeroBalances": {"true"}}, true)
+}
+
+// FuturesBalance 合约账户余额
+func (c *RestClient) FuturesBalance(ctx context.Context) (*[]FuturesBalance, error) {
+	return Do[[]FuturesBalance](c, ctx, FuturesMarket, http.MethodGet, "/fapi/v2/balance", nil, true)
+}
+
+// PositionRisk 合约持仓 symbol为空时返回全部
+func (c *RestClient) PositionRisk(ctx context.Context, symbol string) (*[]PositionRisk, error) {
+	params := url.Values{}
+	if symbol != "" {
+		params.Set("symbol", symbol)
+	}
+	return Do[[]PositionRisk](c, ctx, FuturesMarket, http.MethodGet, "/fapi/v2/positionRisk", params, true)
+}
+
+// NewOrder 下单 params为币安原始参数 响应类型固定为RESULT
+func (c *RestClient) NewOrder(ctx context.Context, market Market, params url.Values) (*Order, error) {
+	params.Set("newOrderRespType", "RESULT")
+	return Do[Order](c, ctx, market, http.MethodPost, paths[market]["order"], params, true)
+}
+
+// CancelOrder 按clientOrderId撤单
+func (c *RestClient) CancelOrder(ctx context.Context, market Market, symbol, clientOrderId string) (*Order, error) {
+	return Do[Order](c, ctx, market, http.MethodDelete, paths[market]["order"], url.Values{
+		"symbol":            {symbol},
+		"origClientOrderId": {clientOrderId},
+	}, true)
+}
+
+// QueryOrder 按clientOrderId查询订单
+func (c *RestClient) QueryOrder(ctx context.Context, market Market, symbol, clientOrderId string) (*Order, error) {
+	return Do[Order](c, ctx, market, http.MethodGet, paths[market]["order"], url.Values{
+		"symbol":            {symbol},
+		"origClientOrderId": {clientOrderId},
+	}, true)
+}
+
+// OpenOrders 当前挂单 symbol为空时返回全部
+func (c *RestClient) OpenOrders(ctx context.Context, market Market, symbol string) (*[]Order, error) {
+	params := url.Values{}
+	if symbol != "" {
+		params.Set("symbol", symbol)
+	}
+	return Do[[]Order](c, ctx, market, http.MethodGet, paths[market]["openOrders"], params, true)
+}
+
+// NewListenKey 创建用户数据流
+func (c *RestClient) NewListenKey(ctx context.Context, market Market) (*ListenKey, error) {
+	return Do[ListenKey](c, ctx, market, http.MethodPost, paths[market]["listenKey"], nil, false)
+}
+
+// KeepaliveListenKey 延长用户数据流有效期 需每30分钟调用一次
+func (c *RestClient) KeepaliveListenKey(ctx context.Context, market Market, listenKey string) error {
+	params := url.Values{}
+	if market == SpotMarket {
+		params.Set("listenKey", listenKey)
+	}
+	_, err := Do[struct{}](c, ctx, market, http.MethodPut, paths[market]["listenKey"], params, false)
+	return err
+}
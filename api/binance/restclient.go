@@ -0,0 +1,172 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// Market 币安的不同交易市场 接口域名与路径各不相同
+type Market string
+
+const (
+	SpotMarket    Market = "spot"
+	FuturesMarket Market = "usdm" // U本位合约
+)
+
+// Endpoints 各市场的REST与组合流地址
+type Endpoints struct {
+	SpotRest    common.BaseURL
+	FuturesRest common.BaseURL
+	SpotWs      common.BaseURL
+	FuturesWs   common.BaseURL
+}
+
+var (
+	DefaultEndpoints = map[common.Destination]Endpoints{
+		common.NormalServer: {
+			SpotRest:    "https://api.binance.com",
+			FuturesRest: "https://fapi.binance.com",
+			SpotWs:      "wss://stream.binance.com:9443/stream",
+			FuturesWs:   "wss://fstream.binance.com/stream",
+		},
+		common.TestServer: {
+			SpotRest:    "https://testnet.binance.vision",
+			FuturesRest: "https://testnet.binancefuture.com",
+			SpotWs:      "wss://testnet.binance.vision/stream",
+			FuturesWs:   "wss://stream.binancefuture.com/stream",
+		},
+	}
+)
+
+// APIError 接口返回的错误
+type APIError struct {
+	Status int    `json:"-"`
+	Code   int    `json:"code"`
+	Msg    string `json:"msg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("binance: status %d code %d %s", e.Status, e.Code, e.Msg)
+}
+
+type RestClient struct {
+	endpoints  Endpoints
+	client     *http.Client
+	keyConfig  KeyConfig
+	RecvWindow time.Duration // 签名请求的有效时间窗口
+	Tracer     trace.Tracer
+	closed     atomic.Bool
+}
+
+// ErrClientClosed 已调用Close
+var ErrClientClosed = errors.New("binance: client closed")
+
+// Close 释放空闲连接 之后的请求返回ErrClientClosed
+func (c *RestClient) Close() {
+	c.closed.Store(true)
+	c.client.CloseIdleConnections()
+}
+
+func NewRestClient(ctx context.Context, keyConfig KeyConfig, env common.Destination, proxy ...string) (*RestClient, error) {
+	endpoints, ok := DefaultEndpoints[env]
+	if !ok {
+		endpoints = DefaultEndpoints[common.NormalServer]
+	}
+	return NewRestClientWithCustom(ctx, keyConfig, endpoints, proxy...)
+}
+
+func NewRestClientWithCustom(ctx context.Context, keyConfig KeyConfig, endpoints Endpoints, proxy ...string) (*RestClient, error) {
+	proxyURL := http.ProxyFromEnvironment
+	if len(proxy) != 0 && proxy[0] != "" {
+		parse, err := url.Parse(proxy[0])
+		if err != nil {
+			return nil, fmt.Errorf("binance: invalid proxy: %w", err)
+		}
+		proxyURL = http.ProxyURL(parse)
+	}
+	return &RestClient{
+		endpoints:  endpoints,
+		keyConfig:  keyConfig,
+		RecvWindow: 5 * time.Second,
+		Tracer:     common.NewTracer(nil),
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy: proxyURL,
+			},
+			Timeout: 30 * time.Second,
+		}}, nil
+}
+
+func (c *RestClient) baseUrl(market Market) common.BaseURL {
+	if market == FuturesMarket {
+		return c.endpoints.FuturesRest
+	}
+	return c.endpoints.SpotRest
+}
+
+// Do 发送请求 signed为true时追加timestamp、recvWindow与签名 参数统一放在查询串中
+func Do[T any](c *RestClient, ctx context.Context, market Market, method, path string, params url.Values, signed bool) (t *T, err error) {
+	if c.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	ctx, span := c.Tracer.Start(ctx, "binance.rest "+path, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", method),
+			attribute.String("binance.endpoint", path),
+			attribute.String("binance.market", string(market))))
+	defer func() {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			span.SetAttributes(attribute.Int("binance.code", apiErr.Code))
+		}
+		common.EndSpan(span, err)
+	}()
+	if params == nil {
+		params = url.Values{}
+	}
+	query := params.Encode()
+	if signed {
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+		params.Set("recvWindow", strconv.FormatInt(c.RecvWindow.Milliseconds(), 10))
+		query = params.Encode()
+		query += "&signature=" + c.keyConfig.MakeSign(query)
+	}
+	uri := string(c.baseUrl(market)) + path
+	if query != "" {
+		uri += "?" + query
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.keyConfig.MakeHeader()
+	rp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", rp.StatusCode))
+	bs, err := io.ReadAll(rp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if rp.StatusCode != http.StatusOK {
+		apiErr := &APIError{Status: rp.StatusCode, Msg: string(bs)}
+		_ = json.Unmarshal(bs, apiErr)
+		return nil, apiErr
+	}
+	return common.Unmarshal[T](bs)
+}
@@ -0,0 +1,234 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+// ErrStreamClosed 已调用Close
+var ErrStreamClosed = errors.New("binance: stream closed")
+
+// StreamClient 组合流连接 通过SUBSCRIBE动态订阅并按流名称分发
+type StreamClient struct {
+	ctx         context.Context
+	url         common.BaseURL
+	proxy       func(req *http.Request) (*url.URL, error)
+	Logger      *slog.Logger // 为nil时使用slog.Default() 逐条消息的日志为Debug级别
+	ReadMonitor func(arg common.Arg)
+	Tracer      trace.Tracer
+	locker      sync.RWMutex
+	conn        *ws.Conn
+	reqId       atomic.Uint64
+	callbacks   map[string]func(env *StreamEnvelope)
+	dialOpts    []ws.Option
+	closed      bool
+}
+
+func NewStreamClient(ctx context.Context, url common.BaseURL, proxy func(req *http.Request) (*url.URL, error)) *StreamClient {
+	return &StreamClient{
+		ctx:         ctx,
+		url:         url,
+		proxy:       proxy,
+		ReadMonitor: func(arg common.Arg) {},
+		Tracer:      common.NewTracer(nil),
+		callbacks:   map[string]func(env *StreamEnvelope){},
+	}
+}
+
+// Subscribe 订阅流并阻塞至ctx结束或连接断开 返回时取消订阅
+func (s *StreamClient) Subscribe(ctx context.Context, stream string, callback func(data json.RawMessage)) error {
+	conn, err := s.checkConn()
+	if err != nil {
+		return err
+	}
+	// 注册监听 需先于发送避免丢失推送
+	s.registerWatch(stream, func(env *StreamEnvelope) {
+		callback(env.Data)
+	})
+	defer s.unregisterWatch(stream)
+	if err := s.request(ctx, conn, "SUBSCRIBE", stream); err != nil {
+		return err
+	}
+	defer func() {
+		_ = s.send(conn, map[string]any{"method": "UNSUBSCRIBE", "params": []string{stream}, "id": s.reqId.Add(1)})
+	}()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-conn.Context().Done():
+		return context.Cause(conn.Context())
+	}
+}
+
+// Alive 连接是否存活 不会触发重新拨号
+func (s *StreamClient) Alive() bool {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+
+	return s.isAlive()
+}
+
+// Close 关闭当前连接 之后的订阅返回ErrStreamClosed
+func (s *StreamClient) Close() {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	s.closed = true
+	if s.conn != nil {
+		s.conn.Close(ErrStreamClosed)
+	}
+}
+
+// SetRecorder 录制之后建立的连接上收发的全部帧
+func (s *StreamClient) SetRecorder(recorder ws.Recorder) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	s.dialOpts = append(s.dialOpts, ws.WithRecorder(recorder))
+}
+
+// request 发送带id的方法调用并等待结果
+func (s *StreamClient) request(ctx context.Context, conn *ws.Conn, method string, params ...string) (err error) {
+	id := s.reqId.Add(1)
+	_, span := s.Tracer.Start(ctx, "binance.ws "+method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("ws.method", method),
+			attribute.StringSlice("ws.params", params),
+			attribute.Int64("ws.id", int64(id))))
+	defer func() { common.EndSpan(span, err) }()
+	key := "id:" + strconv.FormatUint(id, 10)
+	respCh := make(chan *StreamEnvelope, 1)
+	s.registerWatch(key, func(env *StreamEnvelope) {
+		select {
+		case respCh <- env:
+		default:
+		}
+	})
+	defer s.unregisterWatch(key)
+	if err := s.send(conn, map[string]any{"method": method, "params": params, "id": id}); err != nil {
+		return err
+	}
+	select {
+	case env := <-respCh:
+		if env.Error != nil {
+			return env.Error
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-conn.Context().Done():
+		return context.Cause(conn.Context())
+	}
+}
+
+func (s *StreamClient) logger() *slog.Logger {
+	return common.ResolveLogger(s.Logger, nil)
+}
+
+func (s *StreamClient) send(conn *ws.Conn, data any) error {
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if l := s.logger(); l.Enabled(s.ctx, slog.LevelDebug) {
+		l.Debug("binance send", "connId", conn.Id(), "payload", common.RedactJSON(bs))
+	}
+	return conn.Write(bs)
+}
+
+// receive 处理连接上的数据 ch需在启动前注册以免丢失数据
+func (s *StreamClient) receive(conn *ws.Conn, ch <-chan ws.Data) {
+	defer conn.UnregisterWatch("receive")
+	for {
+		select {
+		case <-conn.Context().Done():
+			return
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			if data.Typ != websocket.TextMessage {
+				continue
+			}
+			env := &StreamEnvelope{}
+			if err := json.Unmarshal(data.Data, env); err != nil {
+				s.logger().Error("binance decode", "connId", conn.Id(), "err", err, "data", common.RedactJSON(data.Data))
+				continue
+			}
+			key := env.Stream
+			if env.Id != nil {
+				key = "id:" + strconv.FormatUint(*env.Id, 10)
+			} else {
+				s.ReadMonitor(common.Arg{Channel: env.Stream})
+			}
+			if callback, ok := s.getWatch(key); ok {
+				callback(env)
+			}
+		}
+	}
+}
+
+func (s *StreamClient) isAlive() bool {
+	return s.conn != nil &&
+		s.conn.Alive() &&
+		s.conn.Context().Err() == nil
+}
+
+// checkConn 连接不健康时重新拨号
+func (s *StreamClient) checkConn() (*ws.Conn, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.closed {
+		return nil, ErrStreamClosed
+	}
+	if s.isAlive() {
+		return s.conn, nil
+	}
+	// 服务端发送ping帧 由底层自动回复pong 数据帧均需分发
+	opts := []ws.Option{ws.WithProxy(s.proxy), ws.WithKeepAlive(
+		func(conn *ws.Conn) error { return nil },
+		func(data []byte) bool { return false })}
+	conn, err := ws.DialContext(s.ctx, string(s.url), append(opts, s.dialOpts...)...)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	go s.receive(conn, conn.RegisterWatch("receive"))
+	return conn, nil
+}
+
+func (s *StreamClient) registerWatch(key string, callback func(env *StreamEnvelope)) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	s.callbacks[key] = callback
+}
+
+func (s *StreamClient) unregisterWatch(key string) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	delete(s.callbacks, key)
+}
+
+func (s *StreamClient) getWatch(key string) (func(env *StreamEnvelope), bool) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+
+	f, ok := s.callbacks[key]
+	return f, ok
+}
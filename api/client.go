@@ -3,11 +3,43 @@ package api
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
+	"github.com/kurosann/aqt-sdk/api/binance"
 	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/model"
 	"github.com/kurosann/aqt-sdk/api/okx"
 )
 
+// Client 与交易所无关的客户端 产品统一使用model中的标识
+// 订阅类方法阻塞至ctx结束或连接断开 返回时取消订阅
+type Client interface {
+	common.ClientBase
+	Name() string
+	Close()
+
+	GetInstruments(ctx context.Context, typ model.InstType) ([]model.Instrument, error)
+	GetTicker(ctx context.Context, instId string) (*model.Ticker, error)
+	GetBalances(ctx context.Context) ([]model.Balance, error)
+	GetPositions(ctx context.Context) ([]model.Position, error)
+	PlaceOrder(ctx context.Context, req model.OrderRequest) (*model.Order, error)
+	CancelOrder(ctx context.Context, instId, clOrdId string) error
+	GetOrder(ctx context.Context, instId, clOrdId string) (*model.Order, error)
+	GetOpenOrders(ctx context.Context, instId string) ([]model.Order, error)
+
+	Tickers(ctx context.Context, instId string, callback func(ticker *model.Ticker)) error
+	Candles(ctx context.Context, instId, bar string, callback func(candle *model.Candle)) error
+	MarkPrice(ctx context.Context, instId string, callback func(mp *model.MarkPrice)) error
+	Books(ctx context.Context, instId string, callback func(book *model.BookUpdate)) error
+	Orders(ctx context.Context, callback func(order *model.Order)) error
+	Fills(ctx context.Context, callback func(fill *model.Fill)) error
+	Account(ctx context.Context, callback func(balance *model.Balance)) error
+	Positions(ctx context.Context, callback func(position *model.Position)) error
+}
+
+// ExClient OKX推送格式的客户端
+//
+// Deprecated: 使用Client Candle与MarkPrice由Client.Candles与Client.MarkPrice替代 SpotOrders由Client.Orders替代
 type ExClient interface {
 	common.ClientBase
 	Account(ctx context.Context, callback func(resp *common.WsResp[*common.Balance])) error
@@ -21,19 +53,96 @@ type ExClient interface {
 }
 
 var (
-	clientMap = map[string]func(ctx context.Context, keyConfig common.IKeyConfig, env common.Destination, proxy ...string) ExClient{
-		"okx": func(ctx context.Context, keyConfig common.IKeyConfig, env common.Destination, proxy ...string) ExClient {
-			return okx.NewWsClient(ctx, keyConfig, env, proxy...)
-		},
-	}
+	_ Client   = (*okx.Adapter)(nil)
+	_ Client   = (*binance.Adapter)(nil)
+	_ ExClient = (*okx.ExchangeClient)(nil)
 )
 
-// NewClient no concurrent safe
+// slogSetter 支持结构化日志的客户端
+type slogSetter interface {
+	SetLogger(logger *slog.Logger)
+}
+
+// Credentials 交易所密钥 Passphrase仅部分交易所需要
+type Credentials struct {
+	Apikey     string
+	Secretkey  string
+	Passphrase string
+}
+
+// 内置交易所
+func init() {
+	MustRegister(okx.Venue, Factory{
+		New: func(ctx context.Context, opts Options) (Client, error) {
+			keyConfig := okx.KeyConfig{Apikey: opts.Credentials.Apikey, Secretkey: opts.Credentials.Secretkey, Passphrase: opts.Credentials.Passphrase}
+			client, err := okx.NewAdapter(ctx, okx.WithKeyConfig(keyConfig), okx.WithEnv(opts.Env), okx.WithProxy(opts.Proxy),
+				okx.WithTracerProvider(opts.Tracer))
+			if err != nil {
+				return nil, err
+			}
+			return client, nil
+		},
+		Capabilities: Capabilities{
+			InstTypes:  []model.InstType{model.Spot, model.Swap, model.Futures, model.Option},
+			OrderTypes: []model.OrderType{model.Market, model.Limit, model.PostOnly, model.IOC, model.FOK},
+			Channels:   AllChannels,
+		},
+	})
+	MustRegister(binance.Venue, Factory{
+		New: func(ctx context.Context, opts Options) (Client, error) {
+			keyConfig := binance.KeyConfig{Apikey: opts.Credentials.Apikey, Secretkey: opts.Credentials.Secretkey}
+			client, err := binance.NewAdapter(ctx, binance.WithKeyConfig(keyConfig), binance.WithEnv(opts.Env), binance.WithProxy(opts.Proxy),
+				binance.WithTracerProvider(opts.Tracer))
+			if err != nil {
+				return nil, err
+			}
+			return client, nil
+		},
+		Capabilities: Capabilities{
+			InstTypes:  []model.InstType{model.Spot, model.Swap, model.Futures},
+			OrderTypes: []model.OrderType{model.Market, model.Limit, model.PostOnly, model.IOC, model.FOK},
+			Channels:   AllChannels,
+			// 现货没有标记价格
+			ChannelInstTypes: map[Channel][]model.InstType{
+				ChannelMarkPrice: {model.Swap, model.Futures},
+			},
+		},
+	})
+}
+
+// NewClient 创建OKX推送格式的客户端 仅支持okx
+//
+// Deprecated: 使用New
 func NewClient(ctx context.Context, plm string, keyConfig common.IKeyConfig, env common.Destination, proxy ...string) (ExClient, error) {
-	f, ok := clientMap[plm]
-	if !ok {
-		return nil, fmt.Errorf("no support %s", plm)
+	if plm != okx.Venue {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownVenue, plm)
+	}
+	return okx.NewWsClient(ctx, keyConfig, env, proxy...), nil
+}
+
+// New 创建已注册交易所的客户端 并发安全
+func New(ctx context.Context, venue string, opts ...Option) (Client, error) {
+	factory, err := lookup(venue)
+	if err != nil {
+		return nil, err
+	}
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	client, err := factory.New(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case o.Slog != nil:
+		if c, ok := client.(slogSetter); ok {
+			c.SetLogger(o.Slog)
+		} else {
+			client.SetLog(common.SlogLogger{Logger: o.Slog})
+		}
+	case o.Logger != nil:
+		client.SetLog(o.Logger)
 	}
-	client := f(ctx, keyConfig, env, proxy...)
 	return client, nil
 }
@@ -100,62 +100,96 @@ type UnSubChannel struct {
 	InstId  string `json:"instId"`
 }
 type Arg struct {
-	Channel  string `json:"channel,omitempty"`
-	InstId   string `json:"instId,omitempty"`
-	InstType string `json:"instType,omitempty"`
-	SprdId   string `json:"sprdId,omitempty"`
+	Channel    string `json:"channel,omitempty"`
+	InstId     string `json:"instId,omitempty"`
+	InstType   string `json:"instType,omitempty"`
+	SprdId     string `json:"sprdId,omitempty"`
+	InstFamily string `json:"instFamily,omitempty"`
+	Ccy        string `json:"ccy,omitempty"`
 }
 
 func (a Arg) Key() string {
-	return strings.Join([]string{a.Channel, a.InstId, a.InstType, a.SprdId}, "-")
+	return strings.Join([]string{a.Channel, a.InstId, a.InstType, a.SprdId, a.InstFamily, a.Ccy}, "-")
+}
+
+// appendKey 与Key相同 追加到b中 用于热路径避免分配
+func (a *Arg) appendKey(b []byte) []byte {
+	b = append(b, a.Channel...)
+	b = append(b, '-')
+	b = append(b, a.InstId...)
+	b = append(b, '-')
+	b = append(b, a.InstType...)
+	b = append(b, '-')
+	b = append(b, a.SprdId...)
+	b = append(b, '-')
+	b = append(b, a.InstFamily...)
+	b = append(b, '-')
+	return append(b, a.Ccy...)
 }
 
 type Op struct {
+	Id   string `json:"id,omitempty"`
 	Op   string `json:"op"`
 	Args any    `json:"args"`
 }
 type WsOriginResp struct {
+	Id     string     `json:"id"`
+	Op     string     `json:"op"`
 	Event  string     `json:"event"`
 	ConnId string     `json:"connId"`
 	Code   string     `json:"code"`
 	Msg    string     `json:"msg"`
 	Arg    Arg        `json:"arg"`
+	Action string     `json:"action,omitempty"` // 深度频道的snapshot或update
 	Data   RawMessage `json:"data"`
 }
 type WsResp[T any] struct {
+	Id     string `json:"id"`
+	Op     string `json:"op"`
 	Event  string `json:"event"`
 	ConnId string `json:"connId"`
 	Code   string `json:"code"`
 	Msg    string `json:"msg"`
 	Arg    Arg    `json:"arg"`
+	Action string `json:"action,omitempty"`
 	Data   []T    `json:"data"`
 }
 type PlaceOrderReq struct {
-	InstID     string `json:"instId"`
-	Ccy        string `json:"ccy,omitempty"`
-	ClOrdID    string `json:"clOrdId,omitempty"`
-	Tag        string `json:"tag,omitempty"`
-	ReduceOnly bool   `json:"reduceOnly,omitempty"`
-	Sz         string `json:"sz,omitempty"`
-	Px         string `json:"px,omitempty"`
-	TdMode     string `json:"tdMode"`
-	Side       string `json:"side"`
-	PosSide    string `json:"posSide,omitempty"`
-	OrdType    string `json:"ordType"`
-	TgtCcy     string `json:"tgtCcy,omitempty"`
+	InstID     string `json:"instId" url:"instId,omitempty"`
+	Ccy        string `json:"ccy,omitempty" url:"ccy,omitempty"`
+	ClOrdID    string `json:"clOrdId,omitempty" url:"clOrdId,omitempty"`
+	Tag        string `json:"tag,omitempty" url:"tag,omitempty"`
+	ReduceOnly bool   `json:"reduceOnly,omitempty" url:"reduceOnly,omitempty"`
+	Sz         string `json:"sz,omitempty" url:"sz,omitempty"`
+	Px         string `json:"px,omitempty" url:"px,omitempty"`
+	TdMode     string `json:"tdMode" url:"tdMode,omitempty"`
+	Side       string `json:"side" url:"side,omitempty"`
+	PosSide    string `json:"posSide,omitempty" url:"posSide,omitempty"`
+	OrdType    string `json:"ordType" url:"ordType,omitempty"`
+	TgtCcy     string `json:"tgtCcy,omitempty" url:"tgtCcy,omitempty"`
+}
+type OrdersPendingReq struct {
+	InstType string `json:"instType,omitempty" url:"instType,omitempty"`
+	Uly      string `json:"uly,omitempty" url:"uly,omitempty"`
+	InstId   string `json:"instId,omitempty" url:"instId,omitempty"`
+	OrdType  string `json:"ordType,omitempty" url:"ordType,omitempty"`
+	State    string `json:"state,omitempty" url:"state,omitempty"`
+	After    string `json:"after,omitempty" url:"after,omitempty"`
+	Before   string `json:"before,omitempty" url:"before,omitempty"`
+	Limit    string `json:"limit,omitempty" url:"limit,omitempty"`
 }
 type InstrumentsReq struct {
-	InstType   string `json:"instType"`
-	Uly        string `json:"uly"`
-	InstFamily string `json:"instFamily"`
-	InstId     string `json:"instId"`
+	InstType   string `json:"instType" url:"instType,omitempty"`
+	Uly        string `json:"uly" url:"uly,omitempty"`
+	InstFamily string `json:"instFamily" url:"instFamily,omitempty"`
+	InstId     string `json:"instId" url:"instId,omitempty"`
 }
 type MarkPriceCandlesReq struct {
-	InstID string `json:"instId"`
-	After  int64  `json:"after,omitempty,string"`
-	Before int64  `json:"before,omitempty,string"`
-	Limit  int64  `json:"limit,omitempty,string"`
-	Bar    string `json:"bar,omitempty"`
+	InstID string `json:"instId" url:"instId,omitempty"`
+	After  int64  `json:"after,omitempty,string" url:"after,omitempty"`
+	Before int64  `json:"before,omitempty,string" url:"before,omitempty"`
+	Limit  int64  `json:"limit,omitempty,string" url:"limit,omitempty"`
+	Bar    string `json:"bar,omitempty" url:"bar,omitempty"`
 }
 
 type Resp[T any] struct {
@@ -170,12 +204,30 @@ func MakeArg(channel, instId string) *Arg {
 		InstId:  instId,
 	}
 }
+func MakeInstTypeArg(channel, instType string) *Arg {
+	return &Arg{
+		Channel:  channel,
+		InstType: instType,
+	}
+}
 func MakeSprdArg(channel, sprdId string) *Arg {
 	return &Arg{
 		Channel: channel,
 		SprdId:  sprdId,
 	}
 }
+func MakeInstFamilyArg(channel, instFamily string) *Arg {
+	return &Arg{
+		Channel:    channel,
+		InstFamily: instFamily,
+	}
+}
+func MakeCcyArg(channel, ccy string) *Arg {
+	return &Arg{
+		Channel: channel,
+		Ccy:     ccy,
+	}
+}
 
 type PlaceOrder struct {
 	ClOrdId string `json:"clOrdId"`
@@ -197,8 +249,11 @@ type Order struct {
 	Category           string        `json:"category"`
 	Ccy                string        `json:"ccy"`
 	ClOrdId            string        `json:"clOrdId"`
+	ExecType           string        `json:"execType"`
 	Fee                string        `json:"fee"`
 	FeeCcy             string        `json:"feeCcy"`
+	FillFee            string        `json:"fillFee"`
+	FillFeeCcy         string        `json:"fillFeeCcy"`
 	FillPx             string        `json:"fillPx"`
 	FillSz             string        `json:"fillSz"`
 	FillTime           string        `json:"fillTime"`
@@ -251,6 +306,11 @@ type Candle struct {
 	Confirm     string `json:"confirm"`
 }
 
+// MarshalJSON 与推送格式一致编码为数组
+func (p Candle) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{p.Ts, p.O, p.H, p.L, p.C, p.Vol, p.VolCcy, p.VolCcyQuote, p.Confirm})
+}
+
 func (p *Candle) UnmarshalJSON(bytes []byte) (err error) {
 	str, err := unmarshalSliceString(bytes)
 	if err != nil {
@@ -308,11 +368,11 @@ type Instruments struct {
 }
 
 type TakerVolumeReq struct {
-	Ccy      string `json:"ccy"`
-	InstType string `json:"instType"`
-	Begin    string `json:"begin"`
-	End      string `json:"end"`
-	Period   string `json:"period"`
+	Ccy      string `json:"ccy" url:"ccy,omitempty"`
+	InstType string `json:"instType" url:"instType,omitempty"`
+	Begin    string `json:"begin" url:"begin,omitempty"`
+	End      string `json:"end" url:"end,omitempty"`
+	Period   string `json:"period" url:"period,omitempty"`
 }
 type TakerVolume struct {
 	Ts      string `json:"ts"`
@@ -321,11 +381,11 @@ type TakerVolume struct {
 }
 
 type CandlesticksReq struct {
-	InstID string `json:"instId"`
-	After  int64  `json:"after,omitempty,string"`
-	Before int64  `json:"before,omitempty,string"`
-	Limit  int64  `json:"limit,omitempty,string"`
-	Bar    string `json:"bar,omitempty"`
+	InstID string `json:"instId" url:"instId,omitempty"`
+	After  int64  `json:"after,omitempty,string" url:"after,omitempty"`
+	Before int64  `json:"before,omitempty,string" url:"before,omitempty"`
+	Limit  int64  `json:"limit,omitempty,string" url:"limit,omitempty"`
+	Bar    string `json:"bar,omitempty" url:"bar,omitempty"`
 }
 
 func (t *TakerVolume) UnmarshalJSON(bytes []byte) (err error) {
@@ -396,22 +456,136 @@ type MarkPrice struct {
 	MarkPx   string `json:"markPx"`
 	Ts       string `json:"ts"`
 }
+type FundingRate struct {
+	InstType        string `json:"instType"`
+	InstId          string `json:"instId"`
+	Method          string `json:"method"` // current_period或next_period
+	FundingRate     string `json:"fundingRate"`
+	FundingTime     string `json:"fundingTime"`
+	NextFundingRate string `json:"nextFundingRate"`
+	NextFundingTime string `json:"nextFundingTime"`
+	MinFundingRate  string `json:"minFundingRate"`
+	MaxFundingRate  string `json:"maxFundingRate"`
+	SettState       string `json:"settState"`
+	SettFundingRate string `json:"settFundingRate"`
+	Premium         string `json:"premium"`
+	Ts              string `json:"ts"`
+}
+type IndexTicker struct {
+	InstId  string `json:"instId"`
+	IdxPx   string `json:"idxPx"`
+	High24h string `json:"high24h"`
+	Low24h  string `json:"low24h"`
+	Open24h string `json:"open24h"`
+	SodUtc0 string `json:"sodUtc0"`
+	SodUtc8 string `json:"sodUtc8"`
+	Ts      string `json:"ts"`
+}
+type MarkPriceReq struct {
+	InstType   string `json:"instType" url:"instType,omitempty"`
+	Uly        string `json:"uly" url:"uly,omitempty"`
+	InstFamily string `json:"instFamily" url:"instFamily,omitempty"`
+	InstId     string `json:"instId" url:"instId,omitempty"`
+}
+type TickersReq struct {
+	InstType   string `json:"instType" url:"instType,omitempty"`
+	Uly        string `json:"uly" url:"uly,omitempty"`
+	InstFamily string `json:"instFamily" url:"instFamily,omitempty"`
+}
+type IndexTickersReq struct {
+	QuoteCcy string `json:"quoteCcy" url:"quoteCcy,omitempty"`
+	InstId   string `json:"instId" url:"instId,omitempty"`
+}
+type OptSummaryReq struct {
+	Uly        string `json:"uly" url:"uly,omitempty"`
+	InstFamily string `json:"instFamily" url:"instFamily,omitempty"`
+	ExpTime    string `json:"expTime" url:"expTime,omitempty"` // 到期日 格式为YYMMDD
+}
+
+// OptSummary 期权定价 无后缀的希腊字母以币计价 BS后缀为Black-Scholes模型以美元计价
+type OptSummary struct {
+	InstType string `json:"instType"`
+	InstId   string `json:"instId"`
+	Uly      string `json:"uly"`
+	Delta    string `json:"delta"`
+	Gamma    string `json:"gamma"`
+	Vega     string `json:"vega"`
+	Theta    string `json:"theta"`
+	DeltaBS  string `json:"deltaBS"`
+	GammaBS  string `json:"gammaBS"`
+	VegaBS   string `json:"vegaBS"`
+	ThetaBS  string `json:"thetaBS"`
+	Lever    string `json:"lever"`
+	MarkVol  string `json:"markVol"`
+	BidVol   string `json:"bidVol"`
+	AskVol   string `json:"askVol"`
+	RealVol  string `json:"realVol"`
+	VolLv    string `json:"volLv"` // 平值隐含波动率
+	FwdPx    string `json:"fwdPx"` // 远期价格
+	Ts       string `json:"ts"`
+}
+
+// AccountGreeks 账户希腊字母 BS后缀以美元计价 PA后缀以币计价
+type AccountGreeks struct {
+	Ccy     string `json:"ccy"`
+	DeltaBS string `json:"deltaBS"`
+	DeltaPA string `json:"deltaPA"`
+	GammaBS string `json:"gammaBS"`
+	GammaPA string `json:"gammaPA"`
+	ThetaBS string `json:"thetaBS"`
+	ThetaPA string `json:"thetaPA"`
+	VegaBS  string `json:"vegaBS"`
+	VegaPA  string `json:"vegaPA"`
+	Ts      string `json:"ts"`
+}
+type OpenInterest struct {
+	InstType string `json:"instType"`
+	InstId   string `json:"instId"`
+	Oi       string `json:"oi"`    // 持仓量 张
+	OiCcy    string `json:"oiCcy"` // 持仓量 币
+	OiUsd    string `json:"oiUsd"`
+	Ts       string `json:"ts"`
+}
+type Ticker struct {
+	InstType  string `json:"instType"`
+	InstId    string `json:"instId"`
+	Last      string `json:"last"`
+	LastSz    string `json:"lastSz"`
+	AskPx     string `json:"askPx"`
+	AskSz     string `json:"askSz"`
+	BidPx     string `json:"bidPx"`
+	BidSz     string `json:"bidSz"`
+	Open24h   string `json:"open24h"`
+	High24h   string `json:"high24h"`
+	Low24h    string `json:"low24h"`
+	VolCcy24h string `json:"volCcy24h"`
+	Vol24h    string `json:"vol24h"`
+	SodUtc0   string `json:"sodUtc0"`
+	SodUtc8   string `json:"sodUtc8"`
+	Ts        string `json:"ts"`
+}
 type Spread struct {
 	Price      string
 	Count      string
 	OrderCount string
 }
 type OrderBook struct {
-	Asks []Spread `json:"asks"`
-	Bids []Spread `json:"bids"`
-	Ts   string   `json:"ts"`
+	Asks      []Spread `json:"asks"`
+	Bids      []Spread `json:"bids"`
+	Ts        string   `json:"ts"`
+	Checksum  int64    `json:"checksum"`
+	SeqId     int64    `json:"seqId"`
+	PrevSeqId int64    `json:"prevSeqId"`
 }
 
 func (o *OrderBook) UnmarshalJSON(bytes []byte) (err error) {
 	var tmp = struct {
-		Asks [][]string `json:"asks"`
-		Bids [][]string `json:"bids"`
-		Ts   string     `json:"ts"`
+		Asks      [][]string `json:"asks"`
+		Bids      [][]string `json:"bids"`
+		Ts        string     `json:"ts"`
+		Checksum  int64      `json:"checksum"`
+		SeqId     int64      `json:"seqId"`
+		PrevSeqId int64      `json:"prevSeqId"`
 	}{}
 	err = json.Unmarshal(bytes, &tmp)
 	if err != nil {
@@ -423,34 +597,105 @@ func (o *OrderBook) UnmarshalJSON(bytes []byte) (err error) {
 			return
 		}
 	}()
-	var asks []Spread
-	for _, ask := range tmp.Asks {
-		asks = append(asks, Spread{
-			Price:      ask[0],
-			Count:      ask[1],
-			OrderCount: ask[2],
-		})
-	}
-	var bids []Spread
-	for _, bid := range tmp.Bids {
-		bids = append(bids, Spread{
-			Price:      bid[0],
-			Count:      bid[1],
-			OrderCount: bid[2],
-		})
-	}
-	o.Asks = asks
-	o.Bids = bids
+	o.Asks = toSpreads(tmp.Asks)
+	o.Bids = toSpreads(tmp.Bids)
 	o.Ts = tmp.Ts
+	o.Checksum = tmp.Checksum
+	o.SeqId = tmp.SeqId
+	o.PrevSeqId = tmp.PrevSeqId
 	return nil
 }
 
+// toSpreads 价差档位 产品深度为[价格,数量,废弃字段,订单数] 价差深度为[价格,数量,订单数]
+func toSpreads(rows [][]string) []Spread {
+	var spreads []Spread
+	for _, row := range rows {
+		spreads = append(spreads, Spread{
+			Price:      row[0],
+			Count:      row[1],
+			OrderCount: row[len(row)-1],
+		})
+	}
+	return spreads
+}
+
+type PriceLimit struct {
+	InstType string `json:"instType"`
+	InstId   string `json:"instId"`
+	BuyLmt   string `json:"buyLmt"`
+	SellLmt  string `json:"sellLmt"`
+	Ts       string `json:"ts"`
+	Enabled  bool   `json:"enabled"`
+}
+type CancelOrderReq struct {
+	InstId  string `json:"instId"`
+	OrdId   string `json:"ordId,omitempty"`
+	ClOrdId string `json:"clOrdId,omitempty"`
+}
+type AlgoOrdersPendingReq struct {
+	OrdType  string `json:"ordType" url:"ordType,omitempty"` // 必填 conditional与oco可用逗号同时查询
+	AlgoId   string `json:"algoId" url:"algoId,omitempty"`
+	InstType string `json:"instType" url:"instType,omitempty"`
+	InstId   string `json:"instId" url:"instId,omitempty"`
+	After    string `json:"after" url:"after,omitempty"`
+	Before   string `json:"before" url:"before,omitempty"`
+	Limit    string `json:"limit" url:"limit,omitempty"`
+}
+type AlgoOrder struct {
+	InstType    string `json:"instType"`
+	InstId      string `json:"instId"`
+	AlgoId      string `json:"algoId"`
+	AlgoClOrdId string `json:"algoClOrdId"`
+	OrdType     string `json:"ordType"`
+	Side        string `json:"side"`
+	PosSide     string `json:"posSide"`
+	TdMode      string `json:"tdMode"`
+	Sz          string `json:"sz"`
+	State       string `json:"state"`
+	TriggerPx   string `json:"triggerPx"`
+	OrdPx       string `json:"ordPx"`
+	TpTriggerPx string `json:"tpTriggerPx"`
+	SlTriggerPx string `json:"slTriggerPx"`
+	CTime       string `json:"cTime"`
+	UTime       string `json:"uTime"`
+}
+type CancelAlgoReq struct {
+	InstId string `json:"instId"`
+	AlgoId string `json:"algoId"`
+}
+type CancelAlgo struct {
+	AlgoId      string `json:"algoId"`
+	AlgoClOrdId string `json:"algoClOrdId"`
+	SCode       string `json:"sCode"`
+	SMsg        string `json:"sMsg"`
+}
+type MassCancelReq struct {
+	InstType   string `json:"instType"`
+	InstFamily string `json:"instFamily"`
+}
+type MassCancel struct {
+	Result bool `json:"result"`
+}
+type CancelAllAfter struct {
+	TriggerTime string `json:"triggerTime"`
+	Tag         string `json:"tag"`
+	Ts          string `json:"ts"`
+}
 type Balances struct {
 	Ccy       string `json:"ccy"`
 	Bal       string `json:"bal"`
 	FrozenBal string `json:"frozenBal"`
 	AvailBal  string `json:"availBal"`
 }
+type Trade struct {
+	InstId  string `json:"instId"`
+	TradeId string `json:"tradeId"`
+	Px      string `json:"px"`
+	Sz      string `json:"sz"`
+	Side    string `json:"side"`
+	Count   string `json:"count"`
+	Ts      string `json:"ts"`
+}
 type Trades struct {
 	SprdId   string `json:"sprdId"`
 	TradeId  string `json:"tradeId"`
@@ -475,52 +720,82 @@ type Trades struct {
 	Code string `json:"code"`
 	Msg  string `json:"msg"`
 }
+type BalanceDetail struct {
+	AvailBal      string `json:"availBal"`
+	AvailEq       string `json:"availEq"`
+	BorrowFroz    string `json:"borrowFroz"`
+	CashBal       string `json:"cashBal"`
+	Ccy           string `json:"ccy"`
+	CrossLiab     string `json:"crossLiab"`
+	DisEq         string `json:"disEq"`
+	Eq            string `json:"eq"`
+	EqUsd         string `json:"eqUsd"`
+	FixedBal      string `json:"fixedBal"`
+	FrozenBal     string `json:"frozenBal"`
+	Imr           string `json:"imr"`
+	Interest      string `json:"interest"`
+	IsoEq         string `json:"isoEq"`
+	IsoLiab       string `json:"isoLiab"`
+	IsoUpl        string `json:"isoUpl"`
+	Liab          string `json:"liab"`
+	MaxLoan       string `json:"maxLoan"`
+	MgnRatio      string `json:"mgnRatio"`
+	Mmr           string `json:"mmr"`
+	NotionalLever string `json:"notionalLever"`
+	OrdFrozen     string `json:"ordFrozen"`
+	SpotInUseAmt  string `json:"spotInUseAmt"`
+	SpotIsoBal    string `json:"spotIsoBal"`
+	StgyEq        string `json:"stgyEq"`
+	Twap          string `json:"twap"`
+	UTime         string `json:"uTime"`
+	Upl           string `json:"upl"`
+	UplLiab       string `json:"uplLiab"`
+}
 type Balance struct {
-	AdjEq      string `json:"adjEq"`
-	BorrowFroz string `json:"borrowFroz"`
-	Details    []struct {
-		AvailBal      string `json:"availBal"`
-		AvailEq       string `json:"availEq"`
-		BorrowFroz    string `json:"borrowFroz"`
-		CashBal       string `json:"cashBal"`
-		Ccy           string `json:"ccy"`
-		CrossLiab     string `json:"crossLiab"`
-		DisEq         string `json:"disEq"`
-		Eq            string `json:"eq"`
-		EqUsd         string `json:"eqUsd"`
-		FixedBal      string `json:"fixedBal"`
-		FrozenBal     string `json:"frozenBal"`
-		Imr           string `json:"imr"`
-		Interest      string `json:"interest"`
-		IsoEq         string `json:"isoEq"`
-		IsoLiab       string `json:"isoLiab"`
-		IsoUpl        string `json:"isoUpl"`
-		Liab          string `json:"liab"`
-		MaxLoan       string `json:"maxLoan"`
-		MgnRatio      string `json:"mgnRatio"`
-		Mmr           string `json:"mmr"`
-		NotionalLever string `json:"notionalLever"`
-		OrdFrozen     string `json:"ordFrozen"`
-		SpotInUseAmt  string `json:"spotInUseAmt"`
-		SpotIsoBal    string `json:"spotIsoBal"`
-		StgyEq        string `json:"stgyEq"`
-		Twap          string `json:"twap"`
-		UTime         string `json:"uTime"`
-		Upl           string `json:"upl"`
-		UplLiab       string `json:"uplLiab"`
-	} `json:"details"`
+	AdjEq       string          `json:"adjEq"`
+	BorrowFroz  string          `json:"borrowFroz"`
+	Details     []BalanceDetail `json:"details"`
+	Imr         string          `json:"imr"`
+	IsoEq       string          `json:"isoEq"`
+	MgnRatio    string          `json:"mgnRatio"`
+	Mmr         string          `json:"mmr"`
+	NotionalUsd string          `json:"notionalUsd"`
+	OrdFroz     string          `json:"ordFroz"`
+	TotalEq     string          `json:"totalEq"`
+	UTime       string          `json:"uTime"`
+	Upl         string          `json:"upl"`
+}
+type Position struct {
+	Adl         string `json:"adl"`
+	AvailPos    string `json:"availPos"`
+	AvgPx       string `json:"avgPx"`
+	CTime       string `json:"cTime"`
+	Ccy         string `json:"ccy"`
+	Fee         string `json:"fee"`
+	FundingFee  string `json:"fundingFee"`
 	Imr         string `json:"imr"`
-	IsoEq       string `json:"isoEq"`
+	InstId      string `json:"instId"`
+	InstType    string `json:"instType"`
+	Last        string `json:"last"`
+	Lever       string `json:"lever"`
+	LiqPx       string `json:"liqPx"`
+	Margin      string `json:"margin"`
+	MarkPx      string `json:"markPx"`
+	MgnMode     string `json:"mgnMode"`
 	MgnRatio    string `json:"mgnRatio"`
 	Mmr         string `json:"mmr"`
 	NotionalUsd string `json:"notionalUsd"`
-	OrdFroz     string `json:"ordFroz"`
-	TotalEq     string `json:"totalEq"`
+	Pos         string `json:"pos"`
+	PosCcy      string `json:"posCcy"`
+	PosId       string `json:"posId"`
+	PosSide     string `json:"posSide"`
+	RealizedPnl string `json:"realizedPnl"`
 	UTime       string `json:"uTime"`
 	Upl         string `json:"upl"`
+	UplRatio    string `json:"uplRatio"`
 }
 type PositionReq struct {
-	InstType string `json:"instType"`
-	InstId   string `json:"instId"`
-	PosId    string `json:"posId"`
+	InstType string `json:"instType,omitempty" url:"instType,omitempty"`
+	InstId   string `json:"instId,omitempty" url:"instId,omitempty"`
+	PosId    string `json:"posId,omitempty" url:"posId,omitempty"`
 }
@@ -0,0 +1,460 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// 推送帧的快速解码 单次扫描 不经过反射
+// 数据行中的字符串直接引用帧数据不拷贝 帧数据在读取时独立分配且之后不再修改 因此可安全保留
+// 但保留任意字段会使整帧无法回收 长期保存时应自行拷贝
+
+var errSyntax = errors.New("json: invalid syntax")
+
+// scanner 最小化的json扫描器 只支持解码推送帧所需的结构
+type scanner struct {
+	buf []byte
+	pos int
+}
+
+func (s *scanner) err(what string) error {
+	return fmt.Errorf("%w: %s at offset %d", errSyntax, what, s.pos)
+}
+
+func (s *scanner) space() {
+	for s.pos < len(s.buf) {
+		switch s.buf[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek 跳过空白后的下一个字符 结束时返回0
+func (s *scanner) peek() byte {
+	s.space()
+	if s.pos >= len(s.buf) {
+		return 0
+	}
+	return s.buf[s.pos]
+}
+
+func (s *scanner) expect(c byte) error {
+	if s.peek() != c {
+		return s.err("expected " + string(c))
+	}
+	s.pos++
+	return nil
+}
+
+// raw 读取字符串 返回引号内的原始字节及是否含转义
+func (s *scanner) raw() ([]byte, bool, error) {
+	if err := s.expect('"'); err != nil {
+		return nil, false, err
+	}
+	start, escaped := s.pos, false
+	for s.pos < len(s.buf) {
+		switch s.buf[s.pos] {
+		case '\\':
+			escaped = true
+			s.pos += 2
+			continue
+		case '"':
+			v := s.buf[start:s.pos]
+			s.pos++
+			return v, escaped, nil
+		}
+		s.pos++
+	}
+	return nil, false, s.err("unterminated string")
+}
+
+// str 读取字符串 无转义时引用原始数据 含转义时按标准库解码
+func (s *scanner) str() (string, error) {
+	start := s.pos
+	v, escaped, err := s.raw()
+	if err != nil || len(v) == 0 {
+		return "", err
+	}
+	if escaped {
+		var out string
+		err = json.Unmarshal(s.buf[start:s.pos], &out)
+		return out, err
+	}
+	return unsafe.String(&v[0], len(v)), nil
+}
+
+// intern 读取字符串并复用已出现过的值 用于取值有限且常被长期持有的字段
+func (s *scanner) intern() (string, error) {
+	start := s.pos
+	v, escaped, err := s.raw()
+	if err != nil || len(v) == 0 {
+		return "", err
+	}
+	if escaped {
+		var out string
+		err = json.Unmarshal(s.buf[start:s.pos], &out)
+		return out, err
+	}
+	return interned.get(v), nil
+}
+
+// strOrNull 读取字符串 null视为空
+func (s *scanner) strOrNull() (string, error) {
+	if s.peek() == 'n' {
+		return "", s.literal("null")
+	}
+	return s.str()
+}
+
+// int 读取整数 兼容数字和字符串形式
+func (s *scanner) int() (int64, error) {
+	if s.peek() == '"' {
+		v, _, err := s.raw()
+		if err != nil || len(v) == 0 {
+			return 0, err
+		}
+		return parseInt(v)
+	}
+	if s.peek() == 'n' {
+		return 0, s.literal("null")
+	}
+	start := s.pos
+	for s.pos < len(s.buf) && (s.buf[s.pos] == '-' || s.buf[s.pos] >= '0' && s.buf[s.pos] <= '9') {
+		s.pos++
+	}
+	return parseInt(s.buf[start:s.pos])
+}
+
+func parseInt(b []byte) (int64, error) {
+	if len(b) == 0 {
+		return 0, errSyntax
+	}
+	neg := b[0] == '-'
+	if neg {
+		b = b[1:]
+		if len(b) == 0 {
+			return 0, errSyntax
+		}
+	}
+	var n int64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, errSyntax
+		}
+		n = n*10 + int64(c-'0')
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+func (s *scanner) literal(lit string) error {
+	s.space()
+	if len(s.buf)-s.pos < len(lit) || string(s.buf[s.pos:s.pos+len(lit)]) != lit {
+		return s.err("expected " + lit)
+	}
+	s.pos += len(lit)
+	return nil
+}
+
+// object 遍历对象 fn需消费对应的值
+func (s *scanner) object(fn func(key []byte) error) error {
+	if err := s.expect('{'); err != nil {
+		return err
+	}
+	if s.peek() == '}' {
+		s.pos++
+		return nil
+	}
+	for {
+		key, _, err := s.raw()
+		if err != nil {
+			return err
+		}
+		if err = s.expect(':'); err != nil {
+			return err
+		}
+		if err = fn(key); err != nil {
+			return err
+		}
+		switch s.peek() {
+		case ',':
+			s.pos++
+		case '}':
+			s.pos++
+			return nil
+		default:
+			return s.err("expected , or }")
+		}
+	}
+}
+
+// array 遍历数组 fn需消费当前元素 null视为空数组
+func (s *scanner) array(fn func(i int) error) error {
+	if s.peek() == 'n' {
+		return s.literal("null")
+	}
+	if err := s.expect('['); err != nil {
+		return err
+	}
+	if s.peek() == ']' {
+		s.pos++
+		return nil
+	}
+	for i := 0; ; i++ {
+		if err := fn(i); err != nil {
+			return err
+		}
+		switch s.peek() {
+		case ',':
+			s.pos++
+		case ']':
+			s.pos++
+			return nil
+		default:
+			return s.err("expected , or ]")
+		}
+	}
+}
+
+// skip 跳过任意值
+func (s *scanner) skip() error {
+	switch c := s.peek(); c {
+	case '"':
+		_, _, err := s.raw()
+		return err
+	case '{', '[':
+		depth := 0
+		for s.pos < len(s.buf) {
+			switch s.buf[s.pos] {
+			case '"':
+				if _, _, err := s.raw(); err != nil {
+					return err
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					s.pos++
+					return nil
+				}
+			}
+			s.pos++
+		}
+		return s.err("unterminated value")
+	case 0:
+		return s.err("unexpected end")
+	default:
+		start := s.pos
+		for s.pos < len(s.buf) {
+			switch s.buf[s.pos] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				if s.pos == start {
+					return s.err("unexpected " + string(c))
+				}
+				return nil
+			}
+			s.pos++
+		}
+		return nil
+	}
+}
+
+// internTable 频道、产品等取值有限的字符串池 超过上限后不再收录
+type internTable struct {
+	lock sync.RWMutex
+	m    map[string]string
+}
+
+const maxInterned = 1 << 14
+
+var interned = &internTable{m: map[string]string{}}
+
+func (t *internTable) get(b []byte) string {
+	t.lock.RLock()
+	v, ok := t.m[string(b)]
+	t.lock.RUnlock()
+	if ok {
+		return v
+	}
+	v = string(b)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.m) < maxInterned {
+		t.m[v] = v
+	}
+	return v
+}
+
+// ParseEnvelope 单次扫描解析推送帧的外层字段 arg用于路由 data保留为原始切片不拷贝
+func ParseEnvelope(data []byte, rp *WsOriginResp) error {
+	s := &scanner{buf: data}
+	err := s.object(func(key []byte) (err error) {
+		switch string(key) {
+		case "arg":
+			return s.object(func(key []byte) (err error) {
+				switch string(key) {
+				case "channel":
+					rp.Arg.Channel, err = s.intern()
+				case "instId":
+					rp.Arg.InstId, err = s.intern()
+				case "instType":
+					rp.Arg.InstType, err = s.intern()
+				case "sprdId":
+					rp.Arg.SprdId, err = s.intern()
+				case "instFamily":
+					rp.Arg.InstFamily, err = s.intern()
+				case "ccy":
+					rp.Arg.Ccy, err = s.intern()
+				default:
+					err = s.skip()
+				}
+				return err
+			})
+		case "data":
+			s.space()
+			start := s.pos
+			if err = s.skip(); err == nil {
+				rp.Data = data[start:s.pos]
+			}
+		case "action":
+			rp.Action, err = s.intern()
+		case "event":
+			rp.Event, err = s.intern()
+		case "op":
+			rp.Op, err = s.intern()
+		case "id":
+			rp.Id, err = s.str()
+		case "code":
+			rp.Code, err = s.str()
+		case "msg":
+			rp.Msg, err = s.str()
+		case "connId":
+			rp.ConnId, err = s.str()
+		default:
+			err = s.skip()
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if s.peek() != 0 {
+		return s.err("trailing data")
+	}
+	return nil
+}
+
+// Decoder 将data数组解码并追加到dst 可复用dst的底层数组
+type Decoder[T any] func(data []byte, dst []T) ([]T, error)
+
+// DecodeOrderBooks 深度频道的解码器 复用dst中各档位切片 不分配内存
+func DecodeOrderBooks(data []byte, dst []OrderBook) ([]OrderBook, error) {
+	s := &scanner{buf: data}
+	err := s.array(func(i int) error {
+		dst = grow(dst)
+		book := &dst[len(dst)-1]
+		asks, bids := book.Asks[:0], book.Bids[:0]
+		*book = OrderBook{}
+		err := s.object(func(key []byte) (err error) {
+			switch string(key) {
+			case "asks":
+				asks, err = decodeSpreads(s, asks)
+			case "bids":
+				bids, err = decodeSpreads(s, bids)
+			case "ts":
+				book.Ts, err = s.strOrNull()
+			case "checksum":
+				book.Checksum, err = s.int()
+			case "seqId":
+				book.SeqId, err = s.int()
+			case "prevSeqId":
+				book.PrevSeqId, err = s.int()
+			default:
+				err = s.skip()
+			}
+			return err
+		})
+		book.Asks, book.Bids = asks, bids
+		return err
+	})
+	return dst, err
+}
+
+// decodeSpreads 档位为[价格,数量,废弃字段,订单数]或[价格,数量,订单数]
+func decodeSpreads(s *scanner, dst []Spread) ([]Spread, error) {
+	err := s.array(func(int) error {
+		dst = grow(dst)
+		spread := &dst[len(dst)-1]
+		*spread = Spread{}
+		var last string
+		n := 0
+		err := s.array(func(i int) (err error) {
+			last, err = s.strOrNull()
+			switch i {
+			case 0:
+				spread.Price = last
+			case 1:
+				spread.Count = last
+			}
+			n = i + 1
+			return err
+		})
+		if err == nil && n < 2 {
+			return s.err("spread 协议格式不正确")
+		}
+		spread.OrderCount = last
+		return err
+	})
+	return dst, err
+}
+
+// DecodeTrades 交易频道(trades、trades-all)的解码器 不分配内存
+func DecodeTrades(data []byte, dst []Trade) ([]Trade, error) {
+	s := &scanner{buf: data}
+	err := s.array(func(i int) error {
+		dst = grow(dst)
+		trade := &dst[len(dst)-1]
+		*trade = Trade{}
+		return s.object(func(key []byte) (err error) {
+			switch string(key) {
+			case "instId":
+				trade.InstId, err = s.intern()
+			case "tradeId":
+				trade.TradeId, err = s.strOrNull()
+			case "px":
+				trade.Px, err = s.strOrNull()
+			case "sz":
+				trade.Sz, err = s.strOrNull()
+			case "side":
+				trade.Side, err = s.intern()
+			case "count":
+				trade.Count, err = s.strOrNull()
+			case "ts":
+				trade.Ts, err = s.strOrNull()
+			default:
+				err = s.skip()
+			}
+			return err
+		})
+	})
+	return dst, err
+}
+
+// grow 追加一个元素 优先复用底层数组中已有的元素
+func grow[T any](s []T) []T {
+	if len(s) < cap(s) {
+		return s[:len(s)+1]
+	}
+	var zero T
+	return append(s, zero)
+}
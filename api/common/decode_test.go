@@ -0,0 +1,221 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+// bookFrame books-l2-tbt推送 levels为每侧档位数
+func bookFrame(levels int) []byte {
+	var b strings.Builder
+	b.WriteString(`{"arg":{"channel":"books-l2-tbt","instId":"BTC-USDT"},"action":"update","data":[{"asks":[`)
+	for i := 0; i < levels; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `["%d.1","0.%d","0","%d"]`, 42000+i, i+1, i%7+1)
+	}
+	b.WriteString(`],"bids":[`)
+	for i := 0; i < levels; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `["%d.9","1.%d","0","%d"]`, 41999-i, i, i%5+1)
+	}
+	b.WriteString(`],"ts":"1597026383085","checksum":-855196043,"prevSeqId":123456,"seqId":123457}]}`)
+	return []byte(b.String())
+}
+
+// tradeFrame trades-all推送 n为成交条数
+func tradeFrame(n int) []byte {
+	var b strings.Builder
+	b.WriteString(`{"arg":{"channel":"trades-all","instId":"BTC-USDT"},"data":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"instId":"BTC-USDT","tradeId":"%d","px":"42219.%d","sz":"0.0%d","side":"buy","ts":"1630048897897"}`, 130639474+i, i, i+1)
+	}
+	b.WriteString(`]}`)
+	return []byte(b.String())
+}
+
+func TestParseEnvelope(t *testing.T) {
+	for _, frame := range [][]byte{
+		bookFrame(3),
+		tradeFrame(2),
+		[]byte(`{"event":"subscribe","arg":{"channel":"tickers","instId":"BTC-USDT"},"connId":"a4d3ae55"}`),
+		[]byte(`{"event":"error","code":"60012","msg":"Invalid request: {\"op\": \"subscribe\"}","connId":"a4d3ae55"}`),
+		[]byte(`{"id":"1512","op":"order","code":"0","msg":"","data":[{"clOrdId":"","ordId":"12345689","sCode":"0"}],"extra":{"a":[1,{"b":"]"}],"c":null,"d":true}}`),
+		[]byte(` { "arg" : { "channel" : "account" , "uid" : "77982378" } , "data" : [ ] } `),
+		[]byte(`{"arg":{"channel":"opt-summary","instFamily":"BTC-USD"},"data":[]}`),
+		[]byte(`{"arg":{"channel":"account-greeks","ccy":"BTC","uid":"77982378"},"data":[]}`),
+	} {
+		want := WsOriginResp{}
+		assert.NoError(t, json.Unmarshal(frame, &want))
+		got := WsOriginResp{}
+		assert.NoError(t, ParseEnvelope(frame, &got))
+		assert.Equal(t, want, got, string(frame))
+		assert.Equal(t, want.Arg.Key(), string(got.Arg.appendKey(nil)))
+	}
+	for _, frame := range []string{``, `{`, `{"arg":}`, `{"data":[1,2}`, `{"event":"x"} x`, `[]`} {
+		assert.Error(t, ParseEnvelope([]byte(frame), &WsOriginResp{}), frame)
+	}
+}
+
+func TestDecodeOrderBooks(t *testing.T) {
+	frame := bookFrame(5)
+	rp := WsOriginResp{}
+	assert.NoError(t, ParseEnvelope(frame, &rp))
+	assert.Equal(t, "update", rp.Action)
+
+	var want []*OrderBook
+	assert.NoError(t, json.Unmarshal(rp.Data, &want))
+	got, err := DecodeOrderBooks(rp.Data, nil)
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, *want[0], got[0])
+
+	// 价差深度为[价格,数量,订单数]
+	got, err = DecodeOrderBooks([]byte(`[{"asks":[["1.5","2","3"]],"bids":[],"ts":"1","seqId":"9"}]`), got[:0])
+	assert.NoError(t, err)
+	assert.Equal(t, []Spread{{Price: "1.5", Count: "2", OrderCount: "3"}}, got[0].Asks)
+	assert.Empty(t, got[0].Bids)
+	assert.Equal(t, int64(9), got[0].SeqId)
+
+	_, err = DecodeOrderBooks([]byte(`[{"asks":[["1.5"]]}]`), nil)
+	assert.Error(t, err)
+}
+
+func TestDecodeTrades(t *testing.T) {
+	frame := tradeFrame(3)
+	rp := WsOriginResp{}
+	assert.NoError(t, ParseEnvelope(frame, &rp))
+
+	var want []*Trade
+	assert.NoError(t, json.Unmarshal(rp.Data, &want))
+	got, err := DecodeTrades(rp.Data, nil)
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+	for i := range want {
+		assert.Equal(t, *want[i], got[i])
+	}
+
+	got, err = DecodeTrades([]byte(`[{"instId":"ETH-USDT","px":"1.5","count":null}]`), got[:0])
+	assert.NoError(t, err)
+	assert.Equal(t, []Trade{{InstId: "ETH-USDT", Px: "1.5"}}, got)
+}
+
+func TestDecodeAllocs(t *testing.T) {
+	book, trade := bookFrame(400), tradeFrame(50)
+	var rp WsOriginResp
+	books, _ := DecodeOrderBooks(book[strings.Index(string(book), `"data":`)+7:len(book)-1], nil)
+	trades, _ := DecodeTrades(trade[strings.Index(string(trade), `"data":`)+7:len(trade)-1], nil)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		rp = WsOriginResp{}
+		_ = ParseEnvelope(book, &rp)
+		books, _ = DecodeOrderBooks(rp.Data, books[:0])
+	})
+	assert.Zero(t, allocs)
+	allocs = testing.AllocsPerRun(100, func() {
+		rp = WsOriginResp{}
+		_ = ParseEnvelope(trade, &rp)
+		trades, _ = DecodeTrades(rp.Data, trades[:0])
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestSubscribeDecode(t *testing.T) {
+	c := NewBaseWsClient(context.Background(), Business, "", nil, nil)
+	conn := ws.NewReplayConn(context.Background())
+	c.Attach(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	got := make(chan Trade, 4)
+	go func() {
+		_ = SubscribeDecode(&c, ctx, MakeArg("trades-all", "BTC-USDT"), DecodeTrades, func(resp *WsResp[Trade]) {
+			for _, trade := range resp.Data {
+				got <- trade
+			}
+		})
+	}()
+	assert.Eventually(t, func() bool {
+		_, ok := c.getArgWatch(MakeArg("trades-all", "BTC-USDT"))
+		return ok
+	}, time.Second, time.Millisecond)
+
+	conn.Inject(ws.TextMessage, tradeFrame(2))
+	conn.Inject(ws.TextMessage, tradeFrame(1))
+	for _, id := range []string{"130639474", "130639475", "130639474"} {
+		select {
+		case trade := <-got:
+			assert.Equal(t, id, trade.TradeId)
+		case <-ctx.Done():
+			t.Fatal("callback not fired")
+		}
+	}
+}
+
+func BenchmarkDecodeBooks(b *testing.B) {
+	frame := bookFrame(400)
+	b.Run("unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(frame)))
+		for i := 0; i < b.N; i++ {
+			rp := &WsOriginResp{}
+			_ = json.Unmarshal(frame, rp)
+			_ = Arg.Key(rp.Arg)
+			var books []*OrderBook
+			_ = json.Unmarshal(rp.Data, &books)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(frame)))
+		var buf [128]byte
+		var books []OrderBook
+		for i := 0; i < b.N; i++ {
+			rp := WsOriginResp{}
+			_ = ParseEnvelope(frame, &rp)
+			_ = rp.Arg.appendKey(buf[:0])
+			books, _ = DecodeOrderBooks(rp.Data, books[:0])
+		}
+	})
+}
+
+func BenchmarkDecodeTrades(b *testing.B) {
+	frame := tradeFrame(20)
+	b.Run("unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(frame)))
+		for i := 0; i < b.N; i++ {
+			rp := &WsOriginResp{}
+			_ = json.Unmarshal(frame, rp)
+			_ = Arg.Key(rp.Arg)
+			var trades []*Trade
+			_ = json.Unmarshal(rp.Data, &trades)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(frame)))
+		var buf [128]byte
+		var trades []Trade
+		for i := 0; i < b.N; i++ {
+			rp := WsOriginResp{}
+			_ = ParseEnvelope(frame, &rp)
+			_ = rp.Arg.appendKey(buf[:0])
+			trades, _ = DecodeTrades(rp.Data, trades[:0])
+		}
+	})
+}
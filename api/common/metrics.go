@@ -0,0 +1,51 @@
+package common
+
+import "time"
+
+// 连接状态
+const (
+	WsConnected    = "connected"
+	WsDisconnected = "disconnected"
+)
+
+// REST重试的结果
+const (
+	RetryRetried = "retried" // 失败后再次发送
+	RetryDeduped = "deduped" // 重试前查询到上次请求已生效
+	RetryGaveUp  = "gave_up" // 重试后仍失败或无法确认上次请求是否生效
+)
+
+// Metrics SDK运行指标的钩子 实现需并发安全且不阻塞 默认NopMetrics
+type Metrics interface {
+	// RestRequest 一次REST请求 code为业务错误码 成功为"0" HTTP错误为状态码 网络错误为"network"
+	RestRequest(endpoint, method, code string, elapsed time.Duration)
+	// RestRetry 按重试策略处理失败的请求 outcome为RetryRetried/RetryDeduped/RetryGaveUp
+	RestRetry(endpoint, method, outcome string)
+	// RateLimitWait 请求前等待限速器的时间
+	RateLimitWait(endpoint string, waited time.Duration)
+	// WsState 连接状态变化 state为WsConnected/WsDisconnected
+	WsState(typ SvcType, state string)
+	// WsReconnect 断线后重新拨号
+	WsReconnect(typ SvcType)
+	// WsMessage 收到的推送
+	WsMessage(typ SvcType, channel string, size int)
+	// CallbackLatency 推送回调的执行耗时
+	CallbackLatency(typ SvcType, channel string, elapsed time.Duration)
+	// Subscriptions 订阅数变化 delta为1或-1
+	Subscriptions(typ SvcType, channel string, delta int)
+	// PingRTT 心跳往返时间
+	PingRTT(typ SvcType, rtt time.Duration)
+}
+
+// NopMetrics 不记录任何指标
+type NopMetrics struct{}
+
+func (NopMetrics) RestRequest(endpoint, method, code string, elapsed time.Duration)   {}
+func (NopMetrics) RestRetry(endpoint, method, outcome string)                         {}
+func (NopMetrics) RateLimitWait(endpoint string, waited time.Duration)                {}
+func (NopMetrics) WsState(typ SvcType, state string)                                  {}
+func (NopMetrics) WsReconnect(typ SvcType)                                            {}
+func (NopMetrics) WsMessage(typ SvcType, channel string, size int)                    {}
+func (NopMetrics) CallbackLatency(typ SvcType, channel string, elapsed time.Duration) {}
+func (NopMetrics) Subscriptions(typ SvcType, channel string, delta int)               {}
+func (NopMetrics) PingRTT(typ SvcType, rtt time.Duration)                             {}
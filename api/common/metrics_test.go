@@ -0,0 +1,95 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordMetrics struct {
+	NopMetrics
+	lock   sync.Mutex
+	events []string
+}
+
+func (m *recordMetrics) add(event string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.events = append(m.events, event)
+}
+
+func (m *recordMetrics) has(event string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, e := range m.events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *recordMetrics) WsState(typ SvcType, state string) {
+	m.add(fmt.Sprint("state:", typ, ":", state))
+}
+func (m *recordMetrics) WsReconnect(typ SvcType) { m.add(fmt.Sprint("reconnect:", typ)) }
+func (m *recordMetrics) WsMessage(typ SvcType, channel string, size int) {
+	m.add(fmt.Sprint("message:", channel))
+}
+func (m *recordMetrics) CallbackLatency(typ SvcType, channel string, elapsed time.Duration) {
+	m.add(fmt.Sprint("callback:", channel))
+}
+func (m *recordMetrics) Subscriptions(typ SvcType, channel string, delta int) {
+	m.add(fmt.Sprint("subscriptions:", channel, ":", delta))
+}
+func (m *recordMetrics) PingRTT(typ SvcType, rtt time.Duration) { m.add("rtt") }
+
+func TestWsClientMetrics(t *testing.T) {
+	srv := newEchoServer(t, func(op Op) string {
+		return `{"arg":{"channel":"tickers","instId":"BTC-USDT"},"data":[{"instId":"BTC-USDT","last":"1"}]}`
+	})
+	defer srv.Close()
+
+	m := &recordMetrics{}
+	c := NewBaseWsClient(context.Background(), Public, BaseURL("ws"+strings.TrimPrefix(srv.URL, "http")), nil, nil)
+	c.Metrics = m
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	subCtx, stop := context.WithCancel(ctx)
+	got := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- Subscribe(&c, subCtx, MakeArg("tickers", "BTC-USDT"), func(resp *WsResp[*Ticker]) {
+			select {
+			case got <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	select {
+	case <-got:
+	case <-ctx.Done():
+		t.Fatal("no push")
+	}
+	stop()
+	assert.NoError(t, <-done)
+
+	assert.True(t, m.has("state:Public:connected"))
+	assert.True(t, m.has("subscriptions:tickers:1"))
+	assert.True(t, m.has("subscriptions:tickers:-1"))
+	assert.True(t, m.has("message:tickers"))
+	assert.True(t, m.has("callback:tickers"))
+	assert.Eventually(t, func() bool { return m.has("rtt") }, time.Second, 10*time.Millisecond)
+
+	// 断线后重新拨号
+	c.conn.Close(nil)
+	assert.Eventually(t, func() bool { return m.has("state:Public:disconnected") }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, c.CheckConn())
+	assert.True(t, m.has("reconnect:Public"))
+}
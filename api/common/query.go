@@ -0,0 +1,210 @@
+package common
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// EncodeQuery 将请求参数编码为查询串 不含前导的"?" 按键名排序并转义 签名须使用返回值本身
+//
+// 结构体字段按url标签编码 如`url:"instId,omitempty"` 无url标签时使用json标签的名称与omitempty
+// 标签选项:
+//   - omitempty 零值不编码
+//   - unix      时间编码为秒 默认毫秒
+//   - repeat    切片按重复键编码 默认以逗号拼接
+//
+// 支持字符串、整数、浮点数(不使用科学计数法)、布尔、time.Time、实现encoding.TextMarshaler的类型(如decimal)
+// 以及它们的指针和切片 map[string]string中的空值会被忽略
+func EncodeQuery(params any) (string, error) {
+	values := url.Values{}
+	switch p := params.(type) {
+	case nil:
+		return "", nil
+	case url.Values:
+		return p.Encode(), nil
+	case map[string]string:
+		for k, v := range p {
+			if v != "" {
+				values.Set(k, v)
+			}
+		}
+		return values.Encode(), nil
+	}
+
+	v := reflect.ValueOf(params)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if err := encodeStruct(values, v); err != nil {
+			return "", err
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return "", fmt.Errorf("query: unsupported map key %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			if err := encodeField(values, k.String(), v.MapIndex(k), queryTag{omitempty: true}); err != nil {
+				return "", err
+			}
+		}
+	default:
+		return "", fmt.Errorf("query: unsupported type %s", v.Type())
+	}
+	return values.Encode(), nil
+}
+
+type queryTag struct {
+	omitempty bool
+	unix      bool
+	repeat    bool
+}
+
+// parseTag 解析字段标签 返回空名称表示跳过
+func parseTag(field reflect.StructField) (string, queryTag) {
+	tag, ok := field.Tag.Lookup("url")
+	if !ok {
+		tag, ok = field.Tag.Lookup("json")
+	}
+	if !ok {
+		return field.Name, queryTag{}
+	}
+	if tag == "-" {
+		return "", queryTag{}
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	opts := queryTag{}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			opts.omitempty = true
+		case "unix":
+			opts.unix = true
+		case "repeat":
+			opts.repeat = true
+		}
+	}
+	return name, opts
+}
+
+func encodeStruct(values url.Values, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts := parseTag(field)
+		if name == "" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := encodeStruct(values, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := encodeField(values, name, v.Field(i), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeField(values url.Values, name string, v reflect.Value, opts queryTag) error {
+	// 与json一致 非nil指针即使指向零值也会编码
+	if opts.omitempty && v.IsZero() {
+		return nil
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		if v.Len() == 0 && opts.omitempty {
+			return nil
+		}
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s, err := formatValue(v.Index(i), opts)
+			if err != nil {
+				return fmt.Errorf("query: %s: %w", name, err)
+			}
+			items = append(items, s)
+		}
+		if opts.repeat {
+			values[name] = append(values[name], items...)
+		} else {
+			values.Set(name, strings.Join(items, ","))
+		}
+		return nil
+	}
+	s, err := formatValue(v, opts)
+	if err != nil {
+		return fmt.Errorf("query: %s: %w", name, err)
+	}
+	values.Set(name, s)
+	return nil
+}
+
+func formatValue(v reflect.Value, opts queryTag) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if opts.unix {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		bs, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(bs), err
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		bs, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(bs), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// testDecimal 模拟实现了TextMarshaler的decimal类型
+type testDecimal struct {
+	s string
+}
+
+func (d testDecimal) MarshalText() ([]byte, error) {
+	return []byte(d.s), nil
+}
+
+type queryCase struct {
+	InstId    string      `url:"instId"`
+	Ccy       string      `url:"ccy,omitempty"`
+	Limit     int64       `url:"limit,omitempty"`
+	Sz        float64     `url:"sz,omitempty"`
+	Px        testDecimal `url:"px,omitempty"`
+	Reduce    bool        `url:"reduceOnly"`
+	Skip      bool        `url:"skip,omitempty"`
+	After     time.Time   `url:"after,omitempty"`
+	Begin     time.Time   `url:"begin,omitempty,unix"`
+	InstIds   []string    `url:"instIds,omitempty"`
+	States    []string    `url:"state,omitempty,repeat"`
+	Before    *int        `url:"before,omitempty"`
+	Ignored   string      `url:"-"`
+	JsonOnly  string      `json:"jsonOnly,omitempty"`
+	unexposed string
+}
+
+func TestEncodeQueryGolden(t *testing.T) {
+	before := 0
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123e6, time.UTC)
+	cases := map[string]any{
+		"nil":         nil,
+		"instruments": InstrumentsReq{InstType: "SPOT"},
+		"candles":     CandlesticksReq{InstID: "BTC-USDT", After: 1000000, Limit: 100, Bar: "1m"},
+		"place_order": &PlaceOrderReq{InstID: "BTC-USDT", ClOrdID: "c1"},
+		"struct": queryCase{
+			InstId:    "BTC USDT&x=1",
+			Limit:     1e6,
+			Sz:        1e6,
+			Px:        testDecimal{s: "123.45"},
+			After:     ts,
+			Begin:     ts,
+			InstIds:   []string{"BTC-USDT", "ETH-USDT"},
+			States:    []string{"live", "partially_filled"},
+			Before:    &before,
+			Ignored:   "x",
+			JsonOnly:  "j",
+			unexposed: "u",
+		},
+		"zero_struct": queryCase{},
+		"map":         map[string]string{"ccy": "", "z": "1", "a": "b/c"},
+		"map_any":     map[string]any{"limit": 100, "sz": 0.1, "flag": false, "none": nil},
+		"values":      url.Values{"b": {"2"}, "a": {"1", "3"}},
+	}
+	for name, params := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := EncodeQuery(params)
+			assert.NoError(t, err)
+			path := filepath.Join("testdata", "query", name+".golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+			}
+			want, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), got)
+		})
+	}
+}
+
+func TestEncodeQueryErrors(t *testing.T) {
+	_, err := EncodeQuery(struct {
+		Ch chan int `url:"ch"`
+	}{Ch: make(chan int)})
+	assert.Error(t, err)
+	_, err = EncodeQuery(42)
+	assert.Error(t, err)
+	_, err = EncodeQuery(map[int]string{1: "a"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"context"
+	"sync"
+)
+
+// Shared 多个调用方共享同一个阻塞式订阅 首个调用方发起订阅 最后一个调用方退出时取消
+type Shared struct {
+	lock sync.Mutex
+	ref  int
+	run  *sharedRun
+	last *sharedRun // 最近一次订阅 新订阅需等待其完全退出
+}
+
+type sharedRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Join 加入订阅并阻塞至ctx结束或订阅异常退出 subscribe仅在当前无订阅时调用
+func (s *Shared) Join(ctx context.Context, subscribe func(ctx context.Context) error) error {
+	s.lock.Lock()
+	if s.run != nil {
+		select {
+		case <-s.run.done:
+			// 上一次订阅已退出 重新发起
+			s.run = nil
+		default:
+		}
+	}
+	if s.run == nil {
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		r := &sharedRun{cancel: cancel, done: make(chan struct{})}
+		last := s.last
+		go func() {
+			if last != nil {
+				<-last.done
+			}
+			r.err = subscribe(runCtx)
+			close(r.done)
+		}()
+		s.run, s.last = r, r
+	}
+	r := s.run
+	s.ref++
+	s.lock.Unlock()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case <-r.done:
+		err = r.err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ref--
+	if s.ref == 0 && s.run == r {
+		r.cancel()
+		s.run = nil
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedJoin(t *testing.T) {
+	var s Shared
+	var runs, active atomic.Int32
+	subscribe := func(ctx context.Context) error {
+		runs.Add(1)
+		active.Add(1)
+		defer active.Add(-1)
+		<-ctx.Done()
+		return nil
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	go func() { done <- s.Join(ctx1, subscribe) }()
+	go func() { done <- s.Join(ctx2, subscribe) }()
+	assert.Eventually(t, func() bool { return active.Load() == 1 }, time.Second, time.Millisecond)
+
+	// 仍有调用方时不取消订阅
+	cancel1()
+	assert.NoError(t, <-done)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(1), active.Load())
+
+	cancel2()
+	assert.NoError(t, <-done)
+	assert.Eventually(t, func() bool { return active.Load() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+func TestSharedJoinError(t *testing.T) {
+	var s Shared
+	errBoom := errors.New("boom")
+	err := s.Join(context.Background(), func(ctx context.Context) error { return errBoom })
+	assert.ErrorIs(t, err, errBoom)
+
+	// 上一次订阅退出后重新发起
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var called atomic.Bool
+	assert.NoError(t, s.Join(ctx, func(ctx context.Context) error {
+		called.Store(true)
+		<-ctx.Done()
+		return nil
+	}))
+	assert.True(t, called.Load())
+}
@@ -0,0 +1,204 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+// Redacted 脱敏后的占位
+const Redacted = "***"
+
+// 密钥、签名等敏感字段 比较时忽略大小写
+var sensitiveKeys = map[string]bool{
+	"apikey":               true,
+	"secretkey":            true,
+	"passphrase":           true,
+	"sign":                 true,
+	"signature":            true,
+	"listenkey":            true,
+	"ok-access-key":        true,
+	"ok-access-sign":       true,
+	"ok-access-passphrase": true,
+	"x-mbx-apikey":         true,
+}
+
+// RedactJSON 将JSON中的密钥、签名等字段替换为***
+func RedactJSON(data []byte) string {
+	return string(ws.Redact(data))
+}
+
+// NewRedactHandler 对键名为敏感字段的属性脱敏
+func NewRedactHandler(h slog.Handler) slog.Handler {
+	if _, ok := h.(*redactHandler); ok {
+		return h
+	}
+	return &redactHandler{h}
+}
+
+type redactHandler struct {
+	slog.Handler
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		record.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redacted = append(redacted, redactAttr(a))
+	}
+	return &redactHandler{h.Handler.WithAttrs(redacted)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{h.Handler.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		redacted := make([]any, 0, len(group))
+		for _, g := range group {
+			redacted = append(redacted, redactAttr(g))
+		}
+		return slog.Group(a.Key, redacted...)
+	}
+	return a
+}
+
+// ResolveLogger 组件实际使用的日志 优先logger 其次兼容的ILogger 均为nil时使用slog.Default()
+// 返回值总是经过脱敏
+func ResolveLogger(logger *slog.Logger, log ILogger) *slog.Logger {
+	switch {
+	case logger != nil:
+		if _, ok := logger.Handler().(*redactHandler); ok {
+			return logger
+		}
+		return slog.New(NewRedactHandler(logger.Handler()))
+	case log != nil:
+		return NewSlogLogger(log)
+	}
+	return slog.New(NewRedactHandler(slog.Default().Handler()))
+}
+
+// NewSlogLogger 以ILogger输出的slog.Logger 兼容已有的ILogger实现
+// level为输出的最低级别 默认Info 逐条消息的Debug日志不会转发
+func NewSlogLogger(log ILogger, level ...slog.Leveler) *slog.Logger {
+	if l, ok := log.(SlogLogger); ok {
+		return slog.New(NewRedactHandler(l.Logger.Handler()))
+	}
+	h := &iloggerHandler{log: log, level: slog.LevelInfo}
+	if len(level) != 0 {
+		h.level = level[0]
+	}
+	return slog.New(NewRedactHandler(h))
+}
+
+// iloggerHandler 将slog记录按级别转为ILogger的调用 属性以key=value追加在消息后
+type iloggerHandler struct {
+	log    ILogger
+	level  slog.Leveler
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *iloggerHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *iloggerHandler) Handle(ctx context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&sb, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&sb, h.prefix, a)
+		return true
+	})
+	line := sb.String()
+	switch {
+	case r.Level >= slog.LevelError:
+		h.log.Errorf("%s", line)
+	case r.Level >= slog.LevelWarn:
+		h.log.Warnf("%s", line)
+	case r.Level >= slog.LevelInfo:
+		h.log.Infof("%s", line)
+	default:
+		h.log.Debugf("%s", line)
+	}
+	return nil
+}
+
+func (h *iloggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	merged := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	merged = append(merged, h.attrs...)
+	for _, a := range attrs {
+		if h.prefix != "" {
+			a.Key = h.prefix + a.Key
+		}
+		merged = append(merged, a)
+	}
+	return &iloggerHandler{log: h.log, level: h.level, attrs: merged, prefix: h.prefix}
+}
+
+func (h *iloggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &iloggerHandler{log: h.log, level: h.level, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+func writeAttr(sb *strings.Builder, prefix string, a slog.Attr) {
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, g := range a.Value.Group() {
+			writeAttr(sb, prefix+a.Key+".", g)
+		}
+		return
+	}
+	sb.WriteString(" ")
+	sb.WriteString(prefix + a.Key)
+	sb.WriteString("=")
+	sb.WriteString(a.Value.Resolve().String())
+}
+
+// SlogLogger 以slog.Logger实现ILogger 供仍使用ILogger的组件输出到slog
+type SlogLogger struct {
+	*slog.Logger
+}
+
+func (l SlogLogger) Infof(template string, args ...interface{}) {
+	l.Info(fmt.Sprintf(template, args...))
+}
+
+func (l SlogLogger) Debugf(template string, args ...interface{}) {
+	l.Debug(fmt.Sprintf(template, args...))
+}
+
+func (l SlogLogger) Warnf(template string, args ...interface{}) {
+	l.Warn(fmt.Sprintf(template, args...))
+}
+
+func (l SlogLogger) Errorf(template string, args ...interface{}) {
+	l.Error(fmt.Sprintf(template, args...))
+}
+
+func (l SlogLogger) Panicf(template string, args ...interface{}) {
+	l.Error(fmt.Sprintf(template, args...))
+}
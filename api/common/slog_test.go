@@ -0,0 +1,143 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signKey struct {
+	testKey
+}
+
+func (signKey) MakeWsSign() map[string]string {
+	return map[string]string{"apiKey": "key-123", "passphrase": "pass-123", "timestamp": "1", "sign": "sig-123"}
+}
+
+// syncBuffer 日志在receive协程中写入
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type recordLogger struct {
+	lines []string
+}
+
+func (l *recordLogger) Infof(template string, args ...interface{}) {
+	l.lines = append(l.lines, "INFO "+fmt.Sprintf(template, args...))
+}
+func (l *recordLogger) Debugf(template string, args ...interface{}) {
+	l.lines = append(l.lines, "DEBUG "+fmt.Sprintf(template, args...))
+}
+func (l *recordLogger) Warnf(template string, args ...interface{}) {
+	l.lines = append(l.lines, "WARN "+fmt.Sprintf(template, args...))
+}
+func (l *recordLogger) Errorf(template string, args ...interface{}) {
+	l.lines = append(l.lines, "ERROR "+fmt.Sprintf(template, args...))
+}
+func (l *recordLogger) Panicf(template string, args ...interface{}) {
+	l.lines = append(l.lines, "PANIC "+fmt.Sprintf(template, args...))
+}
+
+func TestRedactJSON(t *testing.T) {
+	got := RedactJSON([]byte(`{"op":"login","args":[{"apiKey":"k","passphrase":"p","timestamp":"1","sign":"s"}]}`))
+	assert.Equal(t, `{"op":"login","args":[{"apiKey":"***","passphrase":"***","timestamp":"1","sign":"***"}]}`, got)
+	assert.Equal(t, `{"instId":"BTC-USDT"}`, RedactJSON([]byte(`{"instId":"BTC-USDT"}`)))
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil)))
+	l.With("apiKey", "k").Info("req", "OK-ACCESS-SIGN", "s", slog.Group("auth", "passphrase", "p"), "instId", "BTC-USDT")
+	out := buf.String()
+	assert.NotContains(t, out, "=k ")
+	assert.Contains(t, out, "apiKey=***")
+	assert.Contains(t, out, "OK-ACCESS-SIGN=***")
+	assert.Contains(t, out, "auth.passphrase=***")
+	assert.Contains(t, out, "instId=BTC-USDT")
+}
+
+func TestNewSlogLogger(t *testing.T) {
+	// 默认不转发Debug
+	rec := &recordLogger{}
+	NewSlogLogger(rec).Debug("ws send")
+	assert.Empty(t, rec.lines)
+
+	l := NewSlogLogger(rec, slog.LevelDebug).With("svc", Public)
+	l.Debug("ws send", "op", "subscribe")
+	l.Info("ws login", "sign", "s")
+	l.WithGroup("arg").Warn("ws recv", "channel", "tickers")
+	l.Error("ws decode", "err", "100%")
+	assert.Equal(t, []string{
+		"DEBUG ws send svc=Public op=subscribe",
+		"INFO ws login svc=Public sign=***",
+		"WARN ws recv svc=Public arg.channel=tickers",
+		"ERROR ws decode svc=Public err=100%",
+	}, rec.lines)
+
+	// SlogLogger不再经ILogger转发
+	var buf bytes.Buffer
+	l = NewSlogLogger(SlogLogger{slog.New(slog.NewTextHandler(&buf, nil))})
+	l.Info("ok", "apiKey", "k")
+	assert.Contains(t, buf.String(), "apiKey=***")
+
+	// 直接赋值的Logger与默认logger同样脱敏
+	buf.Reset()
+	ResolveLogger(slog.New(slog.NewTextHandler(&buf, nil)), nil).Info("ok", "sign", "s")
+	assert.Contains(t, buf.String(), "sign=***")
+	buf.Reset()
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	ResolveLogger(nil, nil).Info("ok", "passphrase", "p")
+	assert.Contains(t, buf.String(), "passphrase=***")
+}
+
+func TestWsClientLogger(t *testing.T) {
+	srv := newEchoServer(t, func(op Op) string {
+		return `{"event":"login","code":"0","msg":""}`
+	})
+	defer srv.Close()
+
+	buf := &syncBuffer{}
+	c := NewBaseWsClient(context.Background(), Private, BaseURL("ws"+strings.TrimPrefix(srv.URL, "http")), signKey{}, nil)
+	c.Logger = slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	assert.NoError(t, c.Login(ctx))
+	out := buf.String()
+	assert.Contains(t, out, "msg=\"ws send\" svc=Private connId=")
+	assert.Contains(t, out, "op=login")
+	assert.Contains(t, out, "msg=\"ws login\" svc=Private latency=")
+	for _, secret := range []string{"key-123", "pass-123", "sig-123"} {
+		assert.NotContains(t, out, secret)
+	}
+
+	// 默认级别下不输出逐条消息
+	buf = &syncBuffer{}
+	c.Logger = slog.New(slog.NewTextHandler(buf, nil))
+	c.isLogin.Store(false)
+	assert.NoError(t, c.Login(ctx))
+	assert.NotContains(t, buf.String(), "ws send")
+	assert.Contains(t, buf.String(), "ws login")
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testKey struct{}
+
+func (testKey) MakeHeader(method, requestPath string, body []byte) http.Header { return http.Header{} }
+func (testKey) MakeWsSign() map[string]string                                  { return map[string]string{} }
+func (testKey) MakeSign(now, method, requestPath string, body []byte) string   { return "" }
+
+func TestWsClientTracing(t *testing.T) {
+	srv := newEchoServer(t, func(op Op) string {
+		switch op.Op {
+		case "login":
+			return `{"event":"login","code":"0","msg":""}`
+		case "order":
+			return `{"id":"` + op.Id + `","op":"order","code":"0","msg":"","data":[{"clOrdId":"a","ordId":"1","sCode":"0"}]}`
+		}
+		return `{"id":"` + op.Id + `","op":"` + op.Op + `","code":"1","msg":"failed","data":[]}`
+	})
+	defer srv.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	c := NewBaseWsClient(context.Background(), Private, BaseURL("ws"+strings.TrimPrefix(srv.URL, "http")), testKey{}, nil)
+	c.Tracer = NewTracer(tp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	ctx, parent := tp.Tracer("test").Start(ctx, "caller")
+	assert.NoError(t, c.Login(ctx))
+	_, err := Request[PlaceOrder](&c, ctx, "order", []PlaceOrderReq{{InstID: "BTC-USDT"}})
+	assert.NoError(t, err)
+	_, err = Request[PlaceOrder](&c, ctx, "cancel-order", []CancelOrderReq{{InstId: "BTC-USDT"}})
+	assert.Error(t, err)
+	parent.End()
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	for _, name := range []string{"okx.ws login", "okx.ws order", "okx.ws cancel-order"} {
+		span, ok := spans[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID(), name)
+		}
+	}
+	assert.Equal(t, codes.Unset, spans["okx.ws order"].Status.Code)
+	assert.Equal(t, codes.Error, spans["okx.ws cancel-order"].Status.Code)
+}
@@ -6,11 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kurosann/aqt-sdk/ws"
 )
@@ -54,6 +61,10 @@ func (w DefaultLogger) Panicf(template string, args ...interface{}) {
 	log.Printf(template, args...)
 }
 
+// ReadIdleTimeout 超过该时间未收到任何帧视为连接已断开 交易所会断开30秒无数据的连接
+// 可通过AddDialOptions(ws.WithReadIdleTimeout(...))覆盖
+const ReadIdleTimeout = 30 * time.Second
+
 type ClientBase interface {
 	SetLog(logger ILogger)
 	SetReadMonitor(f func(arg Arg))
@@ -64,13 +75,21 @@ type WsClient struct {
 	conn        *ws.Conn
 	url         BaseURL
 	keyConfig   IKeyConfig
-	Log         ILogger
+	Logger      *slog.Logger // 为nil时使用Log或slog.Default() 逐条消息的日志为Debug级别
+	Log         ILogger      // Deprecated: 使用Logger Logger为nil时经NewSlogLogger转发
 	ReadMonitor func(arg Arg)
+	Metrics     Metrics
+	Tracer      trace.Tracer
 	locker      sync.RWMutex
 	loginLocker sync.RWMutex
-	isLogin     bool
+	isLogin     atomic.Bool
+	reqId       atomic.Uint64
 	proxy       func(req *http.Request) (*url.URL, error)
 	callbacks   map[string]func(resp *WsOriginResp)
+	subs        map[string]*Arg // 订阅中的频道 关闭时统一取消
+	dialOpts    []ws.Option
+	dialed      bool
+	closed      bool
 }
 
 func NewBaseWsClient(ctx context.Context, typ SvcType, url BaseURL, keyConfig IKeyConfig, proxy func(req *http.Request) (*url.URL, error)) WsClient {
@@ -80,38 +99,75 @@ func NewBaseWsClient(ctx context.Context, typ SvcType, url BaseURL, keyConfig IK
 		url:         url,
 		keyConfig:   keyConfig,
 		proxy:       proxy,
-		Log:         DefaultLogger{},
 		callbacks:   map[string]func(resp *WsOriginResp){},
+		subs:        map[string]*Arg{},
 		ReadMonitor: func(arg Arg) {},
+		Metrics:     NopMetrics{},
+		Tracer:      NewTracer(nil),
 	}
 }
 
+func (w *WsClient) logger() *slog.Logger {
+	return ResolveLogger(w.Logger, w.Log)
+}
+
 func (w *WsClient) send(data any) error {
+	conn := w.getConn()
+	if conn == nil {
+		return ws.ErrClosed
+	}
+	return w.sendTo(conn, data)
+}
+
+func (w *WsClient) sendTo(conn *ws.Conn, data any) error {
 	bs, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
-	w.Log.Infof(fmt.Sprintf("%s:send %s", w.typ, string(bs)))
-	return w.conn.Write(bs)
+	if l := w.logger(); l.Enabled(w.ctx, slog.LevelDebug) {
+		attrs := []any{"svc", w.typ, "connId", conn.Id()}
+		if op, ok := data.(Op); ok {
+			attrs = append(attrs, "op", op.Op)
+		}
+		// 登录参数中的签名等字段脱敏
+		l.Debug("ws send", append(attrs, "payload", RedactJSON(bs))...)
+	}
+	return conn.Write(bs)
 }
 
-func (w *WsClient) Login(ctx context.Context) error {
+func (w *WsClient) Login(ctx context.Context) (err error) {
 	w.loginLocker.Lock()
 	defer w.loginLocker.Unlock()
-	if w.isLogin {
+	if w.isLogin.Load() {
 		return nil
 	}
+	ctx, span := w.Tracer.Start(ctx, "okx.ws login", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("ws.svc", string(w.typ))))
+	start := time.Now()
+	defer func() {
+		EndSpan(span, err)
+		if err == nil {
+			w.logger().Info("ws login", "svc", w.typ, "latency", time.Since(start))
+		}
+	}()
+
+	conn, err := w.checkConn()
+	if err != nil {
+		return err
+	}
+	conn.Transition(ws.StateOpen, ws.StateAuthenticating)
+	defer conn.Transition(ws.StateAuthenticating, ws.StateOpen)
 
 	// 并发控制
 	ctx, cancel := context.WithCancelCause(ctx)
 	defer cancel(nil)
 	// 登录并监听
-	err := w.watch(ctx, "login", Op{
+	err = w.watch(ctx, "login", Op{
 		Op:   "login",
 		Args: []map[string]string{w.keyConfig.MakeWsSign()},
 	}, func(rp *WsOriginResp) {
 		if rp.Code != "0" {
-			w.Log.Errorf(fmt.Sprintf("%s:read ws err: %v", w.typ, rp))
+			w.logger().Error("ws login failed", "svc", w.typ, "connId", rp.ConnId, "code", rp.Code, "msg", rp.Msg)
 			cancel(errors.New(rp.Msg))
 		} else {
 			cancel(nil)
@@ -128,12 +184,22 @@ func (w *WsClient) Login(ctx context.Context) error {
 		return err
 	}
 
-	w.isLogin = true
+	w.isLogin.Store(true)
 	return nil
 }
 
 // 订阅
 func (w *WsClient) subscribe(ctx context.Context, arg *Arg, callback func(resp *WsOriginResp)) (err error) {
+	w.Metrics.Subscriptions(w.typ, arg.Channel, 1)
+	defer w.Metrics.Subscriptions(w.typ, arg.Channel, -1)
+	w.locker.Lock()
+	w.subs[arg.Key()] = arg
+	w.locker.Unlock()
+	defer func() {
+		w.locker.Lock()
+		delete(w.subs, arg.Key())
+		w.locker.Unlock()
+	}()
 	err = w.watch(ctx, arg.Key(), Op{Op: "subscribe", Args: []*Arg{arg}}, callback)
 	if err != nil {
 		return err
@@ -142,16 +208,66 @@ func (w *WsClient) subscribe(ctx context.Context, arg *Arg, callback func(resp *
 	return nil
 }
 
+// 请求 发送带id的操作并等待对应响应
+func (w *WsClient) request(ctx context.Context, op string, args any) (rp *WsOriginResp, err error) {
+	id := strconv.FormatUint(w.reqId.Add(1), 10)
+	start := time.Now()
+	ctx, span := w.Tracer.Start(ctx, "okx.ws "+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("ws.svc", string(w.typ)),
+			attribute.String("ws.op", op),
+			attribute.String("ws.id", id)))
+	defer func() {
+		if rp != nil {
+			span.SetAttributes(attribute.String("okx.code", rp.Code))
+			if rp.Code != "0" && err == nil {
+				span.SetStatus(codes.Error, rp.Msg)
+			}
+		}
+		EndSpan(span, err)
+		attrs := []any{"svc", w.typ, "op", op, "id", id, "latency", time.Since(start)}
+		switch {
+		case err != nil:
+			w.logger().Warn("ws request", append(attrs, "err", err)...)
+		case rp != nil && rp.Code != "0":
+			w.logger().Warn("ws request", append(attrs, "code", rp.Code, "msg", rp.Msg)...)
+		default:
+			w.logger().Debug("ws request", attrs...)
+		}
+	}()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	respCh := make(chan *WsOriginResp, 1)
+	err = w.watch(ctx, id, Op{Id: id, Op: op, Args: args}, func(rp *WsOriginResp) {
+		select {
+		case respCh <- rp:
+		default:
+		}
+		cancel()
+	})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case rp = <-respCh:
+		return rp, nil
+	default:
+		return nil, ctx.Err()
+	}
+}
+
 // 取消订阅
 func (w *WsClient) Unsubscribe(arg *Arg) error {
 	return w.send(Op{Op: "unsubscribe", Args: []*Arg{arg}})
 }
-func (w *WsClient) receive() {
-	ch := w.conn.RegisterWatch("receive")
-	defer w.conn.UnregisterWatch("receive")
+
+// receive 处理连接上的数据 ch需在启动前注册以免丢失数据
+func (w *WsClient) receive(conn *ws.Conn, ch <-chan ws.Data) {
+	defer conn.UnregisterWatch("receive")
+	defer w.Metrics.WsState(w.typ, WsDisconnected)
 	for {
 		select {
-		case <-w.conn.Context().Done():
+		case <-conn.Context().Done():
 			return
 		case data, ok := <-ch:
 			if !ok {
@@ -161,83 +277,199 @@ func (w *WsClient) receive() {
 				continue
 			}
 			rp := &WsOriginResp{}
-			err := json.Unmarshal(data.Data, rp)
+			err := ParseEnvelope(data.Data, rp)
 			if err != nil {
-				w.Log.Errorf(fmt.Sprintf("msg:%v, data:%s", err.Error(), string(data.Data)))
+				w.logger().Error("ws decode", "svc", w.typ, "connId", conn.Id(), "err", err, "data", RedactJSON(data.Data))
 				continue
 			}
 			if rp.Event == "error" {
-				w.Log.Errorf(fmt.Sprintf("error msg:%v, data:%s", rp.Msg, string(data.Data)))
-				w.conn.Close(errors.New(rp.Msg))
+				w.logger().Error("ws error event", "svc", w.typ, "connId", conn.Id(), "code", rp.Code, "msg", rp.Msg)
+				conn.Close(errors.New(rp.Msg))
 			}
 			w.ReadMonitor(rp.Arg)
-			if callback, ok := w.getWatch(rp.Arg.Key()); ok {
+			if rp.Arg.Channel != "" && rp.Event == "" {
+				w.Metrics.WsMessage(w.typ, rp.Arg.Channel, len(data.Data))
+			}
+			if callback, ok := w.getArgWatch(&rp.Arg); ok {
+				start := time.Now()
 				callback(rp)
+				latency := time.Since(start)
+				w.Metrics.CallbackLatency(w.typ, rp.Arg.Channel, latency)
+				w.logger().Debug("ws recv", "svc", w.typ, "connId", conn.Id(), "channel", rp.Arg.Channel,
+					"instId", rp.Arg.InstId, "event", rp.Event, "size", len(data.Data), "latency", latency)
 			}
 			if callback, ok := w.getWatch(rp.Event); ok {
 				callback(rp)
 			}
+			if rp.Id != "" {
+				if callback, ok := w.getWatch(rp.Id); ok {
+					callback(rp)
+				}
+			}
 		}
 	}
 }
 
 // 监听
 func (w *WsClient) watch(ctx context.Context, key string, op Op, callback func(resp *WsOriginResp)) error {
-	if err := w.CheckConn(); err != nil {
-		return err
-	}
-	if err := w.send(op); err != nil {
+	conn, err := w.checkConn()
+	if err != nil {
 		return err
 	}
-
-	// 注册监听
+	// 注册监听 需先于发送避免丢失响应
 	w.registerWatch(key, callback)
 	// 返回则取消监听
 	defer w.unregisterWatch(key)
-	for {
-		// 并发控制
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-w.conn.Context().Done():
-			return context.Cause(w.conn.Context())
-		}
+	if err := w.sendTo(conn, op); err != nil {
+		return err
+	}
+	// 并发控制
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-conn.Context().Done():
+		return context.Cause(conn.Context())
 	}
 }
 
-// 判断连接存活的条件
+// 判断连接存活的条件 需持有locker
 func (w *WsClient) isAlive() bool {
-	return w.conn != nil &&
-		w.conn.Status == ws.Alive
+	return w.conn != nil && w.conn.Alive()
+}
+
+func (w *WsClient) getConn() *ws.Conn {
+	w.locker.RLock()
+	defer w.locker.RUnlock()
+
+	return w.conn
+}
+
+// State 当前连接的状态 尚未拨号时为StateClosed
+func (w *WsClient) State() ws.State {
+	if conn := w.getConn(); conn != nil {
+		return conn.State()
+	}
+	return ws.StateClosed
+}
+
+// Close 优雅关闭 取消全部订阅、发送关闭帧并等待连接的协程退出 之后不再重新拨号
+func (w *WsClient) Close(ctx context.Context) error {
+	w.locker.Lock()
+	w.closed = true
+	conn := w.conn
+	args := make([]*Arg, 0, len(w.subs))
+	for _, arg := range w.subs {
+		args = append(args, arg)
+	}
+	w.locker.Unlock()
+	if conn == nil {
+		return nil
+	}
+	if conn.Alive() && len(args) != 0 {
+		_ = w.sendTo(conn, Op{Op: "unsubscribe", Args: args})
+	}
+	return conn.Shutdown(ctx)
+}
+
+// Alive 连接是否存活 不会触发重新拨号
+func (w *WsClient) Alive() bool {
+	w.locker.RLock()
+	defer w.locker.RUnlock()
+
+	return w.isAlive()
 }
 
-// CheckConn 检查连接是否健康
+// Healthy 未使用(从未连接且没有订阅)或连接存活 用于只检查实际使用中的连接
+func (w *WsClient) Healthy() bool {
+	w.locker.RLock()
+	defer w.locker.RUnlock()
+
+	if !w.dialed && len(w.subs) == 0 {
+		return true
+	}
+	return w.isAlive()
+}
+
+// SetRecorder 录制之后建立的连接上收发的全部帧
+func (w *WsClient) SetRecorder(recorder ws.Recorder) {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+
+	w.dialOpts = append(w.dialOpts, ws.WithRecorder(recorder))
+}
+
+// AddDialOptions 追加之后拨号使用的连接参数
+func (w *WsClient) AddDialOptions(opts ...ws.Option) {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+
+	w.dialOpts = append(w.dialOpts, opts...)
+}
+
+// Attach 使用已有连接(如ws.NewReplayConn)替代拨号 回放时视为已登录
+func (w *WsClient) Attach(conn *ws.Conn) {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+
+	w.conn = conn
+	w.isLogin.Store(true)
+	w.Metrics.WsState(w.typ, WsConnected)
+	go w.receive(conn, conn.RegisterWatch("receive"))
+}
+
+// CheckConn 检查连接是否健康 不健康时重新拨号 Close之后返回ws.ErrClosed
 func (w *WsClient) CheckConn() error {
-	if !w.isAlive() {
-		w.locker.Lock()
-		defer w.locker.Unlock()
-		if w.isAlive() {
-			return nil
-		}
+	_, err := w.checkConn()
+	return err
+}
 
-		// 非健康情况重新进行拨号
-		conn, err := ws.DialContext(w.ctx, string(w.url), ws.WithProxy(w.proxy))
-		if err != nil {
-			return err
-		}
+func (w *WsClient) checkConn() (*ws.Conn, error) {
+	w.locker.RLock()
+	if w.isAlive() {
+		defer w.locker.RUnlock()
+		return w.conn, nil
+	}
+	w.locker.RUnlock()
+	w.locker.Lock()
+	defer w.locker.Unlock()
+	if w.closed {
+		return nil, ws.ErrClosed
+	}
+	if w.isAlive() {
+		return w.conn, nil
+	}
+
+	// 非健康情况重新进行拨号
+	metrics := w.Metrics
+	opts := []ws.Option{
+		ws.WithProxy(w.proxy),
+		ws.WithPingRTT(func(rtt time.Duration) {
+			metrics.PingRTT(w.typ, rtt)
+		}),
 		// 保持连接的依据
-		conn.SetKeepAlive(
+		ws.WithKeepAlive(
 			func(conn *ws.Conn) error {
 				return conn.Write([]byte("ping"))
 			},
 			func(data []byte) bool {
 				return string(data) == "pong"
-			})
-		w.conn = conn
-		w.isLogin = false
-		go w.receive()
+			}),
+		ws.WithReadIdleTimeout(ReadIdleTimeout),
 	}
-	return nil
+	conn, err := ws.DialContext(w.ctx, string(w.url), append(opts, w.dialOpts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if w.dialed {
+		metrics.WsReconnect(w.typ)
+	}
+	w.logger().Info("ws connected", "svc", w.typ, "connId", conn.Id(), "url", string(w.url), "reconnect", w.dialed)
+	w.dialed = true
+	metrics.WsState(w.typ, WsConnected)
+	w.conn = conn
+	w.isLogin.Store(false)
+	go w.receive(conn, conn.RegisterWatch("receive"))
+	return conn, nil
 }
 
 func (w *WsClient) registerWatch(key string, callback func(resp *WsOriginResp)) {
@@ -261,6 +493,17 @@ func (w *WsClient) getWatch(key string) (func(resp *WsOriginResp), bool) {
 	return f, ok
 }
 
+// getArgWatch 按arg查找监听 拼接key时不分配内存
+func (w *WsClient) getArgWatch(arg *Arg) (func(resp *WsOriginResp), bool) {
+	var buf [128]byte
+	key := arg.appendKey(buf[:0])
+	w.locker.RLock()
+	defer w.locker.RUnlock()
+
+	f, ok := w.callbacks[string(key)]
+	return f, ok
+}
+
 func Subscribe[T any](c *WsClient, ctx context.Context, arg *Arg, callback func(resp *WsResp[T])) error {
 	return c.subscribe(ctx, arg, func(resp *WsOriginResp) {
 		if resp.Event == "subscribe" {
@@ -269,7 +512,8 @@ func Subscribe[T any](c *WsClient, ctx context.Context, arg *Arg, callback func(
 		var t []T
 		err := json.Unmarshal(resp.Data, &t)
 		if err != nil {
-			c.Log.Errorf(err.Error())
+			c.logger().Error("ws decode", "svc", c.typ, "connId", resp.ConnId, "channel", resp.Arg.Channel,
+				"instId", resp.Arg.InstId, "err", err)
 			return
 		}
 		callback(&WsResp[T]{
@@ -278,7 +522,65 @@ func Subscribe[T any](c *WsClient, ctx context.Context, arg *Arg, callback func(
 			Code:   resp.Code,
 			Msg:    resp.Msg,
 			Arg:    resp.Arg,
+			Action: resp.Action,
 			Data:   t,
 		})
 	})
 }
+
+// SubscribeDecode 使用指定解码器订阅 适用于books-l2-tbt、trades-all等高频频道
+// resp及其Data在回调返回后会被复用 需保留时自行拷贝
+func SubscribeDecode[T any](c *WsClient, ctx context.Context, arg *Arg, decode Decoder[T], callback func(resp *WsResp[T])) error {
+	pool := &sync.Pool{New: func() any { return &WsResp[T]{} }}
+	return c.subscribe(ctx, arg, func(rp *WsOriginResp) {
+		if rp.Event == "subscribe" {
+			return
+		}
+		resp := pool.Get().(*WsResp[T])
+		defer pool.Put(resp)
+		data, err := decode(rp.Data, resp.Data[:0])
+		if err != nil {
+			c.logger().Error("ws decode", "svc", c.typ, "connId", rp.ConnId, "channel", rp.Arg.Channel,
+				"instId", rp.Arg.InstId, "err", err)
+			return
+		}
+		*resp = WsResp[T]{
+			Event:  rp.Event,
+			ConnId: rp.ConnId,
+			Code:   rp.Code,
+			Msg:    rp.Msg,
+			Arg:    rp.Arg,
+			Action: rp.Action,
+			Data:   data,
+		}
+		callback(resp)
+	})
+}
+
+// Request 发送请求类操作(下单、撤单等)并解析响应
+func Request[T any](c *WsClient, ctx context.Context, op string, args any) (*WsResp[T], error) {
+	rp, err := c.request(ctx, op, args)
+	if err != nil {
+		return nil, err
+	}
+	var t []T
+	if len(rp.Data) != 0 {
+		if err := json.Unmarshal(rp.Data, &t); err != nil {
+			return nil, err
+		}
+	}
+	resp := &WsResp[T]{
+		Id:     rp.Id,
+		Op:     rp.Op,
+		Event:  rp.Event,
+		ConnId: rp.ConnId,
+		Code:   rp.Code,
+		Msg:    rp.Msg,
+		Arg:    rp.Arg,
+		Data:   t,
+	}
+	if rp.Code != "0" {
+		return resp, fmt.Errorf("%s %s: %s", op, rp.Code, rp.Msg)
+	}
+	return resp, nil
+}
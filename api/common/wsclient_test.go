@@ -0,0 +1,120 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+// newEchoServer 对每个带id的请求返回固定响应
+func newEchoServer(t *testing.T, reply func(op Op) string) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(data) == "ping" {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte("pong"))
+				continue
+			}
+			var op Op
+			if err := json.Unmarshal(data, &op); err != nil {
+				continue
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(reply(op)))
+		}
+	}))
+}
+
+func TestRequest(t *testing.T) {
+	srv := newEchoServer(t, func(op Op) string {
+		if op.Op == "order" {
+			return `{"id":"` + op.Id + `","op":"order","code":"0","msg":"","data":[{"clOrdId":"a","ordId":"1","sCode":"0"}]}`
+		}
+		return `{"id":"` + op.Id + `","op":"` + op.Op + `","code":"1","msg":"failed","data":[{"sCode":"51000","sMsg":"bad"}]}`
+	})
+	defer srv.Close()
+
+	c := NewBaseWsClient(context.Background(), Private, BaseURL("ws"+strings.TrimPrefix(srv.URL, "http")), nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rp, err := Request[PlaceOrder](&c, ctx, "order", []PlaceOrderReq{{InstID: "BTC-USDT"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", rp.Data[0].OrdId)
+
+	rp, err = Request[PlaceOrder](&c, ctx, "cancel-order", []CancelOrderReq{{InstId: "BTC-USDT"}})
+	assert.Error(t, err)
+	assert.Equal(t, "51000", rp.Data[0].SCode)
+}
+
+func TestAttachReplayConn(t *testing.T) {
+	c := NewBaseWsClient(context.Background(), Public, "", nil, nil)
+	conn := ws.NewReplayConn(context.Background())
+	c.Attach(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	got := make(chan *WsResp[*MarkPrice], 1)
+	go func() {
+		_ = Subscribe(&c, ctx, MakeArg("mark-price", "BTC-USDT"), func(resp *WsResp[*MarkPrice]) {
+			got <- resp
+		})
+	}()
+	assert.Eventually(t, func() bool {
+		_, ok := c.getWatch(MakeArg("mark-price", "BTC-USDT").Key())
+		return ok
+	}, time.Second, time.Millisecond)
+
+	conn.Inject(ws.TextMessage, []byte(`{"arg":{"channel":"mark-price","instId":"BTC-USDT"},"data":[{"instId":"BTC-USDT","markPx":"100"}]}`))
+	select {
+	case resp := <-got:
+		assert.Equal(t, "100", resp.Data[0].MarkPx)
+	case <-ctx.Done():
+		assert.Fail(t, "callback not fired")
+	}
+}
+
+func TestWsClientClose(t *testing.T) {
+	ops := make(chan Op, 8)
+	srv := newEchoServer(t, func(op Op) string {
+		ops <- op
+		return `{"event":"` + op.Op + `","arg":{"channel":"tickers","instId":"BTC-USDT"}}`
+	})
+	defer srv.Close()
+
+	c := NewBaseWsClient(context.Background(), Public, BaseURL("ws"+strings.TrimPrefix(srv.URL, "http")), nil, nil)
+	assert.Equal(t, ws.StateClosed, c.State())
+	done := make(chan error, 1)
+	go func() {
+		done <- Subscribe(&c, context.Background(), MakeArg("tickers", "BTC-USDT"), func(resp *WsResp[*Ticker]) {})
+	}()
+	assert.Equal(t, "subscribe", (<-ops).Op)
+	assert.Equal(t, ws.StateOpen, c.State())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	assert.NoError(t, c.Close(ctx))
+	op := <-ops
+	assert.Equal(t, "unsubscribe", op.Op)
+	assert.ErrorIs(t, <-done, ws.ErrClosed)
+	assert.Equal(t, ws.StateClosed, c.State())
+	assert.ErrorIs(t, c.CheckConn(), ws.ErrClosed)
+}
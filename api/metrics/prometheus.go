@@ -0,0 +1,136 @@
+// Package metrics 提供common.Metrics的Prometheus实现
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// Prometheus 以Prometheus指标记录SDK的运行情况
+type Prometheus struct {
+	restRequests    *prometheus.CounterVec
+	restLatency     *prometheus.HistogramVec
+	restRetries     *prometheus.CounterVec
+	rateLimitWait   *prometheus.HistogramVec
+	wsConnected     *prometheus.GaugeVec
+	wsReconnects    *prometheus.CounterVec
+	wsMessages      *prometheus.CounterVec
+	wsBytes         *prometheus.CounterVec
+	callbackLatency *prometheus.HistogramVec
+	subscriptions   *prometheus.GaugeVec
+	pingRTT         *prometheus.HistogramVec
+}
+
+var _ common.Metrics = (*Prometheus)(nil)
+
+// NewPrometheus 创建指标并注册到reg namespace为空时使用aqt
+func NewPrometheus(reg prometheus.Registerer, namespace string) (*Prometheus, error) {
+	if namespace == "" {
+		namespace = "aqt"
+	}
+	p := &Prometheus{
+		restRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "requests_total",
+			Help: "REST requests by endpoint, method and response code.",
+		}, []string{"endpoint", "method", "code"}),
+		restLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "request_duration_seconds",
+			Help:    "REST request latency.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 10),
+		}, []string{"endpoint", "method"}),
+		restRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "retries_total",
+			Help: "REST retry decisions by endpoint, method and outcome.",
+		}, []string{"endpoint", "method", "outcome"}),
+		rateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "rate_limit_wait_seconds",
+			Help:    "Time spent waiting for the rate limiter.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+		}, []string{"endpoint"}),
+		wsConnected: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "ws", Name: "connected",
+			Help: "Whether the WebSocket connection is up (1) or down (0).",
+		}, []string{"svc"}),
+		wsReconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "ws", Name: "reconnects_total",
+			Help: "WebSocket redials after the first connection.",
+		}, []string{"svc"}),
+		wsMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "ws", Name: "messages_total",
+			Help: "Pushed messages by channel.",
+		}, []string{"svc", "channel"}),
+		wsBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "ws", Name: "message_bytes_total",
+			Help: "Pushed message bytes by channel.",
+		}, []string{"svc", "channel"}),
+		callbackLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "ws", Name: "callback_duration_seconds",
+			Help:    "Time spent in subscription callbacks.",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"svc", "channel"}),
+		subscriptions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "ws", Name: "subscriptions",
+			Help: "Active subscriptions by channel.",
+		}, []string{"svc", "channel"}),
+		pingRTT: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "ws", Name: "ping_rtt_seconds",
+			Help:    "WebSocket ping round-trip time.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 10),
+		}, []string{"svc"}),
+	}
+	for _, c := range []prometheus.Collector{
+		p.restRequests, p.restLatency, p.restRetries, p.rateLimitWait,
+		p.wsConnected, p.wsReconnects, p.wsMessages, p.wsBytes,
+		p.callbackLatency, p.subscriptions, p.pingRTT,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Prometheus) RestRequest(endpoint, method, code string, elapsed time.Duration) {
+	p.restRequests.WithLabelValues(endpoint, method, code).Inc()
+	p.restLatency.WithLabelValues(endpoint, method).Observe(elapsed.Seconds())
+}
+
+func (p *Prometheus) RestRetry(endpoint, method, outcome string) {
+	p.restRetries.WithLabelValues(endpoint, method, outcome).Inc()
+}
+
+func (p *Prometheus) RateLimitWait(endpoint string, waited time.Duration) {
+	p.rateLimitWait.WithLabelValues(endpoint).Observe(waited.Seconds())
+}
+
+func (p *Prometheus) WsState(typ common.SvcType, state string) {
+	v := 0.0
+	if state == common.WsConnected {
+		v = 1
+	}
+	p.wsConnected.WithLabelValues(string(typ)).Set(v)
+}
+
+func (p *Prometheus) WsReconnect(typ common.SvcType) {
+	p.wsReconnects.WithLabelValues(string(typ)).Inc()
+}
+
+func (p *Prometheus) WsMessage(typ common.SvcType, channel string, size int) {
+	p.wsMessages.WithLabelValues(string(typ), channel).Inc()
+	p.wsBytes.WithLabelValues(string(typ), channel).Add(float64(size))
+}
+
+func (p *Prometheus) CallbackLatency(typ common.SvcType, channel string, elapsed time.Duration) {
+	p.callbackLatency.WithLabelValues(string(typ), channel).Observe(elapsed.Seconds())
+}
+
+func (p *Prometheus) Subscriptions(typ common.SvcType, channel string, delta int) {
+	p.subscriptions.WithLabelValues(string(typ), channel).Add(float64(delta))
+}
+
+func (p *Prometheus) PingRTT(typ common.SvcType, rtt time.Duration) {
+	p.pingRTT.WithLabelValues(string(typ)).Observe(rtt.Seconds())
+}
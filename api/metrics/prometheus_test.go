@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p, err := NewPrometheus(reg, "")
+	assert.NoError(t, err)
+
+	p.RestRequest("/api/v5/trade/order", "POST", "0", 20*time.Millisecond)
+	p.RestRequest("/api/v5/trade/order", "POST", "50013", 30*time.Millisecond)
+	p.RestRetry("/api/v5/trade/order", "POST", common.RetryRetried)
+	p.RestRetry("/api/v5/trade/order", "POST", common.RetryDeduped)
+	p.RateLimitWait("/api/v5/trade/order", time.Millisecond)
+	p.WsState(common.Public, common.WsConnected)
+	p.WsState(common.Private, common.WsDisconnected)
+	p.WsReconnect(common.Public)
+	p.WsMessage(common.Public, "tickers", 100)
+	p.WsMessage(common.Public, "tickers", 50)
+	p.CallbackLatency(common.Public, "tickers", time.Microsecond)
+	p.Subscriptions(common.Public, "tickers", 1)
+	p.Subscriptions(common.Public, "tickers", 1)
+	p.Subscriptions(common.Public, "tickers", -1)
+	p.PingRTT(common.Public, 15*time.Millisecond)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.restRequests.WithLabelValues("/api/v5/trade/order", "POST", "50013")))
+	assert.Equal(t, 1, testutil.CollectAndCount(p.restLatency))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.restRetries.WithLabelValues("/api/v5/trade/order", "POST", common.RetryDeduped)))
+	assert.Equal(t, 2, testutil.CollectAndCount(p.restRetries))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.wsConnected.WithLabelValues("Public")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(p.wsConnected.WithLabelValues("Private")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.wsReconnects.WithLabelValues("Public")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(p.wsMessages.WithLabelValues("Public", "tickers")))
+	assert.Equal(t, 150.0, testutil.ToFloat64(p.wsBytes.WithLabelValues("Public", "tickers")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.subscriptions.WithLabelValues("Public", "tickers")))
+
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	names := map[string]bool{}
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+	assert.True(t, names["aqt_ws_ping_rtt_seconds"])
+	assert.True(t, names["aqt_rest_rate_limit_wait_seconds"])
+	assert.True(t, names["aqt_ws_callback_duration_seconds"])
+
+	// 重复注册返回错误
+	_, err = NewPrometheus(reg, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,236 @@
+// Package model 与交易所无关的领域类型 各交易所适配器负责与原始格式互相转换
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotSupported 交易所不支持该功能
+var ErrNotSupported = errors.New("not supported by venue")
+
+type InstType string
+
+const (
+	Spot    InstType = "SPOT"
+	Swap    InstType = "SWAP" // 永续合约
+	Futures InstType = "FUTURES"
+	Option  InstType = "OPTION"
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+type OrderType string
+
+const (
+	Market   OrderType = "market"
+	Limit    OrderType = "limit"
+	PostOnly OrderType = "post_only"
+	IOC      OrderType = "ioc"
+	FOK      OrderType = "fok"
+)
+
+type OrderStatus string
+
+const (
+	StatusNew             OrderStatus = "new"
+	StatusPartiallyFilled OrderStatus = "partially_filled"
+	StatusFilled          OrderStatus = "filled"
+	StatusCanceled        OrderStatus = "canceled"
+	StatusRejected        OrderStatus = "rejected"
+)
+
+// IsFinal 是否为终态
+func (s OrderStatus) IsFinal() bool {
+	return s == StatusFilled || s == StatusCanceled || s == StatusRejected
+}
+
+type PosSide string
+
+const (
+	Net   PosSide = "net"
+	Long  PosSide = "long"
+	Short PosSide = "short"
+)
+
+// MakeInstId 统一产品标识 现货为BASE-QUOTE 永续为BASE-QUOTE-SWAP
+// 交割合约为BASE-QUOTE-YYMMDD 期权为BASE-QUOTE-YYMMDD-STRIKE-C/P 由suffix依次给出
+// 缺少交割日期或行权信息时无法与现货区分 直接panic
+func MakeInstId(base, quote string, typ InstType, suffix ...string) string {
+	instId := strings.ToUpper(base) + "-" + strings.ToUpper(quote)
+	switch typ {
+	case Swap:
+		return instId + "-SWAP"
+	case Futures:
+		if len(suffix) != 1 || suffix[0] == "" || strings.EqualFold(suffix[0], "SWAP") {
+			panic(fmt.Sprintf("model: futures instId needs an expiry, got %q", suffix))
+		}
+	case Option:
+		if len(suffix) != 3 {
+			panic(fmt.Sprintf("model: option instId needs expiry, strike and C/P, got %q", suffix))
+		}
+	}
+	for _, s := range suffix {
+		instId += "-" + strings.ToUpper(s)
+	}
+	return instId
+}
+
+// ParseInstType 由统一产品标识推断产品类型
+func ParseInstType(instId string) InstType {
+	parts := strings.Split(instId, "-")
+	switch {
+	case len(parts) == 2:
+		return Spot
+	case len(parts) == 3 && parts[2] == "SWAP":
+		return Swap
+	case len(parts) == 3:
+		return Futures
+	default:
+		return Option
+	}
+}
+
+// Instrument 产品信息 数值字段保留交易所原始精度
+type Instrument struct {
+	Venue   string   `json:"venue"`
+	InstId  string   `json:"instId"` // 统一标识
+	Symbol  string   `json:"symbol"` // 交易所原始标识
+	Type    InstType `json:"type"`
+	Base    string   `json:"base"`
+	Quote   string   `json:"quote"`
+	Settle  string   `json:"settle"` // 保证金币种 现货为空
+	TickSz  string   `json:"tickSz"`
+	LotSz   string   `json:"lotSz"`
+	MinSz   string   `json:"minSz"`
+	CtVal   string   `json:"ctVal"` // 合约面值 现货及以币为单位下单的合约为空
+	Inverse bool     `json:"inverse"`
+	Live    bool     `json:"live"`
+}
+
+// OrderRequest 下单请求 Sz以交易所下单单位计
+type OrderRequest struct {
+	InstId     string    `json:"instId"`
+	ClOrdId    string    `json:"clOrdId"`
+	Side       Side      `json:"side"`
+	Type       OrderType `json:"type"`
+	Px         string    `json:"px"`
+	Sz         string    `json:"sz"`
+	PosSide    PosSide   `json:"posSide"`
+	ReduceOnly bool      `json:"reduceOnly"`
+}
+
+// Order 订单 时间为毫秒时间戳
+type Order struct {
+	Venue    string      `json:"venue"`
+	InstId   string      `json:"instId"`
+	OrdId    string      `json:"ordId"`
+	ClOrdId  string      `json:"clOrdId"`
+	Side     Side        `json:"side"`
+	Type     OrderType   `json:"type"`
+	Status   OrderStatus `json:"status"`
+	Px       string      `json:"px"`
+	Sz       string      `json:"sz"`
+	AvgPx    string      `json:"avgPx"`
+	FilledSz string      `json:"filledSz"`
+	CTime    int64       `json:"cTime"`
+	UTime    int64       `json:"uTime"`
+}
+
+// Fill 一笔成交
+type Fill struct {
+	Venue   string `json:"venue"`
+	InstId  string `json:"instId"`
+	OrdId   string `json:"ordId"`
+	ClOrdId string `json:"clOrdId"`
+	TradeId string `json:"tradeId"`
+	Side    Side   `json:"side"`
+	Px      string `json:"px"`
+	Sz      string `json:"sz"`
+	Fee     string `json:"fee"` // 负数表示支出
+	FeeCcy  string `json:"feeCcy"`
+	Maker   bool   `json:"maker"`
+	Ts      int64  `json:"ts"`
+}
+
+// Balance 单币种余额
+type Balance struct {
+	Venue   string `json:"venue"`
+	Account string `json:"account"` // 交易所内的账户 如现货、U本位合约 统一账户为空
+	Ccy     string `json:"ccy"`
+	Total   string `json:"total"`
+	Avail   string `json:"avail"`
+	Frozen  string `json:"frozen"`
+	UTime   int64  `json:"uTime"`
+}
+
+// Position 持仓 Pos带符号 多仓为正 空仓为负
+// 单向持仓时PosSide为net 双向持仓时PosSide为long或short 空仓的Pos同样为负数 各适配器负责统一符号
+type Position struct {
+	Venue   string  `json:"venue"`
+	InstId  string  `json:"instId"`
+	PosSide PosSide `json:"posSide"`
+	Pos     string  `json:"pos"`
+	AvgPx   string  `json:"avgPx"`
+	MarkPx  string  `json:"markPx"`
+	Upl     string  `json:"upl"`
+	Lever   string  `json:"lever"`
+	UTime   int64   `json:"uTime"`
+}
+
+// Ticker 最优买卖价
+type Ticker struct {
+	Venue  string `json:"venue"`
+	InstId string `json:"instId"`
+	Last   string `json:"last"`
+	BidPx  string `json:"bidPx"`
+	BidSz  string `json:"bidSz"`
+	AskPx  string `json:"askPx"`
+	AskSz  string `json:"askSz"`
+	Ts     int64  `json:"ts"`
+}
+
+// Level 深度档位
+type Level struct {
+	Px string `json:"px"`
+	Sz string `json:"sz"`
+}
+
+// BookUpdate 深度推送 Snapshot为true时替换整个深度 否则为增量 数量为0表示删除该档位
+type BookUpdate struct {
+	Venue    string  `json:"venue"`
+	InstId   string  `json:"instId"`
+	Bids     []Level `json:"bids"`
+	Asks     []Level `json:"asks"`
+	Snapshot bool    `json:"snapshot"`
+	SeqId    int64   `json:"seqId"`
+	Ts       int64   `json:"ts"`
+}
+
+// Candle k线 Ts为开盘时间 Confirm为true时k线已完结
+type Candle struct {
+	Venue   string `json:"venue"`
+	InstId  string `json:"instId"`
+	Bar     string `json:"bar"` // 周期 与OKX格式一致 如1m、15m、1H、4H、1D、1W
+	O       string `json:"o"`
+	H       string `json:"h"`
+	L       string `json:"l"`
+	C       string `json:"c"`
+	Vol     string `json:"vol"` // 以基础币种或张数计的成交量
+	Confirm bool   `json:"confirm"`
+	Ts      int64  `json:"ts"`
+}
+
+// MarkPrice 标记价格 仅衍生品
+type MarkPrice struct {
+	Venue  string `json:"venue"`
+	InstId string `json:"instId"`
+	MarkPx string `json:"markPx"`
+	Ts     int64  `json:"ts"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeInstIdRoundTrip(t *testing.T) {
+	for _, c := range []struct {
+		typ    InstType
+		suffix []string
+		want   string
+	}{
+		{Spot, nil, "BTC-USDT"},
+		{Swap, nil, "BTC-USDT-SWAP"},
+		{Futures, []string{"250627"}, "BTC-USDT-250627"},
+		{Option, []string{"250627", "100000", "c"}, "BTC-USDT-250627-100000-C"},
+	} {
+		instId := MakeInstId("btc", "usdt", c.typ, c.suffix...)
+		assert.Equal(t, c.want, instId)
+		assert.Equal(t, c.typ, ParseInstType(instId), instId)
+	}
+
+	assert.Panics(t, func() { MakeInstId("BTC", "USDT", Futures) })
+	assert.Panics(t, func() { MakeInstId("BTC", "USDT", Futures, "SWAP") })
+	assert.Panics(t, func() { MakeInstId("BTC", "USDT", Option, "250627") })
+}
@@ -0,0 +1,491 @@
+package okx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/model"
+)
+
+const Venue = "okx"
+
+// Adapter 将OKX的REST与WS接口转换为与交易所无关的领域类型
+type Adapter struct {
+	Rest   *RestClient
+	Ws     *ExchangeClient
+	TdMode string // 衍生品下单使用的交易模式 默认cross 现货固定为cash
+
+	lock    sync.Mutex
+	orderCb func(order *model.Order)
+	fillCb  func(fill *model.Fill)
+	orders  common.Shared
+}
+
+// NewAdapter REST与WS客户端共用同一组Option
+func NewAdapter(ctx context.Context, opts ...Option) (*Adapter, error) {
+	wsClient, err := NewWsClientWithOptions(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Adapter{
+		Rest:   wsClient.Rest,
+		Ws:     wsClient,
+		TdMode: "cross",
+	}, nil
+}
+
+func (a *Adapter) Name() string {
+	return Venue
+}
+
+func (a *Adapter) SetLog(logger common.ILogger) {
+	a.SetLogger(common.NewSlogLogger(logger))
+}
+
+// SetLogger 结构化日志 密钥与签名字段会被脱敏
+func (a *Adapter) SetLogger(logger *slog.Logger) {
+	a.Rest.Logger = nil
+	if logger != nil {
+		a.Rest.Logger = slog.New(common.NewRedactHandler(logger.Handler()))
+	}
+	a.Ws.SetLogger(logger)
+}
+
+func (a *Adapter) SetMetrics(metrics common.Metrics) {
+	a.Rest.Metrics = metrics
+	a.Ws.SetMetrics(metrics)
+}
+
+func (a *Adapter) SetReadMonitor(f func(arg common.Arg)) {
+	a.Ws.SetReadMonitor(f)
+}
+
+// Close 关闭WS连接与REST客户端
+func (a *Adapter) Close() {
+	a.Ws.Close()
+}
+
+//-------------------------- REST --------------------------
+
+// GetInstruments 获取产品列表
+func (a *Adapter) GetInstruments(ctx context.Context, typ model.InstType) ([]model.Instrument, error) {
+	rp, err := a.Rest.Instruments(ctx, common.InstrumentsReq{InstType: string(typ)})
+	if err != nil {
+		return nil, err
+	}
+	instruments := make([]model.Instrument, 0, len(rp.Data))
+	for _, inst := range rp.Data {
+		instruments = append(instruments, toInstrument(inst))
+	}
+	return instruments, nil
+}
+
+// GetTicker 获取最新行情
+func (a *Adapter) GetTicker(ctx context.Context, instId string) (*model.Ticker, error) {
+	rp, err := a.Rest.Ticker(ctx, instId)
+	if err != nil {
+		return nil, err
+	}
+	if len(rp.Data) == 0 {
+		return nil, fmt.Errorf("okx: no ticker for %s", instId)
+	}
+	return toTicker(&rp.Data[0]), nil
+}
+
+// GetBalances 交易账户各币种余额
+func (a *Adapter) GetBalances(ctx context.Context) ([]model.Balance, error) {
+	rp, err := a.Rest.Balance(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var balances []model.Balance
+	for _, bal := range rp.Data {
+		for _, d := range bal.Details {
+			balances = append(balances, toBalance(d))
+		}
+	}
+	return balances, nil
+}
+
+// GetPositions 全部持仓
+func (a *Adapter) GetPositions(ctx context.Context) ([]model.Position, error) {
+	rp, err := a.Rest.AccountPositions(ctx, common.PositionReq{})
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]model.Position, 0, len(rp.Data))
+	for i := range rp.Data {
+		positions = append(positions, toPosition(&rp.Data[i]))
+	}
+	return positions, nil
+}
+
+// PlaceOrder 下单 现货市价买单同样以基础币种计量
+func (a *Adapter) PlaceOrder(ctx context.Context, req model.OrderRequest) (*model.Order, error) {
+	rp, err := a.Rest.PlaceOrder(ctx, toPlaceOrderReq(req, a.TdMode))
+	if err != nil {
+		return nil, err
+	}
+	if len(rp.Data) == 0 {
+		return nil, fmt.Errorf("okx: empty place order response")
+	}
+	ack := rp.Data[0]
+	if ack.SCode != "" && ack.SCode != "0" {
+		return nil, fmt.Errorf("okx: %s %s", ack.SCode, ack.SMsg)
+	}
+	return &model.Order{
+		Venue:   Venue,
+		InstId:  req.InstId,
+		OrdId:   ack.OrdId,
+		ClOrdId: ack.ClOrdId,
+		Side:    req.Side,
+		Type:    req.Type,
+		Status:  model.StatusNew,
+		Px:      req.Px,
+		Sz:      req.Sz,
+	}, nil
+}
+
+// CancelOrder 按clOrdId撤单
+func (a *Adapter) CancelOrder(ctx context.Context, instId, clOrdId string) error {
+	rp, err := a.Rest.CancelOrder(ctx, instId, clOrdId)
+	if err != nil {
+		return err
+	}
+	if len(rp.Data) != 0 && rp.Data[0].SCode != "" && rp.Data[0].SCode != "0" {
+		return fmt.Errorf("okx: %s %s", rp.Data[0].SCode, rp.Data[0].SMsg)
+	}
+	return nil
+}
+
+// GetOrder 按clOrdId查询订单
+func (a *Adapter) GetOrder(ctx context.Context, instId, clOrdId string) (*model.Order, error) {
+	rp, err := a.Rest.GetOrder(ctx, common.PlaceOrderReq{InstID: instId, ClOrdID: clOrdId})
+	if err != nil {
+		return nil, err
+	}
+	if len(rp.Data) == 0 {
+		return nil, ErrOrderNotFound
+	}
+	return toOrder(&rp.Data[0]), nil
+}
+
+// GetOpenOrders 未完成订单 instId为空时返回全部
+func (a *Adapter) GetOpenOrders(ctx context.Context, instId string) ([]model.Order, error) {
+	rp, err := a.Rest.OrdersPending(ctx, common.OrdersPendingReq{InstId: instId})
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]model.Order, 0, len(rp.Data))
+	for i := range rp.Data {
+		orders = append(orders, *toOrder(&rp.Data[i]))
+	}
+	return orders, nil
+}
+
+//-------------------------- WS --------------------------
+
+// Tickers 行情推送
+func (a *Adapter) Tickers(ctx context.Context, instId string, callback func(ticker *model.Ticker)) error {
+	return a.Ws.PublicClient.Tickers(ctx, instId, func(resp *common.WsResp[*common.Ticker]) {
+		for _, t := range resp.Data {
+			callback(toTicker(t))
+		}
+	})
+}
+
+// Books 五档深度推送 每次推送均为快照
+func (a *Adapter) Books(ctx context.Context, instId string, callback func(book *model.BookUpdate)) error {
+	return a.Ws.PublicClient.Books(ctx, "books5", instId, func(resp *common.WsResp[*common.OrderBook]) {
+		for _, b := range resp.Data {
+			callback(toBookUpdate(resp.Arg.InstId, b))
+		}
+	})
+}
+
+// Candles k线推送 bar如1m、1H、1D 未完结k线同样推送
+func (a *Adapter) Candles(ctx context.Context, instId, bar string, callback func(candle *model.Candle)) error {
+	return a.Ws.BusinessClient.Candle(ctx, bar, instId, func(resp *common.WsResp[*common.Candle]) {
+		for _, c := range resp.Data {
+			callback(toCandle(resp.Arg.InstId, bar, c))
+		}
+	})
+}
+
+// MarkPrice 标记价格推送
+func (a *Adapter) MarkPrice(ctx context.Context, instId string, callback func(mp *model.MarkPrice)) error {
+	return a.Ws.PublicClient.MarkPrice(ctx, instId, func(resp *common.WsResp[*common.MarkPrice]) {
+		for _, mp := range resp.Data {
+			callback(&model.MarkPrice{Venue: Venue, InstId: mp.InstId, MarkPx: mp.MarkPx, Ts: parseMs(mp.Ts)})
+		}
+	})
+}
+
+// Account 余额推送 每个币种回调一次
+func (a *Adapter) Account(ctx context.Context, callback func(balance *model.Balance)) error {
+	return a.Ws.PrivateClient.Account(ctx, func(resp *common.WsResp[*common.Balance]) {
+		for _, bal := range resp.Data {
+			for _, d := range bal.Details {
+				b := toBalance(d)
+				callback(&b)
+			}
+		}
+	})
+}
+
+// Positions 持仓推送
+func (a *Adapter) Positions(ctx context.Context, callback func(position *model.Position)) error {
+	return a.Ws.PrivateClient.AccountPositions(ctx, "ANY", func(resp *common.WsResp[*common.Position]) {
+		for _, p := range resp.Data {
+			pos := toPosition(p)
+			callback(&pos)
+		}
+	})
+}
+
+// Orders 订单推送 与Fills共用订单频道
+func (a *Adapter) Orders(ctx context.Context, callback func(order *model.Order)) error {
+	a.setOrderCallbacks(func() { a.orderCb = callback })
+	defer a.setOrderCallbacks(func() { a.orderCb = nil })
+	return a.watchOrders(ctx)
+}
+
+// Fills 成交推送 由订单频道中带成交信息的推送转换而来
+func (a *Adapter) Fills(ctx context.Context, callback func(fill *model.Fill)) error {
+	a.setOrderCallbacks(func() { a.fillCb = callback })
+	defer a.setOrderCallbacks(func() { a.fillCb = nil })
+	return a.watchOrders(ctx)
+}
+
+func (a *Adapter) setOrderCallbacks(set func()) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	set()
+}
+
+func (a *Adapter) watchOrders(ctx context.Context) error {
+	return a.orders.Join(ctx, func(ctx context.Context) error {
+		return a.Ws.PrivateClient.Orders(ctx, "ANY", a.onOrder)
+	})
+}
+
+func (a *Adapter) onOrder(resp *common.WsResp[*common.Order]) {
+	a.lock.Lock()
+	orderCb, fillCb := a.orderCb, a.fillCb
+	a.lock.Unlock()
+	for _, o := range resp.Data {
+		if orderCb != nil {
+			orderCb(toOrder(o))
+		}
+		if fill, ok := toFill(o); ok && fillCb != nil {
+			fillCb(fill)
+		}
+	}
+}
+
+//-------------------------- 转换 --------------------------
+
+func toPlaceOrderReq(req model.OrderRequest, tdMode string) common.PlaceOrderReq {
+	r := common.PlaceOrderReq{
+		InstID:     req.InstId,
+		ClOrdID:    req.ClOrdId,
+		Side:       string(req.Side),
+		OrdType:    string(req.Type),
+		Px:         req.Px,
+		Sz:         req.Sz,
+		ReduceOnly: req.ReduceOnly,
+		TdMode:     "cash",
+	}
+	if req.PosSide != model.Net {
+		r.PosSide = string(req.PosSide)
+	}
+	if model.ParseInstType(req.InstId) == model.Spot {
+		if req.Type == model.Market {
+			r.TgtCcy = "base_ccy"
+		}
+	} else {
+		r.TdMode = tdMode
+	}
+	return r
+}
+
+func toInstrument(inst common.Instruments) model.Instrument {
+	m := model.Instrument{
+		Venue:   Venue,
+		InstId:  inst.InstId,
+		Symbol:  inst.InstId,
+		Type:    model.InstType(inst.InstType),
+		Base:    inst.BaseCcy,
+		Quote:   inst.QuoteCcy,
+		Settle:  inst.SettleCcy,
+		TickSz:  inst.TickSz,
+		LotSz:   inst.LotSz,
+		MinSz:   inst.MinSz,
+		CtVal:   inst.CtVal,
+		Inverse: inst.CtType == "inverse",
+		Live:    inst.State == "live",
+	}
+	if m.Base == "" {
+		if parts := strings.Split(inst.Uly, "-"); len(parts) == 2 {
+			m.Base, m.Quote = parts[0], parts[1]
+		}
+	}
+	return m
+}
+
+func toTicker(t *common.Ticker) *model.Ticker {
+	return &model.Ticker{
+		Venue:  Venue,
+		InstId: t.InstId,
+		Last:   t.Last,
+		BidPx:  t.BidPx,
+		BidSz:  t.BidSz,
+		AskPx:  t.AskPx,
+		AskSz:  t.AskSz,
+		Ts:     parseMs(t.Ts),
+	}
+}
+
+func toCandle(instId, bar string, c *common.Candle) *model.Candle {
+	return &model.Candle{
+		Venue:   Venue,
+		InstId:  instId,
+		Bar:     bar,
+		O:       c.O,
+		H:       c.H,
+		L:       c.L,
+		C:       c.C,
+		Vol:     c.Vol,
+		Confirm: c.Confirm == "1",
+		Ts:      parseMs(c.Ts),
+	}
+}
+
+func toBookUpdate(instId string, b *common.OrderBook) *model.BookUpdate {
+	return &model.BookUpdate{
+		Venue:    Venue,
+		InstId:   instId,
+		Bids:     toLevels(b.Bids),
+		Asks:     toLevels(b.Asks),
+		Snapshot: true,
+		SeqId:    b.SeqId,
+		Ts:       parseMs(b.Ts),
+	}
+}
+
+func toLevels(spreads []common.Spread) []model.Level {
+	levels := make([]model.Level, 0, len(spreads))
+	for _, s := range spreads {
+		levels = append(levels, model.Level{Px: s.Price, Sz: s.Count})
+	}
+	return levels
+}
+
+func toBalance(d common.BalanceDetail) model.Balance {
+	return model.Balance{
+		Venue:  Venue,
+		Ccy:    d.Ccy,
+		Total:  d.CashBal,
+		Avail:  d.AvailBal,
+		Frozen: d.FrozenBal,
+		UTime:  parseMs(d.UTime),
+	}
+}
+
+// toPosition 双向持仓的空仓pos为正数 统一为负数
+func toPosition(p *common.Position) model.Position {
+	pos := p.Pos
+	if p.PosSide == "short" && pos != "" && !strings.HasPrefix(pos, "-") && !isZero(pos) {
+		pos = "-" + pos
+	}
+	return model.Position{
+		Venue:   Venue,
+		InstId:  p.InstId,
+		PosSide: model.PosSide(p.PosSide),
+		Pos:     pos,
+		AvgPx:   p.AvgPx,
+		MarkPx:  p.MarkPx,
+		Upl:     p.Upl,
+		Lever:   p.Lever,
+		UTime:   parseMs(p.UTime),
+	}
+}
+
+func toOrder(o *common.Order) *model.Order {
+	return &model.Order{
+		Venue:    Venue,
+		InstId:   o.InstId,
+		OrdId:    o.OrdId,
+		ClOrdId:  o.ClOrdId,
+		Side:     model.Side(o.Side),
+		Type:     toOrderType(o.OrdType),
+		Status:   toOrderStatus(o.State),
+		Px:       o.Px,
+		Sz:       o.Sz,
+		AvgPx:    o.AvgPx,
+		FilledSz: o.AccFillSz,
+		CTime:    parseMs(o.CTime),
+		UTime:    parseMs(o.UTime),
+	}
+}
+
+// toFill 订单推送中带有成交信息时转换为成交
+func toFill(o *common.Order) (*model.Fill, bool) {
+	if o.TradeId == "" || o.FillSz == "" || o.FillSz == "0" {
+		return nil, false
+	}
+	return &model.Fill{
+		Venue:   Venue,
+		InstId:  o.InstId,
+		OrdId:   o.OrdId,
+		ClOrdId: o.ClOrdId,
+		TradeId: o.TradeId,
+		Side:    model.Side(o.Side),
+		Px:      o.FillPx,
+		Sz:      o.FillSz,
+		Fee:     o.FillFee,
+		FeeCcy:  o.FillFeeCcy,
+		Maker:   o.ExecType == "M",
+		Ts:      parseMs(o.FillTime),
+	}, true
+}
+
+func toOrderType(ordType string) model.OrderType {
+	switch ordType {
+	case "optimal_limit_ioc":
+		return model.IOC
+	case "mmp", "mmp_and_post_only":
+		return model.PostOnly
+	}
+	return model.OrderType(ordType)
+}
+
+func toOrderStatus(state string) model.OrderStatus {
+	switch OrderState(state) {
+	case OrderPendingNew, OrderLive:
+		return model.StatusNew
+	case OrderPartiallyFilled:
+		return model.StatusPartiallyFilled
+	case OrderFilled:
+		return model.StatusFilled
+	case OrderCanceled, "mmp_canceled":
+		return model.StatusCanceled
+	}
+	return model.StatusRejected
+}
+
+func isZero(v string) bool {
+	f, err := strconv.ParseFloat(v, 64)
+	return err == nil && f == 0
+}
+
+func parseMs(ts string) int64 {
+	v, _ := strconv.ParseInt(ts, 10, 64)
+	return v
+}
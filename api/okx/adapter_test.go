@@ -0,0 +1,70 @@
+package okx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/model"
+)
+
+func TestAdapterPlaceOrderReq(t *testing.T) {
+	r := toPlaceOrderReq(model.OrderRequest{InstId: "BTC-USDT", Side: model.Buy, Type: model.Market, Sz: "0.1", PosSide: model.Net}, "cross")
+	assert.Equal(t, "cash", r.TdMode)
+	assert.Equal(t, "base_ccy", r.TgtCcy)
+	assert.Empty(t, r.PosSide)
+
+	r = toPlaceOrderReq(model.OrderRequest{InstId: "BTC-USDT-SWAP", Side: model.Sell, Type: model.PostOnly, Px: "100", Sz: "1", PosSide: model.Short}, "isolated")
+	assert.Equal(t, "isolated", r.TdMode)
+	assert.Equal(t, "post_only", r.OrdType)
+	assert.Equal(t, "short", r.PosSide)
+	assert.Empty(t, r.TgtCcy)
+}
+
+func TestAdapterOrder(t *testing.T) {
+	o := &common.Order{
+		InstId: "BTC-USDT-SWAP", OrdId: "1", ClOrdId: "c1", Side: "buy", OrdType: "optimal_limit_ioc", State: "partially_filled",
+		Sz: "2", AccFillSz: "1", AvgPx: "100", TradeId: "t1", FillPx: "100", FillSz: "1", FillFee: "-0.05", FillFeeCcy: "USDT",
+		ExecType: "M", FillTime: "1700000000000", UTime: "1700000000001",
+	}
+	order := toOrder(o)
+	assert.Equal(t, model.IOC, order.Type)
+	assert.Equal(t, model.StatusPartiallyFilled, order.Status)
+	assert.Equal(t, "1", order.FilledSz)
+	assert.Equal(t, int64(1700000000001), order.UTime)
+
+	fill, ok := toFill(o)
+	assert.True(t, ok)
+	assert.Equal(t, model.Fill{
+		Venue: Venue, InstId: "BTC-USDT-SWAP", OrdId: "1", ClOrdId: "c1", TradeId: "t1", Side: model.Buy,
+		Px: "100", Sz: "1", Fee: "-0.05", FeeCcy: "USDT", Maker: true, Ts: 1700000000000,
+	}, *fill)
+
+	o.State, o.TradeId, o.FillSz = "mmp_canceled", "", "0"
+	assert.Equal(t, model.StatusCanceled, toOrder(o).Status)
+	_, ok = toFill(o)
+	assert.False(t, ok)
+}
+
+func TestAdapterInstrument(t *testing.T) {
+	inst := toInstrument(common.Instruments{InstId: "BTC-USD-SWAP", InstType: "SWAP", Uly: "BTC-USD", SettleCcy: "BTC", CtType: "inverse", CtVal: "100", TickSz: "0.1", LotSz: "1", MinSz: "1", State: "live"})
+	assert.Equal(t, "BTC", inst.Base)
+	assert.Equal(t, "USD", inst.Quote)
+	assert.True(t, inst.Inverse)
+	assert.True(t, inst.Live)
+	assert.Equal(t, model.Swap, inst.Type)
+}
+
+func TestAdapterPosition(t *testing.T) {
+	// 双向持仓的空仓统一为负数
+	assert.Equal(t, "-2", toPosition(&common.Position{InstId: "BTC-USDT-SWAP", PosSide: "short", Pos: "2"}).Pos)
+	assert.Equal(t, "2", toPosition(&common.Position{InstId: "BTC-USDT-SWAP", PosSide: "long", Pos: "2"}).Pos)
+	assert.Equal(t, "-2", toPosition(&common.Position{InstId: "BTC-USDT-SWAP", PosSide: "net", Pos: "-2"}).Pos)
+	assert.Equal(t, "0", toPosition(&common.Position{InstId: "BTC-USDT-SWAP", PosSide: "short", Pos: "0"}).Pos)
+}
+
+func TestAdapterCandle(t *testing.T) {
+	c := toCandle("BTC-USDT", "1H", &common.Candle{Ts: "1700000000000", O: "1", H: "2", L: "0.5", C: "1.5", Vol: "10", Confirm: "1"})
+	assert.Equal(t, model.Candle{Venue: Venue, InstId: "BTC-USDT", Bar: "1H", O: "1", H: "2", L: "0.5", C: "1.5", Vol: "10", Confirm: true, Ts: 1700000000000}, *c)
+}
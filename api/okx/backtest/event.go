@@ -0,0 +1,258 @@
+package backtest
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx/bar"
+)
+
+// Event 一条历史行情 Channel与实盘频道名一致 如candle1m、mark-price、trades、books5
+// 已完结k线(confirm为1或为空)在收盘时间ts+bar回放 confirm为0的未完结k线按Ts回放
+type Event struct {
+	Ts        int64             `json:"ts"`
+	Channel   string            `json:"channel"`
+	InstId    string            `json:"instId"`
+	Candle    *common.Candle    `json:"candle,omitempty"`
+	MarkPrice *common.MarkPrice `json:"markPrice,omitempty"`
+	Trade     *common.Trade     `json:"trade,omitempty"`
+	Book      *common.OrderBook `json:"book,omitempty"`
+	seq       int
+}
+
+// Source 按时间升序产出行情
+type Source interface {
+	Next() (Event, bool)
+	Err() error
+}
+
+type sliceSource struct {
+	events []Event
+	i      int
+}
+
+// NewSliceSource 内存数据源 events需按时间升序
+func NewSliceSource(events []Event) Source {
+	return &sliceSource{events: events}
+}
+
+func (s *sliceSource) Next() (Event, bool) {
+	if s.i >= len(s.events) {
+		return Event{}, false
+	}
+	s.i++
+	return s.events[s.i-1], true
+}
+
+func (s *sliceSource) Err() error {
+	return nil
+}
+
+type jsonSource struct {
+	scanner *bufio.Scanner
+	err     error
+}
+
+// NewJSONSource 每行一个Event的JSON数据源
+func NewJSONSource(r io.Reader) Source {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &jsonSource{scanner: scanner}
+}
+
+func (s *jsonSource) Next() (Event, bool) {
+	for s.err == nil && s.scanner.Scan() {
+		line := s.scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			s.err = err
+			return Event{}, false
+		}
+		return e, true
+	}
+	if s.err == nil {
+		s.err = s.scanner.Err()
+	}
+	return Event{}, false
+}
+
+func (s *jsonSource) Err() error {
+	return s.err
+}
+
+// LoadCandlesCSV 读取k线CSV 列为ts,o,h,l,c,vol[,volCcy,volCcyQuote,confirm] 首行为表头时自动跳过
+// ts为开盘时间 已完结k线的事件时间为收盘时间
+func LoadCandlesCSV(r io.Reader, bar, instId string) ([]Event, error) {
+	if _, err := barPeriod("candle" + bar); err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for i, rec := range records {
+		if len(rec) < 6 {
+			return nil, fmt.Errorf("line %d: want at least 6 columns, got %d", i+1, len(rec))
+		}
+		ts, err := strconv.ParseInt(rec[0], 10, 64)
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		c := &common.Candle{Ts: rec[0], O: rec[1], H: rec[2], L: rec[3], C: rec[4], Vol: rec[5], Confirm: "1"}
+		if len(rec) >= 9 {
+			c.VolCcy, c.VolCcyQuote, c.Confirm = rec[6], rec[7], rec[8]
+		}
+		e := Event{Ts: ts, Channel: "candle" + bar, InstId: instId, Candle: c}
+		if err := stampCandle(&e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// barPeriod 由candle频道名解析k线周期
+func barPeriod(channel string) (time.Duration, error) {
+	period, err := bar.ParsePeriod(strings.TrimPrefix(channel, "candle"))
+	if err != nil {
+		return 0, fmt.Errorf("backtest: %w", err)
+	}
+	return period, nil
+}
+
+// stampCandle 已完结k线的事件时间不早于收盘时间 避免策略在k线开始时看到整根k线
+// 未完结k线保持原时间 Channel不是candle频道的事件不处理
+func stampCandle(e *Event) error {
+	c := e.Candle
+	if c == nil || !strings.HasPrefix(e.Channel, "candle") || c.Confirm == "0" {
+		return nil
+	}
+	period, err := barPeriod(e.Channel)
+	if err != nil {
+		return err
+	}
+	open, err := strconv.ParseInt(c.Ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("backtest: candle ts %q: %w", c.Ts, err)
+	}
+	e.Ts = max(e.Ts, open+period.Milliseconds())
+	if c.Confirm == "" {
+		confirmed := *c
+		confirmed.Confirm = "1"
+		e.Candle = &confirmed
+	}
+	return nil
+}
+
+// LoadTradesCSV 读取成交CSV 列为ts,px,sz,side[,tradeId]
+func LoadTradesCSV(r io.Reader, instId string) ([]Event, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for i, rec := range records {
+		if len(rec) < 4 {
+			return nil, fmt.Errorf("line %d: want at least 4 columns, got %d", i+1, len(rec))
+		}
+		ts, err := strconv.ParseInt(rec[0], 10, 64)
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		t := &common.Trade{InstId: instId, Ts: rec[0], Px: rec[1], Sz: rec[2], Side: rec[3]}
+		if len(rec) >= 5 {
+			t.TradeId = rec[4]
+		}
+		events = append(events, Event{Ts: ts, Channel: "trades", InstId: instId, Trade: t})
+	}
+	return events, nil
+}
+
+// merger 多路数据源按时间归并 时间相同时保持数据源顺序
+// 已完结k线按收盘时间归并 同一数据源中k线与其他行情混合时需已按收盘时间排序
+type merger struct {
+	sources []Source
+	heap    eventHeap
+	err     error
+}
+
+func newMerger(sources []Source) *merger {
+	m := &merger{sources: sources}
+	for i := range sources {
+		if e, ok := m.pull(i); ok {
+			m.heap = append(m.heap, e)
+		}
+	}
+	heap.Init(&m.heap)
+	return m
+}
+
+// pull 读取第i个数据源的下一条行情
+func (m *merger) pull(i int) (Event, bool) {
+	e, ok := m.sources[i].Next()
+	if !ok {
+		return Event{}, false
+	}
+	if err := stampCandle(&e); err != nil {
+		m.err = err
+		return Event{}, false
+	}
+	e.seq = i
+	return e, true
+}
+
+func (m *merger) Next() (Event, bool) {
+	if len(m.heap) == 0 || m.err != nil {
+		return Event{}, false
+	}
+	e := heap.Pop(&m.heap).(Event)
+	if next, ok := m.pull(e.seq); ok {
+		heap.Push(&m.heap, next)
+	}
+	return e, true
+}
+
+func (m *merger) Err() error {
+	errs := []error{m.err}
+	for _, s := range m.sources {
+		errs = append(errs, s.Err())
+	}
+	return errors.Join(errs...)
+}
+
+type eventHeap []Event
+
+func (h eventHeap) Len() int { return len(h) }
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].Ts != h[j].Ts {
+		return h[i].Ts < h[j].Ts
+	}
+	return h[i].seq < h[j].seq
+}
+func (h eventHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *eventHeap) Push(x any)   { *h = append(*h, x.(Event)) }
+func (h *eventHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
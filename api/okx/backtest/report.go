@@ -0,0 +1,191 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// EquityPoint 权益曲线上的一个点
+type EquityPoint struct {
+	Ts       int64   `json:"ts"`
+	Equity   float64 `json:"equity"`
+	Drawdown float64 `json:"drawdown"`
+}
+
+// Report 回测报告
+type Report struct {
+	QuoteCcy      string             `json:"quoteCcy"`
+	Start         int64              `json:"start"`
+	End           int64              `json:"end"`
+	InitialEquity float64            `json:"initialEquity"`
+	FinalEquity   float64            `json:"finalEquity"`
+	Return        float64            `json:"return"`
+	MaxDrawdown   float64            `json:"maxDrawdown"`
+	Sharpe        float64            `json:"sharpe"`
+	Volume        float64            `json:"volume"`   // 成交额
+	Turnover      float64            `json:"turnover"` // 成交额/初始权益
+	Fills         int                `json:"fills"`
+	Fees          map[string]float64 `json:"fees"`
+	Equity        []EquityPoint      `json:"equity"`
+}
+
+// WriteJSON 导出JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV 导出权益曲线 列为ts,equity,drawdown
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"ts", "equity", "drawdown"}); err != nil {
+		return err
+	}
+	for _, p := range r.Equity {
+		if err := cw.Write([]string{
+			strconv.FormatInt(p.Ts, 10),
+			strconv.FormatFloat(p.Equity, 'f', -1, 64),
+			strconv.FormatFloat(p.Drawdown, 'f', -1, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type reportBuilder struct {
+	quoteCcy string
+	period   time.Duration
+	equity   []EquityPoint
+	peak     float64
+	maxDD    float64
+	volume   float64
+	fills    int
+	fees     map[string]float64
+	insts    map[string]common.Instruments
+}
+
+func newReportBuilder(quoteCcy string, period time.Duration, insts []common.Instruments) *reportBuilder {
+	b := &reportBuilder{quoteCcy: quoteCcy, period: period, fees: map[string]float64{}, insts: map[string]common.Instruments{}}
+	for _, inst := range insts {
+		b.insts[inst.InstId] = inst
+	}
+	return b
+}
+
+// sample 记录权益 同一时间戳只保留最后一次
+func (b *reportBuilder) sample(ts int64, equity float64) {
+	if equity > b.peak {
+		b.peak = equity
+	}
+	var dd float64
+	if b.peak > 0 {
+		dd = (b.peak - equity) / b.peak
+	}
+	if dd > b.maxDD {
+		b.maxDD = dd
+	}
+	p := EquityPoint{Ts: ts, Equity: equity, Drawdown: dd}
+	if n := len(b.equity); n != 0 && b.equity[n-1].Ts == ts {
+		b.equity[n-1] = p
+		return
+	}
+	b.equity = append(b.equity, p)
+}
+
+func (b *reportBuilder) onOrder(o *common.Order) {
+	if o.FillSz == "" {
+		return
+	}
+	px, _ := strconv.ParseFloat(o.FillPx, 64)
+	sz, _ := strconv.ParseFloat(o.FillSz, 64)
+	fee, _ := strconv.ParseFloat(o.FillFee, 64)
+	b.volume += b.notional(o.InstId, px, sz)
+	b.fills++
+	b.fees[o.FillFeeCcy] += fee
+}
+
+// notional 成交额 衍生品按合约面值折算 币本位合约面值即为计价货币
+func (b *reportBuilder) notional(instId string, px, sz float64) float64 {
+	inst, ok := b.insts[instId]
+	if !ok || inst.InstType == "SPOT" || inst.InstType == "MARGIN" {
+		return px * sz
+	}
+	ctVal, err := strconv.ParseFloat(inst.CtVal, 64)
+	if err != nil || ctVal <= 0 {
+		ctVal = 1
+	}
+	if inst.CtType == "inverse" {
+		return sz * ctVal
+	}
+	return px * sz * ctVal
+}
+
+func (b *reportBuilder) build() *Report {
+	r := &Report{
+		QuoteCcy:    b.quoteCcy,
+		MaxDrawdown: b.maxDD,
+		Volume:      b.volume,
+		Fills:       b.fills,
+		Fees:        b.fees,
+		Equity:      b.equity,
+	}
+	if len(b.equity) == 0 {
+		return r
+	}
+	first, last := b.equity[0], b.equity[len(b.equity)-1]
+	r.Start, r.End = first.Ts, last.Ts
+	r.InitialEquity, r.FinalEquity = first.Equity, last.Equity
+	if first.Equity != 0 {
+		r.Return = last.Equity/first.Equity - 1
+		r.Turnover = b.volume / first.Equity
+	}
+	r.Sharpe = b.sharpe()
+	return r
+}
+
+// sharpe 按采样周期重采样权益后计算年化夏普比率 无风险利率视为0
+func (b *reportBuilder) sharpe() float64 {
+	periodMs := b.period.Milliseconds()
+	var samples []float64
+	var bucket int64 = math.MinInt64
+	for _, p := range b.equity {
+		if k := p.Ts / periodMs; k != bucket {
+			bucket = k
+			samples = append(samples, p.Equity)
+		} else {
+			samples[len(samples)-1] = p.Equity
+		}
+	}
+	if len(samples) < 3 {
+		return 0
+	}
+	var returns []float64
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1] != 0 {
+			returns = append(returns, samples[i]/samples[i-1]-1)
+		}
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	if variance == 0 {
+		return 0
+	}
+	return mean / math.Sqrt(variance) * math.Sqrt(float64(365*24*time.Hour)/float64(b.period))
+}
@@ -0,0 +1,346 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+	"github.com/kurosann/aqt-sdk/api/okx/paper"
+)
+
+// Config 回测配置
+type Config struct {
+	Paper       paper.Config  // 撮合参数 Clock由回测接管
+	QuoteCcy    string        // 计算权益的计价币种 默认USDT
+	Period      time.Duration // 计算夏普比率的采样周期 默认1天 不小于1ms
+	CandleDepth float64       // 仅有k线数据时在收盘价模拟的深度数量 默认1e9
+}
+
+// Runner 按时间顺序回放历史行情并在模拟时钟上撮合
+type Runner struct {
+	cfg     Config
+	sources []Source
+	now     time.Time
+	engine  *paper.Engine
+	client  *Client
+	prices  map[string]float64
+	report  *reportBuilder
+}
+
+func NewRunner(cfg Config, sources ...Source) *Runner {
+	if cfg.QuoteCcy == "" {
+		cfg.QuoteCcy = "USDT"
+	}
+	if cfg.Period == 0 {
+		cfg.Period = 24 * time.Hour
+	}
+	if cfg.Period < time.Millisecond {
+		panic(fmt.Sprintf("backtest: invalid period %s: want at least 1ms", cfg.Period))
+	}
+	if cfg.CandleDepth == 0 {
+		cfg.CandleDepth = 1e9
+	}
+	r := &Runner{
+		cfg:     cfg,
+		sources: sources,
+		prices:  map[string]float64{},
+	}
+	cfg.Paper.Clock = func() time.Time { return r.now }
+	r.engine = paper.NewEngine(cfg.Paper)
+	r.client = newClient(r)
+	r.report = newReportBuilder(cfg.QuoteCcy, cfg.Period, cfg.Paper.Instruments)
+	r.engine.OnOrder = r.onOrder
+	return r
+}
+
+// Client 供策略使用的客户端 需在Run之前完成订阅
+func (r *Runner) Client() *Client {
+	return r.client
+}
+
+// Engine 底层撮合引擎
+func (r *Runner) Engine() *paper.Engine {
+	return r.engine
+}
+
+// Run 回放全部行情并生成报告
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	m := newMerger(r.sources)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		e, ok := m.Next()
+		if !ok {
+			break
+		}
+		r.now = time.UnixMilli(e.Ts)
+		r.apply(e)
+		r.client.deliver(e)
+		r.report.sample(e.Ts, r.equity())
+	}
+	if err := m.Err(); err != nil {
+		return nil, err
+	}
+	return r.report.build(), nil
+}
+
+// apply 将行情送入撮合引擎
+func (r *Runner) apply(e Event) {
+	switch {
+	case e.Candle != nil:
+		c := e.Candle
+		depth := strconv.FormatFloat(r.cfg.CandleDepth, 'f', -1, 64)
+		ts := strconv.FormatInt(e.Ts, 10)
+		// k线内最低价与最高价视为发生过成交 可撮合区间内的挂单 已完结k线在收盘时间回放
+		r.engine.OnTrade(&common.Trade{InstId: e.InstId, Px: c.L, Sz: depth, Side: "sell", Ts: ts})
+		r.engine.OnTrade(&common.Trade{InstId: e.InstId, Px: c.H, Sz: depth, Side: "buy", Ts: ts})
+		r.engine.OnBook(e.InstId, &common.OrderBook{
+			Bids: []common.Spread{{Price: c.C, Count: depth}},
+			Asks: []common.Spread{{Price: c.C, Count: depth}},
+			Ts:   ts,
+		})
+		r.setPrice(e.InstId, c.C)
+	case e.Trade != nil:
+		r.engine.OnTrade(e.Trade)
+		r.setPrice(e.InstId, e.Trade.Px)
+	case e.Book != nil:
+		r.engine.OnBook(e.InstId, e.Book)
+		if len(e.Book.Bids) != 0 && len(e.Book.Asks) != 0 {
+			bid, _ := strconv.ParseFloat(e.Book.Bids[0].Price, 64)
+			ask, _ := strconv.ParseFloat(e.Book.Asks[0].Price, 64)
+			r.prices[e.InstId] = (bid + ask) / 2
+		}
+	case e.MarkPrice != nil:
+		r.engine.OnMarkPrice(e.MarkPrice)
+	}
+	r.engine.Advance()
+}
+
+func (r *Runner) setPrice(instId, px string) {
+	if v, err := strconv.ParseFloat(px, 64); err == nil {
+		r.prices[instId] = v
+	}
+}
+
+// equity 以计价币种折算的账户权益 衍生品计入未实现盈亏
+func (r *Runner) equity() float64 {
+	ctx := context.Background()
+	rest := r.engine.Rest()
+	var equity float64
+	if bal, err := rest.Balance(ctx, ""); err == nil && len(bal.Data) != 0 {
+		for _, d := range bal.Data[0].Details {
+			cash, _ := strconv.ParseFloat(d.CashBal, 64)
+			if d.Ccy == r.cfg.QuoteCcy {
+				equity += cash
+				continue
+			}
+			equity += cash * r.prices[d.Ccy+"-"+r.cfg.QuoteCcy]
+		}
+	}
+	if pos, err := rest.AccountPositions(ctx, common.PositionReq{}); err == nil {
+		for _, p := range pos.Data {
+			upl, _ := strconv.ParseFloat(p.Upl, 64)
+			equity += upl
+		}
+	}
+	return equity
+}
+
+func (r *Runner) onOrder(o *common.Order) {
+	r.report.onOrder(o)
+	r.client.deliverOrder(o)
+}
+
+var _ okx.Streamer = (*Client)(nil)
+
+// Client 实现okx.Streamer的回测客户端 订阅立即返回 回调在回放时同步触发
+type Client struct {
+	r           *Runner
+	lock        sync.RWMutex
+	logger      *slog.Logger
+	readMonitor func(arg common.Arg)
+	candles     map[string]func(resp *common.WsResp[*common.Candle])
+	markPrices  map[string]func(resp *common.WsResp[*common.MarkPrice])
+	trades      map[string]func(resp *common.WsResp[*common.Trade])
+	books       map[string]func(resp *common.WsResp[*common.OrderBook])
+	orders      map[string]func(resp *common.WsResp[*common.Order])
+	account     func(resp *common.WsResp[*common.Balance])
+}
+
+func newClient(r *Runner) *Client {
+	return &Client{
+		r:           r,
+		readMonitor: func(arg common.Arg) {},
+		candles:     map[string]func(resp *common.WsResp[*common.Candle]){},
+		markPrices:  map[string]func(resp *common.WsResp[*common.MarkPrice]){},
+		trades:      map[string]func(resp *common.WsResp[*common.Trade]){},
+		books:       map[string]func(resp *common.WsResp[*common.OrderBook]){},
+		orders:      map[string]func(resp *common.WsResp[*common.Order]){},
+	}
+}
+
+// Rest 下单等REST接口
+func (c *Client) Rest() *paper.RestClient {
+	return c.r.engine.Rest()
+}
+
+// Now 当前模拟时间
+func (c *Client) Now() time.Time {
+	return c.r.now
+}
+
+// SetLog 兼容ILogger 使用SetLogger输出结构化日志
+func (c *Client) SetLog(log common.ILogger) {
+	c.SetLogger(common.NewSlogLogger(log))
+}
+
+// SetLogger 回放中推送失败时输出日志 为nil时使用slog.Default()
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.logger = logger
+}
+
+func (c *Client) SetReadMonitor(f func(arg common.Arg)) {
+	c.readMonitor = f
+}
+
+func (c *Client) Account(ctx context.Context, callback func(resp *common.WsResp[*common.Balance])) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.account = callback
+	return nil
+}
+
+func (c *Client) UAccount() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.account = nil
+	return nil
+}
+
+func (c *Client) Candle(ctx context.Context, channel, instId string, callback func(resp *common.WsResp[*common.Candle])) error {
+	return register(c, c.candles, "candle"+channel+"-"+instId, callback)
+}
+
+func (c *Client) UCandle(channel, instId string) error {
+	return unregister(c, c.candles, "candle"+channel+"-"+instId)
+}
+
+func (c *Client) MarkPrice(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.MarkPrice])) error {
+	return register(c, c.markPrices, instId, callback)
+}
+
+func (c *Client) UMarkPrice(instId string) error {
+	return unregister(c, c.markPrices, instId)
+}
+
+// MarketTrades 交易频道
+func (c *Client) MarketTrades(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.Trade])) error {
+	return register(c, c.trades, instId, callback)
+}
+
+// Books 深度频道
+func (c *Client) Books(ctx context.Context, channel, instId string, callback func(resp *common.WsResp[*common.OrderBook])) error {
+	return register(c, c.books, channel+"-"+instId, callback)
+}
+
+// Orders 订单频道 instType为空或ANY时接收全部
+func (c *Client) Orders(ctx context.Context, instType string, callback func(resp *common.WsResp[*common.Order])) error {
+	return register(c, c.orders, instType, callback)
+}
+
+func (c *Client) UOrders(instType string) error {
+	return unregister(c, c.orders, instType)
+}
+
+func (c *Client) SpotOrders(ctx context.Context, callback func(resp *common.WsResp[*common.Order])) error {
+	return c.Orders(ctx, "SPOT", callback)
+}
+
+func (c *Client) USpotOrders() error {
+	return c.UOrders("SPOT")
+}
+
+func register[T any](c *Client, m map[string]func(resp *common.WsResp[T]), key string, callback func(resp *common.WsResp[T])) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m[key] = callback
+	return nil
+}
+
+func unregister[T any](c *Client, m map[string]func(resp *common.WsResp[T]), key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(m, key)
+	return nil
+}
+
+func lookup[T any](c *Client, m map[string]func(resp *common.WsResp[T]), key string) func(resp *common.WsResp[T]) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return m[key]
+}
+
+// deliver 将行情回调给策略
+func (c *Client) deliver(e Event) {
+	arg := common.Arg{Channel: e.Channel, InstId: e.InstId}
+	c.readMonitor(arg)
+	switch {
+	case e.Candle != nil:
+		if cb := lookup(c, c.candles, e.Channel+"-"+e.InstId); cb != nil {
+			cb(&common.WsResp[*common.Candle]{Arg: arg, Data: []*common.Candle{e.Candle}})
+		}
+	case e.MarkPrice != nil:
+		if cb := lookup(c, c.markPrices, e.InstId); cb != nil {
+			cb(&common.WsResp[*common.MarkPrice]{Arg: arg, Data: []*common.MarkPrice{e.MarkPrice}})
+		}
+	case e.Trade != nil:
+		if cb := lookup(c, c.trades, e.InstId); cb != nil {
+			cb(&common.WsResp[*common.Trade]{Arg: arg, Data: []*common.Trade{e.Trade}})
+		}
+	case e.Book != nil:
+		if cb := lookup(c, c.books, e.Channel+"-"+e.InstId); cb != nil {
+			cb(&common.WsResp[*common.OrderBook]{Arg: arg, Data: []*common.OrderBook{e.Book}})
+		}
+	}
+}
+
+func (c *Client) deliverOrder(o *common.Order) {
+	c.lock.RLock()
+	var callbacks []func(resp *common.WsResp[*common.Order])
+	for instType, cb := range c.orders {
+		if instType == "" || strings.EqualFold(instType, "ANY") || instType == o.InstType {
+			callbacks = append(callbacks, cb)
+		}
+	}
+	account := c.account
+	logger := common.ResolveLogger(c.logger, nil)
+	c.lock.RUnlock()
+
+	for _, cb := range callbacks {
+		cb(&common.WsResp[*common.Order]{Arg: common.Arg{Channel: "orders", InstType: o.InstType}, Data: []*common.Order{o}})
+	}
+	if account != nil && o.FillSz != "" {
+		bal, err := c.r.engine.Rest().Balance(context.Background(), "")
+		switch {
+		case err != nil:
+			logger.Warn("backtest: push account failed", "err", err)
+		case len(bal.Data) != 0:
+			account(&common.WsResp[*common.Balance]{Arg: common.Arg{Channel: "account"}, Data: []*common.Balance{&bal.Data[0]}})
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package backtest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx/paper"
+)
+
+const candlesCSV = `ts,o,h,l,c,vol
+86400000,100,101,99,100,10
+172800000,100,111,100,110,10
+259200000,110,121,109,120,10
+345600000,120,121,100,105,10
+`
+
+func TestRunner(t *testing.T) {
+	events, err := LoadCandlesCSV(strings.NewReader(candlesCSV), "1D", "BTC-USDT")
+	assert.NoError(t, err)
+	assert.Len(t, events, 4)
+
+	r := NewRunner(Config{Paper: paper.Config{Balances: map[string]float64{"USDT": 1000}, TakerFee: 0.001, MakerFee: 0.001}},
+		NewSliceSource(events))
+	c := r.Client()
+	ctx := context.Background()
+
+	var fills int
+	assert.NoError(t, c.SpotOrders(ctx, func(resp *common.WsResp[*common.Order]) {
+		if resp.Data[0].FillSz != "" {
+			fills++
+		}
+	}))
+	var n int
+	assert.NoError(t, c.Candle(ctx, "1D", "BTC-USDT", func(resp *common.WsResp[*common.Candle]) {
+		n++
+		switch n {
+		case 1:
+			_, err := c.Rest().PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "market", TgtCcy: "base_ccy", Sz: "5"})
+			assert.NoError(t, err)
+			// 挂单在第三根k线最高价成交
+			_, err = c.Rest().PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "sell", OrdType: "limit", Px: "115", Sz: "5"})
+			assert.NoError(t, err)
+		}
+	}))
+
+	report, err := r.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fills)
+	assert.Equal(t, 2, report.Fills)
+	assert.InDelta(t, 1000, report.InitialEquity, 1)
+	// 买入100 卖出115 两次手续费
+	want := 1000 + 5*15 - 0.001*(500+575)
+	assert.InDelta(t, want, report.FinalEquity, 1e-6)
+	assert.InDelta(t, 1075, report.Volume, 1e-9)
+	assert.InDelta(t, -0.001*1075, report.Fees["USDT"], 1e-9)
+	assert.Len(t, report.Equity, 4)
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.WriteCSV(&buf))
+	assert.Equal(t, 5, strings.Count(buf.String(), "\n"))
+	buf.Reset()
+	assert.NoError(t, report.WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"fills": 2`)
+}
+
+func TestMergeSources(t *testing.T) {
+	a := NewSliceSource([]Event{{Ts: 1, InstId: "a"}, {Ts: 3, InstId: "a"}})
+	b := NewJSONSource(strings.NewReader(`{"ts":2,"instId":"b","candle":["2","1","1","1","1","1","1","1","1"]}
+{"ts":3,"instId":"b"}
+`))
+	m := newMerger([]Source{a, b})
+	var got []string
+	for {
+		e, ok := m.Next()
+		if !ok {
+			break
+		}
+		got = append(got, e.InstId)
+	}
+	assert.NoError(t, m.Err())
+	assert.Equal(t, []string{"a", "b", "a", "b"}, got)
+}
+
+func TestCandleCloseTime(t *testing.T) {
+	events, err := LoadCandlesCSV(strings.NewReader(`ts,o,h,l,c,vol,volCcy,volCcyQuote,confirm
+0,100,101,99,100,10,0,0,1
+60000,100,102,100,101,10,0,0,0
+`), "1m", "BTC-USDT")
+	assert.NoError(t, err)
+	// 已完结k线在收盘时间回放 未完结k线保持原时间
+	assert.Equal(t, int64(60000), events[0].Ts)
+	assert.Equal(t, int64(60000), events[1].Ts)
+	_, err = LoadCandlesCSV(strings.NewReader(candlesCSV), "1M", "BTC-USDT")
+	assert.Error(t, err)
+
+	// 没有confirm的k线视为已完结 排在k线内的成交之后
+	candles := NewJSONSource(strings.NewReader(`{"ts":0,"channel":"candle1m","instId":"BTC-USDT","candle":["0","1","1","1","1","1","1","1",""]}
+`))
+	trades := NewSliceSource([]Event{{Ts: 30000, Channel: "trades", InstId: "BTC-USDT", Trade: &common.Trade{Px: "1", Sz: "1"}}})
+	r := NewRunner(Config{Paper: paper.Config{Balances: map[string]float64{"USDT": 1000}}}, candles, trades)
+	c := r.Client()
+	ctx := context.Background()
+	var seen []string
+	assert.NoError(t, c.MarketTrades(ctx, "BTC-USDT", func(resp *common.WsResp[*common.Trade]) {
+		seen = append(seen, "trade")
+	}))
+	assert.NoError(t, c.Candle(ctx, "1m", "BTC-USDT", func(resp *common.WsResp[*common.Candle]) {
+		seen = append(seen, "candle")
+		assert.Equal(t, "1", resp.Data[0].Confirm)
+		assert.Equal(t, int64(60000), c.Now().UnixMilli())
+	}))
+	_, err = r.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"trade", "candle"}, seen)
+}
+
+func TestReportNotional(t *testing.T) {
+	b := newReportBuilder("USDT", time.Hour, []common.Instruments{
+		{InstId: "BTC-USDT-SWAP", InstType: "SWAP", CtVal: "0.01"},
+		{InstId: "BTC-USD-SWAP", InstType: "SWAP", CtVal: "100", CtType: "inverse"},
+	})
+	b.onOrder(&common.Order{InstId: "BTC-USDT", FillPx: "100", FillSz: "2"})
+	b.onOrder(&common.Order{InstId: "BTC-USDT-SWAP", FillPx: "100", FillSz: "10"})
+	b.onOrder(&common.Order{InstId: "BTC-USD-SWAP", FillPx: "100", FillSz: "3"})
+	// 200 + 100*10*0.01 + 3*100
+	assert.InDelta(t, 510, b.build().Volume, 1e-9)
+}
+
+func TestRunnerPeriod(t *testing.T) {
+	assert.Panics(t, func() { NewRunner(Config{Period: time.Microsecond}) })
+	assert.Panics(t, func() { NewRunner(Config{Period: -time.Hour}) })
+	assert.NotPanics(t, func() { NewRunner(Config{Period: time.Millisecond}) })
+}
@@ -0,0 +1,195 @@
+// Package bar 由成交或k线聚合自定义周期、tick、成交量、成交额与价格区间k线
+package bar
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+const day = 24 * time.Hour
+
+var ErrInvalidSpec = errors.New("bar: invalid spec")
+
+// Bar 聚合后的k线 区间为[Start, End)
+type Bar struct {
+	Start    time.Time
+	End      time.Time // 时间k线为周期结束时间 其它为最后一笔数据的时间
+	O        float64
+	H        float64
+	L        float64
+	C        float64
+	Vol      float64 // 成交量 与源数据的单位一致
+	VolQuote float64 // 成交额 计价币
+	Count    int     // 聚合的成交笔数或源k线根数
+	Confirm  bool    // false为未完结的临时值 之后会被同一Start的数据替换
+}
+
+// Range 最高价与最低价之差
+func (b Bar) Range() float64 {
+	return b.H - b.L
+}
+
+// ToCandle 转换为推送格式的k线
+func (b Bar) ToCandle() *common.Candle {
+	confirm := "0"
+	if b.Confirm {
+		confirm = "1"
+	}
+	return &common.Candle{
+		Ts:          strconv.FormatInt(b.Start.UnixMilli(), 10),
+		O:           formatFloat(b.O),
+		H:           formatFloat(b.H),
+		L:           formatFloat(b.L),
+		C:           formatFloat(b.C),
+		Vol:         formatFloat(b.Vol),
+		VolCcyQuote: formatFloat(b.VolQuote),
+		Confirm:     confirm,
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+type Kind int
+
+const (
+	Time   Kind = iota // 按时间边界
+	Tick               // 按成交笔数
+	Volume             // 按成交量
+	Dollar             // 按成交额
+	Range              // 按最高最低价差
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Time:
+		return "time"
+	case Tick:
+		return "tick"
+	case Volume:
+		return "volume"
+	case Dollar:
+		return "dollar"
+	case Range:
+		return "range"
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Spec k线的闭合规则
+type Spec struct {
+	Kind      Kind
+	Period    time.Duration  // Time 小于一天时不跨越当地零点 不小于一天时须为整天数
+	Location  *time.Location // Time 边界对齐的时区 默认UTC
+	Threshold float64        // Tick、Volume、Dollar、Range 达到后闭合
+}
+
+// TimeBars 时间k线 loc为nil时按UTC对齐
+func TimeBars(period time.Duration, loc *time.Location) Spec {
+	return Spec{Kind: Time, Period: period, Location: loc}
+}
+
+// TickBars 每n笔成交闭合
+func TickBars(n int) Spec {
+	return Spec{Kind: Tick, Threshold: float64(n)}
+}
+
+// VolumeBars 成交量达到vol时闭合
+func VolumeBars(vol float64) Spec {
+	return Spec{Kind: Volume, Threshold: vol}
+}
+
+// DollarBars 成交额达到value时闭合
+func DollarBars(value float64) Spec {
+	return Spec{Kind: Dollar, Threshold: value}
+}
+
+// RangeBars 最高最低价差达到width时闭合
+func RangeBars(width float64) Spec {
+	return Spec{Kind: Range, Threshold: width}
+}
+
+func (s Spec) validate() error {
+	switch s.Kind {
+	case Time:
+		if s.Period <= 0 || (s.Period >= day && s.Period%day != 0) {
+			return fmt.Errorf("%w: period %s", ErrInvalidSpec, s.Period)
+		}
+	case Tick, Volume, Dollar, Range:
+		if s.Threshold <= 0 {
+			return fmt.Errorf("%w: %s threshold %v", ErrInvalidSpec, s.Kind, s.Threshold)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidSpec, s.Kind)
+	}
+	return nil
+}
+
+func (s Spec) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// weekAnchor 多日周期的计数起点 1970-01-05为周一 使周线从周一开始
+var weekAnchor = time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// window ts所在的时间区间 按Location的挂钟时间对齐 夏令时切换时区间长度随之变化
+func (s Spec) window(ts time.Time) (start, end time.Time) {
+	loc := s.location()
+	local := ts.In(loc)
+	y, m, d := local.Date()
+	if s.Period < day {
+		// 按当地挂钟时间划分
+		wall := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+			time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+		n := wall / s.Period
+		start = time.Date(y, m, d, 0, 0, 0, int(n*s.Period), loc)
+		end = time.Date(y, m, d, 0, 0, 0, int((n+1)*s.Period), loc)
+		if next := time.Date(y, m, d+1, 0, 0, 0, 0, loc); end.After(next) {
+			end = next
+		}
+		return start, end
+	}
+	days := int(s.Period / day)
+	n := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(weekAnchor) / day)
+	n -= ((n % days) + days) % days
+	start = time.Date(1970, 1, 5+n, 0, 0, 0, 0, loc)
+	end = time.Date(1970, 1, 5+n+days, 0, 0, 0, 0, loc)
+	return start, end
+}
+
+// ParsePeriod 解析OKX的k线周期 如1m、15m、1H、4H、1D、1Dutc、1W 月线长度不固定不支持
+func ParsePeriod(bar string) (time.Duration, error) {
+	s := strings.TrimSuffix(strings.TrimSuffix(bar, "utc"), "UTC")
+	if len(s) < 2 {
+		return 0, fmt.Errorf("%w: bar %q", ErrInvalidSpec, bar)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: bar %q", ErrInvalidSpec, bar)
+	}
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'H':
+		unit = time.Hour
+	case 'D':
+		unit = day
+	case 'W':
+		unit = 7 * day
+	default:
+		return 0, fmt.Errorf("%w: bar %q", ErrInvalidSpec, bar)
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,300 @@
+package bar
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// agg 一段数据的聚合值
+type agg struct {
+	o, h, l, c float64
+	vol, quote float64
+	count      int
+	last       time.Time
+	set        bool
+}
+
+func (a *agg) add(b agg) {
+	if !b.set {
+		return
+	}
+	if !a.set {
+		*a = b
+		return
+	}
+	a.h = math.Max(a.h, b.h)
+	a.l = math.Min(a.l, b.l)
+	a.c = b.c
+	a.vol += b.vol
+	a.quote += b.quote
+	a.count += b.count
+	a.last = b.last
+}
+
+// Builder 将成交或k线聚合为Spec描述的k线
+// 成交与已完结的源k线计入闭合判断 未完结的源k线只影响临时值 收到同一时间的新值时被替换
+// 回调在持有锁时调用 回调中不可调用Builder的方法
+type Builder struct {
+	Partial bool    // 每次更新都以Confirm=false回调当前k线 默认只回调已闭合的k线
+	CtVal   float64 // 成交额的乘数 合约为面值 默认1
+
+	spec    Spec
+	onBar   func(bar Bar)
+	lock    sync.Mutex
+	open    bool
+	start   time.Time
+	end     time.Time
+	closed  agg       // 成交与已完结的源k线
+	partial agg       // 未完结的源k线
+	srcTs   time.Time // partial的开始时间
+}
+
+// NewBuilder onBar接收闭合的k线 Partial为true时也接收未闭合的临时值
+func NewBuilder(spec Spec, onBar func(bar Bar)) (*Builder, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return &Builder{spec: spec, onBar: onBar, CtVal: 1}, nil
+}
+
+// AddTick 加入一笔成交
+func (b *Builder) AddTick(ts time.Time, px, sz float64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.roll(ts)
+	b.closed.add(agg{o: px, h: px, l: px, c: px, vol: sz, quote: px * sz * b.CtVal, count: 1, last: ts, set: true})
+	b.settle(false)
+}
+
+// AddTrade 加入交易频道的一笔成交
+func (b *Builder) AddTrade(t *common.Trade) error {
+	ts, err := parseMs(t.Ts)
+	if err != nil {
+		return err
+	}
+	px, err := strconv.ParseFloat(t.Px, 64)
+	if err != nil {
+		return err
+	}
+	sz, err := strconv.ParseFloat(t.Sz, 64)
+	if err != nil {
+		return err
+	}
+	b.AddTick(ts, px, sz)
+	return nil
+}
+
+// AddCandle 加入一根源k线 period为其周期
+// confirm为0时作为临时值 同一开始时间的后续推送替换之前的值 为1时计入闭合判断
+func (b *Builder) AddCandle(c *common.Candle, period time.Duration) error {
+	v, ts, err := parseCandle(c)
+	if err != nil {
+		return err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// 上一根未收到完结推送 以最后的值计入
+	if b.partial.set && !b.srcTs.Equal(ts) {
+		b.closed.add(b.partial)
+		b.partial = agg{}
+	}
+	b.roll(ts)
+	v.last = ts.Add(period)
+	if c.Confirm != "1" {
+		b.partial, b.srcTs = v, ts
+		b.emit(false)
+		return nil
+	}
+	b.partial = agg{}
+	b.closed.add(v)
+	b.settle(b.spec.Kind == Time && !v.last.Before(b.end))
+	return nil
+}
+
+// Flush 关闭结束时间不晚于now的时间k线 用于没有新数据时按时闭合
+func (b *Builder) Flush(now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.open && b.spec.Kind == Time && !now.Before(b.end) {
+		b.close()
+	}
+}
+
+// Current 当前未闭合的k线
+func (b *Builder) Current() (Bar, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !b.open {
+		return Bar{}, false
+	}
+	return b.bar(false), true
+}
+
+// roll ts超出当前时间区间时闭合当前k线 并按需开始新的k线
+func (b *Builder) roll(ts time.Time) {
+	if b.open && b.spec.Kind == Time && !ts.Before(b.end) {
+		b.close()
+	}
+	if b.open {
+		return
+	}
+	b.open = true
+	if b.spec.Kind == Time {
+		b.start, b.end = b.spec.window(ts)
+	} else {
+		b.start, b.end = ts, time.Time{}
+	}
+}
+
+// settle 达到阈值或force时闭合 否则回调临时值
+func (b *Builder) settle(force bool) {
+	if force || b.reached() {
+		b.close()
+		return
+	}
+	b.emit(false)
+}
+
+func (b *Builder) reached() bool {
+	a := b.closed
+	switch b.spec.Kind {
+	case Tick:
+		return float64(a.count) >= b.spec.Threshold
+	case Volume:
+		return a.vol >= b.spec.Threshold
+	case Dollar:
+		return a.quote >= b.spec.Threshold
+	case Range:
+		return a.set && a.h-a.l >= b.spec.Threshold
+	}
+	return false
+}
+
+func (b *Builder) close() {
+	b.closed.add(b.partial)
+	b.partial = agg{}
+	if b.closed.set {
+		b.emit(true)
+	}
+	b.closed = agg{}
+	b.open = false
+}
+
+func (b *Builder) emit(confirm bool) {
+	if b.onBar == nil || (!confirm && !b.Partial) {
+		return
+	}
+	b.onBar(b.bar(confirm))
+}
+
+func (b *Builder) bar(confirm bool) Bar {
+	a := b.closed
+	a.add(b.partial)
+	end := b.end
+	if b.spec.Kind != Time {
+		end = a.last
+	}
+	return Bar{
+		Start:    b.start,
+		End:      end,
+		O:        a.o,
+		H:        a.h,
+		L:        a.l,
+		C:        a.c,
+		Vol:      a.vol,
+		VolQuote: a.quote,
+		Count:    a.count,
+		Confirm:  confirm,
+	}
+}
+
+// TradeHandler 用于PublicClient.MarketTrades的回调 格式错误的数据被忽略
+func (b *Builder) TradeHandler() func(resp *common.WsResp[*common.Trade]) {
+	return func(resp *common.WsResp[*common.Trade]) {
+		for _, t := range resp.Data {
+			_ = b.AddTrade(t)
+		}
+	}
+}
+
+// AllTradesHandler 用于BusinessClient.AllTrades的回调 格式错误的数据被忽略
+func (b *Builder) AllTradesHandler() func(resp *common.WsResp[common.Trade]) {
+	return func(resp *common.WsResp[common.Trade]) {
+		for i := range resp.Data {
+			_ = b.AddTrade(&resp.Data[i])
+		}
+	}
+}
+
+// CandleHandler 用于BusinessClient.Candle的回调 period为订阅的k线周期
+func (b *Builder) CandleHandler(period time.Duration) func(resp *common.WsResp[*common.Candle]) {
+	return func(resp *common.WsResp[*common.Candle]) {
+		for _, c := range resp.Data {
+			_ = b.AddCandle(c, period)
+		}
+	}
+}
+
+// CandleSource 历史k线 *okx.RestClient满足该接口
+type CandleSource interface {
+	Candles(ctx context.Context, req common.CandlesticksReq) (*common.Resp[common.Candle], error)
+}
+
+// Backfill 拉取最近limit根bar周期的k线按时间顺序送入builder 用于订阅前预热
+func Backfill(ctx context.Context, src CandleSource, b *Builder, instId, bar string, limit int64) error {
+	period, err := ParsePeriod(bar)
+	if err != nil {
+		return err
+	}
+	rp, err := src.Candles(ctx, common.CandlesticksReq{InstID: instId, Bar: bar, Limit: limit})
+	if err != nil {
+		return err
+	}
+	// 接口按时间倒序返回
+	for i := len(rp.Data) - 1; i >= 0; i-- {
+		if err := b.AddCandle(&rp.Data[i], period); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseCandle(c *common.Candle) (agg, time.Time, error) {
+	ts, err := parseMs(c.Ts)
+	if err != nil {
+		return agg{}, ts, err
+	}
+	var v [4]float64
+	for i, s := range []string{c.O, c.H, c.L, c.C} {
+		if v[i], err = strconv.ParseFloat(s, 64); err != nil {
+			return agg{}, ts, err
+		}
+	}
+	vol, err := strconv.ParseFloat(c.Vol, 64)
+	if err != nil {
+		return agg{}, ts, err
+	}
+	// 成交额缺失时以收盘价估算
+	quote, err := strconv.ParseFloat(c.VolCcyQuote, 64)
+	if err != nil {
+		quote = vol * v[3]
+	}
+	return agg{o: v[0], h: v[1], l: v[2], c: v[3], vol: vol, quote: quote, count: 1, set: true}, ts, nil
+}
+
+func parseMs(s string) (time.Time, error) {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
@@ -0,0 +1,235 @@
+package bar
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func candle(ts time.Time, o, h, l, c, vol float64, confirm bool) *common.Candle {
+	bar := Bar{Start: ts, O: o, H: h, L: l, C: c, Vol: vol, VolQuote: vol * c, Confirm: confirm}
+	return bar.ToCandle()
+}
+
+func TestWindow(t *testing.T) {
+	cases := []struct {
+		spec       Spec
+		ts         string
+		start, end string
+	}{
+		{TimeBars(7*time.Minute, nil), "2024-01-02T00:15:00Z", "2024-01-02T00:14:00Z", "2024-01-02T00:21:00Z"},
+		// 不跨越零点 当天最后一根只有5分钟
+		{TimeBars(7*time.Minute, nil), "2024-01-02T23:58:00Z", "2024-01-02T23:55:00Z", "2024-01-03T00:00:00Z"},
+		{TimeBars(45*time.Minute, nil), "2024-01-02T01:30:00Z", "2024-01-02T01:30:00Z", "2024-01-02T02:15:00Z"},
+		{TimeBars(day, time.FixedZone("UTC+8", 8*3600)), "2024-01-02T17:00:00Z", "2024-01-02T16:00:00Z", "2024-01-03T16:00:00Z"},
+		// 周线从周一开始
+		{TimeBars(7*day, nil), "2024-01-04T12:00:00Z", "2024-01-01T00:00:00Z", "2024-01-08T00:00:00Z"},
+	}
+	for _, c := range cases {
+		start, end := c.spec.window(at(c.ts))
+		assert.True(t, at(c.start).Equal(start), "%s start %s", c.ts, start)
+		assert.True(t, at(c.end).Equal(end), "%s end %s", c.ts, end)
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 夏令时切换当天只有23小时
+	start, end := TimeBars(day, ny).window(at("2024-03-10T12:00:00Z"))
+	assert.Equal(t, 23*time.Hour, end.Sub(start))
+	// 按挂钟时间对齐 切换所在的区间少一小时
+	start, end = TimeBars(4*time.Hour, ny).window(at("2024-03-10T12:00:00Z"))
+	assert.True(t, at("2024-03-10T12:00:00Z").Equal(start), start)
+	assert.Equal(t, 4*time.Hour, end.Sub(start))
+	start, end = TimeBars(4*time.Hour, ny).window(at("2024-03-10T06:00:00Z"))
+	assert.True(t, at("2024-03-10T05:00:00Z").Equal(start), start)
+	assert.Equal(t, 3*time.Hour, end.Sub(start))
+}
+
+func TestTimeBarsFromTrades(t *testing.T) {
+	var bars []Bar
+	b, err := NewBuilder(TimeBars(7*time.Minute, nil), func(bar Bar) { bars = append(bars, bar) })
+	assert.NoError(t, err)
+
+	for _, trade := range []struct {
+		ts     string
+		px, sz float64
+	}{
+		{"2024-01-02T00:14:10Z", 100, 1},
+		{"2024-01-02T00:16:00Z", 105, 2},
+		{"2024-01-02T00:20:59Z", 99, 1},
+		{"2024-01-02T00:21:00Z", 101, 3},
+	} {
+		assert.NoError(t, b.AddTrade(&common.Trade{Ts: strconv.FormatInt(at(trade.ts).UnixMilli(), 10),
+			Px: strconv.FormatFloat(trade.px, 'f', -1, 64), Sz: strconv.FormatFloat(trade.sz, 'f', -1, 64)}))
+	}
+	assert.Len(t, bars, 1)
+	assert.Equal(t, Bar{Start: at("2024-01-02T00:14:00Z"), End: at("2024-01-02T00:21:00Z"),
+		O: 100, H: 105, L: 99, C: 99, Vol: 4, VolQuote: 409, Count: 3, Confirm: true}, bars[0].inUTC())
+
+	cur, ok := b.Current()
+	assert.True(t, ok)
+	assert.Equal(t, 101.0, cur.O)
+	assert.False(t, cur.Confirm)
+
+	// 没有新成交时按时闭合
+	b.Flush(at("2024-01-02T00:27:59Z"))
+	assert.Len(t, bars, 1)
+	b.Flush(at("2024-01-02T00:28:00Z"))
+	assert.Len(t, bars, 2)
+	_, ok = b.Current()
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, b.AddTrade(&common.Trade{Ts: "x"}), strconv.ErrSyntax)
+	_, err = NewBuilder(TimeBars(36*time.Hour, nil), nil)
+	assert.ErrorIs(t, err, ErrInvalidSpec)
+}
+
+func TestCandleConfirm(t *testing.T) {
+	var bars []Bar
+	b, err := NewBuilder(TimeBars(3*time.Minute, nil), func(bar Bar) { bars = append(bars, bar) })
+	assert.NoError(t, err)
+	b.Partial = true
+
+	t0 := at("2024-01-02T00:00:00Z")
+	add := func(c *common.Candle) { assert.NoError(t, b.AddCandle(c, time.Minute)) }
+	add(candle(t0, 10, 11, 9, 10, 1, false))
+	// 同一根的临时值被替换
+	add(candle(t0, 10, 20, 9, 12, 2, false))
+	add(candle(t0, 10, 12, 9, 11, 3, true))
+	add(candle(t0.Add(time.Minute), 11, 13, 11, 12, 1, true))
+	// 上一根临时值未完结即开始下一根 以最后的值计入
+	add(candle(t0.Add(2*time.Minute), 12, 14, 8, 13, 1, false))
+	assert.Len(t, bars, 5)
+	for _, bar := range bars {
+		assert.False(t, bar.Confirm)
+	}
+	assert.Equal(t, 20.0, bars[1].H)
+	assert.Equal(t, 12.0, bars[2].H)
+
+	// 最后一根完结时立即闭合
+	add(candle(t0.Add(2*time.Minute), 12, 15, 8, 14, 2, true))
+	last := bars[len(bars)-1]
+	assert.True(t, last.Confirm)
+	assert.Equal(t, Bar{Start: t0, End: t0.Add(3 * time.Minute), O: 10, H: 15, L: 8, C: 14, Vol: 6, VolQuote: 73, Count: 3, Confirm: true}, last.inUTC())
+
+	// 未完结的源k线不计入阈值
+	bars = nil
+	v, err := NewBuilder(VolumeBars(5), func(bar Bar) { bars = append(bars, bar) })
+	assert.NoError(t, err)
+	assert.NoError(t, v.AddCandle(candle(t0, 1, 1, 1, 1, 10, false), time.Minute))
+	assert.Empty(t, bars)
+	assert.NoError(t, v.AddCandle(candle(t0, 1, 1, 1, 1, 10, true), time.Minute))
+	assert.Len(t, bars, 1)
+	assert.Equal(t, t0.Add(time.Minute), bars[0].End.UTC())
+}
+
+func TestThresholdBars(t *testing.T) {
+	ts := at("2024-01-02T00:00:00Z")
+	run := func(spec Spec, ticks [][2]float64) []Bar {
+		var bars []Bar
+		b, err := NewBuilder(spec, func(bar Bar) { bars = append(bars, bar) })
+		assert.NoError(t, err)
+		for i, tick := range ticks {
+			b.AddTick(ts.Add(time.Duration(i)*time.Second), tick[0], tick[1])
+		}
+		return bars
+	}
+	ticks := [][2]float64{{100, 1}, {101, 2}, {99, 3}, {104, 1}, {100, 5}}
+
+	bars := run(TickBars(2), ticks)
+	assert.Len(t, bars, 2)
+	assert.Equal(t, 2, bars[0].Count)
+	assert.Equal(t, ts.Add(time.Second), bars[0].End.UTC())
+
+	bars = run(VolumeBars(3), ticks)
+	assert.Len(t, bars, 3)
+	assert.Equal(t, []float64{3, 3, 6}, []float64{bars[0].Vol, bars[1].Vol, bars[2].Vol})
+
+	bars = run(DollarBars(400), ticks)
+	assert.Len(t, bars, 2)
+	assert.Equal(t, 599.0, bars[0].VolQuote)
+
+	bars = run(RangeBars(4), ticks)
+	assert.Len(t, bars, 1)
+	assert.Equal(t, 5.0, bars[0].Range())
+	assert.Equal(t, 104.0, bars[0].C)
+}
+
+func TestHeikinAshi(t *testing.T) {
+	var got []Bar
+	h := HeikinAshiHandler(func(bar Bar) { got = append(got, bar) })
+	h(Bar{O: 10, H: 12, L: 8, C: 11, Confirm: true})
+	h(Bar{O: 11, H: 20, L: 11, C: 13})
+	h(Bar{O: 11, H: 14, L: 10, C: 13, Confirm: true})
+	assert.Equal(t, Bar{O: 10.5, H: 12, L: 8, C: 10.25, Confirm: true}, got[0])
+	// 临时值不推进状态
+	assert.Equal(t, 10.375, got[1].O)
+	assert.Equal(t, 10.375, got[2].O)
+	assert.Equal(t, 12.0, got[2].C)
+	assert.Equal(t, 14.0, got[2].H)
+	assert.Equal(t, 10.0, got[2].L)
+}
+
+type candleSource []common.Candle
+
+func (s candleSource) Candles(ctx context.Context, req common.CandlesticksReq) (*common.Resp[common.Candle], error) {
+	return &common.Resp[common.Candle]{Code: "0", Data: s}, nil
+}
+
+func TestBackfill(t *testing.T) {
+	t0 := at("2024-01-02T00:00:00Z")
+	// 倒序返回 最新一根未完结
+	src := candleSource{
+		*candle(t0.Add(5*time.Minute), 6, 6, 6, 6, 1, false),
+		*candle(t0.Add(4*time.Minute), 5, 5, 5, 5, 1, true),
+		*candle(t0.Add(3*time.Minute), 4, 4, 4, 4, 1, true),
+		*candle(t0.Add(2*time.Minute), 3, 3, 3, 3, 1, true),
+		*candle(t0.Add(time.Minute), 2, 2, 2, 2, 1, true),
+		*candle(t0, 1, 1, 1, 1, 1, true),
+	}
+	var bars []Bar
+	b, err := NewBuilder(TimeBars(3*time.Minute, nil), func(bar Bar) { bars = append(bars, bar) })
+	assert.NoError(t, err)
+	assert.NoError(t, Backfill(context.Background(), src, b, "BTC-USDT", "1m", 6))
+	assert.Len(t, bars, 1)
+	assert.Equal(t, 1.0, bars[0].O)
+	assert.Equal(t, 3.0, bars[0].C)
+	cur, ok := b.Current()
+	assert.True(t, ok)
+	assert.Equal(t, 6.0, cur.C)
+	assert.Equal(t, 3, cur.Count)
+
+	assert.ErrorIs(t, Backfill(context.Background(), src, b, "BTC-USDT", "1M", 6), ErrInvalidSpec)
+}
+
+func TestParsePeriod(t *testing.T) {
+	for bar, want := range map[string]time.Duration{"1m": time.Minute, "15m": 15 * time.Minute, "4H": 4 * time.Hour, "1Dutc": day, "1W": 7 * day} {
+		got, err := ParsePeriod(bar)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, bar)
+	}
+	for _, bar := range []string{"", "m", "1M", "0m", "xH"} {
+		_, err := ParsePeriod(bar)
+		assert.ErrorIs(t, err, ErrInvalidSpec, bar)
+	}
+}
+
+func (b Bar) inUTC() Bar {
+	b.Start, b.End = b.Start.UTC(), b.End.UTC()
+	return b
+}
@@ -0,0 +1,34 @@
+package bar
+
+import "math"
+
+// HeikinAshi 平均k线 按顺序传入k线 未闭合的k线不推进状态 可重复传入同一Start的临时值
+type HeikinAshi struct {
+	prev Bar
+	has  bool
+}
+
+// Next 转换一根k线
+func (h *HeikinAshi) Next(bar Bar) Bar {
+	ha := bar
+	ha.C = (bar.O + bar.H + bar.L + bar.C) / 4
+	if h.has {
+		ha.O = (h.prev.O + h.prev.C) / 2
+	} else {
+		ha.O = (bar.O + bar.C) / 2
+	}
+	ha.H = math.Max(bar.H, math.Max(ha.O, ha.C))
+	ha.L = math.Min(bar.L, math.Min(ha.O, ha.C))
+	if bar.Confirm {
+		h.prev, h.has = ha, true
+	}
+	return ha
+}
+
+// HeikinAshiHandler 在回调前转换为平均k线 用于NewBuilder的onBar
+func HeikinAshiHandler(onBar func(bar Bar)) func(bar Bar) {
+	h := &HeikinAshi{}
+	return func(bar Bar) {
+		onBar(h.Next(bar))
+	}
+}
@@ -0,0 +1,105 @@
+// Package basis 资金费率与基差 用于期现套利
+// 按instFamily组织永续、交割合约与现货指数 计算年化资金费率、预测下一期资金费率、永续基差与交割合约的基差期限结构
+package basis
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+const year = 365 * 24 * time.Hour
+
+// DefaultFundingInterval 无法由结算时间推算时使用的资金费收取间隔
+const DefaultFundingInterval = 8 * time.Hour
+
+// InterestRate 预测资金费率时使用的每期利率 OKX为0.01%
+var InterestRate = 0.0001
+
+// Funding 永续合约的资金费率
+type Funding struct {
+	Rate            float64       // 当期资金费率
+	NextRate        float64       // 交易所给出的下一期费率 部分产品为0
+	Predicted       float64       // 预测的下一期费率 优先使用NextRate 否则由溢价估算
+	Annualized      float64       // 当期费率年化
+	Interval        time.Duration // 资金费收取间隔
+	FundingTime     time.Time
+	NextFundingTime time.Time
+	Premium         float64 // 溢价指数
+	Min             float64 // 费率下限 未知时为0
+	Max             float64 // 费率上限 未知时为0
+}
+
+// Point 期限结构上的一个合约
+type Point struct {
+	InstId     string
+	Expiry     time.Time // 永续为零值
+	Days       float64   // 剩余天数 永续为0
+	Px         float64   // 永续为标记价格 交割为买一卖一中间价 无盘口时为最新成交价
+	Basis      float64   // Px-指数
+	Rate       float64   // Basis/指数
+	Annualized float64   // Rate按剩余期限年化 永续为资金费率年化
+}
+
+// Snapshot 某个instFamily的资金费率与基差
+type Snapshot struct {
+	Family  string
+	Index   float64 // 现货指数
+	Funding Funding
+	Perp    Point   // 永续相对指数
+	Term    []Point // 交割合约 按到期时间升序
+	Ts      time.Time
+}
+
+// AnnualizeFunding 单期资金费率年化
+func AnnualizeFunding(rate float64, interval time.Duration) float64 {
+	if interval <= 0 {
+		interval = DefaultFundingInterval
+	}
+	return rate * float64(year) / float64(interval)
+}
+
+// PredictFunding 按OKX的公式由溢价估算资金费率 clamp(P+clamp(I-P,±0.05%),min,max) min与max为0时不限制
+func PredictFunding(premium, min, max float64) float64 {
+	rate := premium + clamp(InterestRate-premium, -0.0005, 0.0005)
+	if min != 0 || max != 0 {
+		rate = clamp(rate, min, max)
+	}
+	return rate
+}
+
+// NewPoint 计算合约相对指数的基差 expiry为零值表示永续
+func NewPoint(instId string, expiry time.Time, px, index float64, now time.Time) Point {
+	p := Point{InstId: instId, Expiry: expiry, Px: px}
+	if index == 0 || px == 0 {
+		return p
+	}
+	p.Basis = px - index
+	p.Rate = p.Basis / index
+	if !expiry.IsZero() {
+		left := expiry.Sub(now)
+		p.Days = left.Hours() / 24
+		if left > 0 {
+			p.Annualized = p.Rate * float64(year) / float64(left)
+		}
+	}
+	return p
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
+
+// parseNum 空值与格式错误视为0
+func parseNum(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func parseTs(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
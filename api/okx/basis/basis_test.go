@@ -0,0 +1,212 @@
+package basis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+func TestFunding(t *testing.T) {
+	assert.InDelta(t, 0.1095, AnnualizeFunding(0.0001, 8*time.Hour), 1e-12)
+	assert.InDelta(t, 0.2190, AnnualizeFunding(0.0001, 4*time.Hour), 1e-12)
+	assert.InDelta(t, 0.1095, AnnualizeFunding(0.0001, 0), 1e-12)
+
+	// 溢价在利率±0.05%以内时取利率
+	assert.InDelta(t, 0.0001, PredictFunding(0.0003, 0, 0), 1e-12)
+	assert.InDelta(t, 0.0015, PredictFunding(0.002, 0, 0), 1e-12)
+	assert.InDelta(t, -0.0015, PredictFunding(-0.002, 0, 0), 1e-12)
+	assert.InDelta(t, 0.00075, PredictFunding(0.002, -0.00075, 0.00075), 1e-12)
+}
+
+func TestNewPoint(t *testing.T) {
+	now := time.UnixMilli(1704153600000)
+	p := NewPoint("BTC-USDT-240402", now.Add(91*24*time.Hour), 42420, 42000, now)
+	assert.InDelta(t, 420, p.Basis, 1e-9)
+	assert.InDelta(t, 0.01, p.Rate, 1e-12)
+	assert.InDelta(t, 91, p.Days, 1e-9)
+	assert.InDelta(t, 0.01*365/91, p.Annualized, 1e-12)
+
+	perp := NewPoint("BTC-USDT-SWAP", time.Time{}, 41958, 42000, now)
+	assert.InDelta(t, -0.001, perp.Rate, 1e-12)
+	assert.Zero(t, perp.Days)
+	assert.Zero(t, perp.Annualized)
+
+	assert.Equal(t, Point{InstId: "X", Px: 1}, NewPoint("X", time.Time{}, 1, 0, now))
+}
+
+const (
+	now     = 1704153600000 // 2024-01-02 00:00 UTC
+	quarter = 1711699200000 // 2024-03-29 08:00 UTC
+	week    = 1704441600000 // 2024-01-05 08:00 UTC
+)
+
+func newToolkit(t *testing.T, public *okx.PublicClient) (*Toolkit, *atomic.Int32) {
+	var instruments atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var data string
+		switch r.URL.Path {
+		case "/api/v5/public/instruments":
+			instruments.Add(1)
+			assert.Equal(t, "BTC-USDT", q.Get("instFamily"))
+			if q.Get("instType") == "SWAP" {
+				data = `[{"instId":"BTC-USDT-SWAP","uly":"BTC-USDT","state":"live"}]`
+			} else {
+				data = `[{"instId":"BTC-USDT-240329","uly":"BTC-USDT","alias":"quarter","expTime":"1711699200000","state":"live"},
+					{"instId":"BTC-USDT-240105","uly":"BTC-USDT","alias":"this_week","expTime":"1704441600000","state":"live"},
+					{"instId":"BTC-USDT-231229","uly":"BTC-USDT","alias":"this_week","expTime":"1703836800000","state":"expired"}]`
+			}
+		case "/api/v5/market/index-tickers":
+			assert.Equal(t, "BTC-USDT", q.Get("instId"))
+			data = `[{"instId":"BTC-USDT","idxPx":"42000","ts":"1704153600000"}]`
+		case "/api/v5/public/mark-price":
+			assert.Equal(t, "BTC-USDT-SWAP", q.Get("instId"))
+			data = `[{"instType":"SWAP","instId":"BTC-USDT-SWAP","markPx":"42021","ts":"1704153600001"}]`
+		case "/api/v5/public/funding-rate":
+			data = `[{"instId":"BTC-USDT-SWAP","fundingRate":"0.0001","fundingTime":"1704182400000","nextFundingRate":"","nextFundingTime":"1704211200000","minFundingRate":"-0.00075","maxFundingRate":"0.00075","premium":"0.002","ts":"1704153600002"}]`
+		case "/api/v5/market/tickers":
+			assert.Equal(t, "FUTURES", q.Get("instType"))
+			data = `[{"instId":"BTC-USDT-240329","last":"43005","bidPx":"43000","askPx":"43002","ts":"1704153600003"},
+				{"instId":"BTC-USDT-240105","last":"42100","bidPx":"","askPx":"","ts":"1704153600000"}]`
+		default:
+			t.Errorf("unexpected %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":` + data + `}`))
+	}))
+	t.Cleanup(srv.Close)
+	rest, err := okx.NewRestClientWithOptions(context.Background(), okx.WithRestURL(common.BaseURL(srv.URL)))
+	assert.NoError(t, err)
+	tk := New(rest, public)
+	tk.now = func() time.Time { return time.UnixMilli(now) }
+	return tk, &instruments
+}
+
+func TestSnapshot(t *testing.T) {
+	tk, instruments := newToolkit(t, nil)
+	u, err := tk.Underlying(context.Background(), "BTC-USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, &Underlying{Family: "BTC-USDT", Uly: "BTC-USDT", Index: "BTC-USDT", Swap: "BTC-USDT-SWAP", Futures: []Future{
+		{InstId: "BTC-USDT-240105", Alias: "this_week", Expiry: time.UnixMilli(week)},
+		{InstId: "BTC-USDT-240329", Alias: "quarter", Expiry: time.UnixMilli(quarter)},
+	}}, u)
+
+	s, err := tk.Snapshot(context.Background(), "BTC-USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), instruments.Load())
+	assert.Equal(t, 42000.0, s.Index)
+	assert.Equal(t, time.UnixMilli(1704153600003), s.Ts)
+
+	f := s.Funding
+	assert.Equal(t, 0.0001, f.Rate)
+	assert.Equal(t, 8*time.Hour, f.Interval)
+	assert.InDelta(t, 0.1095, f.Annualized, 1e-12)
+	assert.Equal(t, 0.002, f.Premium)
+	// 下一期费率为空时由溢价估算并受上下限约束
+	assert.InDelta(t, 0.00075, f.Predicted, 1e-12)
+
+	assert.Equal(t, "BTC-USDT-SWAP", s.Perp.InstId)
+	assert.InDelta(t, 21, s.Perp.Basis, 1e-9)
+	assert.InDelta(t, 0.0005, s.Perp.Rate, 1e-12)
+	assert.Equal(t, f.Annualized, s.Perp.Annualized)
+
+	assert.Len(t, s.Term, 2)
+	assert.Equal(t, 42100.0, s.Term[0].Px)
+	assert.InDelta(t, 3.333333, s.Term[0].Days, 1e-6)
+	assert.Equal(t, 43001.0, s.Term[1].Px)
+	assert.InDelta(t, 1001, s.Term[1].Basis, 1e-9)
+	assert.InDelta(t, 1001.0/42000*365/(float64(quarter-now)/86400000), s.Term[1].Annualized, 1e-12)
+
+	// 到期的交割合约不再出现在期限结构中
+	tk.now = func() time.Time { return time.UnixMilli(week) }
+	s, err = tk.Snapshot(context.Background(), "BTC-USDT")
+	assert.NoError(t, err)
+	assert.Len(t, s.Term, 1)
+	assert.Equal(t, int32(2), instruments.Load())
+
+	tk.Refresh()
+	_, err = tk.Underlying(context.Background(), "BTC-USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), instruments.Load())
+}
+
+type recorder struct {
+	lock sync.Mutex
+	out  []string
+}
+
+func (r *recorder) Record(frame ws.Frame) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if frame.Dir == ws.Outbound {
+		r.out = append(r.out, string(frame.Data))
+	}
+}
+
+func (r *recorder) count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.out)
+}
+
+func TestStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	public := &okx.PublicClient{WsClient: common.NewBaseWsClient(ctx, common.Public, "", nil, nil)}
+	rec := &recorder{}
+	conn := ws.NewReplayConn(ctx, ws.WithRecorder(rec))
+	public.Attach(conn)
+	tk, _ := newToolkit(t, public)
+
+	snaps := make(chan *Snapshot, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- tk.Stream(ctx, "BTC-USDT", func(s *Snapshot) { snaps <- s })
+	}()
+	first := <-snaps
+	assert.Equal(t, 42000.0, first.Index)
+	assert.Equal(t, 43001.0, first.Term[1].Px)
+
+	// 指数、标记价格、资金费率与两个交割合约
+	assert.Eventually(t, func() bool { return rec.count() == 5 }, time.Second, time.Millisecond)
+	for _, frame := range []string{
+		`{"arg":{"channel":"index-tickers","instId":"BTC-USDT"},"data":[{"instId":"BTC-USDT","idxPx":"42100","ts":"1704153601000"}]}`,
+		`{"arg":{"channel":"tickers","instId":"BTC-USDT-240329"},"data":[{"instId":"BTC-USDT-240329","last":"43200","bidPx":"43200","askPx":"43202","ts":"1704153601001"}]}`,
+		`{"arg":{"channel":"funding-rate","instId":"BTC-USDT-SWAP"},"data":[{"instId":"BTC-USDT-SWAP","fundingRate":"0.0002","fundingTime":"1704182400000","nextFundingRate":"0.0003","nextFundingTime":"1704196800000","ts":"1704153601002"}]}`,
+	} {
+		conn.Inject(ws.TextMessage, []byte(frame))
+	}
+	var last *Snapshot
+	assert.Eventually(t, func() bool {
+		for {
+			select {
+			case last = <-snaps:
+			default:
+				return last != nil && last.Funding.Rate == 0.0002
+			}
+		}
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 42100.0, last.Index)
+	assert.Equal(t, 43201.0, last.Term[1].Px)
+	assert.InDelta(t, 1101, last.Term[1].Basis, 1e-9)
+	assert.Equal(t, 4*time.Hour, last.Funding.Interval)
+	assert.Equal(t, 0.0003, last.Funding.Predicted)
+	assert.Equal(t, time.UnixMilli(1704153601002), last.Ts)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("stream not stopped")
+	}
+}
@@ -0,0 +1,304 @@
+package basis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+)
+
+// Rest 使用到的接口 *okx.RestClient满足该接口
+type Rest interface {
+	Instruments(ctx context.Context, req common.InstrumentsReq) (*common.Resp[common.Instruments], error)
+	IndexTickers(ctx context.Context, req common.IndexTickersReq) (*common.Resp[common.IndexTicker], error)
+	MarkPrices(ctx context.Context, req common.MarkPriceReq) (*common.Resp[common.MarkPrice], error)
+	FundingRate(ctx context.Context, instId string) (*common.Resp[common.FundingRate], error)
+	Tickers(ctx context.Context, req common.TickersReq) (*common.Resp[common.Ticker], error)
+}
+
+// Underlying 一个instFamily下的现货指数、永续与交割合约
+type Underlying struct {
+	Family  string
+	Uly     string
+	Index   string // 现货指数 与uly相同
+	Swap    string // 永续合约 没有时为空
+	Futures []Future
+}
+
+type Future struct {
+	InstId string
+	Alias  string // this_week、next_week、quarter、next_quarter
+	Expiry time.Time
+}
+
+// Toolkit 资金费率与基差 REST快照与推送流共用合约关系
+// 推送流订阅的频道在PublicClient上只能有一个回调 不应与market.Store等同时订阅同一频道
+type Toolkit struct {
+	rest   Rest
+	public *okx.PublicClient
+	now    func() time.Time
+	lock   sync.Mutex
+	unders map[string]*Underlying
+}
+
+// New public为nil时只能使用REST快照
+func New(rest Rest, public *okx.PublicClient) *Toolkit {
+	return &Toolkit{rest: rest, public: public, now: time.Now, unders: map[string]*Underlying{}}
+}
+
+// Underlying 通过Instruments查询instFamily下的永续与交割合约 结果被缓存
+func (t *Toolkit) Underlying(ctx context.Context, family string) (*Underlying, error) {
+	t.lock.Lock()
+	u, ok := t.unders[family]
+	t.lock.Unlock()
+	if ok {
+		return u, nil
+	}
+
+	u = &Underlying{Family: family}
+	for _, instType := range []string{"SWAP", "FUTURES"} {
+		rp, err := t.rest.Instruments(ctx, common.InstrumentsReq{InstType: instType, InstFamily: family})
+		if err != nil {
+			return nil, err
+		}
+		for _, inst := range rp.Data {
+			if inst.State != "" && inst.State != "live" {
+				continue
+			}
+			if u.Uly == "" {
+				u.Uly = inst.Uly
+			}
+			if instType == "SWAP" {
+				u.Swap = inst.InstId
+				continue
+			}
+			u.Futures = append(u.Futures, Future{InstId: inst.InstId, Alias: inst.Alias, Expiry: parseTs(inst.ExpTime)})
+		}
+	}
+	if u.Swap == "" && len(u.Futures) == 0 {
+		return nil, fmt.Errorf("basis: no swap or futures for %s", family)
+	}
+	sort.Slice(u.Futures, func(i, j int) bool { return u.Futures[i].Expiry.Before(u.Futures[j].Expiry) })
+	u.Index = u.Uly
+	if u.Index == "" {
+		u.Index = family
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.unders[family] = u
+	return u, nil
+}
+
+// Refresh 清除合约关系的缓存 交割合约到期或上新后调用
+func (t *Toolkit) Refresh() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.unders = map[string]*Underlying{}
+}
+
+// quotes 计算所需的最新行情
+type quotes struct {
+	index   float64
+	mark    float64
+	funding *common.FundingRate
+	futures map[string]float64
+	ts      time.Time
+}
+
+func (q *quotes) touch(ts string) {
+	if v := parseTs(ts); v.After(q.ts) {
+		q.ts = v
+	}
+}
+
+// Snapshot 通过REST查询当前的资金费率与基差
+func (t *Toolkit) Snapshot(ctx context.Context, family string) (*Snapshot, error) {
+	u, err := t.Underlying(ctx, family)
+	if err != nil {
+		return nil, err
+	}
+	q, err := t.load(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return t.build(u, q), nil
+}
+
+func (t *Toolkit) load(ctx context.Context, u *Underlying) (*quotes, error) {
+	q := &quotes{futures: map[string]float64{}}
+	idx, err := t.rest.IndexTickers(ctx, common.IndexTickersReq{InstId: u.Index})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range idx.Data {
+		q.index = parseNum(d.IdxPx)
+		q.touch(d.Ts)
+	}
+	if u.Swap != "" {
+		mp, err := t.rest.MarkPrices(ctx, common.MarkPriceReq{InstType: "SWAP", InstId: u.Swap})
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range mp.Data {
+			q.mark = parseNum(d.MarkPx)
+			q.touch(d.Ts)
+		}
+		fr, err := t.rest.FundingRate(ctx, u.Swap)
+		if err != nil {
+			return nil, err
+		}
+		for i := range fr.Data {
+			q.funding = &fr.Data[i]
+			q.touch(fr.Data[i].Ts)
+		}
+	}
+	if len(u.Futures) != 0 {
+		tickers, err := t.rest.Tickers(ctx, common.TickersReq{InstType: "FUTURES", InstFamily: u.Family})
+		if err != nil {
+			return nil, err
+		}
+		for i := range tickers.Data {
+			q.futures[tickers.Data[i].InstId] = tickerPx(&tickers.Data[i])
+			q.touch(tickers.Data[i].Ts)
+		}
+	}
+	return q, nil
+}
+
+// Stream 以REST快照为起点订阅指数、标记价格、资金费率与交割合约行情 每次推送后回调最新快照
+// 阻塞直到ctx结束或订阅出错 回调在推送协程中调用 不可阻塞
+func (t *Toolkit) Stream(ctx context.Context, family string, onUpdate func(s *Snapshot)) error {
+	if t.public == nil {
+		return errors.New("basis: stream requires a PublicClient")
+	}
+	u, err := t.Underlying(ctx, family)
+	if err != nil {
+		return err
+	}
+	q, err := t.load(ctx, u)
+	if err != nil {
+		return err
+	}
+	var lock sync.Mutex
+	update := func(apply func(q *quotes)) {
+		lock.Lock()
+		apply(q)
+		snap := t.build(u, q)
+		lock.Unlock()
+		onUpdate(snap)
+	}
+	update(func(q *quotes) {})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var subs []func() error
+	subs = append(subs, func() error {
+		return t.public.IndexTickers(ctx, u.Index, func(resp *common.WsResp[*common.IndexTicker]) {
+			for _, d := range resp.Data {
+				update(func(q *quotes) { q.index = parseNum(d.IdxPx); q.touch(d.Ts) })
+			}
+		})
+	})
+	if u.Swap != "" {
+		subs = append(subs, func() error {
+			return t.public.MarkPrice(ctx, u.Swap, func(resp *common.WsResp[*common.MarkPrice]) {
+				for _, d := range resp.Data {
+					update(func(q *quotes) { q.mark = parseNum(d.MarkPx); q.touch(d.Ts) })
+				}
+			})
+		}, func() error {
+			return t.public.FundingRate(ctx, u.Swap, func(resp *common.WsResp[*common.FundingRate]) {
+				for _, d := range resp.Data {
+					update(func(q *quotes) { q.funding = d; q.touch(d.Ts) })
+				}
+			})
+		})
+	}
+	for _, f := range u.Futures {
+		instId := f.InstId
+		subs = append(subs, func() error {
+			return t.public.Tickers(ctx, instId, func(resp *common.WsResp[*common.Ticker]) {
+				for _, d := range resp.Data {
+					update(func(q *quotes) { q.futures[instId] = tickerPx(d); q.touch(d.Ts) })
+				}
+			})
+		})
+	}
+
+	errCh := make(chan error, len(subs))
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func(sub func() error) {
+			defer wg.Done()
+			// ctx结束后的退订错误不返回
+			if err := sub(); err != nil && ctx.Err() == nil {
+				errCh <- err
+				cancel()
+			}
+		}(sub)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+func (t *Toolkit) build(u *Underlying, q *quotes) *Snapshot {
+	now := t.now()
+	s := &Snapshot{Family: u.Family, Index: q.index, Ts: q.ts}
+	if u.Swap != "" {
+		s.Perp = NewPoint(u.Swap, time.Time{}, q.mark, q.index, now)
+		if q.funding != nil {
+			s.Funding = toFunding(q.funding, q.mark, q.index)
+			s.Perp.Annualized = s.Funding.Annualized
+		}
+	}
+	for _, f := range u.Futures {
+		if !f.Expiry.IsZero() && !f.Expiry.After(now) {
+			continue
+		}
+		s.Term = append(s.Term, NewPoint(f.InstId, f.Expiry, q.futures[f.InstId], q.index, now))
+	}
+	return s
+}
+
+func toFunding(fr *common.FundingRate, mark, index float64) Funding {
+	f := Funding{
+		Rate:            parseNum(fr.FundingRate),
+		NextRate:        parseNum(fr.NextFundingRate),
+		FundingTime:     parseTs(fr.FundingTime),
+		NextFundingTime: parseTs(fr.NextFundingTime),
+		Min:             parseNum(fr.MinFundingRate),
+		Max:             parseNum(fr.MaxFundingRate),
+		Interval:        DefaultFundingInterval,
+	}
+	if !f.FundingTime.IsZero() && f.NextFundingTime.After(f.FundingTime) {
+		f.Interval = f.NextFundingTime.Sub(f.FundingTime)
+	}
+	f.Annualized = AnnualizeFunding(f.Rate, f.Interval)
+	if fr.Premium != "" {
+		f.Premium = parseNum(fr.Premium)
+	} else if index != 0 && mark != 0 {
+		f.Premium = mark/index - 1
+	}
+	f.Predicted = f.NextRate
+	if fr.NextFundingRate == "" {
+		f.Predicted = PredictFunding(f.Premium, f.Min, f.Max)
+	}
+	return f
+}
+
+// tickerPx 买一卖一中间价 无盘口时为最新成交价
+func tickerPx(t *common.Ticker) float64 {
+	bid, ask := parseNum(t.BidPx), parseNum(t.AskPx)
+	if bid > 0 && ask > 0 {
+		return (bid + ask) / 2
+	}
+	return parseNum(t.Last)
+}
@@ -0,0 +1,157 @@
+package okx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+const (
+	minCancelAllAfter = 10 * time.Second
+	maxCancelAllAfter = 120 * time.Second
+)
+
+// DeadManSwitch 基于cancel-all-after的看门狗
+// 客户端健康时定期重置倒计时 不健康时停止重置让交易所撤销全部挂单
+type DeadManSwitch struct {
+	rest        CancelAllAfterTrader
+	timeout     time.Duration
+	interval    time.Duration
+	checks      []func() bool
+	lock        sync.RWMutex
+	lastArm     time.Time
+	triggerTime time.Time
+	lastErr     error
+	Logger      *slog.Logger // 为nil时使用slog.Default()
+	// OnSkip 因不健康跳过重置时回调
+	OnSkip func()
+}
+
+// NewDeadManSwitch timeout取值范围10s~120s checks为额外的健康检查 如PrivateClient.Alive
+func NewDeadManSwitch(rest CancelAllAfterTrader, timeout time.Duration, checks ...func() bool) *DeadManSwitch {
+	if timeout < minCancelAllAfter {
+		timeout = minCancelAllAfter
+	}
+	if timeout > maxCancelAllAfter {
+		timeout = maxCancelAllAfter
+	}
+	return &DeadManSwitch{
+		rest:     rest,
+		timeout:  timeout,
+		interval: timeout / 3,
+		checks:   checks,
+		OnSkip:   func() {},
+	}
+}
+
+// WithExchangeClient 将全部ws连接的存活状态加入健康检查
+// 私有连接必须存活 公共与业务连接在使用后(连接过或有订阅)需存活
+func (d *DeadManSwitch) WithExchangeClient(client *ExchangeClient) *DeadManSwitch {
+	d.checks = append(d.checks, client.PrivateClient.Alive, client.PublicClient.Healthy, client.BusinessClient.Healthy)
+	return d
+}
+
+// Timeout 倒计时时长
+func (d *DeadManSwitch) Timeout() time.Duration {
+	return d.timeout
+}
+
+// LastArm 最近一次成功重置的时间
+func (d *DeadManSwitch) LastArm() time.Time {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.lastArm
+}
+
+// TriggerTime 交易所返回的预计触发撤单时间
+func (d *DeadManSwitch) TriggerTime() time.Time {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.triggerTime
+}
+
+// Err 最近一次重置的错误
+func (d *DeadManSwitch) Err() error {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.lastErr
+}
+
+// Healthy 所有健康检查均通过 REST客户端已关闭时不健康
+func (d *DeadManSwitch) Healthy() bool {
+	if c, ok := d.rest.(interface{ Closed() bool }); ok && c.Closed() {
+		return false
+	}
+	for _, check := range d.checks {
+		if !check() {
+			return false
+		}
+	}
+	return true
+}
+
+// Arm 重置倒计时
+func (d *DeadManSwitch) Arm(ctx context.Context) error {
+	rp, err := d.rest.CancelAllAfter(ctx, int(d.timeout/time.Second))
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.lastErr = err
+	if err != nil {
+		return err
+	}
+	d.lastArm = time.Now()
+	if len(rp.Data) != 0 {
+		if ms, err := strconv.ParseInt(rp.Data[0].TriggerTime, 10, 64); err == nil {
+			d.triggerTime = time.UnixMilli(ms)
+		}
+	}
+	return nil
+}
+
+// Disarm 取消倒计时 用于正常退出
+func (d *DeadManSwitch) Disarm(ctx context.Context) error {
+	_, err := d.rest.CancelAllAfter(ctx, 0)
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.triggerTime = time.Time{}
+	return nil
+}
+
+// Run 启动时立即开始倒计时 之后定期检查并仅在健康时重置 阻塞直到ctx结束 退出时不会自动取消倒计时
+// 启动时不检查健康状态 从未建立连接的客户端同样受到保护
+func (d *DeadManSwitch) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	d.arm(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if d.Healthy() {
+			d.arm(ctx)
+		} else {
+			d.OnSkip()
+		}
+	}
+}
+
+func (d *DeadManSwitch) arm(ctx context.Context) {
+	if err := d.Arm(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		common.ResolveLogger(d.Logger, nil).Warn("cancel-all-after arm", "timeout", d.timeout, "err", err)
+	}
+}
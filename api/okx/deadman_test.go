@@ -0,0 +1,88 @@
+package okx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func TestDeadManSwitch(t *testing.T) {
+	var armed atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"timeOut":"10"}`, string(bs))
+		armed.Add(1)
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"triggerTime":"1587971460","tag":"","ts":"1587971400"}]}`))
+	}))
+	defer srv.Close()
+	rest := NewRestClientWithCustom(context.Background(), config, common.NormalServer,
+		map[common.Destination]common.BaseURL{common.NormalServer: common.BaseURL(srv.URL)})
+
+	var healthy atomic.Bool
+	d := NewDeadManSwitch(rest, time.Second, healthy.Load)
+	assert.Equal(t, 10*time.Second, d.Timeout())
+
+	healthy.Store(true)
+	assert.NoError(t, d.Arm(context.Background()))
+	assert.Equal(t, int32(1), armed.Load())
+	assert.False(t, d.LastArm().IsZero())
+	assert.Equal(t, time.UnixMilli(1587971460), d.TriggerTime())
+}
+
+type fakeCancelAllAfter struct {
+	armed atomic.Int32
+}
+
+func (f *fakeCancelAllAfter) CancelAllAfter(ctx context.Context, timeOut int) (*common.Resp[common.CancelAllAfter], error) {
+	if timeOut != 0 {
+		f.armed.Add(1)
+	}
+	return &common.Resp[common.CancelAllAfter]{Code: "0"}, nil
+}
+
+func TestDeadManSwitchRun(t *testing.T) {
+	rest := &fakeCancelAllAfter{}
+	var healthy atomic.Bool
+	d := NewDeadManSwitch(rest, 10*time.Second, healthy.Load)
+	d.interval = 10 * time.Millisecond
+	var skipped atomic.Int32
+	d.OnSkip = func() { skipped.Add(1) }
+
+	// 不健康时启动同样开始倒计时 之后不再重置
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	_ = d.Run(ctx)
+	assert.Equal(t, int32(1), rest.armed.Load())
+	assert.Positive(t, skipped.Load())
+	assert.False(t, d.LastArm().IsZero())
+
+	healthy.Store(true)
+	ctx, cancel = context.WithTimeout(context.Background(), 35*time.Millisecond)
+	defer cancel()
+	_ = d.Run(ctx)
+	assert.GreaterOrEqual(t, rest.armed.Load(), int32(3))
+}
+
+func TestDeadManSwitchExchangeClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &ExchangeClient{
+		PublicClient:   &PublicClient{WsClient: common.NewBaseWsClient(ctx, common.Public, "", nil, nil)},
+		BusinessClient: &BusinessClient{WsClient: common.NewBaseWsClient(ctx, common.Business, "", nil, nil)},
+		PrivateClient:  &PrivateClient{WsClient: common.NewBaseWsClient(ctx, common.Private, "", nil, nil)},
+	}
+	d := NewDeadManSwitch(&fakeCancelAllAfter{}, 10*time.Second).WithExchangeClient(client)
+	assert.Len(t, d.checks, 3)
+	// 私有连接未连接时不健康
+	assert.False(t, d.Healthy())
+	assert.True(t, client.PublicClient.Healthy())
+	assert.True(t, client.BusinessClient.Healthy())
+}
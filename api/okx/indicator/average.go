@@ -0,0 +1,235 @@
+package indicator
+
+import (
+	"fmt"
+	"math"
+)
+
+// ema 指数平均 前n个值使用简单平均作为种子 k为平滑系数
+type ema struct {
+	n     int
+	k     float64
+	count int
+	sum   float64
+	v     float64
+}
+
+// mustPeriod 周期须为正数 否则panic
+func mustPeriod(name string, n int) {
+	if n <= 0 {
+		panic(fmt.Sprintf("indicator: invalid %s period %d: want at least 1", name, n))
+	}
+}
+
+func newEMA(n int) ema {
+	return ema{n: n, k: 2 / float64(n+1)}
+}
+
+// newRMA Wilder平滑 用于RSI与ATR
+func newRMA(n int) ema {
+	return ema{n: n, k: 1 / float64(n)}
+}
+
+func (e *ema) next(x float64, commit bool) float64 {
+	count, sum, v := e.count+1, e.sum, e.v
+	if count <= e.n {
+		sum += x
+		v = sum / float64(count)
+	} else {
+		v += e.k * (x - v)
+	}
+	if commit {
+		e.count, e.sum, e.v = count, sum, v
+	}
+	return v
+}
+
+func (e *ema) ready() bool {
+	return e.count >= e.n
+}
+
+// window 固定长度的滑动窗口 维护和与平方和
+type window struct {
+	buf   []float64
+	pos   int
+	full  bool
+	sum   float64
+	sumSq float64
+	fresh int // 距上次重算的写入次数 定期重算消除累计误差
+}
+
+func newWindow(n int) window {
+	return window{buf: make([]float64, n)}
+}
+
+// with 加入x后的和、平方和与数量 不修改窗口
+func (w *window) with(x float64) (sum, sumSq float64, n int) {
+	sum, sumSq, n = w.sum+x, w.sumSq+x*x, w.pos+1
+	if w.full {
+		old := w.buf[w.pos]
+		sum, sumSq, n = sum-old, sumSq-old*old, len(w.buf)
+	}
+	return sum, sumSq, n
+}
+
+func (w *window) push(x float64) {
+	w.sum, w.sumSq, _ = w.with(x)
+	w.buf[w.pos] = x
+	w.pos++
+	if w.pos == len(w.buf) {
+		w.pos, w.full = 0, true
+	}
+	if w.fresh++; w.fresh >= len(w.buf) && w.full {
+		w.sum, w.sumSq, w.fresh = 0, 0, 0
+		for _, v := range w.buf {
+			w.sum += v
+			w.sumSq += v * v
+		}
+	}
+}
+
+// EMA 收盘价的指数平均
+type EMA struct {
+	s series[float64]
+	e ema
+}
+
+// NewEMA n<=0时panic
+func NewEMA(n int) *EMA {
+	mustPeriod("EMA", n)
+	return &EMA{e: newEMA(n)}
+}
+
+func (e *EMA) Update(c Candle) float64 {
+	return e.s.update(c, func(c Candle, commit bool) float64 {
+		return e.e.next(c.C, commit)
+	})
+}
+
+func (e *EMA) Ready() bool {
+	return e.e.ready()
+}
+
+// SMA 收盘价的简单平均
+type SMA struct {
+	s series[float64]
+	w window
+}
+
+// NewSMA n<=0时panic
+func NewSMA(n int) *SMA {
+	mustPeriod("SMA", n)
+	return &SMA{w: newWindow(n)}
+}
+
+func (a *SMA) Update(c Candle) float64 {
+	return a.s.update(c, func(c Candle, commit bool) float64 {
+		sum, _, n := a.w.with(c.C)
+		if commit {
+			a.w.push(c.C)
+		}
+		return sum / float64(n)
+	})
+}
+
+func (a *SMA) Ready() bool {
+	return a.w.full
+}
+
+// Band 布林带
+type Band struct {
+	Mid   float64
+	Upper float64
+	Lower float64
+	Width float64 // (Upper-Lower)/Mid
+}
+
+// Bollinger 布林带 n为周期 k为标准差倍数
+type Bollinger struct {
+	s series[Band]
+	w window
+	k float64
+}
+
+// NewBollinger n<=0时panic
+func NewBollinger(n int, k float64) *Bollinger {
+	mustPeriod("Bollinger", n)
+	return &Bollinger{w: newWindow(n), k: k}
+}
+
+func (b *Bollinger) Update(c Candle) Band {
+	return b.s.update(c, func(c Candle, commit bool) Band {
+		sum, sumSq, n := b.w.with(c.C)
+		if commit {
+			b.w.push(c.C)
+		}
+		mid := sum / float64(n)
+		dev := b.k * math.Sqrt(math.Max(sumSq/float64(n)-mid*mid, 0))
+		band := Band{Mid: mid, Upper: mid + dev, Lower: mid - dev}
+		if mid != 0 {
+			band.Width = (band.Upper - band.Lower) / mid
+		}
+		return band
+	})
+}
+
+func (b *Bollinger) Ready() bool {
+	return b.w.full
+}
+
+// VWAP 以典型价(H+L+C)/3加权的成交均价 n为0时从创建或Reset起累计 否则为最近n根
+type VWAP struct {
+	s      series[float64]
+	pv, v  window
+	cumPV  float64
+	cumV   float64
+	n      int
+	closed int
+}
+
+// NewVWAP n<0时panic
+func NewVWAP(n int) *VWAP {
+	if n < 0 {
+		panic(fmt.Sprintf("indicator: invalid VWAP period %d: want at least 0", n))
+	}
+	w := &VWAP{n: n}
+	if n > 0 {
+		w.pv, w.v = newWindow(n), newWindow(n)
+	}
+	return w
+}
+
+func (w *VWAP) Update(c Candle) float64 {
+	return w.s.update(c, func(c Candle, commit bool) float64 {
+		pv := (c.H + c.L + c.C) / 3 * c.Vol
+		sumPV, sumV := w.cumPV+pv, w.cumV+c.Vol
+		if w.n > 0 {
+			sumPV, _, _ = w.pv.with(pv)
+			sumV, _, _ = w.v.with(c.Vol)
+		}
+		if commit {
+			w.cumPV, w.cumV = sumPV, sumV
+			if w.n > 0 {
+				w.pv.push(pv)
+				w.v.push(c.Vol)
+			}
+			w.closed++
+		}
+		if sumV == 0 {
+			return c.C
+		}
+		return sumPV / sumV
+	})
+}
+
+// Reset 重新开始累计 用于按交易时段锚定
+func (w *VWAP) Reset() {
+	*w = *NewVWAP(w.n)
+}
+
+func (w *VWAP) Ready() bool {
+	if w.n > 0 {
+		return w.pv.full
+	}
+	return w.closed > 0
+}
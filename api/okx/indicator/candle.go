@@ -0,0 +1,120 @@
+// Package indicator 增量技术指标 每次更新O(1)
+// 未完结的k线只计算临时值 同一时间的后续推送替换之前的临时值 已完结的k线计入状态
+// 指标不是并发安全的 应在同一个协程中更新
+package indicator
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx/bar"
+)
+
+// Candle 解析为数值的k线
+type Candle struct {
+	Ts      time.Time // 开始时间
+	O       float64
+	H       float64
+	L       float64
+	C       float64
+	Vol     float64
+	Confirm bool
+}
+
+// ParseCandle 解析推送或接口返回的k线
+func ParseCandle(c *common.Candle) (Candle, error) {
+	ms, err := strconv.ParseInt(c.Ts, 10, 64)
+	if err != nil {
+		return Candle{}, err
+	}
+	out := Candle{Ts: time.UnixMilli(ms), Confirm: c.Confirm == "1"}
+	for _, f := range []struct {
+		dst *float64
+		src string
+	}{{&out.O, c.O}, {&out.H, c.H}, {&out.L, c.L}, {&out.C, c.C}, {&out.Vol, c.Vol}} {
+		if *f.dst, err = strconv.ParseFloat(f.src, 64); err != nil {
+			return Candle{}, err
+		}
+	}
+	return out, nil
+}
+
+// ParseCandles 解析并按时间升序排列 接口返回为倒序
+func ParseCandles(rows []common.Candle) ([]Candle, error) {
+	out := make([]Candle, 0, len(rows))
+	for i := range rows {
+		c, err := ParseCandle(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Ts.Before(out[j].Ts) })
+	return out, nil
+}
+
+// FromBar 由聚合k线转换
+func FromBar(b bar.Bar) Candle {
+	return Candle{Ts: b.Start, O: b.O, H: b.H, L: b.L, C: b.C, Vol: b.Vol, Confirm: b.Confirm}
+}
+
+// Indicator 以k线更新的指标
+type Indicator[T any] interface {
+	// Update 加入一根k线并返回最新值 早于已完结k线的数据被忽略
+	Update(c Candle) T
+	// Ready 已完结的k线足够计算出有效值
+	Ready() bool
+}
+
+// Warmup 按顺序送入历史k线 返回最后的值
+func Warmup[T any](ind Indicator[T], history []Candle) T {
+	var v T
+	for _, c := range history {
+		v = ind.Update(c)
+	}
+	return v
+}
+
+// Handler 用于BusinessClient.Candle的回调 格式错误的数据被忽略
+func Handler[T any](ind Indicator[T], onValue func(c Candle, v T)) func(resp *common.WsResp[*common.Candle]) {
+	return func(resp *common.WsResp[*common.Candle]) {
+		for _, row := range resp.Data {
+			c, err := ParseCandle(row)
+			if err != nil {
+				continue
+			}
+			v := ind.Update(c)
+			if onValue != nil {
+				onValue(c, v)
+			}
+		}
+	}
+}
+
+// series 处理k线的完结语义 step的commit为true时写入状态 否则只计算
+type series[T any] struct {
+	last    time.Time // 最后一根已完结k线的时间
+	pending Candle
+	has     bool
+	out     T
+}
+
+func (s *series[T]) update(c Candle, step func(c Candle, commit bool) T) T {
+	if !s.last.IsZero() && !c.Ts.After(s.last) {
+		return s.out
+	}
+	// 上一根未收到完结推送即开始新的一根 以最后的值计入
+	if s.has && c.Ts.After(s.pending.Ts) {
+		s.out = step(s.pending, true)
+		s.last, s.has = s.pending.Ts, false
+	}
+	if c.Confirm {
+		s.out = step(c, true)
+		s.last, s.has = c.Ts, false
+		return s.out
+	}
+	s.pending, s.has = c, true
+	return step(c, false)
+}
@@ -0,0 +1,261 @@
+package indicator
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx/bar"
+)
+
+var t0 = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// history 随机游走的已完结k线
+func history(n int) []Candle {
+	r := rand.New(rand.NewSource(1))
+	out := make([]Candle, n)
+	px := 100.0
+	for i := range out {
+		o := px
+		px += r.NormFloat64()
+		h, l := math.Max(o, px)+r.Float64(), math.Min(o, px)-r.Float64()
+		out[i] = Candle{Ts: t0.Add(time.Duration(i) * time.Minute), O: o, H: h, L: l, C: px, Vol: 1 + r.Float64()*10, Confirm: true}
+	}
+	return out
+}
+
+// naiveEMA 与ema相同的种子规则 逐根重算
+func naiveEMA(xs []float64, n int) []float64 {
+	out := make([]float64, len(xs))
+	k := 2 / float64(n+1)
+	var sum, v float64
+	for i, x := range xs {
+		if i < n {
+			sum += x
+			v = sum / float64(i+1)
+		} else {
+			v += k * (x - v)
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func closes(cs []Candle) []float64 {
+	out := make([]float64, len(cs))
+	for i, c := range cs {
+		out[i] = c.C
+	}
+	return out
+}
+
+func TestEMAConfirm(t *testing.T) {
+	cs := history(50)
+	want := naiveEMA(closes(cs), 10)
+
+	e := NewEMA(10)
+	for i, c := range cs {
+		// 未完结的临时值不影响状态
+		tmp := c
+		tmp.Confirm, tmp.C = false, c.C+5
+		e.Update(tmp)
+		e.Update(tmp)
+		assert.InDelta(t, want[i], e.Update(c), 1e-9)
+		assert.Equal(t, i >= 9, e.Ready())
+	}
+	// 重复与过期的k线被忽略
+	assert.InDelta(t, want[49], e.Update(cs[10]), 1e-9)
+	last := cs[49]
+	last.C = 0
+	assert.InDelta(t, want[49], e.Update(last), 1e-9)
+
+	// 未收到完结推送即开始下一根 以最后的值计入
+	e = NewEMA(3)
+	pending := cs[0]
+	pending.Confirm = false
+	e.Update(pending)
+	e.Update(cs[1])
+	assert.InDelta(t, naiveEMA(closes(cs[:2]), 3)[1], e.Update(cs[1]), 1e-9)
+	assert.True(t, e.s.last.Equal(cs[1].Ts))
+}
+
+func TestWindowIndicators(t *testing.T) {
+	cs := history(200)
+	sma, boll, vwap := NewSMA(20), NewBollinger(20, 2), NewVWAP(20)
+	cum := NewVWAP(0)
+	var cumPV, cumV float64
+	for i, c := range cs {
+		lo := max(0, i-19)
+		var sum, sumSq, pv, v float64
+		for _, w := range cs[lo : i+1] {
+			sum += w.C
+			sumSq += w.C * w.C
+			pv += (w.H + w.L + w.C) / 3 * w.Vol
+			v += w.Vol
+		}
+		n := float64(i + 1 - lo)
+		mean := sum / n
+		dev := 2 * math.Sqrt(sumSq/n-mean*mean)
+		cumPV += (c.H + c.L + c.C) / 3 * c.Vol
+		cumV += c.Vol
+
+		assert.InDelta(t, mean, sma.Update(c), 1e-9)
+		band := boll.Update(c)
+		assert.InDelta(t, mean+dev, band.Upper, 1e-6)
+		assert.InDelta(t, mean-dev, band.Lower, 1e-6)
+		assert.InDelta(t, pv/v, vwap.Update(c), 1e-9)
+		assert.InDelta(t, cumPV/cumV, cum.Update(c), 1e-9)
+	}
+	assert.True(t, sma.Ready())
+	assert.True(t, boll.Ready())
+
+	cum.Reset()
+	assert.False(t, cum.Ready())
+	assert.Equal(t, cs[0].C, cum.Update(Candle{Ts: t0, C: cs[0].C}))
+}
+
+func TestWindowDrift(t *testing.T) {
+	w := newWindow(3)
+	w.push(1e16)
+	for i := 0; i < 10; i++ {
+		w.push(1)
+	}
+	assert.Equal(t, 3.0, w.sum)
+}
+
+func TestRSIAndATR(t *testing.T) {
+	cs := history(100)
+	rsi, atr := NewRSI(14), NewATR(14)
+	k := 1.0 / 14
+	var gain, loss, tr float64
+	for i, c := range cs {
+		v := rsi.Update(c)
+		a := atr.Update(c)
+		// Wilder平滑 前14个值为简单平均
+		cur := c.H - c.L
+		if i > 0 {
+			prev := cs[i-1].C
+			cur = math.Max(cur, math.Max(math.Abs(c.H-prev), math.Abs(c.L-prev)))
+			g, l := math.Max(c.C-prev, 0), math.Max(prev-c.C, 0)
+			if i <= 14 {
+				gain += (g - gain) / float64(i)
+				loss += (l - loss) / float64(i)
+			} else {
+				gain += k * (g - gain)
+				loss += k * (l - loss)
+			}
+			assert.InDelta(t, 100-100/(1+gain/loss), v, 1e-9)
+		}
+		if i < 14 {
+			tr += (cur - tr) / float64(i+1)
+		} else {
+			tr += k * (cur - tr)
+		}
+		assert.InDelta(t, tr, a, 1e-9)
+	}
+	assert.True(t, rsi.Ready())
+	assert.True(t, atr.Ready())
+
+	up := NewRSI(3)
+	for i := 0; i < 5; i++ {
+		up.Update(Candle{Ts: t0.Add(time.Duration(i) * time.Minute), C: float64(i), Confirm: true})
+	}
+	assert.Equal(t, 100.0, up.Update(Candle{Ts: t0.Add(5 * time.Minute), C: 5}))
+}
+
+func TestMACD(t *testing.T) {
+	cs := history(100)
+	fast, slow := naiveEMA(closes(cs), 12), naiveEMA(closes(cs), 26)
+	line := make([]float64, len(cs))
+	for i := range cs {
+		line[i] = fast[i] - slow[i]
+	}
+	// 信号线只取慢线就绪后的差值
+	signal := naiveEMA(line[25:], 9)
+
+	m := NewMACD(12, 26, 9)
+	Warmup[MACDValue](m, cs[:33])
+	assert.False(t, m.Ready())
+	v := Warmup[MACDValue](m, cs[33:])
+	assert.True(t, m.Ready())
+	assert.InDelta(t, line[99], v.MACD, 1e-9)
+	assert.InDelta(t, signal[74], v.Signal, 1e-9)
+	assert.InDelta(t, line[99]-signal[74], v.Hist, 1e-9)
+}
+
+func TestInvalidPeriod(t *testing.T) {
+	assert.PanicsWithValue(t, "indicator: invalid EMA period 0: want at least 1", func() { NewEMA(0) })
+	assert.Panics(t, func() { NewSMA(-1) })
+	assert.Panics(t, func() { NewBollinger(0, 2) })
+	assert.Panics(t, func() { NewVWAP(-1) })
+	assert.NotPanics(t, func() { NewVWAP(0) })
+	assert.Panics(t, func() { NewRSI(0) })
+	assert.Panics(t, func() { NewATR(0) })
+	assert.Panics(t, func() { NewMACD(12, 0, 9) })
+	assert.Panics(t, func() { NewFundingBasis(0) })
+	assert.Panics(t, func() { NewBookImbalance(5, 0) })
+}
+
+func TestParseCandles(t *testing.T) {
+	rows := []common.Candle{
+		{Ts: "1704153660000", O: "2", H: "3", L: "1", C: "2.5", Vol: "10", Confirm: "0"},
+		{Ts: "1704153600000", O: "1", H: "2", L: "0.5", C: "2", Vol: "5", Confirm: "1"},
+	}
+	cs, err := ParseCandles(rows)
+	assert.NoError(t, err)
+	assert.Equal(t, []Candle{
+		{Ts: time.UnixMilli(1704153600000), O: 1, H: 2, L: 0.5, C: 2, Vol: 5, Confirm: true},
+		{Ts: time.UnixMilli(1704153660000), O: 2, H: 3, L: 1, C: 2.5, Vol: 10},
+	}, cs)
+	_, err = ParseCandles([]common.Candle{{Ts: "1", O: "x"}})
+	assert.Error(t, err)
+
+	var got []float64
+	sma := NewSMA(2)
+	Handler[float64](sma, func(c Candle, v float64) { got = append(got, v) })(&common.WsResp[*common.Candle]{Data: []*common.Candle{&rows[1], &rows[0], {Ts: "x"}}})
+	assert.Equal(t, []float64{2, 2.25}, got)
+
+	c := FromBar(bar.Bar{Start: t0, O: 1, H: 2, L: 0, C: 1.5, Vol: 3, Confirm: true})
+	assert.Equal(t, Candle{Ts: t0, O: 1, H: 2, L: 0, C: 1.5, Vol: 3, Confirm: true}, c)
+}
+
+func TestFundingBasis(t *testing.T) {
+	f := NewFundingBasis(2)
+	v := f.Update(101, 100, 0.0001)
+	assert.InDelta(t, 0.01, v.Basis, 1e-12)
+	assert.InDelta(t, 0.0099, v.Adjusted, 1e-12)
+	assert.InDelta(t, 0.0099, v.Smoothed, 1e-12)
+	assert.False(t, f.Ready())
+	v = f.Update(100, 100, -0.0001)
+	assert.InDelta(t, 0.0001, v.Adjusted, 1e-12)
+	assert.InDelta(t, 0.005, v.Smoothed, 1e-12)
+	assert.True(t, f.Ready())
+	assert.InDelta(t, 0.005, f.Update(1, 0, 0).Smoothed, 1e-12)
+}
+
+func TestImbalance(t *testing.T) {
+	book := &common.OrderBook{
+		Bids: []common.Spread{{Price: "99", Count: "3"}, {Price: "98", Count: "5"}},
+		Asks: []common.Spread{{Price: "101", Count: "1"}, {Price: "102", Count: "1"}},
+	}
+	v, err := Imbalance(book, 1)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, v, 1e-12)
+	v, err = Imbalance(book, 0)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.6, v, 1e-12)
+	v, err = Imbalance(&common.OrderBook{}, 5)
+	assert.NoError(t, err)
+	assert.Zero(t, v)
+
+	b := NewBookImbalance(1, 2)
+	v, err = b.Update(book)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, v, 1e-12)
+	_, err = b.Update(&common.OrderBook{Bids: []common.Spread{{Count: "x"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package indicator
+
+import (
+	"strconv"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+type BasisValue struct {
+	Basis    float64 // 永续相对指数的溢价率 perp/index-1
+	Adjusted float64 // 扣除下一期资金费后的溢价率 多头持有到结算的净溢价
+	Smoothed float64 // Adjusted的指数平均
+}
+
+// FundingBasis 资金费调整后的基差 每次行情更新调用
+type FundingBasis struct {
+	e ema
+}
+
+// NewFundingBasis n为平滑的更新次数 n<=0时panic
+func NewFundingBasis(n int) *FundingBasis {
+	mustPeriod("FundingBasis", n)
+	return &FundingBasis{e: newEMA(n)}
+}
+
+// Update perpPx为永续标记价格 indexPx为指数价格 fundingRate为当期资金费率
+func (f *FundingBasis) Update(perpPx, indexPx, fundingRate float64) BasisValue {
+	if indexPx == 0 {
+		return BasisValue{Smoothed: f.e.v}
+	}
+	basis := perpPx/indexPx - 1
+	adjusted := basis - fundingRate
+	return BasisValue{Basis: basis, Adjusted: adjusted, Smoothed: f.e.next(adjusted, true)}
+}
+
+func (f *FundingBasis) Ready() bool {
+	return f.e.ready()
+}
+
+// Imbalance 前depth档买卖挂单量的不平衡度 范围[-1,1] 正值表示买盘较厚 depth不大于0时使用全部档位
+func Imbalance(book *common.OrderBook, depth int) (float64, error) {
+	bid, err := depthSize(book.Bids, depth)
+	if err != nil {
+		return 0, err
+	}
+	ask, err := depthSize(book.Asks, depth)
+	if err != nil {
+		return 0, err
+	}
+	if bid+ask == 0 {
+		return 0, nil
+	}
+	return (bid - ask) / (bid + ask), nil
+}
+
+func depthSize(levels []common.Spread, depth int) (float64, error) {
+	if depth > 0 && depth < len(levels) {
+		levels = levels[:depth]
+	}
+	var sum float64
+	for _, l := range levels {
+		sz, err := strconv.ParseFloat(l.Count, 64)
+		if err != nil {
+			return 0, err
+		}
+		sum += sz
+	}
+	return sum, nil
+}
+
+// BookImbalance 平滑后的盘口不平衡度 每次深度推送调用 增量深度需先合并为完整盘口
+type BookImbalance struct {
+	depth int
+	e     ema
+}
+
+// NewBookImbalance depth为统计的档位数 n为平滑的更新次数 n<=0时panic
+func NewBookImbalance(depth, n int) *BookImbalance {
+	mustPeriod("BookImbalance", n)
+	return &BookImbalance{depth: depth, e: newEMA(n)}
+}
+
+func (b *BookImbalance) Update(book *common.OrderBook) (float64, error) {
+	v, err := Imbalance(book, b.depth)
+	if err != nil {
+		return b.e.v, err
+	}
+	return b.e.next(v, true), nil
+}
+
+func (b *BookImbalance) Ready() bool {
+	return b.e.ready()
+}
@@ -0,0 +1,116 @@
+package indicator
+
+import "math"
+
+// RSI 相对强弱 使用Wilder平滑
+type RSI struct {
+	s       series[float64]
+	gain    ema
+	loss    ema
+	prev    float64
+	started bool
+}
+
+// NewRSI n<=0时panic
+func NewRSI(n int) *RSI {
+	mustPeriod("RSI", n)
+	return &RSI{gain: newRMA(n), loss: newRMA(n)}
+}
+
+func (r *RSI) Update(c Candle) float64 {
+	return r.s.update(c, func(c Candle, commit bool) float64 {
+		if !r.started {
+			if commit {
+				r.prev, r.started = c.C, true
+			}
+			return 50
+		}
+		diff := c.C - r.prev
+		gain := r.gain.next(math.Max(diff, 0), commit)
+		loss := r.loss.next(math.Max(-diff, 0), commit)
+		if commit {
+			r.prev = c.C
+		}
+		switch {
+		case loss == 0 && gain == 0:
+			return 50
+		case loss == 0:
+			return 100
+		}
+		return 100 - 100/(1+gain/loss)
+	})
+}
+
+func (r *RSI) Ready() bool {
+	return r.gain.ready()
+}
+
+// ATR 平均真实波幅 使用Wilder平滑
+type ATR struct {
+	s       series[float64]
+	tr      ema
+	prev    float64
+	started bool
+}
+
+// NewATR n<=0时panic
+func NewATR(n int) *ATR {
+	mustPeriod("ATR", n)
+	return &ATR{tr: newRMA(n)}
+}
+
+func (a *ATR) Update(c Candle) float64 {
+	return a.s.update(c, func(c Candle, commit bool) float64 {
+		tr := c.H - c.L
+		if a.started {
+			tr = math.Max(tr, math.Max(math.Abs(c.H-a.prev), math.Abs(c.L-a.prev)))
+		}
+		if commit {
+			a.prev, a.started = c.C, true
+		}
+		return a.tr.next(tr, commit)
+	})
+}
+
+func (a *ATR) Ready() bool {
+	return a.tr.ready()
+}
+
+type MACDValue struct {
+	MACD   float64 // 快线与慢线之差
+	Signal float64 // MACD的指数平均
+	Hist   float64 // MACD-Signal
+}
+
+// MACD 常用参数为12、26、9 信号线从慢线就绪后开始计算
+type MACD struct {
+	s      series[MACDValue]
+	fast   ema
+	slow   ema
+	signal ema
+}
+
+// NewMACD 任一周期<=0时panic
+func NewMACD(fast, slow, signal int) *MACD {
+	mustPeriod("MACD fast", fast)
+	mustPeriod("MACD slow", slow)
+	mustPeriod("MACD signal", signal)
+	return &MACD{fast: newEMA(fast), slow: newEMA(slow), signal: newEMA(signal)}
+}
+
+func (m *MACD) Update(c Candle) MACDValue {
+	return m.s.update(c, func(c Candle, commit bool) MACDValue {
+		// 慢线就绪前的差值只是种子平均 不计入信号线
+		warm := m.slow.count+1 < m.slow.n
+		macd := m.fast.next(c.C, commit) - m.slow.next(c.C, commit)
+		if warm {
+			return MACDValue{MACD: macd, Signal: macd}
+		}
+		signal := m.signal.next(macd, commit)
+		return MACDValue{MACD: macd, Signal: signal, Hist: macd - signal}
+	})
+}
+
+func (m *MACD) Ready() bool {
+	return m.slow.ready() && m.signal.ready()
+}
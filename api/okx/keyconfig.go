@@ -17,7 +17,15 @@ type KeyConfig struct {
 }
 
 func (c KeyConfig) MakeHeader(method, requestPath string, body []byte) http.Header {
-	now := time.Now().UTC().Format("2006-01-02T15:04:05.999Z")
+	return c.makeHeader(time.Now(), method, requestPath, body)
+}
+
+func (c KeyConfig) MakeWsSign() map[string]string {
+	return c.makeWsSign(time.Now())
+}
+
+func (c KeyConfig) makeHeader(t time.Time, method, requestPath string, body []byte) http.Header {
+	now := t.UTC().Format("2006-01-02T15:04:05.999Z")
 	sign := c.MakeSign(now, method, requestPath, body)
 	return map[string][]string{
 		"OK-ACCESS-KEY":        {c.Apikey},
@@ -27,8 +35,8 @@ func (c KeyConfig) MakeHeader(method, requestPath string, body []byte) http.Head
 	}
 }
 
-func (c KeyConfig) MakeWsSign() map[string]string {
-	now := fmt.Sprint(time.Now().UTC().Unix())
+func (c KeyConfig) makeWsSign(t time.Time) map[string]string {
+	now := fmt.Sprint(t.UTC().Unix())
 	sign := c.MakeSign(now, http.MethodGet, "/users/self/verify", []byte(""))
 	return map[string]string{
 		"apiKey":     c.Apikey,
@@ -43,3 +51,17 @@ func (c KeyConfig) MakeSign(now, method, requestPath string, body []byte) (sign
 	hash.Write(append([]byte(now+method+requestPath), body...))
 	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
 }
+
+// clockKeyConfig 使用自定义时钟生成签名时间戳
+type clockKeyConfig struct {
+	KeyConfig
+	now func() time.Time
+}
+
+func (c clockKeyConfig) MakeHeader(method, requestPath string, body []byte) http.Header {
+	return c.makeHeader(c.now(), method, requestPath, body)
+}
+
+func (c clockKeyConfig) MakeWsSign() map[string]string {
+	return c.makeWsSign(c.now())
+}
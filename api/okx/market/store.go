@@ -0,0 +1,509 @@
+// Package market 按需订阅的行情快照 同步读取最新的标记价格、最优买卖价、成交、资金费率与持仓量
+package market
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+)
+
+// Kind 行情数据的种类 每种对应一个频道
+type Kind int
+
+const (
+	Mark         Kind = iota // mark-price
+	BBO                      // bbo-tbt
+	LastTrade                // trades 提供BusinessClient时使用trades-all
+	Funding                  // funding-rate 仅永续合约
+	OpenInterest             // open-interest
+	kinds
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Mark:
+		return "mark"
+	case BBO:
+		return "bbo"
+	case LastTrade:
+		return "trade"
+	case Funding:
+		return "funding"
+	case OpenInterest:
+		return "open_interest"
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// DefaultStaleAfter 超过该时间未更新视为过期 成交不按时间判断
+var DefaultStaleAfter = map[Kind]time.Duration{
+	Mark:         10 * time.Second,
+	BBO:          10 * time.Second,
+	Funding:      2 * time.Minute,
+	OpenInterest: time.Minute,
+}
+
+type Quote struct {
+	BidPx float64
+	BidSz float64
+	AskPx float64
+	AskSz float64
+}
+
+// Mid 买一卖一的中间价
+func (q Quote) Mid() float64 {
+	return (q.BidPx + q.AskPx) / 2
+}
+
+type Trade struct {
+	TradeId string
+	Px      float64
+	Sz      float64
+	Side    string
+}
+
+type FundingRate struct {
+	Rate            float64 // 当期资金费率
+	NextRate        float64 // 预测的下一期费率 部分产品为0
+	FundingTime     time.Time
+	NextFundingTime time.Time
+}
+
+type Interest struct {
+	Oi    float64 // 张
+	OiCcy float64 // 币
+	OiUsd float64
+}
+
+// Value 最新值
+type Value[T any] struct {
+	V        T
+	Ts       time.Time // 交易所时间
+	Received time.Time // 本地收到时间
+	Stale    bool      // 超过StaleAfter未更新 或订阅已断开、已释放
+}
+
+// Snapshot 某个产品的全部最新值 未收到过的数据为零值且Stale为true
+type Snapshot struct {
+	InstId  string
+	Mark    Value[float64]
+	BBO     Value[Quote]
+	Trade   Value[Trade]
+	Funding Value[FundingRate]
+	OI      Value[Interest]
+}
+
+// Update 变更通知 Stale为true表示订阅断开
+type Update struct {
+	InstId string
+	Kind   Kind
+	Stale  bool
+}
+
+type state struct {
+	mark    Value[float64]
+	bbo     Value[Quote]
+	trade   Value[Trade]
+	funding Value[FundingRate]
+	oi      Value[Interest]
+	set     [kinds]bool // 收到过数据
+	down    [kinds]bool // 订阅已断开或已释放
+}
+
+type subKey struct {
+	instId string
+	kind   Kind
+}
+
+type sub struct {
+	refs   int
+	cancel context.CancelFunc
+	done   chan struct{} // 订阅协程退出时关闭
+}
+
+// Store 行情快照 线程安全
+// 同一频道在WsClient上只能有一个回调 由Store订阅的频道不应再直接订阅
+type Store struct {
+	StaleAfter map[Kind]time.Duration // 默认DefaultStaleAfter的副本 修改不影响其他Store
+	Retry      time.Duration          // 订阅断开后重新订阅的间隔 默认1s
+
+	ctx      context.Context
+	public   *okx.PublicClient
+	business *okx.BusinessClient
+	now      func() time.Time
+	lock     sync.RWMutex
+	states   map[string]*state
+	subs     map[subKey]*sub
+	stopping map[subKey]chan struct{} // 已释放但协程尚未退出的订阅
+	hooks    map[int]func(u Update)
+	hookSeq  int
+}
+
+// NewStore business可为nil 不为nil时成交使用trades-all
+func NewStore(ctx context.Context, public *okx.PublicClient, business *okx.BusinessClient) *Store {
+	return &Store{
+		StaleAfter: maps.Clone(DefaultStaleAfter),
+		Retry:      time.Second,
+		ctx:        ctx,
+		public:     public,
+		business:   business,
+		now:        time.Now,
+		states:     map[string]*state{},
+		subs:       map[subKey]*sub{},
+		stopping:   map[subKey]chan struct{}{},
+		hooks:      map[int]func(u Update){},
+	}
+}
+
+// Acquire 订阅instId的各类数据 同一数据共用一个订阅
+// 返回的release释放本次引用 引用数为0时退订 可重复调用
+func (s *Store) Acquire(instId string, kinds ...Kind) (release func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, kind := range kinds {
+		key := subKey{instId: instId, kind: kind}
+		if sb, ok := s.subs[key]; ok {
+			sb.refs++
+			continue
+		}
+		ctx, cancel := context.WithCancel(s.ctx)
+		sb := &sub{refs: 1, cancel: cancel, done: make(chan struct{})}
+		s.subs[key] = sb
+		// 同一频道的回调以key注册 需等待之前的订阅退出
+		prev := s.stopping[key]
+		delete(s.stopping, key)
+		go s.run(ctx, key, prev, sb.done)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { s.release(instId, kinds) })
+	}
+}
+
+func (s *Store) release(instId string, kinds []Kind) {
+	s.lock.Lock()
+	var released []Kind
+	for _, kind := range kinds {
+		key := subKey{instId: instId, kind: kind}
+		sb, ok := s.subs[key]
+		if !ok {
+			continue
+		}
+		if sb.refs--; sb.refs > 0 {
+			continue
+		}
+		sb.cancel()
+		delete(s.subs, key)
+		s.stopping[key] = sb.done
+		if st, ok := s.states[instId]; ok && !st.down[kind] {
+			st.down[kind] = true
+			released = append(released, kind)
+		}
+	}
+	s.lock.Unlock()
+	for _, kind := range released {
+		s.notify(Update{InstId: instId, Kind: kind, Stale: true})
+	}
+}
+
+// Refs 当前的引用数
+func (s *Store) Refs(instId string, kind Kind) int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if sb, ok := s.subs[subKey{instId: instId, kind: kind}]; ok {
+		return sb.refs
+	}
+	return 0
+}
+
+// OnUpdate 注册变更通知 在推送的协程中同步调用 不可阻塞 返回的函数用于注销
+func (s *Store) OnUpdate(fn func(u Update)) (cancel func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.hookSeq++
+	id := s.hookSeq
+	s.hooks[id] = fn
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		delete(s.hooks, id)
+	}
+}
+
+func (s *Store) notify(u Update) {
+	s.lock.RLock()
+	hooks := make([]func(u Update), 0, len(s.hooks))
+	for _, fn := range s.hooks {
+		hooks = append(hooks, fn)
+	}
+	s.lock.RUnlock()
+	for _, fn := range hooks {
+		fn(u)
+	}
+}
+
+// run 订阅直到释放 断开后按Retry重新订阅
+func (s *Store) run(ctx context.Context, key subKey, prev <-chan struct{}, done chan struct{}) {
+	defer func() {
+		close(done)
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.stopping[key] == done {
+			delete(s.stopping, key)
+		}
+	}()
+	if prev != nil {
+		<-prev
+	}
+	for ctx.Err() == nil {
+		_ = s.subscribe(ctx, key)
+		if ctx.Err() != nil {
+			return
+		}
+		s.down(key)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.Retry):
+		}
+	}
+}
+
+func (s *Store) down(key subKey) {
+	s.lock.Lock()
+	st, ok := s.states[key.instId]
+	changed := ok && st.set[key.kind] && !st.down[key.kind]
+	if ok {
+		st.down[key.kind] = true
+	}
+	s.lock.Unlock()
+	if changed {
+		s.notify(Update{InstId: key.instId, Kind: key.kind, Stale: true})
+	}
+}
+
+func (s *Store) subscribe(ctx context.Context, key subKey) error {
+	instId := key.instId
+	switch key.kind {
+	case Mark:
+		return s.public.MarkPrice(ctx, instId, func(resp *common.WsResp[*common.MarkPrice]) {
+			for _, d := range resp.Data {
+				px, err := strconv.ParseFloat(d.MarkPx, 64)
+				if err != nil {
+					continue
+				}
+				s.set(instId, Mark, d.Ts, func(st *state, ts, received time.Time) {
+					st.mark = Value[float64]{V: px, Ts: ts, Received: received}
+				})
+			}
+		})
+	case BBO:
+		return s.public.BooksDecode(ctx, "bbo-tbt", instId, func(resp *common.WsResp[common.OrderBook]) {
+			for i := range resp.Data {
+				q, ok := toQuote(&resp.Data[i])
+				if !ok {
+					continue
+				}
+				s.set(instId, BBO, resp.Data[i].Ts, func(st *state, ts, received time.Time) {
+					st.bbo = Value[Quote]{V: q, Ts: ts, Received: received}
+				})
+			}
+		})
+	case LastTrade:
+		if s.business != nil {
+			return s.business.AllTrades(ctx, instId, func(resp *common.WsResp[common.Trade]) {
+				for i := range resp.Data {
+					s.setTrade(instId, &resp.Data[i])
+				}
+			})
+		}
+		return s.public.MarketTrades(ctx, instId, func(resp *common.WsResp[*common.Trade]) {
+			for _, t := range resp.Data {
+				s.setTrade(instId, t)
+			}
+		})
+	case Funding:
+		return s.public.FundingRate(ctx, instId, func(resp *common.WsResp[*common.FundingRate]) {
+			for _, d := range resp.Data {
+				rate, err := strconv.ParseFloat(d.FundingRate, 64)
+				if err != nil {
+					continue
+				}
+				f := FundingRate{Rate: rate, NextRate: parseNum(d.NextFundingRate), FundingTime: parseTs(d.FundingTime), NextFundingTime: parseTs(d.NextFundingTime)}
+				s.set(instId, Funding, d.Ts, func(st *state, ts, received time.Time) {
+					st.funding = Value[FundingRate]{V: f, Ts: ts, Received: received}
+				})
+			}
+		})
+	case OpenInterest:
+		return s.public.OpenInterest(ctx, instId, func(resp *common.WsResp[*common.OpenInterest]) {
+			for _, d := range resp.Data {
+				oi, err := strconv.ParseFloat(d.Oi, 64)
+				if err != nil {
+					continue
+				}
+				i := Interest{Oi: oi, OiCcy: parseNum(d.OiCcy), OiUsd: parseNum(d.OiUsd)}
+				s.set(instId, OpenInterest, d.Ts, func(st *state, ts, received time.Time) {
+					st.oi = Value[Interest]{V: i, Ts: ts, Received: received}
+				})
+			}
+		})
+	}
+	return fmt.Errorf("market: unknown kind %s", key.kind)
+}
+
+func (s *Store) setTrade(instId string, t *common.Trade) {
+	px, err1 := strconv.ParseFloat(t.Px, 64)
+	sz, err2 := strconv.ParseFloat(t.Sz, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+	// 快速解码的字符串引用帧数据 保存前拷贝
+	trade := Trade{TradeId: strings.Clone(t.TradeId), Px: px, Sz: sz, Side: t.Side}
+	s.set(instId, LastTrade, t.Ts, func(st *state, ts, received time.Time) {
+		st.trade = Value[Trade]{V: trade, Ts: ts, Received: received}
+	})
+}
+
+// set 在锁内写入 然后通知
+func (s *Store) set(instId string, kind Kind, ts string, write func(st *state, ts, received time.Time)) {
+	s.lock.Lock()
+	st, ok := s.states[instId]
+	if !ok {
+		st = &state{}
+		s.states[instId] = st
+	}
+	write(st, parseTs(ts), s.now())
+	st.set[kind], st.down[kind] = true, false
+	s.lock.Unlock()
+	s.notify(Update{InstId: instId, Kind: kind})
+}
+
+func (s *Store) stale(st *state, kind Kind, received time.Time) bool {
+	if st == nil || !st.set[kind] || st.down[kind] {
+		return true
+	}
+	after := s.StaleAfter[kind]
+	return after > 0 && s.now().Sub(received) > after
+}
+
+// Mark 标记价格 未收到过时ok为false
+func (s *Store) Mark(instId string) (v Value[float64], ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	st := s.states[instId]
+	if st == nil || !st.set[Mark] {
+		return v, false
+	}
+	v = st.mark
+	v.Stale = s.stale(st, Mark, v.Received)
+	return v, true
+}
+
+// BBO 最优买卖价
+func (s *Store) BBO(instId string) (v Value[Quote], ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	st := s.states[instId]
+	if st == nil || !st.set[BBO] {
+		return v, false
+	}
+	v = st.bbo
+	v.Stale = s.stale(st, BBO, v.Received)
+	return v, true
+}
+
+// LastTrade 最新成交
+func (s *Store) LastTrade(instId string) (v Value[Trade], ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	st := s.states[instId]
+	if st == nil || !st.set[LastTrade] {
+		return v, false
+	}
+	v = st.trade
+	v.Stale = s.stale(st, LastTrade, v.Received)
+	return v, true
+}
+
+// Funding 资金费率
+func (s *Store) Funding(instId string) (v Value[FundingRate], ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	st := s.states[instId]
+	if st == nil || !st.set[Funding] {
+		return v, false
+	}
+	v = st.funding
+	v.Stale = s.stale(st, Funding, v.Received)
+	return v, true
+}
+
+// OpenInterest 持仓总量
+func (s *Store) OpenInterest(instId string) (v Value[Interest], ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	st := s.states[instId]
+	if st == nil || !st.set[OpenInterest] {
+		return v, false
+	}
+	v = st.oi
+	v.Stale = s.stale(st, OpenInterest, v.Received)
+	return v, true
+}
+
+// Snapshot 某个产品的全部最新值
+func (s *Store) Snapshot(instId string) Snapshot {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	snap := Snapshot{InstId: instId}
+	st := s.states[instId]
+	if st != nil {
+		snap.Mark, snap.BBO, snap.Trade, snap.Funding, snap.OI = st.mark, st.bbo, st.trade, st.funding, st.oi
+	}
+	snap.Mark.Stale = s.stale(st, Mark, snap.Mark.Received)
+	snap.BBO.Stale = s.stale(st, BBO, snap.BBO.Received)
+	snap.Trade.Stale = s.stale(st, LastTrade, snap.Trade.Received)
+	snap.Funding.Stale = s.stale(st, Funding, snap.Funding.Received)
+	snap.OI.Stale = s.stale(st, OpenInterest, snap.OI.Received)
+	return snap
+}
+
+func toQuote(book *common.OrderBook) (Quote, bool) {
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return Quote{}, false
+	}
+	bid, ask := book.Bids[0], book.Asks[0]
+	q := Quote{BidPx: parseNum(bid.Price), BidSz: parseNum(bid.Count), AskPx: parseNum(ask.Price), AskSz: parseNum(ask.Count)}
+	return q, q.BidPx > 0 && q.AskPx > 0
+}
+
+// parseNum 空值与格式错误视为0
+func parseNum(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func parseTs(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,204 @@
+package market
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+type recorder struct {
+	lock sync.Mutex
+	out  []string
+}
+
+func (r *recorder) Record(frame ws.Frame) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if frame.Dir == ws.Outbound {
+		r.out = append(r.out, string(frame.Data))
+	}
+}
+
+// sent 是否发送过包含全部片段的帧
+func (r *recorder) sent(parts ...string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, f := range r.out {
+		ok := true
+		for _, p := range parts {
+			ok = ok && strings.Contains(f, p)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func newStore(t *testing.T) (*Store, *ws.Conn, *recorder, *atomic.Int64) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	pub := &okx.PublicClient{WsClient: common.NewBaseWsClient(ctx, common.Public, "", nil, nil)}
+	rec := &recorder{}
+	conn := ws.NewReplayConn(ctx, ws.WithRecorder(rec))
+	pub.Attach(conn)
+
+	s := NewStore(ctx, pub, nil)
+	s.Retry = 10 * time.Millisecond
+	offset := &atomic.Int64{}
+	s.now = func() time.Time { return time.Now().Add(time.Duration(offset.Load())) }
+	return s, conn, rec, offset
+}
+
+func TestStoreValues(t *testing.T) {
+	s, conn, rec, offset := newStore(t)
+	var lock sync.Mutex
+	var updates []Update
+	s.OnUpdate(func(u Update) {
+		lock.Lock()
+		defer lock.Unlock()
+		updates = append(updates, u)
+	})
+
+	inst := "BTC-USDT-SWAP"
+	release := s.Acquire(inst, Mark, BBO, LastTrade, Funding, OpenInterest)
+	defer release()
+	for _, ch := range []string{"mark-price", "bbo-tbt", `"trades"`, "funding-rate", "open-interest"} {
+		assert.Eventually(t, func() bool { return rec.sent("subscribe", ch) }, time.Second, time.Millisecond, ch)
+	}
+	_, ok := s.Mark(inst)
+	assert.False(t, ok)
+
+	for _, frame := range []string{
+		`{"arg":{"channel":"mark-price","instId":"BTC-USDT-SWAP"},"data":[{"instId":"BTC-USDT-SWAP","markPx":"42000.5","ts":"1704153600000"}]}`,
+		`{"arg":{"channel":"bbo-tbt","instId":"BTC-USDT-SWAP"},"data":[{"asks":[["42001","3","0","2"]],"bids":[["42000","5","0","1"]],"ts":"1704153600001","seqId":1}]}`,
+		`{"arg":{"channel":"trades","instId":"BTC-USDT-SWAP"},"data":[{"instId":"BTC-USDT-SWAP","tradeId":"7","px":"42000.8","sz":"2","side":"sell","ts":"1704153600002"}]}`,
+		`{"arg":{"channel":"funding-rate","instId":"BTC-USDT-SWAP"},"data":[{"instId":"BTC-USDT-SWAP","fundingRate":"0.0001","fundingTime":"1704182400000","nextFundingRate":"","nextFundingTime":"1704211200000","ts":"1704153600003"}]}`,
+		`{"arg":{"channel":"open-interest","instId":"BTC-USDT-SWAP"},"data":[{"instId":"BTC-USDT-SWAP","oi":"1000","oiCcy":"10","oiUsd":"420000","ts":"1704153600004"}]}`,
+	} {
+		conn.Inject(ws.TextMessage, []byte(frame))
+	}
+	assert.Eventually(t, func() bool {
+		_, ok := s.OpenInterest(inst)
+		return ok
+	}, time.Second, time.Millisecond)
+
+	mark, ok := s.Mark(inst)
+	assert.True(t, ok)
+	assert.False(t, mark.Stale)
+	assert.Equal(t, 42000.5, mark.V)
+	assert.Equal(t, time.UnixMilli(1704153600000), mark.Ts)
+
+	bbo, _ := s.BBO(inst)
+	assert.Equal(t, Quote{BidPx: 42000, BidSz: 5, AskPx: 42001, AskSz: 3}, bbo.V)
+	assert.Equal(t, 42000.5, bbo.V.Mid())
+	trade, _ := s.LastTrade(inst)
+	assert.Equal(t, Trade{TradeId: "7", Px: 42000.8, Sz: 2, Side: "sell"}, trade.V)
+	funding, _ := s.Funding(inst)
+	assert.Equal(t, 0.0001, funding.V.Rate)
+	assert.Zero(t, funding.V.NextRate)
+	assert.Equal(t, time.UnixMilli(1704211200000), funding.V.NextFundingTime)
+	oi, _ := s.OpenInterest(inst)
+	assert.Equal(t, Interest{Oi: 1000, OiCcy: 10, OiUsd: 420000}, oi.V)
+
+	lock.Lock()
+	assert.Len(t, updates, 5)
+	lock.Unlock()
+
+	// 超过各自的StaleAfter后过期 成交不按时间判断
+	offset.Store(int64(30 * time.Second))
+	snap := s.Snapshot(inst)
+	assert.True(t, snap.Mark.Stale)
+	assert.True(t, snap.BBO.Stale)
+	assert.False(t, snap.Trade.Stale)
+	assert.False(t, snap.Funding.Stale)
+	assert.False(t, snap.OI.Stale)
+	assert.Equal(t, 42000.5, snap.Mark.V)
+
+	empty := s.Snapshot("ETH-USDT-SWAP")
+	assert.True(t, empty.Mark.Stale)
+	assert.Zero(t, empty.Mark.V)
+}
+
+func TestStoreStaleAfterCopy(t *testing.T) {
+	s, _, _, _ := newStore(t)
+	want := DefaultStaleAfter[Mark]
+	s.StaleAfter[Mark] = time.Hour
+	assert.Equal(t, want, DefaultStaleAfter[Mark])
+	other, _, _, _ := newStore(t)
+	assert.Equal(t, want, other.StaleAfter[Mark])
+}
+
+func TestStoreRefCount(t *testing.T) {
+	s, conn, rec, _ := newStore(t)
+	inst := "BTC-USDT"
+	first := s.Acquire(inst, Mark)
+	second := s.Acquire(inst, Mark)
+	assert.Equal(t, 2, s.Refs(inst, Mark))
+	assert.Eventually(t, func() bool { return rec.sent("subscribe", "mark-price") }, time.Second, time.Millisecond)
+
+	conn.Inject(ws.TextMessage, []byte(`{"arg":{"channel":"mark-price","instId":"BTC-USDT"},"data":[{"markPx":"1","ts":"1"}]}`))
+	assert.Eventually(t, func() bool {
+		_, ok := s.Mark(inst)
+		return ok
+	}, time.Second, time.Millisecond)
+
+	first()
+	first()
+	assert.Equal(t, 1, s.Refs(inst, Mark))
+	mark, _ := s.Mark(inst)
+	assert.False(t, mark.Stale)
+
+	second()
+	assert.Zero(t, s.Refs(inst, Mark))
+	assert.Eventually(t, func() bool { return rec.sent("unsubscribe", "mark-price") }, time.Second, time.Millisecond)
+	mark, _ = s.Mark(inst)
+	assert.True(t, mark.Stale)
+
+	// 重新订阅后恢复
+	release := s.Acquire(inst, Mark)
+	defer release()
+	assert.Eventually(t, func() bool {
+		conn.Inject(ws.TextMessage, []byte(`{"arg":{"channel":"mark-price","instId":"BTC-USDT"},"data":[{"markPx":"2","ts":"2"}]}`))
+		mark, _ := s.Mark(inst)
+		return mark.V == 2 && !mark.Stale
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestStoreDown(t *testing.T) {
+	s, conn, rec, _ := newStore(t)
+	down := make(chan Update, 1)
+	s.OnUpdate(func(u Update) {
+		if u.Stale {
+			down <- u
+		}
+	})
+	release := s.Acquire("BTC-USDT", Mark)
+	defer release()
+	assert.Eventually(t, func() bool { return rec.sent("subscribe", "mark-price") }, time.Second, time.Millisecond)
+	conn.Inject(ws.TextMessage, []byte(`{"arg":{"channel":"mark-price","instId":"BTC-USDT"},"data":[{"markPx":"1","ts":"1"}]}`))
+	assert.Eventually(t, func() bool {
+		_, ok := s.Mark("BTC-USDT")
+		return ok
+	}, time.Second, time.Millisecond)
+
+	// 连接断开后标记过期
+	conn.Close(nil)
+	select {
+	case u := <-down:
+		assert.Equal(t, Update{InstId: "BTC-USDT", Kind: Mark, Stale: true}, u)
+	case <-time.After(time.Second):
+		t.Fatal("no stale update")
+	}
+	mark, _ := s.Mark("BTC-USDT")
+	assert.True(t, mark.Stale)
+}
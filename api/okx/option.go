@@ -0,0 +1,280 @@
+package okx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+var (
+	ErrUnknownEnv   = errors.New("okx: unknown env")
+	ErrInvalidProxy = errors.New("okx: invalid proxy")
+	ErrMissingURL   = errors.New("okx: missing base url")
+	ErrClientClosed = errors.New("okx: client closed")
+)
+
+// RateLimiter 请求前等待配额 *rate.Limiter满足该接口
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// options 客户端构造参数 由Option修改
+type options struct {
+	env        common.Destination
+	signer     common.IKeyConfig
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	proxy      string
+	restURL    common.BaseURL
+	wsURLs     map[common.SvcType]common.BaseURL
+	logger     *slog.Logger
+	limiter    RateLimiter
+	clock      func() time.Time
+	userAgent  string
+	brokerCode string
+	demo       bool
+	dialOpts   []ws.Option
+	retry      RetryPolicy
+	metrics    common.Metrics
+	tracer     trace.Tracer
+}
+
+type Option func(o *options)
+
+// WithEnv 服务器环境 默认NormalServer
+func WithEnv(env common.Destination) Option {
+	return func(o *options) {
+		o.env = env
+	}
+}
+
+// WithKeyConfig 使用密钥签名
+func WithKeyConfig(keyConfig KeyConfig) Option {
+	return func(o *options) {
+		o.signer = keyConfig
+	}
+}
+
+// WithSigner 自定义签名 如托管在外部的密钥 WithClock对其不生效
+func WithSigner(signer common.IKeyConfig) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
+// WithHTTPClient 使用已有的http.Client 此时忽略WithTransport/WithTimeout/WithProxy
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithTransport 自定义REST请求的Transport 此时忽略WithProxy
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout REST请求超时 默认30秒
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithProxy 代理地址 支持http/https/socks5/socks5h 为空时使用环境变量
+func WithProxy(proxy string) Option {
+	return func(o *options) {
+		o.proxy = proxy
+	}
+}
+
+// WithRestURL 覆盖REST地址
+func WithRestURL(baseUrl common.BaseURL) Option {
+	return func(o *options) {
+		o.restURL = baseUrl
+	}
+}
+
+// WithWsURL 覆盖某一类WS连接的地址
+func WithWsURL(typ common.SvcType, baseUrl common.BaseURL) Option {
+	return func(o *options) {
+		if o.wsURLs == nil {
+			o.wsURLs = map[common.SvcType]common.BaseURL{}
+		}
+		o.wsURLs[typ] = baseUrl
+	}
+}
+
+// WithLogger 兼容ILogger 等价于WithSlog(common.NewSlogLogger(logger)) 为nil时使用slog.Default()
+func WithLogger(logger common.ILogger) Option {
+	return func(o *options) {
+		o.logger = nil
+		if logger != nil {
+			o.logger = common.NewSlogLogger(logger)
+		}
+	}
+}
+
+// WithSlog 结构化日志 为nil时使用slog.Default() 密钥与签名字段会被脱敏
+func WithSlog(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = nil
+		if logger != nil {
+			o.logger = slog.New(common.NewRedactHandler(logger.Handler()))
+		}
+	}
+}
+
+// WithRateLimiter REST请求限速
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithClock 签名使用的时钟 用于校正本地时间偏差
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithBrokerCode 下单未指定tag时填入的经纪商代码
+func WithBrokerCode(code string) Option {
+	return func(o *options) {
+		o.brokerCode = code
+	}
+}
+
+// WithDemoTrading 模拟盘 等价于WithEnv(common.TestServer)
+func WithDemoTrading(demo bool) Option {
+	return func(o *options) {
+		o.demo = demo
+	}
+}
+
+// WithDialOptions WS拨号参数
+func WithDialOptions(opts ...ws.Option) Option {
+	return func(o *options) {
+		o.dialOpts = append(o.dialOpts, opts...)
+	}
+}
+
+// WithRetryPolicy REST请求重试策略 默认不重试 可通过ContextWithRetryPolicy按调用覆盖
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithMetrics 记录REST与WS的运行指标
+func WithMetrics(metrics common.Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// WithTracerProvider 为REST请求、WS请求与登录创建span
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = common.NewTracer(provider)
+	}
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{
+		env:     common.NormalServer,
+		signer:  KeyConfig{},
+		timeout: 30 * time.Second,
+		tracer:  common.NewTracer(nil),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.demo {
+		o.env = common.TestServer
+	}
+	if _, ok := common.DefaultRestUrl[o.env]; !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownEnv, o.env)
+	}
+	if key, ok := o.signer.(KeyConfig); ok && o.clock != nil {
+		o.signer = clockKeyConfig{KeyConfig: key, now: o.clock}
+	}
+	return o, nil
+}
+
+func (o *options) isDemo() bool {
+	return o.env == common.TestServer
+}
+
+// proxyFunc 解析代理地址
+func (o *options) proxyFunc() (func(req *http.Request) (*url.URL, error), error) {
+	if o.proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(o.proxy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidProxy, u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: missing host", ErrInvalidProxy)
+	}
+	return http.ProxyURL(u), nil
+}
+
+func (o *options) newHTTPClient() (*http.Client, error) {
+	if o.httpClient != nil {
+		return o.httpClient, nil
+	}
+	transport := o.transport
+	if transport == nil {
+		proxy, err := o.proxyFunc()
+		if err != nil {
+			return nil, err
+		}
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = proxy
+		transport = t
+	}
+	return &http.Client{Transport: transport, Timeout: o.timeout}, nil
+}
+
+func (o *options) restBaseURL() common.BaseURL {
+	if o.restURL != "" {
+		return o.restURL
+	}
+	return common.DefaultRestUrl[o.env]
+}
+
+func (o *options) wsBaseURL(typ common.SvcType) (common.BaseURL, error) {
+	if u, ok := o.wsURLs[typ]; ok && u != "" {
+		return u, nil
+	}
+	if u, ok := common.DefaultWsUrls[o.env][typ]; ok {
+		return u, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrMissingURL, typ)
+}
@@ -0,0 +1,121 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+type countLimiter struct {
+	n atomic.Int32
+}
+
+func (l *countLimiter) Wait(ctx context.Context) error {
+	l.n.Add(1)
+	return ctx.Err()
+}
+
+func TestNewClientOptionsErrors(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewRestClientWithOptions(ctx, WithEnv(common.Destination(99)))
+	assert.ErrorIs(t, err, ErrUnknownEnv)
+	_, err = NewWsClientWithOptions(ctx, WithEnv(common.Destination(99)))
+	assert.ErrorIs(t, err, ErrUnknownEnv)
+
+	_, err = NewRestClientWithOptions(ctx, WithProxy("ftp://127.0.0.1:21"))
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+	_, err = NewWsClientWithOptions(ctx, WithProxy("://bad"))
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+	_, err = NewAdapter(ctx, WithProxy("socks5://"))
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+
+	_, err = NewRestClientWithOptions(ctx, WithProxy("socks5://127.0.0.1:1080"))
+	assert.NoError(t, err)
+	_, err = NewWsClientWithOptions(ctx, WithProxy("http://127.0.0.1:8080"), WithWsURL(common.Public, "ws://127.0.0.1:1"))
+	assert.NoError(t, err)
+}
+
+func TestRestClientOptions(t *testing.T) {
+	var got http.Header
+	var tag string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		if r.Method == http.MethodPost {
+			var req common.PlaceOrderReq
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			tag = req.Tag
+		}
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+	}))
+	defer srv.Close()
+
+	limiter := &countLimiter{}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c, err := NewRestClientWithOptions(context.Background(),
+		WithKeyConfig(config),
+		WithRestURL(common.BaseURL(srv.URL)),
+		WithDemoTrading(true),
+		WithClock(func() time.Time { return now }),
+		WithUserAgent("aqt-test/1.0"),
+		WithBrokerCode("broker1"),
+		WithRateLimiter(limiter),
+	)
+	assert.NoError(t, err)
+
+	_, err = c.Instruments(context.Background(), common.InstrumentsReq{InstType: "SPOT"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", got.Get("x-simulated-trading"))
+	assert.Equal(t, "aqt-test/1.0", got.Get("User-Agent"))
+	assert.Equal(t, "2024-01-02T03:04:05Z", got.Get("OK-ACCESS-TIMESTAMP"))
+
+	_, err = c.PlaceOrder(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT"})
+	assert.NoError(t, err)
+	assert.Equal(t, "broker1", tag)
+	_, err = c.PlaceOrder(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT", Tag: "mine"})
+	assert.NoError(t, err)
+	assert.Equal(t, "mine", tag)
+	assert.Equal(t, int32(3), limiter.n.Load())
+}
+
+func TestAdapterClose(t *testing.T) {
+	a, err := NewAdapter(context.Background(), WithRestURL("http://127.0.0.1:1"))
+	assert.NoError(t, err)
+	a.Close()
+	_, err = a.Rest.Instruments(context.Background(), common.InstrumentsReq{InstType: "SPOT"})
+	assert.ErrorIs(t, err, ErrClientClosed)
+	assert.ErrorIs(t, a.Ws.PublicClient.CheckConn(), ws.ErrClosed)
+}
+
+func TestExchangeClientCloseRest(t *testing.T) {
+	w, err := NewWsClientWithOptions(context.Background(), WithRestURL("http://127.0.0.1:1"))
+	assert.NoError(t, err)
+	assert.False(t, w.Rest.Closed())
+	w.Close()
+	assert.True(t, w.Rest.Closed())
+	assert.ErrorIs(t, w.PrivateClient.CheckConn(), ws.ErrClosed)
+}
+
+func TestNilLoggerOptions(t *testing.T) {
+	ctx := context.Background()
+	assert.NotPanics(t, func() {
+		a, err := NewAdapter(ctx, WithSlog(nil), WithRestURL("http://127.0.0.1:1"))
+		assert.NoError(t, err)
+		assert.Nil(t, a.Rest.Logger)
+		a.SetLogger(nil)
+		a.Ws.SetLogger(nil)
+		a.Close()
+	})
+	assert.NotPanics(t, func() {
+		_, err := NewWsClientWithOptions(ctx, WithLogger(nil))
+		assert.NoError(t, err)
+	})
+}
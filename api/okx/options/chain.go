@@ -0,0 +1,174 @@
+package options
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// Option 期权合约
+type Option struct {
+	InstId string
+	Family string
+	Type   string // C或P
+	Strike float64
+	Expiry time.Time
+	CtVal  float64
+	CtMult float64
+}
+
+// ParseInstrument 解析Instruments中的期权 非期权返回false
+func ParseInstrument(inst *common.Instruments) (Option, bool) {
+	if inst.OptType == "" {
+		return Option{}, false
+	}
+	o, ok := ParseInstId(inst.InstId)
+	if !ok {
+		return Option{}, false
+	}
+	if ms, err := strconv.ParseInt(inst.ExpTime, 10, 64); err == nil && ms > 0 {
+		o.Expiry = time.UnixMilli(ms)
+	}
+	if inst.InstFamily != "" {
+		o.Family = inst.InstFamily
+	}
+	o.Type = inst.OptType
+	if stk, err := strconv.ParseFloat(inst.Stk, 64); err == nil {
+		o.Strike = stk
+	}
+	o.CtVal, _ = strconv.ParseFloat(inst.CtVal, 64)
+	o.CtMult, _ = strconv.ParseFloat(inst.CtMult, 64)
+	return o, true
+}
+
+// ParseInstId 由期权instId解析 如BTC-USD-240329-40000-C 到期时间按交割惯例为UTC 8点
+func ParseInstId(instId string) (Option, bool) {
+	parts := strings.Split(instId, "-")
+	if len(parts) != 5 || (parts[4] != Call && parts[4] != Put) {
+		return Option{}, false
+	}
+	day, err := time.Parse("060102", parts[2])
+	if err != nil {
+		return Option{}, false
+	}
+	stk, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil {
+		return Option{}, false
+	}
+	return Option{
+		InstId: instId,
+		Family: parts[0] + "-" + parts[1],
+		Type:   parts[4],
+		Strike: stk,
+		Expiry: day.Add(8 * time.Hour).Local(),
+	}, true
+}
+
+// Chain 一个instFamily下的期权 按到期时间、行权价、类型排序
+type Chain struct {
+	Family  string
+	Options []Option
+	index   map[string]int
+}
+
+func NewChain(family string, options []Option) *Chain {
+	c := &Chain{Family: family, Options: options, index: make(map[string]int, len(options))}
+	sort.Slice(c.Options, func(i, j int) bool {
+		a, b := c.Options[i], c.Options[j]
+		if !a.Expiry.Equal(b.Expiry) {
+			return a.Expiry.Before(b.Expiry)
+		}
+		if a.Strike != b.Strike {
+			return a.Strike < b.Strike
+		}
+		return a.Type < b.Type
+	})
+	for i, o := range c.Options {
+		c.index[o.InstId] = i
+	}
+	return c
+}
+
+// InstrumentsSource *okx.RestClient满足该接口
+type InstrumentsSource interface {
+	Instruments(ctx context.Context, req common.InstrumentsReq) (*common.Resp[common.Instruments], error)
+}
+
+// LoadChain 通过Instruments查询instFamily下交易中的期权
+func LoadChain(ctx context.Context, src InstrumentsSource, family string) (*Chain, error) {
+	rp, err := src.Instruments(ctx, common.InstrumentsReq{InstType: "OPTION", InstFamily: family})
+	if err != nil {
+		return nil, err
+	}
+	var options []Option
+	for i := range rp.Data {
+		if s := rp.Data[i].State; s != "" && s != "live" {
+			continue
+		}
+		if o, ok := ParseInstrument(&rp.Data[i]); ok {
+			options = append(options, o)
+		}
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("options: no options for %s", family)
+	}
+	return NewChain(family, options), nil
+}
+
+func (c *Chain) Get(instId string) (Option, bool) {
+	i, ok := c.index[instId]
+	if !ok {
+		return Option{}, false
+	}
+	return c.Options[i], true
+}
+
+// Expiries 到期时间 升序
+func (c *Chain) Expiries() []time.Time {
+	var out []time.Time
+	for _, o := range c.Options {
+		if n := len(out); n == 0 || !out[n-1].Equal(o.Expiry) {
+			out = append(out, o.Expiry)
+		}
+	}
+	return out
+}
+
+// Strikes 某到期日的行权价 升序
+func (c *Chain) Strikes(expiry time.Time) []float64 {
+	var out []float64
+	for _, o := range c.Options {
+		if !o.Expiry.Equal(expiry) {
+			continue
+		}
+		if n := len(out); n == 0 || out[n-1] != o.Strike {
+			out = append(out, o.Strike)
+		}
+	}
+	return out
+}
+
+// Find 按到期时间、行权价与类型查找
+func (c *Chain) Find(expiry time.Time, strike float64, optType string) (Option, bool) {
+	i := sort.Search(len(c.Options), func(i int) bool {
+		o := c.Options[i]
+		if !o.Expiry.Equal(expiry) {
+			return o.Expiry.After(expiry)
+		}
+		if o.Strike != strike {
+			return o.Strike > strike
+		}
+		return o.Type >= optType
+	})
+	if i < len(c.Options) {
+		if o := c.Options[i]; o.Expiry.Equal(expiry) && o.Strike == strike && o.Type == optType {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
@@ -0,0 +1,253 @@
+package options
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+	"github.com/kurosann/aqt-sdk/ws"
+)
+
+func TestBlack76(t *testing.T) {
+	assert.InDelta(t, 7.965567, Price(Call, 100, 100, 1, 0.2), 1e-6)
+	assert.InDelta(t, 7.965567, Price(Put, 100, 100, 1, 0.2), 1e-6)
+	// 看涨-看跌平价
+	for _, k := range []float64{60, 90, 100, 130} {
+		assert.InDelta(t, 100-k, Price(Call, 100, k, 0.5, 0.6)-Price(Put, 100, k, 0.5, 0.6), 1e-9)
+	}
+	assert.Equal(t, 20.0, Price(Call, 120, 100, 0, 0.5))
+	assert.Equal(t, 0.0, Price(Put, 120, 100, 1, 0))
+
+	// 希腊字母与有限差分一致
+	f, k, tt, vol, h := 42000.0, 45000.0, 30.0/365, 0.55, 1e-3
+	for _, typ := range []string{Call, Put} {
+		g := Greek(typ, f, k, tt, vol)
+		delta := (Price(typ, f+h, k, tt, vol) - Price(typ, f-h, k, tt, vol)) / (2 * h)
+		assert.InDelta(t, delta, g.Delta, 1e-6, typ)
+		gamma := (Price(typ, f+1, k, tt, vol) - 2*Price(typ, f, k, tt, vol) + Price(typ, f-1, k, tt, vol))
+		assert.InDelta(t, gamma, g.Gamma, 1e-8, typ)
+		vega := (Price(typ, f, k, tt, vol+h) - Price(typ, f, k, tt, vol-h)) / (2 * h) / 100
+		assert.InDelta(t, vega, g.Vega, 1e-4, typ)
+		day := 1.0 / 365
+		theta := Price(typ, f, k, tt-day, vol) - Price(typ, f, k, tt, vol)
+		assert.InDelta(t, theta, g.Theta, math.Abs(g.Theta)*0.02, typ)
+	}
+	assert.Equal(t, Greeks{Delta: -1}, Greek(Put, 90, 100, 0, 0.5))
+	assert.Equal(t, Greeks{}, Greek(Call, 90, 100, 0, 0.5))
+}
+
+func TestImpliedVol(t *testing.T) {
+	for _, c := range []struct {
+		typ        string
+		f, k, t, v float64
+	}{
+		{Call, 100, 100, 1, 0.2},
+		{Put, 42000, 30000, 7.0 / 365, 0.9},
+		{Call, 42000, 60000, 90.0 / 365, 0.65},
+		{Call, 42000, 20000, 180.0 / 365, 1.2},
+		{Put, 2500, 2600, 1.0 / 365, 0.5},
+	} {
+		px := Price(c.typ, c.f, c.k, c.t, c.v)
+		vol, err := ImpliedVol(c.typ, px, c.f, c.k, c.t)
+		assert.NoError(t, err)
+		assert.InDelta(t, c.v, vol, 1e-6, c)
+	}
+	for _, px := range []float64{0, 10, 110, 120} {
+		_, err := ImpliedVol(Call, px, 110, 100, 1)
+		assert.ErrorIs(t, err, ErrNoVol, px)
+	}
+	_, err := ImpliedVol(Call, 5, 100, 100, 0)
+	assert.ErrorIs(t, err, ErrNoVol)
+}
+
+type instruments []common.Instruments
+
+func (i instruments) Instruments(ctx context.Context, req common.InstrumentsReq) (*common.Resp[common.Instruments], error) {
+	if req.InstType != "OPTION" || req.InstFamily != "BTC-USD" {
+		return &common.Resp[common.Instruments]{}, nil
+	}
+	return &common.Resp[common.Instruments]{Data: i}, nil
+}
+
+func inst(instId, optType, stk, exp string) common.Instruments {
+	return common.Instruments{InstId: instId, InstFamily: "BTC-USD", OptType: optType, Stk: stk, ExpTime: exp, CtVal: "1", CtMult: "0.01", State: "live"}
+}
+
+const (
+	jan = "1704441600000" // 2024-01-05 08:00 UTC
+	mar = "1711699200000" // 2024-03-29 08:00 UTC
+)
+
+func TestChain(t *testing.T) {
+	src := instruments{
+		inst("BTC-USD-240329-40000-P", "P", "40000", mar),
+		inst("BTC-USD-240105-44000-C", "C", "44000", jan),
+		inst("BTC-USD-240105-40000-P", "P", "40000", jan),
+		inst("BTC-USD-240105-40000-C", "C", "40000", jan),
+		inst("BTC-USD-240329-40000-C", "C", "40000", mar),
+		{InstId: "BTC-USD-240329", InstFamily: "BTC-USD", ExpTime: mar},
+	}
+	src[0].State = "suspend"
+	c, err := LoadChain(context.Background(), src, "BTC-USD")
+	assert.NoError(t, err)
+	assert.Len(t, c.Options, 4)
+	assert.Equal(t, []time.Time{time.UnixMilli(1704441600000), time.UnixMilli(1711699200000)}, c.Expiries())
+	assert.Equal(t, []float64{40000, 44000}, c.Strikes(time.UnixMilli(1704441600000)))
+	assert.Equal(t, []string{"BTC-USD-240105-40000-C", "BTC-USD-240105-40000-P", "BTC-USD-240105-44000-C", "BTC-USD-240329-40000-C"},
+		[]string{c.Options[0].InstId, c.Options[1].InstId, c.Options[2].InstId, c.Options[3].InstId})
+
+	o, ok := c.Find(time.UnixMilli(1704441600000), 40000, Put)
+	assert.True(t, ok)
+	assert.Equal(t, Option{InstId: "BTC-USD-240105-40000-P", Family: "BTC-USD", Type: Put, Strike: 40000,
+		Expiry: time.UnixMilli(1704441600000), CtVal: 1, CtMult: 0.01}, o)
+	_, ok = c.Find(time.UnixMilli(1704441600000), 44000, Put)
+	assert.False(t, ok)
+	_, ok = c.Get("BTC-USD-240329-40000-P")
+	assert.False(t, ok)
+
+	_, err = LoadChain(context.Background(), src, "ETH-USD")
+	assert.Error(t, err)
+
+	// instId中的到期日按UTC 8点
+	p, ok := ParseInstId("ETH-USD-240329-2500.5-C")
+	assert.True(t, ok)
+	assert.Equal(t, Option{InstId: "ETH-USD-240329-2500.5-C", Family: "ETH-USD", Type: Call, Strike: 2500.5, Expiry: time.UnixMilli(1711699200000)}, p)
+	for _, id := range []string{"BTC-USD-SWAP", "BTC-USD-240329", "BTC-USD-240329-40000-X", "BTC-USD-2403-40000-C"} {
+		_, ok := ParseInstId(id)
+		assert.False(t, ok, id)
+	}
+}
+
+func summary(instId, vol, fwd, ts string) *common.OptSummary {
+	return &common.OptSummary{InstType: "OPTION", InstId: instId, Uly: "BTC-USD", MarkVol: vol, FwdPx: fwd, Ts: ts}
+}
+
+func newSurface() *Surface {
+	s := NewSurface("BTC-USD", nil)
+	s.now = func() time.Time { return time.UnixMilli(1704153600000) } // 2024-01-02 00:00 UTC
+	return s
+}
+
+func TestSurface(t *testing.T) {
+	s := newSurface()
+	updates := 0
+	s.OnUpdate(func() { updates++ })
+	s.Update([]*common.OptSummary{
+		summary("BTC-USD-240105-40000-C", "0.70", "42000", "1"),
+		summary("BTC-USD-240105-40000-P", "0.60", "42000", "1"),
+		summary("BTC-USD-240105-44000-C", "0.50", "42000", "1"),
+		summary("BTC-USD-240105-44000-P", "0.80", "42000", "1"),
+		summary("BTC-USD-240329-40000-P", "0.65", "43000", "1"),
+		summary("BTC-USD-240329-44000-C", "0.55", "43000", "1"),
+		summary("BTC-USD-231229-40000-C", "0.90", "42000", "1"),
+		summary("ETH-USD-240105-2500-C", "0.9", "2300", "1"),
+	})
+	assert.Equal(t, 1, updates)
+	_, ok := s.Quote("ETH-USD-240105-2500-C")
+	assert.False(t, ok)
+
+	jan, mar := time.UnixMilli(1704441600000), time.UnixMilli(1711699200000)
+	assert.Equal(t, []time.Time{jan, mar}, s.Expiries())
+	// 行权价低于远期取看跌 否则取看涨
+	assert.Equal(t, []SmilePoint{{Strike: 40000, Vol: 0.60, Fwd: 42000}, {Strike: 44000, Vol: 0.50, Fwd: 42000}}, s.Smile(jan))
+
+	v, ok := s.Vol(jan, 41000)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.575, v, 1e-12)
+	v, _ = s.Vol(jan, 30000)
+	assert.InDelta(t, 0.60, v, 1e-12)
+	v, _ = s.Vol(time.UnixMilli(1704153600000-1), 44000)
+	assert.InDelta(t, 0.50, v, 1e-12)
+	v, _ = s.Vol(mar.Add(time.Hour), 44000)
+	assert.InDelta(t, 0.55, v, 1e-12)
+
+	// 到期日之间按总方差插值
+	mid := jan.Add(mar.Sub(jan) / 2)
+	now := s.now()
+	t1, t2, tm := Years(jan, now), Years(mar, now), Years(mid, now)
+	want := math.Sqrt((0.6*0.6*t1 + 0.65*0.65*t2) / 2 / tm)
+	v, _ = s.Vol(mid, 40000)
+	assert.InDelta(t, want, v, 1e-12)
+
+	// 旧的推送不覆盖新的
+	s.Update([]*common.OptSummary{
+		summary("BTC-USD-240105-40000-P", "0.62", "42100", "3"),
+		summary("BTC-USD-240105-44000-C", "0.40", "42100", "0"),
+	})
+	q, _ := s.Quote("BTC-USD-240105-40000-P")
+	assert.Equal(t, 0.62, q.MarkVol)
+	assert.Equal(t, time.UnixMilli(3), q.Ts)
+	q, _ = s.Quote("BTC-USD-240105-44000-C")
+	assert.Equal(t, 0.50, q.MarkVol)
+
+	_, ok = newSurface().Vol(jan, 40000)
+	assert.False(t, ok)
+}
+
+func TestQuoteModel(t *testing.T) {
+	now := time.UnixMilli(1704153600000)
+	expiry := time.UnixMilli(1711699200000)
+	f, k, vol := 43000.0, 45000.0, 0.55
+	g := Greek(Call, f, k, Years(expiry, now), vol)
+	q, ok := ParseSummary(&common.OptSummary{
+		InstId:  "BTC-USD-240329-45000-C",
+		MarkVol: "0.55",
+		FwdPx:   "43000",
+		DeltaBS: "0.4863",
+		GammaBS: "0.0000345",
+		VegaBS:  "83.86",
+		ThetaBS: "-26.41",
+		Ts:      "1704153600000",
+	})
+	assert.True(t, ok)
+	px, model := q.Model(now)
+	assert.Equal(t, g, model)
+	assert.InDelta(t, Price(Call, f, k, Years(expiry, now), vol), px, 1e-9)
+	assert.InDelta(t, q.BS.Delta, model.Delta, 1e-3)
+	assert.InDelta(t, q.BS.Gamma, model.Gamma, 1e-6)
+	assert.InDelta(t, q.BS.Vega, model.Vega, 1)
+	assert.InDelta(t, q.BS.Theta, model.Theta, 1)
+}
+
+type summaries []common.OptSummary
+
+func (s summaries) OptSummary(ctx context.Context, req common.OptSummaryReq) (*common.Resp[common.OptSummary], error) {
+	return &common.Resp[common.OptSummary]{Data: s}, nil
+}
+
+func TestSurfaceLive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	public := &okx.PublicClient{WsClient: common.NewBaseWsClient(ctx, common.Public, "", nil, nil)}
+	conn := ws.NewReplayConn(ctx)
+	public.Attach(conn)
+
+	chain := NewChain("BTC-USD", []Option{{InstId: "BTC-USD-240105-40000-C", Family: "BTC-USD", Type: Call, Strike: 40000,
+		Expiry: time.UnixMilli(1704441600000), CtVal: 1, CtMult: 0.01}})
+	s := NewSurface("BTC-USD", chain)
+	assert.NoError(t, s.Load(ctx, summaries{*summary("BTC-USD-240105-40000-C", "0.5", "42000", "1")}))
+	q, _ := s.Quote("BTC-USD-240105-40000-C")
+	assert.Equal(t, 0.01, q.CtMult)
+
+	updated := make(chan struct{}, 1)
+	s.OnUpdate(func() { updated <- struct{}{} })
+	go func() { _ = s.Subscribe(ctx, public) }()
+	assert.Eventually(t, func() bool {
+		conn.Inject(ws.TextMessage, []byte(`{"arg":{"channel":"opt-summary","instFamily":"BTC-USD"},"data":[{"instType":"OPTION","instId":"BTC-USD-240105-40000-C","markVol":"0.7","fwdPx":"42500","ts":"2"}]}`))
+		select {
+		case <-updated:
+			return true
+		case <-time.After(5 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	q, _ = s.Quote("BTC-USD-240105-40000-C")
+	assert.Equal(t, 0.7, q.MarkVol)
+	assert.Equal(t, 42500.0, q.Fwd)
+	assert.Equal(t, 0.01, q.CtMult)
+}
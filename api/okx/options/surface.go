@@ -0,0 +1,250 @@
+package options
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+)
+
+// Quote opt-summary中的一个期权
+type Quote struct {
+	Option
+	MarkVol float64
+	BidVol  float64
+	AskVol  float64
+	Fwd     float64 // 远期价格
+	BS      Greeks  // OKX给出的BS希腊字母
+	Ts      time.Time
+}
+
+// ParseSummary instId无法解析时返回false
+func ParseSummary(s *common.OptSummary) (Quote, bool) {
+	o, ok := ParseInstId(s.InstId)
+	if !ok {
+		return Quote{}, false
+	}
+	q := Quote{
+		Option:  o,
+		MarkVol: parseNum(s.MarkVol),
+		BidVol:  parseNum(s.BidVol),
+		AskVol:  parseNum(s.AskVol),
+		Fwd:     parseNum(s.FwdPx),
+		BS: Greeks{
+			Delta: parseNum(s.DeltaBS),
+			Gamma: parseNum(s.GammaBS),
+			Vega:  parseNum(s.VegaBS),
+			Theta: parseNum(s.ThetaBS),
+		},
+	}
+	if ms, err := strconv.ParseInt(s.Ts, 10, 64); err == nil {
+		q.Ts = time.UnixMilli(ms)
+	}
+	return q, true
+}
+
+// Model 以标记波动率与远期价格计算的Black-76价格与希腊字母 用于核对OKX的BS希腊字母
+func (q Quote) Model(now time.Time) (float64, Greeks) {
+	t := Years(q.Expiry, now)
+	return Price(q.Type, q.Fwd, q.Strike, t, q.MarkVol), Greek(q.Type, q.Fwd, q.Strike, t, q.MarkVol)
+}
+
+// SmilePoint 某到期日上一个行权价的波动率 取虚值一侧的期权
+type SmilePoint struct {
+	Strike float64
+	Vol    float64
+	Fwd    float64
+}
+
+// SummarySource *okx.RestClient满足该接口
+type SummarySource interface {
+	OptSummary(ctx context.Context, req common.OptSummaryReq) (*common.Resp[common.OptSummary], error)
+}
+
+// Surface 行权价×到期时间的隐含波动率曲面 由opt-summary更新
+type Surface struct {
+	Family   string
+	chain    *Chain
+	now      func() time.Time
+	lock     sync.RWMutex
+	quotes   map[string]Quote
+	onUpdate []func()
+}
+
+// NewSurface chain可为nil 提供时以其中的到期时间与合约面值为准
+func NewSurface(family string, chain *Chain) *Surface {
+	return &Surface{Family: family, chain: chain, now: time.Now, quotes: map[string]Quote{}}
+}
+
+// OnUpdate 每次更新后回调 在推送协程中调用 不可阻塞
+func (s *Surface) OnUpdate(fn func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onUpdate = append(s.onUpdate, fn)
+}
+
+// Update 合并一批opt-summary 其他instFamily的期权被忽略
+func (s *Surface) Update(data []*common.OptSummary) {
+	s.lock.Lock()
+	for _, d := range data {
+		q, ok := ParseSummary(d)
+		if !ok || q.Family != s.Family {
+			continue
+		}
+		if s.chain != nil {
+			if o, ok := s.chain.Get(q.InstId); ok {
+				q.Option = o
+			}
+		}
+		if old, ok := s.quotes[q.InstId]; ok && q.Ts.Before(old.Ts) {
+			continue
+		}
+		s.quotes[q.InstId] = q
+	}
+	fns := s.onUpdate
+	s.lock.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Load 通过REST查询全部期权定价
+func (s *Surface) Load(ctx context.Context, src SummarySource) error {
+	rp, err := src.OptSummary(ctx, common.OptSummaryReq{InstFamily: s.Family})
+	if err != nil {
+		return err
+	}
+	data := make([]*common.OptSummary, len(rp.Data))
+	for i := range rp.Data {
+		data[i] = &rp.Data[i]
+	}
+	s.Update(data)
+	return nil
+}
+
+// Subscribe 订阅opt-summary持续更新 阻塞直到ctx结束或订阅出错
+func (s *Surface) Subscribe(ctx context.Context, public *okx.PublicClient) error {
+	return public.OptSummary(ctx, s.Family, func(resp *common.WsResp[*common.OptSummary]) {
+		s.Update(resp.Data)
+	})
+}
+
+func (s *Surface) Quote(instId string) (Quote, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	q, ok := s.quotes[instId]
+	return q, ok
+}
+
+// Expiries 有报价且未到期的到期时间 升序
+func (s *Surface) Expiries() []time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	now := s.now()
+	seen := map[int64]bool{}
+	var out []time.Time
+	for _, q := range s.quotes {
+		if ms := q.Expiry.UnixMilli(); q.Expiry.After(now) && !seen[ms] {
+			seen[ms] = true
+			out = append(out, q.Expiry)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// Smile 某到期日的波动率微笑 按行权价升序 行权价低于远期价格取看跌 否则取看涨 缺少一侧时使用另一侧
+func (s *Surface) Smile(expiry time.Time) []SmilePoint {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.smile(expiry)
+}
+
+func (s *Surface) smile(expiry time.Time) []SmilePoint {
+	byStrike := map[float64]Quote{}
+	for _, q := range s.quotes {
+		if !q.Expiry.Equal(expiry) || q.MarkVol <= 0 {
+			continue
+		}
+		old, ok := byStrike[q.Strike]
+		if !ok {
+			byStrike[q.Strike] = q
+			continue
+		}
+		otm := Call
+		if q.Strike < q.Fwd {
+			otm = Put
+		}
+		if old.Type != otm {
+			byStrike[q.Strike] = q
+		}
+	}
+	out := make([]SmilePoint, 0, len(byStrike))
+	for k, q := range byStrike {
+		out = append(out, SmilePoint{Strike: k, Vol: q.MarkVol, Fwd: q.Fwd})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Strike < out[j].Strike })
+	return out
+}
+
+// Vol 任意行权价与到期时间的波动率
+// 同一到期日内按行权价线性插值 不同到期日之间按总方差线性插值 超出范围时取最近的值
+func (s *Surface) Vol(expiry time.Time, strike float64) (float64, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	now := s.now()
+	var expiries []time.Time
+	seen := map[int64]bool{}
+	for _, q := range s.quotes {
+		if ms := q.Expiry.UnixMilli(); q.Expiry.After(now) && q.MarkVol > 0 && !seen[ms] {
+			seen[ms] = true
+			expiries = append(expiries, q.Expiry)
+		}
+	}
+	if len(expiries) == 0 {
+		return 0, false
+	}
+	sort.Slice(expiries, func(i, j int) bool { return expiries[i].Before(expiries[j]) })
+
+	i := sort.Search(len(expiries), func(i int) bool { return !expiries[i].Before(expiry) })
+	switch {
+	case i < len(expiries) && expiries[i].Equal(expiry):
+		return interp(s.smile(expiry), strike), true
+	case i == 0:
+		return interp(s.smile(expiries[0]), strike), true
+	case i == len(expiries):
+		return interp(s.smile(expiries[i-1]), strike), true
+	}
+	e1, e2 := expiries[i-1], expiries[i]
+	t1, t2, t := Years(e1, now), Years(e2, now), Years(expiry, now)
+	v1, v2 := interp(s.smile(e1), strike), interp(s.smile(e2), strike)
+	w := v1*v1*t1 + (v2*v2*t2-v1*v1*t1)*(t-t1)/(t2-t1)
+	if w <= 0 {
+		return v2, true
+	}
+	return math.Sqrt(w / t), true
+}
+
+func interp(smile []SmilePoint, strike float64) float64 {
+	n := len(smile)
+	i := sort.Search(n, func(i int) bool { return smile[i].Strike >= strike })
+	switch {
+	case i == 0:
+		return smile[0].Vol
+	case i == n:
+		return smile[n-1].Vol
+	}
+	a, b := smile[i-1], smile[i]
+	return a.Vol + (b.Vol-a.Vol)*(strike-a.Strike)/(b.Strike-a.Strike)
+}
+
+// parseNum 空值与格式错误视为0
+func parseNum(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
@@ -0,0 +1,494 @@
+package okx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+type OrderState string
+
+const (
+	OrderPendingNew      OrderState = "pending_new"
+	OrderLive            OrderState = "live"
+	OrderPartiallyFilled OrderState = "partially_filled"
+	OrderFilled          OrderState = "filled"
+	OrderCanceled        OrderState = "canceled"
+	OrderRejected        OrderState = "rejected"
+	OrderUnknown         OrderState = "unknown" // 无法识别的交易所状态 不视为终态
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderFinished = errors.New("order finished before reaching state")
+	ErrOrderUnknown  = errors.New("order state unknown")
+)
+
+// 正常流转中的状态顺序 终态撤单/拒单不参与比较
+var orderStateRank = map[OrderState]int{
+	OrderPendingNew:      0,
+	OrderLive:            1,
+	OrderPartiallyFilled: 2,
+	OrderFilled:          3,
+}
+
+// IsFinal 是否为终态
+func (s OrderState) IsFinal() bool {
+	return s == OrderFilled || s == OrderCanceled || s == OrderRejected
+}
+
+// Reached 当前状态是否已经达到(或越过)目标状态
+func (s OrderState) Reached(target OrderState) bool {
+	if s == target {
+		return true
+	}
+	cur, ok1 := orderStateRank[s]
+	want, ok2 := orderStateRank[target]
+	return ok1 && ok2 && cur >= want
+}
+
+// toOrderState 交易所订单状态映射 mmp_canceled视为撤单 无法识别的状态映射为OrderUnknown
+func toOrderState(state string) OrderState {
+	switch state {
+	case "live":
+		return OrderLive
+	case "partially_filled":
+		return OrderPartiallyFilled
+	case "filled":
+		return OrderFilled
+	case "canceled", "mmp_canceled":
+		return OrderCanceled
+	case "":
+		return OrderPendingNew
+	default:
+		return OrderUnknown
+	}
+}
+
+// ManagedOrder 订单管理器中跟踪的订单快照
+type ManagedOrder struct {
+	ClOrdId string
+	OrdId   string
+	InstId  string
+	State   OrderState
+	UTime   int64
+	Order   *common.Order // 最近一次推送或查询到的订单详情
+	Err     error         // 拒单原因
+}
+
+type managedOrder struct {
+	ManagedOrder
+	accFillSz  float64
+	changed    chan struct{}
+	span       trace.Span // 下单至终态的span 未经Place下单的订单为nil
+	uncertain  bool       // 下单请求结果不明 需按clOrdId对账
+	finishedAt time.Time
+}
+
+// OrderManager 订单生命周期管理 合并REST回报与orders频道推送
+type OrderManager struct {
+	rest    RestTrader
+	private PrivateTrader
+	prefix  string
+	seq     atomic.Uint64
+	lock    sync.RWMutex
+	orders  map[string]*managedOrder
+	now     func() time.Time
+	pruned  time.Time
+	// FinishedTTL 终态订单保留的时长 之后从Get中移除 默认10分钟
+	FinishedTTL time.Duration
+	Logger      *slog.Logger // 为nil时使用slog.Default()
+	Tracer      trace.Tracer
+	// OnUpdate 订单状态变化回调
+	OnUpdate func(order ManagedOrder)
+}
+
+// NewOrderManager prefix为自定义订单号前缀 需以字母开头、仅含字母数字且不超过16位 不符合时panic
+func NewOrderManager(rest RestTrader, private PrivateTrader, prefix string) *OrderManager {
+	if prefix == "" {
+		prefix = "aqt"
+	}
+	if !clOrdIdPrefix.MatchString(prefix) {
+		panic(fmt.Sprintf("okx: invalid clOrdId prefix %q: want a letter followed by up to 15 letters or digits", prefix))
+	}
+	return &OrderManager{
+		rest:        rest,
+		private:     private,
+		prefix:      prefix,
+		orders:      map[string]*managedOrder{},
+		now:         time.Now,
+		FinishedTTL: 10 * time.Minute,
+		Tracer:      common.NewTracer(nil),
+		OnUpdate:    func(order ManagedOrder) {},
+	}
+}
+
+// clOrdIdPrefix OKX要求clOrdId以字母开头且仅含字母数字
+var clOrdIdPrefix = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]{0,15}$`)
+
+// NextClOrdId 生成自定义订单号 字母数字组合且不超过32位 超长时截断时间戳部分以保留前缀
+func (m *OrderManager) NextClOrdId() string {
+	suffix := strconv.FormatInt(m.now().UnixMilli(), 36) + strconv.FormatUint(m.seq.Add(1), 36)
+	if n := 32 - len(m.prefix); len(suffix) > n {
+		suffix = suffix[len(suffix)-n:]
+	}
+	return m.prefix + suffix
+}
+
+// Place 下单 未指定ClOrdID时自动生成
+// 交易所明确拒单时订单置为拒单 网络错误等结果不明时保持pending_new并按clOrdId查询
+// 仍无法确认时返回ErrOrderUnknown 由Reconcile继续对账
+// 以ctx为父创建订单span 订单状态变化记录为span事件 到达终态时结束
+func (m *OrderManager) Place(ctx context.Context, req common.PlaceOrderReq) (ManagedOrder, error) {
+	if req.ClOrdID == "" {
+		req.ClOrdID = m.NextClOrdId()
+	}
+	ctx, span := m.Tracer.Start(ctx, "okx.order", trace.WithAttributes(
+		attribute.String("okx.clOrdId", req.ClOrdID),
+		attribute.String("okx.instId", req.InstID),
+		attribute.String("okx.side", req.Side),
+		attribute.String("okx.ordType", req.OrdType),
+		attribute.String("okx.px", req.Px),
+		attribute.String("okx.sz", req.Sz)))
+	m.track(req.ClOrdID, req.InstID, span)
+
+	rp, err := m.rest.PlaceOrder(ctx, req)
+	if err == nil && len(rp.Data) != 0 && rp.Data[0].SCode != "" && rp.Data[0].SCode != "0" {
+		err = fmt.Errorf("%s: %s", rp.Data[0].SCode, rp.Data[0].SMsg)
+	}
+	if err != nil && ambiguous(err) {
+		return m.resolve(ctx, req, err)
+	}
+	if err != nil {
+		m.reject(req.ClOrdID, err)
+		o, _ := m.Get(req.ClOrdID)
+		return o, err
+	}
+	if len(rp.Data) != 0 {
+		m.ack(req.ClOrdID, rp.Data[0].OrdId)
+	}
+	o, _ := m.Get(req.ClOrdID)
+	return o, nil
+}
+
+// resolve 下单结果不明时按clOrdId查询 订单不存在视为拒单
+func (m *OrderManager) resolve(ctx context.Context, req common.PlaceOrderReq, placeErr error) (ManagedOrder, error) {
+	m.lock.Lock()
+	if o, ok := m.orders[req.ClOrdID]; ok {
+		o.uncertain = true
+	}
+	m.lock.Unlock()
+
+	rp, err := m.rest.GetOrder(ctx, common.PlaceOrderReq{InstID: req.InstID, ClOrdID: req.ClOrdID})
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Code == codeOrderNotExist:
+		m.reject(req.ClOrdID, placeErr)
+		o, _ := m.Get(req.ClOrdID)
+		return o, placeErr
+	case err != nil:
+		m.logger().Warn("order manager resolve", "clOrdId", req.ClOrdID, "err", err)
+		o, _ := m.Get(req.ClOrdID)
+		return o, fmt.Errorf("%w: %w", ErrOrderUnknown, placeErr)
+	}
+	for i := range rp.Data {
+		m.OnOrder(&rp.Data[i])
+	}
+	o, _ := m.Get(req.ClOrdID)
+	return o, nil
+}
+
+// ambiguous 请求可能已生效 网络错误、超时、5xx与系统繁忙时下单结果不明
+func ambiguous(err error) bool {
+	return retryable(err) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrClientClosed)
+}
+
+// Get 获取订单快照
+func (m *OrderManager) Get(clOrdId string) (ManagedOrder, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	o, ok := m.orders[clOrdId]
+	if !ok {
+		return ManagedOrder{}, false
+	}
+	return o.ManagedOrder, true
+}
+
+// Orders 获取全部未完结订单
+func (m *OrderManager) Orders() []ManagedOrder {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var orders []ManagedOrder
+	for _, o := range m.orders {
+		if !o.State.IsFinal() {
+			orders = append(orders, o.ManagedOrder)
+		}
+	}
+	return orders
+}
+
+// Wait 等待订单到达指定状态 订单先进入其他终态时返回ErrOrderFinished
+func (m *OrderManager) Wait(ctx context.Context, clOrdId string, state OrderState) (ManagedOrder, error) {
+	for {
+		m.lock.RLock()
+		o, ok := m.orders[clOrdId]
+		if !ok {
+			m.lock.RUnlock()
+			return ManagedOrder{}, ErrOrderNotFound
+		}
+		snapshot, changed := o.ManagedOrder, o.changed
+		m.lock.RUnlock()
+
+		if snapshot.State.Reached(state) {
+			return snapshot, nil
+		}
+		if snapshot.State.IsFinal() {
+			return snapshot, ErrOrderFinished
+		}
+		select {
+		case <-ctx.Done():
+			return snapshot, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (m *OrderManager) logger() *slog.Logger {
+	return common.ResolveLogger(m.Logger, nil)
+}
+
+// Run 订阅orders频道并在每次(重新)订阅时与未成交订单对账 阻塞直到ctx结束
+func (m *OrderManager) Run(ctx context.Context, instType string) error {
+	for {
+		go func() {
+			if err := m.Reconcile(ctx, instType); err != nil && ctx.Err() == nil {
+				m.logger().Error("order manager reconcile", "instType", instType, "err", err)
+			}
+		}()
+		err := m.private.Orders(ctx, instType, func(resp *common.WsResp[*common.Order]) {
+			for _, o := range resp.Data {
+				m.OnOrder(o)
+			}
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			m.logger().Warn("order manager resubscribe", "instType", instType, "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Reconcile 与orders-pending对账 不在挂单列表中的未完结订单逐个查询最终状态 单个查询失败不影响其余订单
+func (m *OrderManager) Reconcile(ctx context.Context, instType string) error {
+	rp, err := m.rest.OrdersPending(ctx, common.OrdersPendingReq{InstType: instType})
+	if err != nil {
+		return err
+	}
+	pending := map[string]struct{}{}
+	for i := range rp.Data {
+		o := rp.Data[i]
+		pending[o.ClOrdId] = struct{}{}
+		m.OnOrder(&o)
+	}
+	var check []ManagedOrder
+	uncertain := map[string]bool{}
+	m.lock.RLock()
+	for _, o := range m.orders {
+		if _, ok := pending[o.ClOrdId]; ok || o.State.IsFinal() {
+			continue
+		}
+		// 刚下单尚未得到回报的订单不参与对账 下单结果不明的除外
+		if o.State == OrderPendingNew && o.OrdId == "" && !o.uncertain {
+			continue
+		}
+		check = append(check, o.ManagedOrder)
+		uncertain[o.ClOrdId] = o.uncertain
+	}
+	m.lock.RUnlock()
+	var errs []error
+	for _, o := range check {
+		detail, err := m.rest.GetOrder(ctx, common.PlaceOrderReq{InstID: o.InstId, ClOrdID: o.ClOrdId})
+		var apiErr *APIError
+		if uncertain[o.ClOrdId] && errors.As(err, &apiErr) && apiErr.Code == codeOrderNotExist {
+			m.reject(o.ClOrdId, err)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", o.ClOrdId, err))
+			continue
+		}
+		for i := range detail.Data {
+			m.OnOrder(&detail.Data[i])
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// OnOrder 应用一条订单更新 根据uTime与累计成交量丢弃乱序及重复的推送
+func (m *OrderManager) OnOrder(order *common.Order) {
+	if order == nil || order.ClOrdId == "" {
+		return
+	}
+	uTime, _ := strconv.ParseInt(order.UTime, 10, 64)
+	accFillSz, _ := strconv.ParseFloat(order.AccFillSz, 64)
+	state := toOrderState(order.State)
+
+	if state == OrderUnknown {
+		m.logger().Warn("order manager unknown state", "clOrdId", order.ClOrdId, "state", order.State)
+	}
+
+	m.lock.Lock()
+	m.prune()
+	o, ok := m.orders[order.ClOrdId]
+	if !ok {
+		o = m.newOrder(order.ClOrdId, order.InstId)
+	}
+	if o.State.IsFinal() && o.UTime != 0 {
+		m.lock.Unlock()
+		return
+	}
+	if uTime < o.UTime || (uTime == o.UTime && accFillSz <= o.accFillSz && state == o.State) {
+		m.lock.Unlock()
+		return
+	}
+	o.OrdId = order.OrdId
+	o.InstId = order.InstId
+	o.State = state
+	o.UTime = uTime
+	o.Order = order
+	o.accFillSz = accFillSz
+	o.uncertain = false
+	if !state.IsFinal() {
+		o.finishedAt = time.Time{}
+	}
+	m.traceOrder(o, order)
+	snapshot := m.notify(o)
+	m.lock.Unlock()
+
+	m.OnUpdate(snapshot)
+}
+
+func (m *OrderManager) newOrder(clOrdId, instId string) *managedOrder {
+	o := &managedOrder{
+		ManagedOrder: ManagedOrder{ClOrdId: clOrdId, InstId: instId, State: OrderPendingNew},
+		changed:      make(chan struct{}),
+	}
+	m.orders[clOrdId] = o
+	return o
+}
+
+func (m *OrderManager) track(clOrdId, instId string, span trace.Span) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.prune()
+	o, ok := m.orders[clOrdId]
+	if !ok {
+		o = m.newOrder(clOrdId, instId)
+	}
+	if o.span != nil || o.State.IsFinal() {
+		span.End()
+		return
+	}
+	o.span = span
+}
+
+func (m *OrderManager) ack(clOrdId, ordId string) {
+	m.lock.Lock()
+	o, ok := m.orders[clOrdId]
+	if !ok || o.OrdId != "" {
+		m.lock.Unlock()
+		return
+	}
+	o.OrdId = ordId
+	if o.span != nil {
+		o.span.AddEvent("order.ack", trace.WithAttributes(attribute.String("okx.ordId", ordId)))
+	}
+	snapshot := m.notify(o)
+	m.lock.Unlock()
+
+	m.OnUpdate(snapshot)
+}
+
+func (m *OrderManager) reject(clOrdId string, err error) {
+	m.lock.Lock()
+	o, ok := m.orders[clOrdId]
+	// 推送已先于REST回报到达时以推送为准
+	if !ok || o.UTime != 0 {
+		m.lock.Unlock()
+		return
+	}
+	o.State = OrderRejected
+	o.Err = err
+	o.uncertain = false
+	if o.span != nil {
+		common.EndSpan(o.span, err)
+		o.span = nil
+	}
+	snapshot := m.notify(o)
+	m.lock.Unlock()
+
+	m.OnUpdate(snapshot)
+}
+
+// traceOrder 将orders频道的状态变化记录为span事件 终态时结束span 调用方需持有写锁
+func (m *OrderManager) traceOrder(o *managedOrder, order *common.Order) {
+	if o.span == nil {
+		return
+	}
+	o.span.AddEvent("order."+string(o.State), trace.WithAttributes(
+		attribute.String("okx.ordId", order.OrdId),
+		attribute.String("okx.accFillSz", order.AccFillSz),
+		attribute.String("okx.fillPx", order.FillPx),
+		attribute.String("okx.fillSz", order.FillSz),
+		attribute.String("okx.uTime", order.UTime)))
+	if o.State.IsFinal() {
+		o.span.SetAttributes(attribute.String("okx.state", string(o.State)))
+		o.span.End()
+		o.span = nil
+	}
+}
+
+// prune 移除超过FinishedTTL的终态订单 每分钟最多执行一次 调用方需持有写锁
+func (m *OrderManager) prune() {
+	now := m.now()
+	if now.Sub(m.pruned) < time.Minute {
+		return
+	}
+	m.pruned = now
+	for id, o := range m.orders {
+		if !o.finishedAt.IsZero() && now.Sub(o.finishedAt) > m.FinishedTTL {
+			delete(m.orders, id)
+		}
+	}
+}
+
+// notify 唤醒等待者 调用方需持有写锁
+func (m *OrderManager) notify(o *managedOrder) ManagedOrder {
+	if o.State.IsFinal() && o.finishedAt.IsZero() {
+		o.finishedAt = m.now()
+	}
+	close(o.changed)
+	o.changed = make(chan struct{})
+	return o.ManagedOrder
+}
@@ -0,0 +1,195 @@
+package okx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func TestOrderManagerOnOrder(t *testing.T) {
+	m := NewOrderManager(nil, nil, "t")
+	m.OnOrder(&common.Order{ClOrdId: "a1", InstId: "BTC-USDT", State: "live", UTime: "100"})
+	m.OnOrder(&common.Order{ClOrdId: "a1", InstId: "BTC-USDT", State: "partially_filled", AccFillSz: "1", UTime: "300"})
+	// 乱序
+	m.OnOrder(&common.Order{ClOrdId: "a1", InstId: "BTC-USDT", State: "live", UTime: "200"})
+	o, ok := m.Get("a1")
+	assert.True(t, ok)
+	assert.Equal(t, OrderPartiallyFilled, o.State)
+
+	// 同一时间戳的成交推进
+	m.OnOrder(&common.Order{ClOrdId: "a1", InstId: "BTC-USDT", State: "filled", AccFillSz: "2", UTime: "300"})
+	o, _ = m.Get("a1")
+	assert.Equal(t, OrderFilled, o.State)
+
+	// 终态之后不再变化
+	m.OnOrder(&common.Order{ClOrdId: "a1", InstId: "BTC-USDT", State: "canceled", UTime: "400"})
+	o, _ = m.Get("a1")
+	assert.Equal(t, OrderFilled, o.State)
+	assert.Empty(t, m.Orders())
+}
+
+func TestOrderManagerWait(t *testing.T) {
+	m := NewOrderManager(nil, nil, "t")
+	m.OnOrder(&common.Order{ClOrdId: "a1", State: "live", UTime: "1"})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.OnOrder(&common.Order{ClOrdId: "a1", State: "filled", AccFillSz: "1", UTime: "2"})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	o, err := m.Wait(ctx, "a1", OrderPartiallyFilled)
+	assert.NoError(t, err)
+	assert.Equal(t, OrderFilled, o.State)
+
+	m.OnOrder(&common.Order{ClOrdId: "a2", State: "canceled", UTime: "1"})
+	_, err = m.Wait(ctx, "a2", OrderFilled)
+	assert.ErrorIs(t, err, ErrOrderFinished)
+}
+
+func TestOrderManagerPlaceRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"clOrdId":"x","ordId":"","sCode":"51008","sMsg":"insufficient balance"}]}`))
+	}))
+	defer srv.Close()
+	rest := NewRestClientWithCustom(context.Background(), config, common.NormalServer,
+		map[common.Destination]common.BaseURL{common.NormalServer: common.BaseURL(srv.URL)})
+	m := NewOrderManager(rest, nil, "t")
+	o, err := m.Place(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "market", TdMode: "cash", Sz: "1"})
+	assert.Error(t, err)
+	assert.Equal(t, OrderRejected, o.State)
+	assert.NotEmpty(t, o.ClOrdId)
+}
+
+func TestOrderManagerPlaceAmbiguous(t *testing.T) {
+	var lookup atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v5/trade/order":
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/api/v5/trade/order":
+			if body := lookup.Load().(string); body != "" {
+				_, _ = w.Write([]byte(body))
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/api/v5/trade/orders-pending":
+			_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	rest := NewRestClientWithCustom(context.Background(), config, common.NormalServer,
+		map[common.Destination]common.BaseURL{common.NormalServer: common.BaseURL(srv.URL)})
+	m := NewOrderManager(rest, nil, "t")
+	ctx := ContextWithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 1})
+	req := func(clOrdId string) common.PlaceOrderReq {
+		return common.PlaceOrderReq{InstID: "BTC-USDT", ClOrdID: clOrdId, Side: "buy", OrdType: "limit", TdMode: "cash", Px: "1", Sz: "1"}
+	}
+
+	// 网关错误后查询到订单
+	lookup.Store(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT","clOrdId":"a1","ordId":"9","state":"live","uTime":"1"}]}`)
+	o, err := m.Place(ctx, req("a1"))
+	assert.NoError(t, err)
+	assert.Equal(t, OrderLive, o.State)
+	assert.Equal(t, "9", o.OrdId)
+
+	// 订单不存在视为拒单
+	lookup.Store(`{"code":"51603","msg":"Order does not exist","data":[]}`)
+	o, err = m.Place(ctx, req("a2"))
+	assert.Error(t, err)
+	assert.Equal(t, OrderRejected, o.State)
+
+	// 查询也失败时保持pending_new 由对账确认
+	lookup.Store("")
+	o, err = m.Place(ctx, req("a3"))
+	assert.ErrorIs(t, err, ErrOrderUnknown)
+	assert.Equal(t, OrderPendingNew, o.State)
+	lookup.Store(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT","clOrdId":"a3","ordId":"10","state":"filled","accFillSz":"1","uTime":"2"}]}`)
+	assert.NoError(t, m.Reconcile(ctx, "SPOT"))
+	o, _ = m.Get("a3")
+	assert.Equal(t, OrderFilled, o.State)
+}
+
+func TestOrderManagerPrune(t *testing.T) {
+	now := time.UnixMilli(0)
+	m := NewOrderManager(nil, nil, "t")
+	m.now = func() time.Time { return now }
+	m.OnOrder(&common.Order{ClOrdId: "a1", State: "filled", AccFillSz: "1", UTime: "1"})
+	m.OnOrder(&common.Order{ClOrdId: "a2", State: "live", UTime: "1"})
+
+	now = now.Add(5 * time.Minute)
+	m.OnOrder(&common.Order{ClOrdId: "a3", State: "live", UTime: "1"})
+	_, ok := m.Get("a1")
+	assert.True(t, ok)
+
+	now = now.Add(10 * time.Minute)
+	m.OnOrder(&common.Order{ClOrdId: "a4", State: "live", UTime: "1"})
+	_, ok = m.Get("a1")
+	assert.False(t, ok)
+	_, ok = m.Get("a2")
+	assert.True(t, ok)
+}
+
+func TestOrderManagerUnknownState(t *testing.T) {
+	m := NewOrderManager(nil, nil, "t")
+	m.OnOrder(&common.Order{ClOrdId: "a1", State: "mmp_canceled", UTime: "1"})
+	m.OnOrder(&common.Order{ClOrdId: "a2", State: "frozen", UTime: "1"})
+	o, _ := m.Get("a1")
+	assert.Equal(t, OrderCanceled, o.State)
+	o, _ = m.Get("a2")
+	assert.Equal(t, OrderUnknown, o.State)
+	assert.Len(t, m.Orders(), 1)
+}
+
+func TestOrderManagerNextClOrdId(t *testing.T) {
+	m := NewOrderManager(nil, nil, "strategyAlpha012")
+	m.now = func() time.Time { return time.UnixMilli(1_700_000_000_000) }
+	id := m.NextClOrdId()
+	assert.Equal(t, "strategyAlpha012"+strconv.FormatInt(1_700_000_000_000, 36)+"1", id)
+
+	// 超长时截断时间戳部分 保留前缀与序号
+	m.seq.Store(1 << 62)
+	id = m.NextClOrdId()
+	assert.Len(t, id, 32)
+	assert.True(t, strings.HasPrefix(id, "strategyAlpha012"))
+	assert.True(t, strings.HasSuffix(id, strconv.FormatUint(1<<62+1, 36)))
+
+	for _, prefix := range []string{"1abc", "ab-c", "strategyalpha0123456789"} {
+		assert.Panics(t, func() { NewOrderManager(nil, nil, prefix) }, prefix)
+	}
+}
+
+func TestOrderManagerReconcileContinues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v5/trade/orders-pending":
+			_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+		case r.URL.Query().Get("clOrdId") == "a1":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT","clOrdId":"a2","ordId":"2","state":"filled","accFillSz":"1","uTime":"2"}]}`))
+		}
+	}))
+	defer srv.Close()
+	rest := NewRestClientWithCustom(context.Background(), config, common.NormalServer,
+		map[common.Destination]common.BaseURL{common.NormalServer: common.BaseURL(srv.URL)})
+	m := NewOrderManager(rest, nil, "t")
+	m.OnOrder(&common.Order{ClOrdId: "a1", OrdId: "1", InstId: "BTC-USDT", State: "live", UTime: "1"})
+	m.OnOrder(&common.Order{ClOrdId: "a2", OrdId: "2", InstId: "BTC-USDT", State: "live", UTime: "1"})
+
+	ctx := ContextWithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 1})
+	err := m.Reconcile(ctx, "SPOT")
+	assert.ErrorContains(t, err, "a1")
+	o, _ := m.Get("a2")
+	assert.Equal(t, OrderFilled, o.State)
+}
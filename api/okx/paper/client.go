@@ -0,0 +1,174 @@
+package paper
+
+import (
+	"context"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+)
+
+var (
+	_ okx.RestTrader    = (*RestClient)(nil)
+	_ okx.PrivateTrader = (*PrivateClient)(nil)
+)
+
+// RestClient 模拟盘REST交易接口
+type RestClient struct {
+	e *Engine
+}
+
+// PlaceOrder 下单
+func (c *RestClient) PlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.Resp[common.PlaceOrder], error) {
+	ack, err := c.e.place(req)
+	if err != nil {
+		return nil, err
+	}
+	return ok(ack), nil
+}
+
+// CancelOrder 撤单
+func (c *RestClient) CancelOrder(ctx context.Context, instId, clOrdId string) (*common.Resp[common.PlaceOrder], error) {
+	ack, err := c.e.cancel(common.CancelOrderReq{InstId: instId, ClOrdId: clOrdId})
+	if err != nil {
+		return nil, err
+	}
+	return ok(ack), nil
+}
+
+// CancelBatchOrders 批量撤单 单个失败通过sCode返回
+func (c *RestClient) CancelBatchOrders(ctx context.Context, reqs []common.CancelOrderReq) (*common.Resp[common.PlaceOrder], error) {
+	rp := &common.Resp[common.PlaceOrder]{Code: "0"}
+	for _, req := range reqs {
+		ack, err := c.e.cancel(req)
+		ack.SCode = "0"
+		if err != nil {
+			ack.SCode, ack.SMsg = "51400", err.Error()
+		}
+		rp.Data = append(rp.Data, ack)
+	}
+	return rp, nil
+}
+
+// GetOrder 获取订单信息
+func (c *RestClient) GetOrder(ctx context.Context, req common.PlaceOrderReq) (*common.Resp[common.Order], error) {
+	c.e.lock.Lock()
+	defer c.e.lock.Unlock()
+
+	o, found := c.e.orders[c.e.clOrds[req.ClOrdID]]
+	if !found || (req.InstID != "" && o.req.InstID != req.InstID) {
+		return nil, ErrUnknownOrder
+	}
+	return &common.Resp[common.Order]{Code: "0", Data: []common.Order{*o.toCommon(nil)}}, nil
+}
+
+// OrdersPending 获取未成交订单列表
+func (c *RestClient) OrdersPending(ctx context.Context, req common.OrdersPendingReq) (*common.Resp[common.Order], error) {
+	c.e.lock.Lock()
+	defer c.e.lock.Unlock()
+
+	rp := &common.Resp[common.Order]{Code: "0"}
+	for _, o := range c.e.orders {
+		if o.state != "" && o.state != "live" && o.state != "partially_filled" {
+			continue
+		}
+		if (req.InstId != "" && o.req.InstID != req.InstId) ||
+			(req.InstType != "" && o.inst.InstType != req.InstType) ||
+			(req.OrdType != "" && o.req.OrdType != req.OrdType) {
+			continue
+		}
+		rp.Data = append(rp.Data, *o.toCommon(nil))
+	}
+	return rp, nil
+}
+
+// Balance 交易账户余额
+func (c *RestClient) Balance(ctx context.Context, ccy string) (*common.Resp[common.Balance], error) {
+	c.e.lock.Lock()
+	defer c.e.lock.Unlock()
+
+	return &common.Resp[common.Balance]{Code: "0", Data: []common.Balance{*c.e.commonBalance(ccy)}}, nil
+}
+
+// AccountPositions 账户持仓信息
+func (c *RestClient) AccountPositions(ctx context.Context, req common.PositionReq) (*common.Resp[common.Position], error) {
+	c.e.lock.Lock()
+	defer c.e.lock.Unlock()
+
+	rp := &common.Resp[common.Position]{Code: "0"}
+	for _, p := range c.e.positions.Positions() {
+		if p.Pos == 0 ||
+			(req.InstId != "" && p.InstId != req.InstId) ||
+			(req.InstType != "" && p.InstType != req.InstType) {
+			continue
+		}
+		rp.Data = append(rp.Data, *c.e.commonPosition(p))
+	}
+	return rp, nil
+}
+
+// PrivateClient 模拟盘私有频道 订阅方法与实盘一致阻塞直到ctx结束
+type PrivateClient struct {
+	e *Engine
+}
+
+// Account 资金频道
+func (c *PrivateClient) Account(ctx context.Context, callback func(resp *common.WsResp[*common.Balance])) error {
+	return watch(ctx, c.e.subs, c.e.subs.account, subscription[*common.Balance]{callback: callback})
+}
+func (c *PrivateClient) UAccount() error {
+	removeByInstType(c.e.subs, c.e.subs.account, "")
+	return nil
+}
+
+// AccountPositions 持仓频道
+func (c *PrivateClient) AccountPositions(ctx context.Context, instType string, callback func(resp *common.WsResp[*common.Position])) error {
+	return watch(ctx, c.e.subs, c.e.subs.positions, subscription[*common.Position]{instType: instType, callback: callback})
+}
+
+// Orders 订单频道
+func (c *PrivateClient) Orders(ctx context.Context, instType string, callback func(resp *common.WsResp[*common.Order])) error {
+	return watch(ctx, c.e.subs, c.e.subs.orders, subscription[*common.Order]{instType: instType, callback: callback})
+}
+
+// UOrders 取消订阅订单频道
+func (c *PrivateClient) UOrders(instType string) error {
+	removeByInstType(c.e.subs, c.e.subs.orders, instType)
+	return nil
+}
+
+// PlaceOrder 下单
+func (c *PrivateClient) PlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.WsResp[common.PlaceOrder], error) {
+	ack, err := c.e.place(req)
+	return wsResp("order", ack, err)
+}
+
+// CancelOrder 撤单
+func (c *PrivateClient) CancelOrder(ctx context.Context, req common.CancelOrderReq) (*common.WsResp[common.PlaceOrder], error) {
+	ack, err := c.e.cancel(req)
+	return wsResp("cancel-order", ack, err)
+}
+
+func watch[T any](ctx context.Context, s *subscribers, m map[int]subscription[T], sub subscription[T]) error {
+	id := add(s, m, sub)
+	defer remove(s, m, id)
+	<-ctx.Done()
+	return nil
+}
+
+func ok(ack common.PlaceOrder) *common.Resp[common.PlaceOrder] {
+	ack.SCode = "0"
+	return &common.Resp[common.PlaceOrder]{Code: "0", Data: []common.PlaceOrder{ack}}
+}
+
+func wsResp(op string, ack common.PlaceOrder, err error) (*common.WsResp[common.PlaceOrder], error) {
+	rp := &common.WsResp[common.PlaceOrder]{Op: op, Code: "0"}
+	ack.SCode = "0"
+	if err != nil {
+		rp.Code, rp.Msg = "1", "Operation failed."
+		ack.SCode, ack.SMsg = "51000", err.Error()
+		rp.Data = []common.PlaceOrder{ack}
+		return rp, err
+	}
+	rp.Data = []common.PlaceOrder{ack}
+	return rp, nil
+}
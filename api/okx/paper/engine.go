@@ -0,0 +1,793 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+)
+
+const (
+	OrdTypeMarket   = "market"
+	OrdTypeLimit    = "limit"
+	OrdTypePostOnly = "post_only"
+	OrdTypeIOC      = "ioc"
+	OrdTypeFOK      = "fok"
+)
+
+var (
+	ErrUnknownOrder = errors.New("order does not exist")
+	ErrInsufficient = errors.New("insufficient balance")
+)
+
+// Config 模拟盘配置
+type Config struct {
+	Balances    map[string]float64   // 初始资金
+	MakerFee    float64              // 挂单手续费率 正数表示收取 如0.0008
+	TakerFee    float64              // 吃单手续费率
+	Latency     time.Duration        // 下单到进入撮合的延迟
+	Instruments []common.Instruments // 产品信息 未配置时按instId推断
+	Clock       func() time.Time     // 自定义时钟 回测使用 设置后延迟仅在行情事件时推进
+}
+
+type level struct {
+	px float64
+	sz float64
+}
+
+type book struct {
+	bids []level // 价格降序
+	asks []level // 价格升序
+}
+
+type balance struct {
+	cash   float64
+	frozen float64
+}
+
+type order struct {
+	req        common.PlaceOrderReq
+	inst       common.Instruments
+	ordId      string
+	px         float64
+	sz         float64
+	accFill    float64
+	fillValue  float64
+	fee        float64
+	feeCcy     string
+	state      string
+	frozen     float64
+	frozenCcy  string
+	cTime      int64
+	uTime      int64
+	activateAt time.Time
+}
+
+type fill struct {
+	px      float64
+	sz      float64
+	fee     float64
+	tradeId string
+	maker   bool
+}
+
+// Engine 本地模拟撮合引擎 行情来自PublicClient推送或录制数据
+type Engine struct {
+	cfg       Config
+	lock      sync.Mutex
+	now       func() time.Time
+	seq       int64
+	tradeSeq  int64
+	insts     map[string]common.Instruments
+	books     map[string]*book
+	orders    map[string]*order
+	clOrds    map[string]string
+	pending   []*order
+	balances  map[string]*balance
+	positions *okx.PositionTracker
+	subs      *subscribers
+	// OnOrder 订单变化钩子 在推送给订阅者前同步调用 用于回测统计
+	OnOrder func(o *common.Order)
+}
+
+func NewEngine(cfg Config) *Engine {
+	e := &Engine{
+		cfg:       cfg,
+		now:       cfg.Clock,
+		insts:     map[string]common.Instruments{},
+		books:     map[string]*book{},
+		orders:    map[string]*order{},
+		clOrds:    map[string]string{},
+		balances:  map[string]*balance{},
+		positions: okx.NewPositionTracker(nil),
+		subs:      newSubscribers(),
+		OnOrder:   func(o *common.Order) {},
+	}
+	if e.now == nil {
+		e.now = time.Now
+	}
+	for ccy, amount := range cfg.Balances {
+		e.balances[ccy] = &balance{cash: amount}
+	}
+	for _, inst := range cfg.Instruments {
+		e.insts[inst.InstId] = inst
+		e.positions.SetInstrument(inst)
+	}
+	return e
+}
+
+// Rest 与okx.RestClient一致的交易接口
+func (e *Engine) Rest() *RestClient {
+	return &RestClient{e: e}
+}
+
+// Private 与okx.PrivateClient一致的私有频道接口
+func (e *Engine) Private() *PrivateClient {
+	return &PrivateClient{e: e}
+}
+
+// Feed 订阅实盘公共行情驱动撮合 阻塞直到ctx结束或订阅出错
+func (e *Engine) Feed(ctx context.Context, pc *okx.PublicClient, bookChannel string, instIds ...string) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var wg sync.WaitGroup
+	run := func(f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(); err != nil {
+				cancel(err)
+			}
+		}()
+	}
+	for _, instId := range instIds {
+		instId := instId
+		run(func() error {
+			return pc.Books(ctx, bookChannel, instId, func(resp *common.WsResp[*common.OrderBook]) {
+				for _, ob := range resp.Data {
+					e.OnBookAction(instId, resp.Action, ob)
+				}
+			})
+		})
+		run(func() error {
+			return pc.MarketTrades(ctx, instId, func(resp *common.WsResp[*common.Trade]) {
+				for _, t := range resp.Data {
+					e.OnTrade(t)
+				}
+			})
+		})
+	}
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// OnBook 以深度快照替换本地深度并撮合穿价的挂单
+func (e *Engine) OnBook(instId string, ob *common.OrderBook) {
+	e.OnBookAction(instId, "snapshot", ob)
+}
+
+// OnBookAction action为update时按价格档位合并增量 数量为0的档位被删除 收到快照前的增量被忽略
+// 其他取值视为快照 适用于books、books-l2-tbt等增量频道
+func (e *Engine) OnBookAction(instId, action string, ob *common.OrderBook) {
+	e.lock.Lock()
+	b := e.books[instId]
+	switch {
+	case action != "update":
+		b = &book{bids: toLevels(ob.Bids), asks: toLevels(ob.Asks)}
+		sort.Slice(b.bids, func(i, j int) bool { return b.bids[i].px > b.bids[j].px })
+		sort.Slice(b.asks, func(i, j int) bool { return b.asks[i].px < b.asks[j].px })
+		e.books[instId] = b
+	case b == nil:
+		e.lock.Unlock()
+		return
+	default:
+		b.bids = mergeLevels(b.bids, ob.Bids, true)
+		b.asks = mergeLevels(b.asks, ob.Asks, false)
+	}
+	var ev events
+	e.activate(&ev)
+	if mid := b.mid(); mid > 0 {
+		e.positions.OnMarkPrice(&common.MarkPrice{InstId: instId, MarkPx: formatFloat(mid)})
+	}
+	for _, o := range e.restingOrders(instId) {
+		if o.req.Side == "buy" {
+			e.matchLevels(o, &b.asks, true, &ev)
+		} else {
+			e.matchLevels(o, &b.bids, true, &ev)
+		}
+	}
+	e.lock.Unlock()
+	e.dispatch(ev)
+}
+
+// OnTrade 成交价穿过挂单价格时按挂单价成交
+func (e *Engine) OnTrade(t *common.Trade) {
+	px, err1 := strconv.ParseFloat(t.Px, 64)
+	sz, err2 := strconv.ParseFloat(t.Sz, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	e.lock.Lock()
+	var ev events
+	e.activate(&ev)
+	for _, o := range e.restingOrders(t.InstId) {
+		if sz <= 0 {
+			break
+		}
+		through := (o.req.Side == "buy" && (px < o.px || px == o.px && t.Side == "sell")) ||
+			(o.req.Side == "sell" && (px > o.px || px == o.px && t.Side == "buy"))
+		if !through {
+			continue
+		}
+		qty := math.Min(sz, o.sz-o.accFill)
+		sz -= qty
+		e.fill(o, o.px, qty, true, &ev)
+	}
+	e.lock.Unlock()
+	e.dispatch(ev)
+}
+
+// OnMarkPrice 更新标记价格 用于计算未实现盈亏
+func (e *Engine) OnMarkPrice(mp *common.MarkPrice) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.positions.OnMarkPrice(mp)
+}
+
+// Advance 处理已到达撮合时间的订单 实时模式由定时器调用
+func (e *Engine) Advance() {
+	e.lock.Lock()
+	var ev events
+	e.activate(&ev)
+	e.lock.Unlock()
+	e.dispatch(ev)
+}
+
+func (e *Engine) place(req common.PlaceOrderReq) (common.PlaceOrder, error) {
+	ack := common.PlaceOrder{ClOrdId: req.ClOrdID, Tag: req.Tag}
+	sz, err := strconv.ParseFloat(req.Sz, 64)
+	if err != nil || sz <= 0 {
+		return ack, fmt.Errorf("invalid sz %q", req.Sz)
+	}
+	var px float64
+	switch req.OrdType {
+	case OrdTypeMarket:
+	case OrdTypeLimit, OrdTypePostOnly, OrdTypeIOC, OrdTypeFOK:
+		if px, err = strconv.ParseFloat(req.Px, 64); err != nil || px <= 0 {
+			return ack, fmt.Errorf("invalid px %q", req.Px)
+		}
+	default:
+		return ack, fmt.Errorf("unsupported ordType %q", req.OrdType)
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return ack, fmt.Errorf("invalid side %q", req.Side)
+	}
+
+	e.lock.Lock()
+	if req.ClOrdID != "" {
+		if _, ok := e.clOrds[req.ClOrdID]; ok {
+			e.lock.Unlock()
+			return ack, fmt.Errorf("duplicated clOrdId %q", req.ClOrdID)
+		}
+	}
+	e.seq++
+	now := e.now()
+	o := &order{
+		req:        req,
+		inst:       e.instrument(req.InstID),
+		ordId:      strconv.FormatInt(e.seq, 10),
+		px:         px,
+		sz:         sz,
+		state:      "",
+		cTime:      now.UnixMilli(),
+		uTime:      now.UnixMilli(),
+		activateAt: now.Add(e.cfg.Latency),
+	}
+	if err := e.freeze(o); err != nil {
+		e.lock.Unlock()
+		return ack, err
+	}
+	ack.OrdId = o.ordId
+	e.orders[o.ordId] = o
+	if req.ClOrdID != "" {
+		e.clOrds[req.ClOrdID] = o.ordId
+	}
+	e.pending = append(e.pending, o)
+	var ev events
+	if e.cfg.Latency == 0 {
+		e.activate(&ev)
+	}
+	e.lock.Unlock()
+	e.dispatch(ev)
+
+	if e.cfg.Latency > 0 && e.cfg.Clock == nil {
+		time.AfterFunc(e.cfg.Latency, e.Advance)
+	}
+	return ack, nil
+}
+
+func (e *Engine) cancel(req common.CancelOrderReq) (common.PlaceOrder, error) {
+	ack := common.PlaceOrder{ClOrdId: req.ClOrdId, OrdId: req.OrdId}
+	e.lock.Lock()
+	ordId := req.OrdId
+	if ordId == "" {
+		ordId = e.clOrds[req.ClOrdId]
+	}
+	o, ok := e.orders[ordId]
+	if !ok || (req.InstId != "" && o.req.InstID != req.InstId) {
+		e.lock.Unlock()
+		return ack, ErrUnknownOrder
+	}
+	ack.OrdId, ack.ClOrdId = o.ordId, o.req.ClOrdID
+	if o.state == "filled" || o.state == "canceled" {
+		e.lock.Unlock()
+		return ack, fmt.Errorf("order %s already %s", o.ordId, o.state)
+	}
+	var ev events
+	e.finish(o, "canceled", &ev)
+	e.lock.Unlock()
+	e.dispatch(ev)
+	return ack, nil
+}
+
+// activate 将延迟到期的订单送入撮合 调用方需持有锁
+func (e *Engine) activate(ev *events) {
+	now := e.now()
+	var remain []*order
+	for _, o := range e.pending {
+		if o.state == "canceled" {
+			continue
+		}
+		if o.activateAt.After(now) {
+			remain = append(remain, o)
+			continue
+		}
+		e.execute(o, ev)
+	}
+	e.pending = remain
+}
+
+func (e *Engine) execute(o *order, ev *events) {
+	b := e.books[o.req.InstID]
+	if b == nil {
+		b = &book{}
+	}
+	levels := &b.asks
+	if o.req.Side == "sell" {
+		levels = &b.bids
+	}
+	switch o.req.OrdType {
+	case OrdTypeMarket:
+		e.matchLevels(o, levels, false, ev)
+		if o.state != "filled" {
+			e.finish(o, "canceled", ev)
+		}
+	case OrdTypeLimit:
+		e.matchLevels(o, levels, false, ev)
+		if o.state == "" {
+			e.update(o, "live", nil, ev)
+		}
+	case OrdTypePostOnly:
+		if len(*levels) != 0 && o.marketable((*levels)[0].px) {
+			e.finish(o, "canceled", ev)
+			return
+		}
+		e.update(o, "live", nil, ev)
+	case OrdTypeIOC:
+		e.matchLevels(o, levels, false, ev)
+		if o.state != "filled" {
+			e.finish(o, "canceled", ev)
+		}
+	case OrdTypeFOK:
+		var available float64
+		for _, l := range *levels {
+			if !o.marketable(l.px) {
+				break
+			}
+			available += l.sz
+		}
+		if available+1e-12 < o.sz {
+			e.finish(o, "canceled", ev)
+			return
+		}
+		e.matchLevels(o, levels, false, ev)
+	}
+}
+
+// matchLevels 依次吃掉对手盘 成交后扣减深度避免重复使用流动性
+func (e *Engine) matchLevels(o *order, levels *[]level, maker bool, ev *events) {
+	for len(*levels) != 0 && !o.filled() {
+		l := &(*levels)[0]
+		if !o.marketable(l.px) {
+			return
+		}
+		px := l.px
+		if maker {
+			px = o.px
+		}
+		qty := math.Min(l.sz, o.sz-o.accFill)
+		if o.quoteSz() {
+			qty = math.Min(l.sz, (o.sz-o.fillValue)/px)
+		}
+		if inst := o.inst; o.req.Side == "buy" && o.frozenCcy == "" && (inst.InstType == "SPOT" || inst.InstType == "MARGIN") {
+			_, quote := ccys(inst)
+			b := e.balance(quote)
+			qty = math.Min(qty, (b.cash-b.frozen)/(px*(1+e.cfg.TakerFee)))
+		}
+		if qty <= 1e-12 {
+			return
+		}
+		l.sz -= qty
+		if l.sz <= 1e-12 {
+			*levels = (*levels)[1:]
+		}
+		e.fill(o, px, qty, maker, ev)
+		if o.state == "filled" || o.state == "canceled" {
+			return
+		}
+	}
+}
+
+// quoteSz sz是否以计价货币表示 现货市价买单未指定tgtCcy时默认为quote_ccy
+func (o *order) quoteSz() bool {
+	if o.req.OrdType != OrdTypeMarket {
+		return false
+	}
+	return o.req.TgtCcy == "quote_ccy" || o.req.TgtCcy == "" && o.req.Side == "buy" && o.inst.InstType == "SPOT"
+}
+
+// filled sz按计价货币表示时以成交金额判断
+func (o *order) filled() bool {
+	if o.quoteSz() {
+		return o.sz-o.fillValue <= 1e-9
+	}
+	return o.sz-o.accFill <= 1e-12
+}
+
+func (o *order) marketable(px float64) bool {
+	if o.req.OrdType == OrdTypeMarket {
+		return true
+	}
+	if o.req.Side == "buy" {
+		return px <= o.px
+	}
+	return px >= o.px
+}
+
+func (e *Engine) fill(o *order, px, sz float64, maker bool, ev *events) {
+	inst := o.inst
+	rate := e.cfg.TakerFee
+	if maker {
+		rate = e.cfg.MakerFee
+	}
+	ctVal := ctValue(inst)
+	notional := px * sz * ctVal
+	if inst.CtType == "inverse" {
+		notional = sz * ctVal / px
+	}
+	f := fill{px: px, sz: sz, fee: -notional * rate, maker: maker}
+	e.tradeSeq++
+	f.tradeId = strconv.FormatInt(e.tradeSeq, 10)
+
+	base, quote := ccys(inst)
+	if inst.InstType == "SPOT" || inst.InstType == "MARGIN" {
+		o.feeCcy = quote
+		if o.req.Side == "buy" {
+			e.unfreeze(o, o.px*sz*(1+e.cfg.TakerFee))
+			e.balance(quote).cash -= notional - f.fee
+			e.balance(base).cash += sz
+		} else {
+			e.unfreeze(o, sz)
+			e.balance(base).cash -= sz
+			e.balance(quote).cash += notional + f.fee
+		}
+	} else {
+		o.feeCcy = settleCcy(inst)
+		if inst.CtType == "inverse" {
+			o.feeCcy = base
+		}
+	}
+
+	o.accFill += sz
+	o.fillValue += px * sz
+	o.fee += f.fee
+	state := "partially_filled"
+	if o.filled() {
+		state = "filled"
+	}
+	e.update(o, state, &f, ev)
+
+	if inst.InstType != "SPOT" && inst.InstType != "MARGIN" {
+		// 衍生品以结算币种记账已实现盈亏
+		posSide := o.req.PosSide
+		if posSide == "" {
+			posSide = okx.PosSideNet
+		}
+		before, _ := e.positions.Position(inst.InstId, posSide)
+		e.positions.OnOrder(o.toCommon(&f))
+		after, _ := e.positions.Position(inst.InstId, posSide)
+		e.balance(o.feeCcy).cash += after.RealizedPnl - before.RealizedPnl + f.fee
+		ev.positions = append(ev.positions, e.commonPosition(after))
+	}
+	if state == "filled" {
+		e.release(o)
+	}
+	ev.account = true
+}
+
+func (e *Engine) update(o *order, state string, f *fill, ev *events) {
+	o.state = state
+	o.uTime = e.now().UnixMilli()
+	ev.orders = append(ev.orders, o.toCommon(f))
+}
+
+func (e *Engine) finish(o *order, state string, ev *events) {
+	e.release(o)
+	e.update(o, state, nil, ev)
+	ev.account = true
+}
+
+// freeze 现货下单冻结资金 衍生品不做保证金校验
+func (e *Engine) freeze(o *order) error {
+	inst := o.inst
+	if inst.InstType != "SPOT" && inst.InstType != "MARGIN" {
+		return nil
+	}
+	base, quote := ccys(inst)
+	var amount float64
+	ccy := quote
+	switch {
+	case o.req.Side == "sell":
+		ccy, amount = base, o.sz
+	case o.req.OrdType == OrdTypeMarket:
+		// 市价买单成交时按实际可用资金检查
+		return nil
+	default:
+		amount = o.px * o.sz * (1 + e.cfg.TakerFee)
+	}
+	b := e.balance(ccy)
+	if b.cash-b.frozen < amount-1e-12 {
+		return ErrInsufficient
+	}
+	b.frozen += amount
+	o.frozen, o.frozenCcy = amount, ccy
+	return nil
+}
+
+func (e *Engine) unfreeze(o *order, amount float64) {
+	if o.frozenCcy == "" {
+		return
+	}
+	amount = math.Min(amount, o.frozen)
+	o.frozen -= amount
+	e.balance(o.frozenCcy).frozen -= amount
+}
+
+func (e *Engine) release(o *order) {
+	e.unfreeze(o, o.frozen)
+}
+
+func (e *Engine) balance(ccy string) *balance {
+	b, ok := e.balances[ccy]
+	if !ok {
+		b = &balance{}
+		e.balances[ccy] = b
+	}
+	return b
+}
+
+func (e *Engine) restingOrders(instId string) []*order {
+	var orders []*order
+	for _, o := range e.orders {
+		if o.req.InstID == instId && (o.state == "live" || o.state == "partially_filled") {
+			orders = append(orders, o)
+		}
+	}
+	// 价格优先 时间优先
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].px != orders[j].px {
+			return (orders[i].req.Side == "buy") == (orders[i].px > orders[j].px)
+		}
+		return orders[i].cTime < orders[j].cTime
+	})
+	return orders
+}
+
+func (e *Engine) instrument(instId string) common.Instruments {
+	if inst, ok := e.insts[instId]; ok {
+		return inst
+	}
+	inst := common.Instruments{InstId: instId, InstType: "SPOT", CtVal: "1"}
+	parts := strings.Split(instId, "-")
+	if len(parts) >= 2 {
+		inst.BaseCcy, inst.QuoteCcy = parts[0], parts[1]
+		inst.SettleCcy = parts[1]
+	}
+	switch {
+	case len(parts) == 3 && parts[2] == "SWAP":
+		inst.InstType = "SWAP"
+	case len(parts) == 3:
+		inst.InstType = "FUTURES"
+	}
+	e.insts[instId] = inst
+	return inst
+}
+
+func (e *Engine) commonPosition(p okx.TrackedPosition) *common.Position {
+	return &common.Position{
+		InstId:      p.InstId,
+		InstType:    p.InstType,
+		PosSide:     p.PosSide,
+		Pos:         formatFloat(p.Pos),
+		AvgPx:       formatFloat(p.AvgPx),
+		MarkPx:      formatFloat(p.MarkPx),
+		Upl:         formatFloat(p.UnrealizedPnl),
+		RealizedPnl: formatFloat(p.RealizedPnl),
+		UTime:       strconv.FormatInt(e.now().UnixMilli(), 10),
+	}
+}
+
+func (e *Engine) commonBalance(ccy string) *common.Balance {
+	var details []common.BalanceDetail
+	ccys := make([]string, 0, len(e.balances))
+	for c := range e.balances {
+		if ccy == "" || ccy == c {
+			ccys = append(ccys, c)
+		}
+	}
+	sort.Strings(ccys)
+	uTime := strconv.FormatInt(e.now().UnixMilli(), 10)
+	for _, c := range ccys {
+		b := e.balances[c]
+		details = append(details, common.BalanceDetail{
+			Ccy:       c,
+			CashBal:   formatFloat(b.cash),
+			Eq:        formatFloat(b.cash),
+			AvailBal:  formatFloat(b.cash - b.frozen),
+			AvailEq:   formatFloat(b.cash - b.frozen),
+			FrozenBal: formatFloat(b.frozen),
+			OrdFrozen: formatFloat(b.frozen),
+			UTime:     uTime,
+		})
+	}
+	return &common.Balance{Details: details, UTime: uTime}
+}
+
+func (o *order) toCommon(f *fill) *common.Order {
+	c := &common.Order{
+		InstId:    o.req.InstID,
+		InstType:  o.inst.InstType,
+		OrdId:     o.ordId,
+		ClOrdId:   o.req.ClOrdID,
+		Tag:       o.req.Tag,
+		Px:        o.req.Px,
+		Sz:        o.req.Sz,
+		OrdType:   o.req.OrdType,
+		Side:      o.req.Side,
+		PosSide:   o.req.PosSide,
+		TdMode:    o.req.TdMode,
+		TgtCcy:    o.req.TgtCcy,
+		AccFillSz: formatFloat(o.accFill),
+		Fee:       formatFloat(o.fee),
+		FeeCcy:    o.feeCcy,
+		State:     o.state,
+		CTime:     strconv.FormatInt(o.cTime, 10),
+		UTime:     strconv.FormatInt(o.uTime, 10),
+	}
+	if o.state == "" {
+		c.State = "live"
+	}
+	if o.accFill > 0 {
+		c.AvgPx = formatFloat(o.fillValue / o.accFill)
+	}
+	if f != nil {
+		c.TradeId = f.tradeId
+		c.FillPx = formatFloat(f.px)
+		c.FillSz = formatFloat(f.sz)
+		c.FillFee = formatFloat(f.fee)
+		c.FillFeeCcy = o.feeCcy
+		c.FillTime = c.UTime
+		c.ExecType = "T"
+		if f.maker {
+			c.ExecType = "M"
+		}
+	}
+	return c
+}
+
+func (b *book) mid() float64 {
+	if len(b.bids) == 0 || len(b.asks) == 0 {
+		return 0
+	}
+	return (b.bids[0].px + b.asks[0].px) / 2
+}
+
+func toLevels(spreads []common.Spread) []level {
+	levels := make([]level, 0, len(spreads))
+	for _, s := range spreads {
+		px, err1 := strconv.ParseFloat(s.Price, 64)
+		sz, err2 := strconv.ParseFloat(s.Count, 64)
+		if err1 != nil || err2 != nil || sz <= 0 {
+			continue
+		}
+		levels = append(levels, level{px: px, sz: sz})
+	}
+	return levels
+}
+
+// mergeLevels 按价格合并增量档位 desc为价格降序
+func mergeLevels(levels []level, spreads []common.Spread, desc bool) []level {
+	for _, s := range spreads {
+		px, err1 := strconv.ParseFloat(s.Price, 64)
+		sz, err2 := strconv.ParseFloat(s.Count, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		i := sort.Search(len(levels), func(i int) bool {
+			if desc {
+				return levels[i].px <= px
+			}
+			return levels[i].px >= px
+		})
+		switch {
+		case i < len(levels) && levels[i].px == px && sz <= 0:
+			levels = append(levels[:i], levels[i+1:]...)
+		case i < len(levels) && levels[i].px == px:
+			levels[i].sz = sz
+		case sz > 0:
+			levels = append(levels, level{})
+			copy(levels[i+1:], levels[i:])
+			levels[i] = level{px: px, sz: sz}
+		}
+	}
+	return levels
+}
+
+func ctValue(inst common.Instruments) float64 {
+	if inst.InstType == "SPOT" || inst.InstType == "MARGIN" {
+		return 1
+	}
+	v, err := strconv.ParseFloat(inst.CtVal, 64)
+	if err != nil || v <= 0 {
+		return 1
+	}
+	return v
+}
+
+func ccys(inst common.Instruments) (base, quote string) {
+	base, quote = inst.BaseCcy, inst.QuoteCcy
+	if parts := strings.Split(inst.InstId, "-"); len(parts) >= 2 {
+		if base == "" {
+			base = parts[0]
+		}
+		if quote == "" {
+			quote = parts[1]
+		}
+	}
+	return base, quote
+}
+
+func settleCcy(inst common.Instruments) string {
+	if inst.SettleCcy != "" {
+		return inst.SettleCcy
+	}
+	_, quote := ccys(inst)
+	return quote
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,168 @@
+package paper
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/okx"
+)
+
+func spotBook() *common.OrderBook {
+	return &common.OrderBook{
+		Bids: []common.Spread{{Price: "99", Count: "1"}, {Price: "98", Count: "2"}},
+		Asks: []common.Spread{{Price: "101", Count: "1"}, {Price: "102", Count: "2"}},
+	}
+}
+
+func TestEngineSpot(t *testing.T) {
+	e := NewEngine(Config{Balances: map[string]float64{"USDT": 1000}, TakerFee: 0.001, MakerFee: 0.0005})
+	e.OnBook("BTC-USDT", spotBook())
+	rest := e.Rest()
+	ctx := context.Background()
+
+	// 市价买单吃两档
+	_, err := rest.PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "market", TgtCcy: "base_ccy", Sz: "2", ClOrdID: "m1"})
+	assert.NoError(t, err)
+	o, err := rest.GetOrder(ctx, common.PlaceOrderReq{ClOrdID: "m1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "filled", o.Data[0].State)
+	assert.Equal(t, "101.5", o.Data[0].AvgPx)
+
+	// post_only穿价被撤销
+	_, err = rest.PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "post_only", Px: "102", Sz: "1", ClOrdID: "p1"})
+	assert.NoError(t, err)
+	o, _ = rest.GetOrder(ctx, common.PlaceOrderReq{ClOrdID: "p1"})
+	assert.Equal(t, "canceled", o.Data[0].State)
+
+	// fok深度不足被撤销
+	_, _ = rest.PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "sell", OrdType: "fok", Px: "99", Sz: "2", ClOrdID: "f1"})
+	o, _ = rest.GetOrder(ctx, common.PlaceOrderReq{ClOrdID: "f1"})
+	assert.Equal(t, "canceled", o.Data[0].State)
+
+	// 限价挂单由成交穿价撮合
+	_, err = rest.PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "sell", OrdType: "limit", Px: "105", Sz: "1", ClOrdID: "l1"})
+	assert.NoError(t, err)
+	pending, _ := rest.OrdersPending(ctx, common.OrdersPendingReq{})
+	assert.Len(t, pending.Data, 1)
+	e.OnTrade(&common.Trade{InstId: "BTC-USDT", Px: "106", Sz: "5", Side: "buy"})
+	o, _ = rest.GetOrder(ctx, common.PlaceOrderReq{ClOrdID: "l1"})
+	assert.Equal(t, "filled", o.Data[0].State)
+
+	bal, _ := rest.Balance(ctx, "BTC")
+	assert.Equal(t, "1", bal.Data[0].Details[0].CashBal)
+	bal, _ = rest.Balance(ctx, "USDT")
+	// 1000 - 203*1.001 + 105*(1-0.0005)
+	assert.InDelta(t, 1000-203*1.001+105*0.9995, parse(bal.Data[0].Details[0].CashBal), 1e-9)
+
+	// 余额不足
+	_, err = rest.PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "sell", OrdType: "limit", Px: "105", Sz: "5"})
+	assert.ErrorIs(t, err, ErrInsufficient)
+}
+
+func TestEngineSwapWithOrderManager(t *testing.T) {
+	now := time.UnixMilli(1_000)
+	e := NewEngine(Config{
+		Balances:    map[string]float64{"USDT": 1000},
+		Latency:     100 * time.Millisecond,
+		Clock:       func() time.Time { return now },
+		Instruments: []common.Instruments{{InstId: "BTC-USDT-SWAP", InstType: "SWAP", CtVal: "0.1", SettleCcy: "USDT"}},
+	})
+	e.OnBook("BTC-USDT-SWAP", spotBook())
+
+	m := okx.NewOrderManager(e.Rest(), e.Private(), "t")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = e.Private().Orders(ctx, "SWAP", func(resp *common.WsResp[*common.Order]) {
+			for _, o := range resp.Data {
+				m.OnOrder(o)
+			}
+		})
+	}()
+	assert.Eventually(t, func() bool {
+		e.subs.lock.RLock()
+		defer e.subs.lock.RUnlock()
+		return len(e.subs.orders) == 1
+	}, time.Second, time.Millisecond)
+
+	o, err := m.Place(ctx, common.PlaceOrderReq{InstID: "BTC-USDT-SWAP", Side: "buy", OrdType: "ioc", Px: "101", Sz: "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, okx.OrderPendingNew, o.State)
+
+	// 模拟时钟推进后才进入撮合
+	now = now.Add(200 * time.Millisecond)
+	e.Advance()
+	o, _ = m.Get(o.ClOrdId)
+	assert.Equal(t, okx.OrderCanceled, o.State)
+	assert.Equal(t, "1", o.Order.AccFillSz)
+
+	pos, _ := e.Rest().AccountPositions(ctx, common.PositionReq{})
+	assert.Equal(t, "1", pos.Data[0].Pos)
+	cancel()
+	wg.Wait()
+}
+
+func TestEngineQuoteMarketBuy(t *testing.T) {
+	e := NewEngine(Config{Balances: map[string]float64{"USDT": 1000}})
+	e.OnBook("BTC-USDT", spotBook())
+	e.OnBook("DOGE-USDT", &common.OrderBook{Asks: []common.Spread{{Price: "0.1", Count: "1000"}}})
+	rest := e.Rest()
+	ctx := context.Background()
+
+	// 现货市价买单未指定tgtCcy时sz为计价货币
+	_, err := rest.PlaceOrder(ctx, common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "market", Sz: "152", ClOrdID: "q1"})
+	assert.NoError(t, err)
+	o, _ := rest.GetOrder(ctx, common.PlaceOrderReq{ClOrdID: "q1"})
+	assert.Equal(t, "filled", o.Data[0].State)
+	assert.Equal(t, "1.5", o.Data[0].AccFillSz)
+
+	// 成交数量大于计价金额时仍按成交金额判断
+	_, err = rest.PlaceOrder(ctx, common.PlaceOrderReq{InstID: "DOGE-USDT", Side: "buy", OrdType: "market", Sz: "20", ClOrdID: "q2"})
+	assert.NoError(t, err)
+	o, _ = rest.GetOrder(ctx, common.PlaceOrderReq{ClOrdID: "q2"})
+	assert.Equal(t, "filled", o.Data[0].State)
+	assert.Equal(t, "200", o.Data[0].AccFillSz)
+
+	bal, _ := rest.Balance(ctx, "USDT")
+	assert.InDelta(t, 1000-152-20, parse(bal.Data[0].Details[0].CashBal), 1e-9)
+}
+
+func TestEngineBookUpdate(t *testing.T) {
+	e := NewEngine(Config{Balances: map[string]float64{"USDT": 1000}})
+	// 收到快照前的增量被忽略
+	e.OnBookAction("BTC-USDT", "update", &common.OrderBook{Asks: []common.Spread{{Price: "90", Count: "1"}}})
+	assert.Nil(t, e.books["BTC-USDT"])
+
+	e.OnBookAction("BTC-USDT", "snapshot", spotBook())
+	e.OnBookAction("BTC-USDT", "update", &common.OrderBook{
+		Bids: []common.Spread{{Price: "99", Count: "0"}, {Price: "98.5", Count: "3"}},
+		Asks: []common.Spread{{Price: "101", Count: "4"}, {Price: "103", Count: "1"}, {Price: "100.5", Count: "0"}},
+	})
+	b := e.books["BTC-USDT"]
+	assert.Equal(t, []level{{98.5, 3}, {98, 2}}, b.bids)
+	assert.Equal(t, []level{{101, 4}, {102, 2}, {103, 1}}, b.asks)
+
+	_, err := e.Rest().PlaceOrder(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "limit", Px: "101", Sz: "3", ClOrdID: "l1"})
+	assert.NoError(t, err)
+	o, _ := e.Rest().GetOrder(context.Background(), common.PlaceOrderReq{ClOrdID: "l1"})
+	assert.Equal(t, "filled", o.Data[0].State)
+	assert.Equal(t, []level{{101, 1}, {102, 2}, {103, 1}}, b.asks)
+
+	// 快照替换全部档位
+	e.OnBookAction("BTC-USDT", "snapshot", spotBook())
+	assert.Equal(t, []level{{101, 1}, {102, 2}}, e.books["BTC-USDT"].asks)
+}
+
+func parse(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
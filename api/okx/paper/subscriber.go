@@ -0,0 +1,105 @@
+package paper
+
+import (
+	"sync"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// events 一次撮合产生的推送 释放引擎锁后统一分发
+type events struct {
+	orders    []*common.Order
+	positions []*common.Position
+	account   bool
+}
+
+type subscription[T any] struct {
+	instType string
+	callback func(resp *common.WsResp[T])
+}
+
+type subscribers struct {
+	lock      sync.RWMutex
+	seq       int
+	orders    map[int]subscription[*common.Order]
+	positions map[int]subscription[*common.Position]
+	account   map[int]subscription[*common.Balance]
+}
+
+func newSubscribers() *subscribers {
+	return &subscribers{
+		orders:    map[int]subscription[*common.Order]{},
+		positions: map[int]subscription[*common.Position]{},
+		account:   map[int]subscription[*common.Balance]{},
+	}
+}
+
+func add[T any](s *subscribers, m map[int]subscription[T], sub subscription[T]) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.seq++
+	m[s.seq] = sub
+	return s.seq
+}
+
+func remove[T any](s *subscribers, m map[int]subscription[T], id int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(m, id)
+}
+
+func removeByInstType[T any](s *subscribers, m map[int]subscription[T], instType string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, sub := range m {
+		if sub.instType == instType {
+			delete(m, id)
+		}
+	}
+}
+
+func publish[T any](s *subscribers, m map[int]subscription[T], channel string, data []T, instType func(T) string) {
+	if len(data) == 0 {
+		return
+	}
+	s.lock.RLock()
+	var subs []subscription[T]
+	for _, sub := range m {
+		subs = append(subs, sub)
+	}
+	s.lock.RUnlock()
+
+	for _, sub := range subs {
+		var matched []T
+		for _, d := range data {
+			if sub.instType == "" || sub.instType == "ANY" || sub.instType == instType(d) {
+				matched = append(matched, d)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		sub.callback(&common.WsResp[T]{
+			Arg:  common.Arg{Channel: channel, InstType: sub.instType},
+			Data: matched,
+		})
+	}
+}
+
+// dispatch 分发推送 需在释放引擎锁后调用
+func (e *Engine) dispatch(ev events) {
+	for _, o := range ev.orders {
+		e.OnOrder(o)
+	}
+	publish(e.subs, e.subs.orders, "orders", ev.orders, func(o *common.Order) string { return o.InstType })
+	publish(e.subs, e.subs.positions, "positions", ev.positions, func(p *common.Position) string { return p.InstType })
+	if ev.account {
+		e.lock.Lock()
+		b := e.commonBalance("")
+		e.lock.Unlock()
+		publish(e.subs, e.subs.account, "account", []*common.Balance{b}, func(b *common.Balance) string { return "" })
+	}
+}
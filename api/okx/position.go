@@ -0,0 +1,415 @@
+package okx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+const (
+	PosSideNet   = "net"
+	PosSideLong  = "long"
+	PosSideShort = "short"
+)
+
+// TrackedPosition 本地根据成交计算的持仓
+type TrackedPosition struct {
+	InstId        string
+	InstType      string
+	PosSide       string
+	Pos           float64 // 净持仓为带符号数量 合约为张数 现货/杠杆为币数
+	AvgPx         float64
+	MarkPx        float64
+	RealizedPnl   float64
+	UnrealizedPnl float64
+	Fees          map[string]float64 // 按手续费币种累计 与交易所一致负数为扣除
+	UTime         int64
+}
+
+// instrumentSpec 计算盈亏所需的合约参数
+type instrumentSpec struct {
+	instType string
+	ctVal    float64
+	inverse  bool
+}
+
+// PositionDiff 本地持仓与交易所持仓的差异
+type PositionDiff struct {
+	InstId   string
+	PosSide  string
+	Local    float64
+	Exchange float64
+}
+
+// orderFills 订单已计入的成交与累计手续费 订单结束后移除
+type orderFills struct {
+	trades map[string]struct{}
+	fee    float64
+}
+
+// PositionTracker 由orders频道成交及mark-price推送维护持仓与盈亏
+type PositionTracker struct {
+	// FinishedTTL 订单结束后忽略其重复推送的时长 默认10分钟
+	FinishedTTL time.Duration
+
+	rest      RestTrader
+	lock      sync.RWMutex
+	now       func() time.Time
+	positions map[string]*TrackedPosition
+	specs     map[string]instrumentSpec
+	orders    map[string]*orderFills
+	finished  map[string]time.Time
+	pruned    time.Time
+}
+
+func NewPositionTracker(rest RestTrader) *PositionTracker {
+	return &PositionTracker{
+		FinishedTTL: 10 * time.Minute,
+		rest:        rest,
+		now:         time.Now,
+		positions:   map[string]*TrackedPosition{},
+		specs:       map[string]instrumentSpec{},
+		orders:      map[string]*orderFills{},
+		finished:    map[string]time.Time{},
+	}
+}
+
+// LoadInstruments 从产品信息加载合约面值 rest需提供Instruments 如*RestClient
+func (t *PositionTracker) LoadInstruments(ctx context.Context, instType string) error {
+	src, ok := t.rest.(interface {
+		Instruments(ctx context.Context, req common.InstrumentsReq) (*common.Resp[common.Instruments], error)
+	})
+	if !ok {
+		return errors.New("position tracker: rest does not provide instruments")
+	}
+	rp, err := src.Instruments(ctx, common.InstrumentsReq{InstType: instType})
+	if err != nil {
+		return err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, inst := range rp.Data {
+		t.specs[inst.InstId] = toInstrumentSpec(inst)
+	}
+	return nil
+}
+
+// SetInstrument 手动设置产品信息
+func (t *PositionTracker) SetInstrument(inst common.Instruments) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.specs[inst.InstId] = toInstrumentSpec(inst)
+}
+
+func toInstrumentSpec(inst common.Instruments) instrumentSpec {
+	spec := instrumentSpec{instType: inst.InstType, ctVal: 1}
+	if v, err := strconv.ParseFloat(inst.CtVal, 64); err == nil && v > 0 {
+		spec.ctVal = v
+		if m, err := strconv.ParseFloat(inst.CtMult, 64); err == nil && m > 0 {
+			spec.ctVal *= m
+		}
+	}
+	spec.inverse = inst.CtType == "inverse"
+	return spec
+}
+
+// OnOrder 处理订单推送中的成交 同一订单的tradeId只计算一次 订单结束后不再处理其推送
+func (t *PositionTracker) OnOrder(o *common.Order) {
+	if o == nil {
+		return
+	}
+	ordKey := o.InstId + "-" + o.OrdId
+	if o.OrdId == "" {
+		ordKey = o.InstId + "-" + o.ClOrdId
+	}
+	final := o.State == "filled" || o.State == "canceled" || o.State == "mmp_canceled"
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.now()
+	t.prune(now)
+	if _, ok := t.finished[ordKey]; ok {
+		return
+	}
+	if o.TradeId != "" {
+		t.fill(ordKey, o)
+	}
+	if final {
+		delete(t.orders, ordKey)
+		t.finished[ordKey] = now
+	}
+}
+
+// fill 计入一笔成交 调用方需持有锁
+func (t *PositionTracker) fill(ordKey string, o *common.Order) {
+	px, err1 := strconv.ParseFloat(o.FillPx, 64)
+	sz, err2 := strconv.ParseFloat(o.FillSz, 64)
+	if err1 != nil || err2 != nil || sz == 0 {
+		return
+	}
+	ts, _ := strconv.ParseInt(o.FillTime, 10, 64)
+
+	of, ok := t.orders[ordKey]
+	if !ok {
+		of = &orderFills{trades: map[string]struct{}{}}
+		t.orders[ordKey] = of
+	}
+	if _, ok := of.trades[o.TradeId]; ok {
+		return
+	}
+	of.trades[o.TradeId] = struct{}{}
+	fee := of.fillFee(o)
+
+	posSide := o.PosSide
+	if posSide == "" || o.InstType == "SPOT" {
+		posSide = PosSideNet
+	}
+	p := t.position(o.InstId, o.InstType, posSide)
+	delta := sz
+	if o.Side == "sell" {
+		delta = -sz
+	}
+	spec := t.spec(o.InstId, o.InstType)
+	p.RealizedPnl += spec.apply(p, delta, px)
+	if feeCcy := o.FillFeeCcy; feeCcy != "" {
+		p.Fees[feeCcy] += fee
+	} else if o.FeeCcy != "" {
+		p.Fees[o.FeeCcy] += fee
+	}
+	p.UnrealizedPnl = spec.upl(p)
+	if ts > p.UTime {
+		p.UTime = ts
+	}
+}
+
+// fillFee 单笔成交手续费 推送缺少fillFee时取订单累计手续费fee的增量
+func (of *orderFills) fillFee(o *common.Order) float64 {
+	prev := of.fee
+	accFee, accErr := strconv.ParseFloat(o.Fee, 64)
+	if accErr == nil {
+		of.fee = accFee
+	}
+	if v, err := strconv.ParseFloat(o.FillFee, 64); err == nil {
+		return v
+	}
+	if accErr != nil {
+		return 0
+	}
+	return accFee - prev
+}
+
+// prune 移除超过FinishedTTL的已结束订单 每分钟最多执行一次 调用方需持有锁
+func (t *PositionTracker) prune(now time.Time) {
+	if now.Sub(t.pruned) < time.Minute {
+		return
+	}
+	t.pruned = now
+	for key, at := range t.finished {
+		if now.Sub(at) > t.FinishedTTL {
+			delete(t.finished, key)
+		}
+	}
+}
+
+// OnMarkPrice 更新标记价格并重新计算未实现盈亏
+func (t *PositionTracker) OnMarkPrice(mp *common.MarkPrice) {
+	if mp == nil {
+		return
+	}
+	px, err := strconv.ParseFloat(mp.MarkPx, 64)
+	if err != nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, p := range t.positions {
+		if p.InstId != mp.InstId {
+			continue
+		}
+		p.MarkPx = px
+		p.UnrealizedPnl = t.spec(p.InstId, p.InstType).upl(p)
+	}
+}
+
+// Position 获取单个持仓
+func (t *PositionTracker) Position(instId, posSide string) (TrackedPosition, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	p, ok := t.positions[instId+"-"+posSide]
+	if !ok {
+		return TrackedPosition{}, false
+	}
+	return copyPosition(p), true
+}
+
+// Positions 获取全部持仓
+func (t *PositionTracker) Positions() []TrackedPosition {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	positions := make([]TrackedPosition, 0, len(t.positions))
+	for _, p := range t.positions {
+		positions = append(positions, copyPosition(p))
+	}
+	return positions
+}
+
+// Verify 与交易所持仓对比 仅比较衍生品及杠杆持仓数量
+// 双向持仓模式下交易所的空仓数量为正数 按绝对值比较
+func (t *PositionTracker) Verify(ctx context.Context) ([]PositionDiff, error) {
+	rp, err := t.rest.AccountPositions(ctx, common.PositionReq{})
+	if err != nil {
+		return nil, err
+	}
+	exchange := map[string]common.Position{}
+	for _, p := range rp.Data {
+		exchange[p.InstId+"-"+p.PosSide] = p
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var diffs []PositionDiff
+	for key, p := range t.positions {
+		if p.InstType == "SPOT" {
+			continue
+		}
+		var remote float64
+		if ep, ok := exchange[key]; ok {
+			remote, _ = strconv.ParseFloat(ep.Pos, 64)
+			delete(exchange, key)
+		}
+		local := p.Pos
+		if p.PosSide != PosSideNet {
+			local, remote = math.Abs(local), math.Abs(remote)
+		}
+		if !floatEqual(local, remote) {
+			diffs = append(diffs, PositionDiff{InstId: p.InstId, PosSide: p.PosSide, Local: p.Pos, Exchange: remote})
+		}
+	}
+	for _, ep := range exchange {
+		remote, _ := strconv.ParseFloat(ep.Pos, 64)
+		if remote != 0 {
+			diffs = append(diffs, PositionDiff{InstId: ep.InstId, PosSide: ep.PosSide, Exchange: remote})
+		}
+	}
+	return diffs, nil
+}
+
+// RunVerify 定期对账 阻塞直到ctx结束
+func (t *PositionTracker) RunVerify(ctx context.Context, interval time.Duration, onDiff func(diffs []PositionDiff, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			diffs, err := t.Verify(ctx)
+			if err != nil || len(diffs) != 0 {
+				onDiff(diffs, err)
+			}
+		}
+	}
+}
+
+func (t *PositionTracker) position(instId, instType, posSide string) *TrackedPosition {
+	key := instId + "-" + posSide
+	p, ok := t.positions[key]
+	if !ok {
+		p = &TrackedPosition{InstId: instId, InstType: instType, PosSide: posSide, Fees: map[string]float64{}}
+		t.positions[key] = p
+	}
+	return p
+}
+
+func (t *PositionTracker) spec(instId, instType string) instrumentSpec {
+	if spec, ok := t.specs[instId]; ok {
+		return spec
+	}
+	return instrumentSpec{instType: instType, ctVal: 1}
+}
+
+// apply 计入一笔带方向的成交 返回产生的已实现盈亏
+func (s instrumentSpec) apply(p *TrackedPosition, delta, px float64) (realized float64) {
+	if p.Pos == 0 || math.Signbit(p.Pos) == math.Signbit(delta) {
+		p.AvgPx = s.avg(math.Abs(p.Pos), p.AvgPx, math.Abs(delta), px)
+		p.Pos += delta
+		return 0
+	}
+	closed := math.Min(math.Abs(delta), math.Abs(p.Pos))
+	realized = s.pnl(closed*sign(p.Pos), p.AvgPx, px)
+	p.Pos += delta
+	switch {
+	case floatEqual(p.Pos, 0):
+		p.Pos = 0
+		p.AvgPx = 0
+	case math.Signbit(p.Pos) == math.Signbit(delta):
+		// 反手 剩余部分以成交价开仓
+		p.AvgPx = px
+	}
+	return realized
+}
+
+func (s instrumentSpec) avg(qty, avgPx, addQty, px float64) float64 {
+	if qty+addQty == 0 {
+		return 0
+	}
+	if s.inverse && avgPx > 0 && px > 0 {
+		return (qty + addQty) / (qty/avgPx + addQty/px)
+	}
+	return (qty*avgPx + addQty*px) / (qty + addQty)
+}
+
+// pnl 带方向数量从开仓价到平仓价的盈亏 反向合约以币计价
+func (s instrumentSpec) pnl(qty, openPx, closePx float64) float64 {
+	if s.inverse {
+		if openPx == 0 || closePx == 0 {
+			return 0
+		}
+		return qty * s.ctVal * (1/openPx - 1/closePx)
+	}
+	return qty * s.ctVal * (closePx - openPx)
+}
+
+func (s instrumentSpec) upl(p *TrackedPosition) float64 {
+	if p.MarkPx == 0 || p.Pos == 0 {
+		return 0
+	}
+	return s.pnl(p.Pos, p.AvgPx, p.MarkPx)
+}
+
+func copyPosition(p *TrackedPosition) TrackedPosition {
+	c := *p
+	c.Fees = make(map[string]float64, len(p.Fees))
+	for k, v := range p.Fees {
+		c.Fees[k] = v
+	}
+	return c
+}
+
+func sign(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+func floatEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func (p TrackedPosition) String() string {
+	return fmt.Sprintf("%s %s pos:%v avgPx:%v rpnl:%v upl:%v", p.InstId, p.PosSide, p.Pos, p.AvgPx, p.RealizedPnl, p.UnrealizedPnl)
+}
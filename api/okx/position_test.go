@@ -0,0 +1,131 @@
+package okx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func TestPositionTrackerSwap(t *testing.T) {
+	tracker := NewPositionTracker(nil)
+	tracker.SetInstrument(common.Instruments{InstId: "BTC-USDT-SWAP", InstType: "SWAP", CtVal: "0.01", CtType: "linear"})
+	fill := func(tradeId, side, px, sz string) {
+		tracker.OnOrder(&common.Order{
+			InstId: "BTC-USDT-SWAP", InstType: "SWAP", PosSide: "net", Side: side, TradeId: tradeId,
+			FillPx: px, FillSz: sz, FillFee: "-0.1", FillFeeCcy: "USDT",
+		})
+	}
+	fill("1", "buy", "100", "10")
+	fill("2", "buy", "200", "10")
+	// 重复推送
+	fill("2", "buy", "200", "10")
+	p, ok := tracker.Position("BTC-USDT-SWAP", PosSideNet)
+	assert.True(t, ok)
+	assert.InDelta(t, 20, p.Pos, 1e-9)
+	assert.InDelta(t, 150, p.AvgPx, 1e-9)
+
+	// 反手 平20张开10张空
+	fill("3", "sell", "250", "30")
+	p, _ = tracker.Position("BTC-USDT-SWAP", PosSideNet)
+	assert.InDelta(t, -10, p.Pos, 1e-9)
+	assert.InDelta(t, 250, p.AvgPx, 1e-9)
+	assert.InDelta(t, 20*0.01*100, p.RealizedPnl, 1e-9)
+	assert.InDelta(t, -0.3, p.Fees["USDT"], 1e-9)
+
+	tracker.OnMarkPrice(&common.MarkPrice{InstId: "BTC-USDT-SWAP", MarkPx: "240"})
+	p, _ = tracker.Position("BTC-USDT-SWAP", PosSideNet)
+	assert.InDelta(t, 10*0.01*10, p.UnrealizedPnl, 1e-9)
+}
+
+func TestPositionTrackerInverse(t *testing.T) {
+	tracker := NewPositionTracker(nil)
+	tracker.SetInstrument(common.Instruments{InstId: "BTC-USD-SWAP", InstType: "SWAP", CtVal: "100", CtType: "inverse"})
+	tracker.OnOrder(&common.Order{InstId: "BTC-USD-SWAP", InstType: "SWAP", PosSide: "long", Side: "buy", TradeId: "1", FillPx: "100", FillSz: "1"})
+	tracker.OnOrder(&common.Order{InstId: "BTC-USD-SWAP", InstType: "SWAP", PosSide: "long", Side: "sell", TradeId: "2", FillPx: "200", FillSz: "1"})
+	p, _ := tracker.Position("BTC-USD-SWAP", PosSideLong)
+	assert.InDelta(t, 0, p.Pos, 1e-9)
+	assert.InDelta(t, 100*(1.0/100-1.0/200), p.RealizedPnl, 1e-9)
+}
+
+func TestPositionTrackerOrderFills(t *testing.T) {
+	tracker := NewPositionTracker(nil)
+	now := time.UnixMilli(0)
+	tracker.now = func() time.Time { return now }
+	push := func(tradeId, state, fee string) {
+		tracker.OnOrder(&common.Order{
+			InstId: "BTC-USDT-SWAP", InstType: "SWAP", OrdId: "9", PosSide: "net", Side: "buy", State: state,
+			TradeId: tradeId, FillPx: "100", FillSz: "1", Fee: fee, FeeCcy: "USDT",
+		})
+	}
+	// 缺少fillFee时按累计手续费的增量计入
+	push("1", "partially_filled", "-0.1")
+	push("2", "partially_filled", "-0.3")
+	push("2", "partially_filled", "-0.3")
+	assert.Len(t, tracker.orders, 1)
+	push("3", "filled", "-0.6")
+	p, _ := tracker.Position("BTC-USDT-SWAP", PosSideNet)
+	assert.InDelta(t, 3, p.Pos, 1e-9)
+	assert.InDelta(t, -0.6, p.Fees["USDT"], 1e-9)
+
+	// 订单结束后移除去重状态 重复推送被忽略
+	assert.Empty(t, tracker.orders)
+	push("3", "filled", "-0.6")
+	p, _ = tracker.Position("BTC-USDT-SWAP", PosSideNet)
+	assert.InDelta(t, 3, p.Pos, 1e-9)
+
+	now = now.Add(tracker.FinishedTTL + time.Minute)
+	tracker.OnOrder(&common.Order{InstId: "BTC-USDT-SWAP", OrdId: "10", State: "canceled"})
+	assert.Len(t, tracker.finished, 1)
+	assert.Empty(t, tracker.orders)
+}
+
+func TestPositionTrackerVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v5/account/positions", r.URL.Path)
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[
+			{"instId":"BTC-USDT-SWAP","instType":"SWAP","posSide":"short","pos":"2"},
+			{"instId":"BTC-USDT-SWAP","instType":"SWAP","posSide":"long","pos":"1"},
+			{"instId":"ETH-USDT-SWAP","instType":"SWAP","posSide":"net","pos":"-3"}]}`))
+	}))
+	defer srv.Close()
+	rest, err := NewRestClientWithOptions(context.Background(), WithRestURL(common.BaseURL(srv.URL)))
+	assert.NoError(t, err)
+
+	tracker := NewPositionTracker(rest)
+	open := func(instId, posSide, side, sz string) {
+		tracker.OnOrder(&common.Order{InstId: instId, InstType: "SWAP", OrdId: instId + posSide, PosSide: posSide, Side: side, TradeId: "1", FillPx: "100", FillSz: sz})
+	}
+	// 双向持仓的空仓本地为负数 交易所为正数
+	open("BTC-USDT-SWAP", PosSideShort, "sell", "2")
+	open("BTC-USDT-SWAP", PosSideLong, "buy", "2")
+	open("ETH-USDT-SWAP", PosSideNet, "sell", "3")
+	diffs, err := tracker.Verify(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []PositionDiff{{InstId: "BTC-USDT-SWAP", PosSide: PosSideLong, Local: 2, Exchange: 1}}, diffs)
+}
+
+// fakePositions 只实现AccountPositions的RestTrader
+type fakePositions struct {
+	RestTrader
+	data []common.Position
+}
+
+func (f *fakePositions) AccountPositions(ctx context.Context, req common.PositionReq) (*common.Resp[common.Position], error) {
+	return &common.Resp[common.Position]{Code: "0", Data: f.data}, nil
+}
+
+func TestPositionTrackerFakeRest(t *testing.T) {
+	tracker := NewPositionTracker(&fakePositions{data: []common.Position{{InstId: "BTC-USDT-SWAP", PosSide: PosSideNet, Pos: "1"}}})
+	assert.Error(t, tracker.LoadInstruments(context.Background(), "SWAP"))
+
+	tracker.OnOrder(&common.Order{InstId: "BTC-USDT-SWAP", InstType: "SWAP", OrdId: "1", PosSide: PosSideNet, Side: "buy", TradeId: "1", FillPx: "100", FillSz: "1"})
+	diffs, err := tracker.Verify(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+}
@@ -2,33 +2,110 @@ package okx
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/kurosann/aqt-sdk/api/common"
 )
 
 // PlaceOrder 下单
 func (c *RestClient) PlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.Resp[common.PlaceOrder], error) {
+	if req.Tag == "" {
+		req.Tag = c.brokerCode
+	}
 	return Post[common.PlaceOrder](c, ctx, "/api/v5/trade/order", req)
 }
 
 // CancelOrder 取消挂单信息
 func (c *RestClient) CancelOrder(ctx context.Context, instId, clOrdId string) (*common.Resp[common.PlaceOrder], error) {
-	return Get[common.PlaceOrder](c, ctx, "/api/v5/trade/cancel-order", map[string]string{
+	return Post[common.PlaceOrder](c, ctx, "/api/v5/trade/cancel-order", map[string]string{
 		"clOrdId": clOrdId,
 		"instId":  instId,
 	})
 }
 
+// CancelBatchOrders 批量撤单 每次最多20个
+func (c *RestClient) CancelBatchOrders(ctx context.Context, reqs []common.CancelOrderReq) (*common.Resp[common.PlaceOrder], error) {
+	return Post[common.PlaceOrder](c, ctx, "/api/v5/trade/cancel-batch-orders", reqs)
+}
+
+// MassCancel 撤销MMP订单 仅适用于期权
+func (c *RestClient) MassCancel(ctx context.Context, req common.MassCancelReq) (*common.Resp[common.MassCancel], error) {
+	return Post[common.MassCancel](c, ctx, "/api/v5/trade/mass-cancel", req)
+}
+
+// CancelAllAfter 倒计时全部撤单 timeOut为秒 0表示取消倒计时
+func (c *RestClient) CancelAllAfter(ctx context.Context, timeOut int) (*common.Resp[common.CancelAllAfter], error) {
+	return Post[common.CancelAllAfter](c, ctx, "/api/v5/trade/cancel-all-after", map[string]string{
+		"timeOut": strconv.Itoa(timeOut),
+	})
+}
+
 // GetOrder 获取订单信息
 func (c *RestClient) GetOrder(ctx context.Context, req common.PlaceOrderReq) (*common.Resp[common.Order], error) {
 	return Get[common.Order](c, ctx, "/api/v5/trade/order", req)
 }
 
+// OrdersPending 获取未成交订单列表
+func (c *RestClient) OrdersPending(ctx context.Context, req common.OrdersPendingReq) (*common.Resp[common.Order], error) {
+	return Get[common.Order](c, ctx, "/api/v5/trade/orders-pending", req)
+}
+
+// AlgoOrdersPending 获取未完成策略委托单列表
+func (c *RestClient) AlgoOrdersPending(ctx context.Context, req common.AlgoOrdersPendingReq) (*common.Resp[common.AlgoOrder], error) {
+	return Get[common.AlgoOrder](c, ctx, "/api/v5/trade/orders-algo-pending", req)
+}
+
+// CancelAlgos 撤销策略委托订单 每次最多10个
+func (c *RestClient) CancelAlgos(ctx context.Context, reqs []common.CancelAlgoReq) (*common.Resp[common.CancelAlgo], error) {
+	return Post[common.CancelAlgo](c, ctx, "/api/v5/trade/cancel-algos", reqs)
+}
+
 // Instruments 获取产品信息
 func (c *RestClient) Instruments(ctx context.Context, req common.InstrumentsReq) (*common.Resp[common.Instruments], error) {
 	return Get[common.Instruments](c, ctx, "/api/v5/public/instruments", req)
 }
 
+// PriceLimit 获取限价
+func (c *RestClient) PriceLimit(ctx context.Context, instId string) (*common.Resp[common.PriceLimit], error) {
+	return Get[common.PriceLimit](c, ctx, "/api/v5/public/price-limit", map[string]string{
+		"instId": instId,
+	})
+}
+
+// Ticker 获取单个产品行情
+func (c *RestClient) Ticker(ctx context.Context, instId string) (*common.Resp[common.Ticker], error) {
+	return Get[common.Ticker](c, ctx, "/api/v5/market/ticker", map[string]string{
+		"instId": instId,
+	})
+}
+
+// Tickers 获取产品行情 交割与永续需指定uly或instFamily
+func (c *RestClient) Tickers(ctx context.Context, req common.TickersReq) (*common.Resp[common.Ticker], error) {
+	return Get[common.Ticker](c, ctx, "/api/v5/market/tickers", req)
+}
+
+// IndexTickers 获取指数行情
+func (c *RestClient) IndexTickers(ctx context.Context, req common.IndexTickersReq) (*common.Resp[common.IndexTicker], error) {
+	return Get[common.IndexTicker](c, ctx, "/api/v5/market/index-tickers", req)
+}
+
+// MarkPrices 获取标记价格
+func (c *RestClient) MarkPrices(ctx context.Context, req common.MarkPriceReq) (*common.Resp[common.MarkPrice], error) {
+	return Get[common.MarkPrice](c, ctx, "/api/v5/public/mark-price", req)
+}
+
+// FundingRate 获取永续合约当前资金费率
+func (c *RestClient) FundingRate(ctx context.Context, instId string) (*common.Resp[common.FundingRate], error) {
+	return Get[common.FundingRate](c, ctx, "/api/v5/public/funding-rate", map[string]string{
+		"instId": instId,
+	})
+}
+
+// OptSummary 获取期权定价 需指定uly或instFamily
+func (c *RestClient) OptSummary(ctx context.Context, req common.OptSummaryReq) (*common.Resp[common.OptSummary], error) {
+	return Get[common.OptSummary](c, ctx, "/api/v5/public/opt-summary", req)
+}
+
 // MarkPriceCandles 获取当前k线标价
 func (c *RestClient) MarkPriceCandles(ctx context.Context, req common.MarkPriceCandlesReq) (*common.Resp[common.MarkPriceCandle], error) {
 	return Get[common.MarkPriceCandle](c, ctx, "/api/v5/market/mark-price-candles", req)
@@ -85,7 +162,21 @@ func (c *RestClient) Balance(ctx context.Context, ccy string) (*common.Resp[comm
 	})
 }
 
+// AccountGreeks 查看账户希腊字母 ccy为空时返回全部币种
+func (c *RestClient) AccountGreeks(ctx context.Context, ccy string) (*common.Resp[common.AccountGreeks], error) {
+	return Get[common.AccountGreeks](c, ctx, "/api/v5/account/greeks", map[string]string{
+		"ccy": ccy,
+	})
+}
+
 // Positions 账户持仓信息
+//
+// Deprecated: 返回类型不是持仓 使用AccountPositions
 func (c *RestClient) Positions(ctx context.Context, req common.PositionReq) (*common.Resp[common.Balances], error) {
 	return Get[common.Balances](c, ctx, "/api/v5/account/positions", req)
 }
+
+// AccountPositions 账户持仓信息
+func (c *RestClient) AccountPositions(ctx context.Context, req common.PositionReq) (*common.Resp[common.Position], error) {
+	return Get[common.Position](c, ctx, "/api/v5/account/positions", req)
+}
@@ -6,117 +6,234 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"net/url"
-	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/kurosann/aqt-sdk/api/common"
 )
 
 type RestClient struct {
-	baseUrl   common.BaseURL
-	ctx       context.Context
-	client    *http.Client
-	cancel    context.CancelFunc
-	keyConfig KeyConfig
-	isTest    bool
-	limitReq  int
-	locker    sync.RWMutex
+	baseUrl    common.BaseURL
+	ctx        context.Context
+	client     *http.Client
+	cancel     context.CancelFunc
+	keyConfig  common.IKeyConfig
+	isTest     bool
+	limiter    RateLimiter
+	retry      RetryPolicy
+	userAgent  string
+	brokerCode string
+	Logger     *slog.Logger   // 为nil时使用Log或slog.Default()
+	Log        common.ILogger // Deprecated: 使用Logger Logger为nil时经common.NewSlogLogger转发
+	Metrics    common.Metrics
+	Tracer     trace.Tracer
+	locker     sync.RWMutex
 }
 
+// NewRestClientWithOptions 按Option创建REST客户端 参数错误时返回error
+func NewRestClientWithOptions(ctx context.Context, opts ...Option) (*RestClient, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	client, err := o.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c := &RestClient{
+		ctx:        ctx,
+		cancel:     cancel,
+		baseUrl:    o.restBaseURL(),
+		client:     client,
+		keyConfig:  o.signer,
+		isTest:     o.isDemo(),
+		limiter:    o.limiter,
+		retry:      o.retry,
+		userAgent:  o.userAgent,
+		brokerCode: o.brokerCode,
+		Metrics:    common.NopMetrics{},
+		Tracer:     o.tracer,
+	}
+	if o.logger != nil {
+		c.Logger = o.logger
+	}
+	if o.metrics != nil {
+		c.Metrics = o.metrics
+	}
+	return c, nil
+}
+
+// Deprecated: 参数错误时panic 使用NewRestClientWithOptions
 func NewRestClient(ctx context.Context, keyConfig KeyConfig, env common.Destination, proxy ...string) *RestClient {
 	return NewRestClientWithCustom(ctx, keyConfig, env, common.DefaultRestUrl, proxy...)
 }
 
+// Deprecated: 参数错误时panic 使用NewRestClientWithOptions
 func NewRestClientWithCustom(ctx context.Context, keyConfig KeyConfig, env common.Destination, urls map[common.Destination]common.BaseURL, proxy ...string) *RestClient {
-	ctx, cancel := context.WithCancel(ctx)
 	baseUrl, ok := urls[env]
 	if !ok {
 		panic("not support env")
 	}
-	proxyURL := http.ProxyFromEnvironment
-	if len(proxy) != 0 && proxy[0] != "" {
-		parse, err := url.Parse(proxy[0])
-		if err != nil {
-			panic(err.Error())
-		}
-		proxyURL = http.ProxyURL(parse)
-	}
-	return &RestClient{
-		ctx:       ctx,
-		cancel:    cancel,
-		baseUrl:   baseUrl,
-		keyConfig: keyConfig,
-		isTest:    env == common.TestServer,
-		client: &http.Client{
-			Transport: &http.Transport{
-				Proxy: proxyURL,
-			},
-			Timeout: 30 * time.Second,
-		}}
+	opts := []Option{WithKeyConfig(keyConfig), WithEnv(env), WithRestURL(baseUrl)}
+	if len(proxy) != 0 {
+		opts = append(opts, WithProxy(proxy[0]))
+	}
+	c, err := NewRestClientWithOptions(ctx, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return c
 }
 
 func Get[T any](c *RestClient, ctx context.Context, url string, params interface{}) (*common.Resp[T], error) {
-	return Do[T](c, c.MakeRequest(ctx, http.MethodGet, url, params))
+	return send[T](c, ctx, http.MethodGet, url, params)
 }
 func Post[T any](c *RestClient, ctx context.Context, url string, params interface{}) (*common.Resp[T], error) {
-	return Do[T](c, c.MakeRequest(ctx, http.MethodPost, url, params))
+	return send[T](c, ctx, http.MethodPost, url, params)
+}
+
+// HTTPError 非200的响应
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%v %v, statusCode is %v, msg: %v", e.Method, e.URL, e.StatusCode, e.Body)
 }
-func Do[T any](c *RestClient, req *http.Request) (*common.Resp[T], error) {
+
+// APIError 业务错误 code非0
+type APIError struct {
+	Code string
+	Msg  string
+}
+
+func (e *APIError) Error() string {
+	return e.Msg
+}
+
+func Do[T any](c *RestClient, req *http.Request) (t *common.Resp[T], err error) {
+	ctx, span := c.Tracer.Start(req.Context(), "okx.rest "+req.URL.Path, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("okx.endpoint", req.URL.Path),
+			attribute.Int("okx.attempt", attemptFromContext(req.Context()))))
+	defer func() {
+		span.SetAttributes(attribute.String("okx.code", errorCode(err)))
+		common.EndSpan(span, err)
+	}()
+	req = req.WithContext(ctx)
+
+	if c.limiter != nil {
+		start := time.Now()
+		_, wait := c.Tracer.Start(ctx, "okx.rate_limit")
+		err := c.limiter.Wait(ctx)
+		common.EndSpan(wait, err)
+		c.Metrics.RateLimitWait(req.URL.Path, time.Since(start))
+		if err != nil {
+			return nil, err
+		}
+	}
 	rp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer rp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", rp.StatusCode))
 	bs, err := io.ReadAll(rp.Body)
 	if err != nil {
 		return nil, err
 	}
 	if rp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%v %v, statusCode is %v, msg: %v", req.Method, req.URL.String(), rp.StatusCode, string(bs))
+		return nil, &HTTPError{Method: req.Method, URL: req.URL.String(), StatusCode: rp.StatusCode, Body: string(bs)}
 	}
-	t, err := common.Unmarshal[common.Resp[T]](bs)
+	t, err = common.Unmarshal[common.Resp[T]](bs)
 	if err != nil {
 		return nil, err
 	}
 	if t.Code != "0" {
-		return nil, fmt.Errorf(t.Msg)
+		return nil, &APIError{Code: t.Code, Msg: t.Msg}
 	}
 	return t, nil
 }
+
+// Close 取消进行中的请求并释放空闲连接 之后的请求返回ErrClientClosed
+func (c *RestClient) Close() {
+	c.cancel()
+	c.client.CloseIdleConnections()
+}
+
+// Closed 是否已调用Close或创建时的ctx已结束
+func (c *RestClient) Closed() bool {
+	return c.ctx.Err() != nil
+}
+
+// mergeCancel ctx结束或客户端关闭时取消返回的ctx
+func mergeCancel(ctx, client context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(client, func() { cancel(ErrClientClosed) })
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+func (c *RestClient) logger() *slog.Logger {
+	return common.ResolveLogger(c.Logger, c.Log)
+}
+
+// MakeRequest 构造签名后的请求 参数无法编码时返回nil
+//
+// Deprecated: 使用NewRequest
 func (c *RestClient) MakeRequest(ctx context.Context, method, url string, params interface{}) *http.Request {
-	bs, _ := json.Marshal(params)
-	uri := ""
+	req, _ := c.NewRequest(ctx, method, url, params)
+	return req
+}
+
+// NewRequest 构造签名后的请求 GET参数编码为查询串 签名与发送使用同一个查询串
+func (c *RestClient) NewRequest(ctx context.Context, method, url string, params interface{}) (*http.Request, error) {
+	var body []byte
+	requestPath := url
 	if method == http.MethodGet {
-		uri += makeUri(bs)
-		bs = nil
+		query, err := common.EncodeQuery(params)
+		if err != nil {
+			return nil, err
+		}
+		if query != "" {
+			requestPath += "?" + query
+		}
+	} else if params != nil {
+		bs, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		body = bs
+	}
+	req, err := http.NewRequestWithContext(ctx, method, string(c.baseUrl)+requestPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	header := c.keyConfig.MakeHeader(method, requestPath, body)
+	if header == nil {
+		header = http.Header{}
 	}
-	req, _ := http.NewRequestWithContext(ctx, method, string(c.baseUrl)+url+uri, bytes.NewReader(bs))
-	header := c.keyConfig.MakeHeader(method, url+uri, bs)
 	if c.isTest {
 		header["x-simulated-trading"] = []string{"1"}
 	}
+	if c.userAgent != "" {
+		header.Set("User-Agent", c.userAgent)
+	}
 	req.Header = header
 	if method == http.MethodPost {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	return req
-}
-func makeUri(bs []byte) (uri string) {
-	query := map[string]interface{}{}
-	_ = json.Unmarshal(bs, &query)
-	if len(query) != 0 {
-		var fields []string
-		for k, item := range query {
-			if item != "" {
-				fields = append(fields, fmt.Sprintf("%v=%v", k, item))
-			}
-		}
-		if len(fields) != 0 {
-			uri += "?"
-			uri += strings.Join(fields, "&")
-		}
-	}
-	return uri
+	return req, nil
 }
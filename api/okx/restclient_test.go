@@ -3,6 +3,8 @@ package okx
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -63,3 +65,36 @@ func TestGetCandlesticks(t *testing.T) {
 	}
 	fmt.Println(rp)
 }
+
+func TestMakeRequestSignsQuery(t *testing.T) {
+	key := KeyConfig{Apikey: "key", Secretkey: "secret", Passphrase: "pass"}
+	var requestURI, sign, want string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.URL.RequestURI()
+		sign = r.Header.Get("OK-ACCESS-SIGN")
+		want = key.MakeSign(r.Header.Get("OK-ACCESS-TIMESTAMP"), r.Method, requestURI, nil)
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+	}))
+	defer srv.Close()
+	client, err := NewRestClientWithOptions(context.Background(), WithKeyConfig(key), WithRestURL(common.BaseURL(srv.URL)))
+	assert.NoError(t, err)
+
+	_, err = client.Candles(context.Background(), common.CandlesticksReq{InstID: "BTC-USDT", After: 1000000, Bar: "1m"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v5/market/candles?after=1000000&bar=1m&instId=BTC-USDT", requestURI)
+	assert.Equal(t, want, sign)
+
+	_, err = client.OrdersPending(context.Background(), common.OrdersPendingReq{InstId: "BTC USDT"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v5/trade/orders-pending?instId=BTC+USDT", requestURI)
+	assert.Equal(t, want, sign)
+
+	// 旧接口保持原签名 与NewRequest构造相同的请求
+	req, err := client.NewRequest(context.Background(), http.MethodGet, "/api/v5/market/candles", common.CandlesticksReq{InstID: "BTC-USDT", Bar: "1m"})
+	assert.NoError(t, err)
+	legacy := client.MakeRequest(context.Background(), http.MethodGet, "/api/v5/market/candles", common.CandlesticksReq{InstID: "BTC-USDT", Bar: "1m"})
+	assert.Equal(t, req.URL.String(), legacy.URL.String())
+	_, err = client.NewRequest(context.Background(), http.MethodPost, "/api/v5/trade/order", func() {})
+	assert.Error(t, err)
+	assert.Nil(t, client.MakeRequest(context.Background(), http.MethodPost, "/api/v5/trade/order", func() {}))
+}
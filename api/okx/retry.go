@@ -0,0 +1,244 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// RetryPolicy REST请求重试策略 MaxAttempts不大于1时不重试
+// GET请求遇到网络错误、429/5xx及系统繁忙(50001/50013)时重试
+// 下单/撤单仅在携带clOrdId时重试 重试前先按clOrdId查询订单避免重复
+type RetryPolicy struct {
+	MaxAttempts int           // 总尝试次数 含首次请求
+	BaseDelay   time.Duration // 首次重试前的等待 之后逐次翻倍
+	MaxDelay    time.Duration // 等待上限 0表示不限
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// 可重试的业务错误码
+var retryCodes = map[string]bool{
+	"50001": true, // 服务暂时不可用
+	"50013": true, // 系统繁忙
+}
+
+// 订单不存在
+const codeOrderNotExist = "51603"
+
+// backoff 第attempt次失败后的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+type retryPolicyKey struct{}
+
+// ContextWithRetryPolicy 覆盖单次调用的重试策略 MaxAttempts为1时不重试
+func ContextWithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+func (c *RestClient) retryPolicy(ctx context.Context) RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	return c.retry
+}
+
+type attemptKey struct{}
+
+// attemptFromContext 当前是第几次尝试 直接调用Do时为1
+func attemptFromContext(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// 下单类请求重试前的查重 返回true表示上次请求已生效
+var orderDedup = map[string]func(order *common.Order) bool{
+	"/api/v5/trade/order": func(order *common.Order) bool {
+		return true
+	},
+	"/api/v5/trade/cancel-order": func(order *common.Order) bool {
+		return order.State == string(OrderCanceled) || order.State == "mmp_canceled"
+	},
+}
+
+type orderKey struct {
+	InstId  string `json:"instId" url:"instId"`
+	ClOrdId string `json:"clOrdId" url:"clOrdId"`
+}
+
+// idempotencyKey 下单类请求的查重依据 不支持查重时返回nil
+func idempotencyKey(url string, params interface{}) *orderKey {
+	if _, ok := orderDedup[url]; !ok {
+		return nil
+	}
+	bs, err := json.Marshal(params)
+	if err != nil {
+		return nil
+	}
+	key := &orderKey{}
+	if err := json.Unmarshal(bs, key); err != nil || key.ClOrdId == "" || key.InstId == "" {
+		return nil
+	}
+	return key
+}
+
+// send 按重试策略发送请求 每次尝试重新签名
+func send[T any](c *RestClient, ctx context.Context, method, url string, params interface{}) (*common.Resp[T], error) {
+	if c.ctx.Err() != nil {
+		return nil, ErrClientClosed
+	}
+	// 关闭客户端时取消进行中的请求
+	ctx, stop := mergeCancel(ctx, c.ctx)
+	defer stop()
+	policy := c.retryPolicy(ctx)
+	var key *orderKey
+	if method != http.MethodGet {
+		if key = idempotencyKey(url, params); key == nil {
+			policy = RetryPolicy{}
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := c.NewRequest(context.WithValue(ctx, attemptKey{}, attempt), method, url, params)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		rp, err := Do[T](c, req)
+		elapsed := time.Since(start)
+		c.Metrics.RestRequest(url, method, errorCode(err), elapsed)
+		c.logger().Debug("rest request", "method", method, "endpoint", url, "attempt", attempt,
+			"code", errorCode(err), "latency", elapsed)
+		if err == nil {
+			return rp, nil
+		}
+		if !retryable(err) {
+			return nil, err
+		}
+		if attempt >= policy.MaxAttempts {
+			if attempt > 1 {
+				c.Metrics.RestRetry(url, method, common.RetryGaveUp)
+			}
+			return nil, err
+		}
+		delay := policy.backoff(attempt)
+		c.logger().Warn("rest retry", "method", method, "endpoint", url, "attempt", attempt, "delay", delay, "err", err)
+		if err := sleep(ctx, delay); err != nil {
+			c.Metrics.RestRetry(url, method, common.RetryGaveUp)
+			return nil, err
+		}
+		if key != nil {
+			order, lookupErr := c.lookupOrder(ctx, key)
+			if lookupErr != nil {
+				// 无法确认上次请求是否生效 不再重试
+				c.Metrics.RestRetry(url, method, common.RetryGaveUp)
+				return nil, errors.Join(err, lookupErr)
+			}
+			if order != nil && orderDedup[url](order) {
+				c.Metrics.RestRetry(url, method, common.RetryDeduped)
+				return dedupResp[T](order)
+			}
+		}
+		c.Metrics.RestRetry(url, method, common.RetryRetried)
+	}
+}
+
+// lookupOrder 按clOrdId查询订单 不存在时返回nil
+func (c *RestClient) lookupOrder(ctx context.Context, key *orderKey) (*common.Order, error) {
+	rp, err := Get[common.Order](c, ctx, "/api/v5/trade/order", key)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code == codeOrderNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rp.Data) == 0 {
+		return nil, nil
+	}
+	return &rp.Data[0], nil
+}
+
+// dedupResp 以查询到的订单构造成功响应
+func dedupResp[T any](order *common.Order) (*common.Resp[T], error) {
+	bs, err := json.Marshal(common.Resp[common.PlaceOrder]{
+		Code: "0",
+		Data: []common.PlaceOrder{{ClOrdId: order.ClOrdId, OrdId: order.OrdId, Tag: order.Tag, SCode: "0"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return common.Unmarshal[common.Resp[T]](bs)
+}
+
+// errorCode 指标中使用的错误码
+func errorCode(err error) string {
+	if err == nil {
+		return "0"
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return strconv.Itoa(httpErr.StatusCode)
+	}
+	return "network"
+}
+
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryCodes[apiErr.Code]
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	// 解析错误不重试
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,181 @@
+package okx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func newRetryClient(t *testing.T, handler http.HandlerFunc) *RestClient {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := NewRestClientWithOptions(context.Background(),
+		WithRestURL(common.BaseURL(srv.URL)),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+	assert.NoError(t, err)
+	return c
+}
+
+func TestRestClientRetryGet(t *testing.T) {
+	var calls atomic.Int32
+	c := newRetryClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			_, _ = w.Write([]byte(`{"code":"50013","msg":"System is busy","data":[]}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+		}
+	})
+	m := &restMetrics{}
+	c.Metrics = m
+
+	_, err := c.Instruments(context.Background(), common.InstrumentsReq{InstType: "SPOT"})
+	assert.NoError(t, err)
+	assert.Len(t, m.codes, 3)
+	assert.Equal(t, []string{common.RetryRetried, common.RetryRetried}, m.retries)
+
+	// 单次调用关闭重试
+	calls.Store(0)
+	ctx := ContextWithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 1})
+	_, err = c.Instruments(ctx, common.InstrumentsReq{InstType: "SPOT"})
+	var httpErr *HTTPError
+	assert.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// 业务错误不重试
+	calls.Store(10)
+	c2 := newRetryClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"code":"51000","msg":"Parameter error","data":[]}`))
+	})
+	m2 := &restMetrics{}
+	c2.Metrics = m2
+	_, err = c2.Instruments(context.Background(), common.InstrumentsReq{InstType: "SPOT"})
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "51000", apiErr.Code)
+	assert.Len(t, m2.codes, 1)
+	assert.Empty(t, m2.retries)
+
+	// 重试用尽
+	c3 := newRetryClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	m3 := &restMetrics{}
+	c3.Metrics = m3
+	_, err = c3.Instruments(context.Background(), common.InstrumentsReq{InstType: "SPOT"})
+	assert.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, []string{common.RetryRetried, common.RetryRetried, common.RetryGaveUp}, m3.retries)
+}
+
+func TestRestClientRetryPlaceOrder(t *testing.T) {
+	var posts, gets atomic.Int32
+	var exists atomic.Bool
+	c := newRetryClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+			assert.Equal(t, "c1", r.URL.Query().Get("clOrdId"))
+			if exists.Load() {
+				_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT","ordId":"9","clOrdId":"c1","state":"live"}]}`))
+			} else {
+				_, _ = w.Write([]byte(`{"code":"51603","msg":"Order does not exist","data":[]}`))
+			}
+			return
+		}
+		if posts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"clOrdId":"c1","ordId":"10","sCode":"0"}]}`))
+	})
+
+	// 未携带clOrdId时不重试
+	_, err := c.PlaceOrder(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), posts.Load())
+
+	// 订单不存在时重新下单
+	posts.Store(0)
+	rp, err := c.PlaceOrder(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT", ClOrdID: "c1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10", rp.Data[0].OrdId)
+	assert.Equal(t, int32(2), posts.Load())
+	assert.Equal(t, int32(1), gets.Load())
+
+	// 上次请求已生效时不再下单
+	posts.Store(0)
+	exists.Store(true)
+	m := &restMetrics{}
+	c.Metrics = m
+	rp, err = c.PlaceOrder(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT", ClOrdID: "c1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "9", rp.Data[0].OrdId)
+	assert.Equal(t, "c1", rp.Data[0].ClOrdId)
+	assert.Equal(t, int32(1), posts.Load())
+	assert.Equal(t, []string{common.RetryDeduped}, m.retries)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	assert.Equal(t, 100*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 300*time.Millisecond, p.backoff(3))
+	assert.Equal(t, 300*time.Millisecond, p.backoff(10))
+}
+
+type restMetrics struct {
+	common.NopMetrics
+	codes   []string
+	retries []string
+	waits   int
+}
+
+func (m *restMetrics) RestRetry(endpoint, method, outcome string) {
+	m.retries = append(m.retries, outcome)
+}
+
+func (m *restMetrics) RestRequest(endpoint, method, code string, elapsed time.Duration) {
+	m.codes = append(m.codes, method+" "+endpoint+" "+code)
+}
+
+func (m *restMetrics) RateLimitWait(endpoint string, waited time.Duration) {
+	m.waits++
+}
+
+func TestRestClientMetrics(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"code":"50001","msg":"Service temporarily unavailable","data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+	}))
+	defer srv.Close()
+
+	m := &restMetrics{}
+	c, err := NewRestClientWithOptions(context.Background(),
+		WithRestURL(common.BaseURL(srv.URL)),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2}),
+		WithRateLimiter(&countLimiter{}),
+		WithMetrics(m),
+	)
+	assert.NoError(t, err)
+	_, err = c.Instruments(context.Background(), common.InstrumentsReq{InstType: "SPOT"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"GET /api/v5/public/instruments 50001",
+		"GET /api/v5/public/instruments 0",
+	}, m.codes)
+	assert.Equal(t, 2, m.waits)
+}
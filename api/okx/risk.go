@@ -0,0 +1,418 @@
+package okx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+type RiskRule string
+
+const (
+	RuleKillSwitch    RiskRule = "kill_switch"
+	RuleOrderNotional RiskRule = "max_order_notional"
+	RuleOrderSize     RiskRule = "fat_finger_size"
+	RulePosition      RiskRule = "max_position"
+	RuleOpenOrders    RiskRule = "max_open_orders"
+	RulePriceBand     RiskRule = "price_band"
+	RulePriceLimit    RiskRule = "price_limit"
+	RuleRate          RiskRule = "orders_per_second"
+	RuleNoReference   RiskRule = "no_reference_price"
+)
+
+// RiskError 风控拒单
+type RiskError struct {
+	Rule   RiskRule
+	InstId string
+	Reason string
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("risk rejected %s by %s: %s", e.InstId, e.Rule, e.Reason)
+}
+
+// Is 按规则比较 支持errors.Is(err, &RiskError{Rule: RulePriceBand})
+func (e *RiskError) Is(target error) bool {
+	var t *RiskError
+	if !errors.As(target, &t) {
+		return false
+	}
+	return t.Rule == "" || t.Rule == e.Rule
+}
+
+// RiskConfig 风控参数 零值表示不检查
+type RiskConfig struct {
+	MaxOrderNotional   float64            // 单笔最大名义价值(计价币)
+	MaxOrderSz         float64            // 单笔最大数量 防止胖手指
+	MaxPosition        float64            // 默认单产品最大持仓(绝对值)
+	MaxPositions       map[string]float64 // 按产品覆盖最大持仓
+	MaxOpenOrders      int                // 最大挂单数
+	PriceBand          float64            // 相对标记价格的最大偏离比例 如0.05
+	CheckPriceLimit    bool               // 是否使用price-limit限价检查
+	MaxOrdersPerSecond int                // 每秒最大下单数
+	MassCancelFamilies []string           // 触发熔断时需要mass-cancel的期权instFamily
+}
+
+// RiskGuard 下单前风控 包装REST与WS下单入口
+type RiskGuard struct {
+	config     RiskConfig
+	rest       RestTrader
+	private    PrivateTrader
+	lock       sync.RWMutex
+	markPx     map[string]float64
+	limits     map[string][2]float64
+	ctVal      map[string]float64
+	instType   map[string]string
+	sent       []time.Time
+	killed     atomic.Bool
+	position   func(instId string) float64
+	openOrders func() int
+	// OnReject 拒单告警
+	OnReject func(err *RiskError)
+	// OnKill 熔断告警
+	OnKill func(reason string)
+}
+
+func NewRiskGuard(rest RestTrader, private PrivateTrader, config RiskConfig) *RiskGuard {
+	return &RiskGuard{
+		config:     config,
+		rest:       rest,
+		private:    private,
+		markPx:     map[string]float64{},
+		limits:     map[string][2]float64{},
+		ctVal:      map[string]float64{},
+		instType:   map[string]string{},
+		position:   func(instId string) float64 { return 0 },
+		openOrders: func() int { return 0 },
+		OnReject:   func(err *RiskError) {},
+		OnKill:     func(reason string) {},
+	}
+}
+
+// SetPositionSource 设置持仓来源 返回带方向的净持仓
+func (g *RiskGuard) SetPositionSource(f func(instId string) float64) {
+	g.position = f
+}
+
+// SetOpenOrdersSource 设置挂单数来源
+func (g *RiskGuard) SetOpenOrdersSource(f func() int) {
+	g.openOrders = f
+}
+
+// UsePositionTracker 使用PositionTracker的净持仓
+func (g *RiskGuard) UsePositionTracker(t *PositionTracker) {
+	g.SetPositionSource(func(instId string) float64 {
+		var pos float64
+		for _, p := range t.Positions() {
+			if p.InstId == instId {
+				pos += p.Pos
+			}
+		}
+		return pos
+	})
+}
+
+// UseOrderManager 使用OrderManager的未完结订单数
+func (g *RiskGuard) UseOrderManager(m *OrderManager) {
+	g.SetOpenOrdersSource(func() int { return len(m.Orders()) })
+}
+
+// SetInstrument 设置合约面值 用于名义价值计算
+func (g *RiskGuard) SetInstrument(inst common.Instruments) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.ctVal[inst.InstId] = toInstrumentSpec(inst).ctVal
+	g.instType[inst.InstId] = inst.InstType
+}
+
+// OnMarkPrice 更新标记价格
+func (g *RiskGuard) OnMarkPrice(mp *common.MarkPrice) {
+	px, err := strconv.ParseFloat(mp.MarkPx, 64)
+	if err != nil {
+		return
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.markPx[mp.InstId] = px
+}
+
+// OnPriceLimit 更新限价
+func (g *RiskGuard) OnPriceLimit(pl *common.PriceLimit) {
+	buy, err1 := strconv.ParseFloat(pl.BuyLmt, 64)
+	sell, err2 := strconv.ParseFloat(pl.SellLmt, 64)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if err1 != nil || err2 != nil || (!pl.Enabled && pl.BuyLmt == "") {
+		delete(g.limits, pl.InstId)
+		return
+	}
+	g.limits[pl.InstId] = [2]float64{buy, sell}
+}
+
+// Check 检查订单 通过时计入下单频率
+func (g *RiskGuard) Check(req common.PlaceOrderReq) error {
+	if err := g.check(req); err != nil {
+		var riskErr *RiskError
+		if errors.As(err, &riskErr) {
+			g.OnReject(riskErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (g *RiskGuard) check(req common.PlaceOrderReq) error {
+	reject := func(rule RiskRule, format string, args ...any) error {
+		return &RiskError{Rule: rule, InstId: req.InstID, Reason: fmt.Sprintf(format, args...)}
+	}
+	if g.killed.Load() {
+		return reject(RuleKillSwitch, "kill switch engaged")
+	}
+	sz, err := strconv.ParseFloat(req.Sz, 64)
+	if err != nil {
+		return fmt.Errorf("invalid sz %q: %w", req.Sz, err)
+	}
+	var px float64
+	if req.Px != "" {
+		if px, err = strconv.ParseFloat(req.Px, 64); err != nil {
+			return fmt.Errorf("invalid px %q: %w", req.Px, err)
+		}
+	}
+
+	// 持仓与挂单来源可能持有自己的锁 在g.lock之外取得快照
+	max := g.maxPosition(req.InstID)
+	var pos float64
+	if max > 0 {
+		pos = g.position(req.InstID)
+	}
+	var openOrders int
+	if g.config.MaxOpenOrders > 0 && !req.ReduceOnly {
+		openOrders = g.openOrders()
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	mark, hasMark := g.markPx[req.InstID]
+	ctVal, ok := g.ctVal[req.InstID]
+	if !ok {
+		ctVal = 1
+	}
+	refPx := px
+	if refPx == 0 {
+		refPx = mark
+	}
+	// qty为张数或基础货币数量 notional为计价货币金额
+	qty, notional := sz, sz*ctVal*refPx
+	quote := g.quoteSz(req)
+	if quote {
+		qty, notional = 0, sz
+		if refPx > 0 {
+			qty = sz / refPx
+		}
+	}
+	needQty := g.config.MaxOrderSz > 0 || max > 0
+	if needQty && quote && refPx == 0 {
+		return reject(RuleNoReference, "no price to convert quote sz")
+	}
+
+	if g.config.MaxOrderSz > 0 && qty > g.config.MaxOrderSz {
+		return reject(RuleOrderSize, "sz %v > %v", qty, g.config.MaxOrderSz)
+	}
+	if g.config.MaxOrderNotional > 0 {
+		if !quote && refPx == 0 {
+			return reject(RuleNoReference, "no price to evaluate notional")
+		}
+		if notional > g.config.MaxOrderNotional {
+			return reject(RuleOrderNotional, "notional %v > %v", notional, g.config.MaxOrderNotional)
+		}
+	}
+	if px != 0 && g.config.PriceBand > 0 {
+		if !hasMark || mark <= 0 {
+			return reject(RuleNoReference, "no mark price to evaluate price band")
+		}
+		if dev := math.Abs(px-mark) / mark; dev > g.config.PriceBand {
+			return reject(RulePriceBand, "px %v deviates %.4f from mark %v", px, dev, mark)
+		}
+	}
+	if px != 0 && g.config.CheckPriceLimit {
+		if lmt, ok := g.limits[req.InstID]; ok {
+			if req.Side == "buy" && px > lmt[0] {
+				return reject(RulePriceLimit, "buy px %v > buyLmt %v", px, lmt[0])
+			}
+			if req.Side == "sell" && px < lmt[1] {
+				return reject(RulePriceLimit, "sell px %v < sellLmt %v", px, lmt[1])
+			}
+		}
+	}
+	if max > 0 {
+		delta := qty
+		if req.Side == "sell" {
+			delta = -qty
+		}
+		// 只减仓的订单不会扩大风险
+		if after := pos + delta; !req.ReduceOnly && math.Abs(after) > max && math.Abs(after) > math.Abs(pos) {
+			return reject(RulePosition, "position %v would exceed %v", after, max)
+		}
+	}
+	if g.config.MaxOpenOrders > 0 && !req.ReduceOnly && openOrders >= g.config.MaxOpenOrders {
+		return reject(RuleOpenOrders, "open orders %v >= %v", openOrders, g.config.MaxOpenOrders)
+	}
+	if g.config.MaxOrdersPerSecond > 0 {
+		now := time.Now()
+		i := 0
+		for ; i < len(g.sent) && now.Sub(g.sent[i]) >= time.Second; i++ {
+		}
+		g.sent = g.sent[i:]
+		if len(g.sent) >= g.config.MaxOrdersPerSecond {
+			return reject(RuleRate, "more than %v orders per second", g.config.MaxOrdersPerSecond)
+		}
+		g.sent = append(g.sent, now)
+	}
+	return nil
+}
+
+// quoteSz sz是否为计价货币金额 现货市价买单未指定tgtCcy时默认为quote_ccy 调用方需持有锁
+func (g *RiskGuard) quoteSz(req common.PlaceOrderReq) bool {
+	if req.TgtCcy == "quote_ccy" {
+		return true
+	}
+	if req.TgtCcy != "" || req.OrdType != "market" || req.Side != "buy" {
+		return false
+	}
+	if instType, ok := g.instType[req.InstID]; ok && instType != "" {
+		return instType == "SPOT"
+	}
+	return len(strings.Split(req.InstID, "-")) == 2
+}
+
+func (g *RiskGuard) maxPosition(instId string) float64 {
+	if max, ok := g.config.MaxPositions[instId]; ok {
+		return max
+	}
+	return g.config.MaxPosition
+}
+
+// PlaceOrder 风控后通过REST下单
+func (g *RiskGuard) PlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.Resp[common.PlaceOrder], error) {
+	if err := g.Check(req); err != nil {
+		return nil, err
+	}
+	return g.rest.PlaceOrder(ctx, req)
+}
+
+// WsPlaceOrder 风控后通过WS下单
+func (g *RiskGuard) WsPlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.WsResp[common.PlaceOrder], error) {
+	if err := g.Check(req); err != nil {
+		return nil, err
+	}
+	return g.private.PlaceOrder(ctx, req)
+}
+
+// Killed 熔断是否开启
+func (g *RiskGuard) Killed() bool {
+	return g.killed.Load()
+}
+
+// Resume 解除熔断
+func (g *RiskGuard) Resume() {
+	g.killed.Store(false)
+}
+
+// algoOrdTypes 熔断时撤销的策略委托类型 orders-algo-pending每次只能查询一种 conditional与oco除外
+var algoOrdTypes = []string{"conditional,oco", "trigger", "move_order_stop", "iceberg", "twap"}
+
+// Kill 开启熔断 拒绝后续下单并撤销全部挂单及策略委托
+func (g *RiskGuard) Kill(ctx context.Context, reason string) error {
+	g.killed.Store(true)
+	g.OnKill(reason)
+
+	var errs []error
+	canceler, ok := g.rest.(interface {
+		MassCancel(ctx context.Context, req common.MassCancelReq) (*common.Resp[common.MassCancel], error)
+	})
+	for _, family := range g.config.MassCancelFamilies {
+		if !ok {
+			break
+		}
+		if _, err := canceler.MassCancel(ctx, common.MassCancelReq{InstType: "OPTION", InstFamily: family}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var batch []common.CancelOrderReq
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if _, err := g.rest.CancelBatchOrders(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+		batch = nil
+	}
+	// 按ordId向前翻页直到没有挂单
+	for after := ""; ; {
+		rp, err := g.rest.OrdersPending(ctx, common.OrdersPendingReq{After: after})
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if len(rp.Data) == 0 {
+			break
+		}
+		for _, o := range rp.Data {
+			batch = append(batch, common.CancelOrderReq{InstId: o.InstId, OrdId: o.OrdId})
+			if len(batch) == 20 {
+				flush()
+			}
+		}
+		flush()
+		last := rp.Data[len(rp.Data)-1].OrdId
+		if last == after {
+			break
+		}
+		after = last
+	}
+	if algo, ok := g.rest.(interface {
+		AlgoOrdersPending(ctx context.Context, req common.AlgoOrdersPendingReq) (*common.Resp[common.AlgoOrder], error)
+		CancelAlgos(ctx context.Context, reqs []common.CancelAlgoReq) (*common.Resp[common.CancelAlgo], error)
+	}); ok {
+		for _, ordType := range algoOrdTypes {
+			for after := ""; ; {
+				rp, err := algo.AlgoOrdersPending(ctx, common.AlgoOrdersPendingReq{OrdType: ordType, After: after})
+				if err != nil {
+					errs = append(errs, err)
+					break
+				}
+				if len(rp.Data) == 0 {
+					break
+				}
+				for i := 0; i < len(rp.Data); i += 10 {
+					var reqs []common.CancelAlgoReq
+					for _, o := range rp.Data[i:min(i+10, len(rp.Data))] {
+						reqs = append(reqs, common.CancelAlgoReq{InstId: o.InstId, AlgoId: o.AlgoId})
+					}
+					if _, err := algo.CancelAlgos(ctx, reqs); err != nil {
+						errs = append(errs, err)
+					}
+				}
+				last := rp.Data[len(rp.Data)-1].AlgoId
+				if last == after {
+					break
+				}
+				after = last
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package okx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func TestRiskGuardCheck(t *testing.T) {
+	g := NewRiskGuard(nil, nil, RiskConfig{
+		MaxOrderNotional:   1000,
+		MaxOrderSz:         50,
+		MaxPosition:        20,
+		PriceBand:          0.05,
+		MaxOrdersPerSecond: 2,
+	})
+	var rejected atomic.Int32
+	g.OnReject = func(err *RiskError) { rejected.Add(1) }
+	g.SetInstrument(common.Instruments{InstId: "BTC-USDT-SWAP", CtVal: "0.1"})
+	g.OnMarkPrice(&common.MarkPrice{InstId: "BTC-USDT-SWAP", MarkPx: "100"})
+
+	order := func(side, px, sz string) common.PlaceOrderReq {
+		return common.PlaceOrderReq{InstID: "BTC-USDT-SWAP", Side: side, Px: px, Sz: sz, OrdType: "limit", TdMode: "cross"}
+	}
+	assert.ErrorIs(t, g.Check(order("buy", "100", "60")), &RiskError{Rule: RuleOrderSize})
+	assert.ErrorIs(t, g.Check(order("buy", "120", "1")), &RiskError{Rule: RulePriceBand})
+	assert.ErrorIs(t, g.Check(order("buy", "101", "30")), &RiskError{Rule: RulePosition})
+
+	g.SetPositionSource(func(instId string) float64 { return 15 })
+	assert.NoError(t, g.Check(order("sell", "100", "30")))
+	assert.NoError(t, g.Check(order("buy", "100", "1")))
+	assert.ErrorIs(t, g.Check(order("buy", "100", "1")), &RiskError{Rule: RuleRate})
+	assert.Equal(t, int32(4), rejected.Load())
+}
+
+func TestRiskGuardKill(t *testing.T) {
+	var canceled, algoCanceled atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after := r.URL.Query().Get("after")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v5/trade/orders-pending"):
+			// 每页两个挂单 共两页
+			switch after {
+			case "":
+				_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT","ordId":"4"},{"instId":"ETH-USDT","ordId":"3"}]}`))
+			case "3":
+				_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT","ordId":"2"},{"instId":"ETH-USDT","ordId":"1"}]}`))
+			default:
+				_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+			}
+		case r.URL.Path == "/api/v5/trade/cancel-batch-orders":
+			canceled.Add(1)
+			_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+		case r.URL.Path == "/api/v5/trade/orders-algo-pending":
+			if r.URL.Query().Get("ordType") == "trigger" && after == "" {
+				_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT","algoId":"a1"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+		case r.URL.Path == "/api/v5/trade/cancel-algos":
+			algoCanceled.Add(1)
+			_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	rest := NewRestClientWithCustom(context.Background(), config, common.NormalServer,
+		map[common.Destination]common.BaseURL{common.NormalServer: common.BaseURL(srv.URL)})
+
+	g := NewRiskGuard(rest, nil, RiskConfig{})
+	assert.NoError(t, g.Kill(context.Background(), "test"))
+	assert.Equal(t, int32(2), canceled.Load())
+	assert.Equal(t, int32(1), algoCanceled.Load())
+
+	_, err := g.PlaceOrder(context.Background(), common.PlaceOrderReq{InstID: "BTC-USDT", Sz: "1"})
+	var riskErr *RiskError
+	assert.True(t, errors.As(err, &riskErr))
+	assert.Equal(t, RuleKillSwitch, riskErr.Rule)
+
+	g.Resume()
+	assert.False(t, g.Killed())
+}
+
+func TestRiskGuardReference(t *testing.T) {
+	g := NewRiskGuard(nil, nil, RiskConfig{MaxOrderSz: 2, MaxOrderNotional: 1000, PriceBand: 0.05})
+	g.SetInstrument(common.Instruments{InstId: "BTC-USDT", InstType: "SPOT"})
+
+	// 没有标记价格时价格带拒单
+	limit := common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "limit", Px: "100", Sz: "1"}
+	assert.ErrorIs(t, g.Check(limit), &RiskError{Rule: RuleNoReference})
+
+	// 现货市价买单sz为计价货币 按标记价格换算数量
+	buy := common.PlaceOrderReq{InstID: "BTC-USDT", Side: "buy", OrdType: "market", Sz: "150"}
+	assert.ErrorIs(t, g.Check(buy), &RiskError{Rule: RuleNoReference})
+	g.OnMarkPrice(&common.MarkPrice{InstId: "BTC-USDT", MarkPx: "100"})
+	assert.NoError(t, g.Check(limit))
+	assert.NoError(t, g.Check(buy))
+	buy.Sz = "250"
+	assert.ErrorIs(t, g.Check(buy), &RiskError{Rule: RuleOrderSize})
+	buy.Sz, buy.TgtCcy = "2", "base_ccy"
+	assert.NoError(t, g.Check(buy))
+	buy.Sz, buy.TgtCcy = "1500", "quote_ccy"
+	assert.ErrorIs(t, g.Check(buy), &RiskError{Rule: RuleOrderSize})
+}
+
+func TestRiskGuardSourcesOutsideLock(t *testing.T) {
+	g := NewRiskGuard(nil, nil, RiskConfig{MaxPosition: 10, MaxOpenOrders: 5})
+	// 来源中访问RiskGuard不会死锁
+	g.SetPositionSource(func(instId string) float64 {
+		g.OnMarkPrice(&common.MarkPrice{InstId: instId, MarkPx: "100"})
+		return 1
+	})
+	g.SetOpenOrdersSource(func() int {
+		g.SetInstrument(common.Instruments{InstId: "BTC-USDT-SWAP", CtVal: "0.1"})
+		return 1
+	})
+	assert.NoError(t, g.Check(common.PlaceOrderReq{InstID: "BTC-USDT-SWAP", Side: "buy", OrdType: "limit", Px: "100", Sz: "1"}))
+}
@@ -0,0 +1,133 @@
+package okx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+func newTestTracer() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func spanAttr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func spansNamed(spans tracetest.SpanStubs, name string) []tracetest.SpanStub {
+	var found []tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == name {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+func TestRestClientTracing(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+	}))
+	defer srv.Close()
+
+	tp, exporter := newTestTracer()
+	c, err := NewRestClientWithOptions(context.Background(),
+		WithRestURL(common.BaseURL(srv.URL)),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2}),
+		WithRateLimiter(&countLimiter{}),
+		WithTracerProvider(tp),
+	)
+	assert.NoError(t, err)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "caller")
+	_, err = c.Instruments(ctx, common.InstrumentsReq{InstType: "SPOT"})
+	parent.End()
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	rest := spansNamed(spans, "okx.rest /api/v5/public/instruments")
+	if assert.Len(t, rest, 2) {
+		for i, span := range rest {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+			assert.Equal(t, int64(i+1), spanAttr(span, "okx.attempt").AsInt64())
+			assert.Equal(t, "GET", spanAttr(span, "http.method").AsString())
+		}
+		assert.Equal(t, int64(503), spanAttr(rest[0], "http.status_code").AsInt64())
+		assert.Equal(t, "503", spanAttr(rest[0], "okx.code").AsString())
+		assert.Equal(t, "0", spanAttr(rest[1], "okx.code").AsString())
+	}
+	limits := spansNamed(spans, "okx.rate_limit")
+	if assert.Len(t, limits, 2) {
+		assert.Equal(t, rest[0].SpanContext.SpanID(), limits[0].Parent.SpanID())
+	}
+}
+
+func TestOrderManagerTracing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"clOrdId":"o1","ordId":"100","sCode":"0"}]}`))
+	}))
+	defer srv.Close()
+
+	tp, exporter := newTestTracer()
+	rest, err := NewRestClientWithOptions(context.Background(), WithRestURL(common.BaseURL(srv.URL)), WithTracerProvider(tp))
+	assert.NoError(t, err)
+	m := NewOrderManager(rest, nil, "t")
+	m.Tracer = rest.Tracer
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "strategy")
+	_, err = m.Place(ctx, common.PlaceOrderReq{ClOrdID: "o1", InstID: "BTC-USDT", Side: "buy", OrdType: "limit", Px: "100", Sz: "2"})
+	parent.End()
+	assert.NoError(t, err)
+	assert.Empty(t, spansNamed(exporter.GetSpans(), "okx.order"), "span stays open until final state")
+
+	m.OnOrder(&common.Order{ClOrdId: "o1", OrdId: "100", InstId: "BTC-USDT", State: "live", UTime: "1"})
+	m.OnOrder(&common.Order{ClOrdId: "o1", OrdId: "100", InstId: "BTC-USDT", State: "partially_filled", AccFillSz: "1", FillSz: "1", FillPx: "100", UTime: "2"})
+	m.OnOrder(&common.Order{ClOrdId: "o1", OrdId: "100", InstId: "BTC-USDT", State: "filled", AccFillSz: "2", FillSz: "1", FillPx: "100", UTime: "3"})
+
+	spans := exporter.GetSpans()
+	orders := spansNamed(spans, "okx.order")
+	if !assert.Len(t, orders, 1) {
+		return
+	}
+	order := orders[0]
+	assert.Equal(t, parent.SpanContext().SpanID(), order.Parent.SpanID())
+	assert.Equal(t, "filled", spanAttr(order, "okx.state").AsString())
+	var events []string
+	for _, e := range order.Events {
+		events = append(events, e.Name)
+	}
+	assert.Equal(t, []string{"order.ack", "order.live", "order.partially_filled", "order.filled"}, events)
+
+	rests := spansNamed(spans, "okx.rest /api/v5/trade/order")
+	if assert.Len(t, rests, 1) {
+		assert.Equal(t, order.SpanContext.SpanID(), rests[0].Parent.SpanID())
+	}
+
+	// 已完结的订单不再跟踪 span立即结束
+	exporter.Reset()
+	m.OnOrder(&common.Order{ClOrdId: "late", State: "canceled", UTime: "1"})
+	_, err = m.Place(context.Background(), common.PlaceOrderReq{ClOrdID: "late", InstID: "BTC-USDT"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(spansNamed(exporter.GetSpans(), "okx.order")) == 1 }, time.Second, time.Millisecond)
+}
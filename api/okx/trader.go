@@ -0,0 +1,54 @@
+package okx
+
+import (
+	"context"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+)
+
+// RestTrader 交易相关REST接口 实盘RestClient与模拟盘均实现
+type RestTrader interface {
+	PlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.Resp[common.PlaceOrder], error)
+	CancelOrder(ctx context.Context, instId, clOrdId string) (*common.Resp[common.PlaceOrder], error)
+	CancelBatchOrders(ctx context.Context, reqs []common.CancelOrderReq) (*common.Resp[common.PlaceOrder], error)
+	GetOrder(ctx context.Context, req common.PlaceOrderReq) (*common.Resp[common.Order], error)
+	OrdersPending(ctx context.Context, req common.OrdersPendingReq) (*common.Resp[common.Order], error)
+	Balance(ctx context.Context, ccy string) (*common.Resp[common.Balance], error)
+	AccountPositions(ctx context.Context, req common.PositionReq) (*common.Resp[common.Position], error)
+}
+
+// CancelAllAfterTrader DeadManSwitch所需的REST接口 实盘RestClient实现
+type CancelAllAfterTrader interface {
+	CancelAllAfter(ctx context.Context, timeOut int) (*common.Resp[common.CancelAllAfter], error)
+}
+
+// PrivateTrader 私有频道及WS交易接口 实盘PrivateClient与模拟盘均实现
+type PrivateTrader interface {
+	Account(ctx context.Context, callback func(resp *common.WsResp[*common.Balance])) error
+	UAccount() error
+	AccountPositions(ctx context.Context, instType string, callback func(resp *common.WsResp[*common.Position])) error
+	Orders(ctx context.Context, instType string, callback func(resp *common.WsResp[*common.Order])) error
+	UOrders(instType string) error
+	PlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.WsResp[common.PlaceOrder], error)
+	CancelOrder(ctx context.Context, req common.CancelOrderReq) (*common.WsResp[common.PlaceOrder], error)
+}
+
+// Streamer OKX格式的行情与私有推送 实盘ExchangeClient与回测客户端均实现
+type Streamer interface {
+	common.ClientBase
+	Account(ctx context.Context, callback func(resp *common.WsResp[*common.Balance])) error
+	UAccount() error
+	Candle(ctx context.Context, channel, instId string, callback func(resp *common.WsResp[*common.Candle])) error
+	UCandle(channel, instId string) error
+	MarkPrice(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.MarkPrice])) error
+	UMarkPrice(instId string) error
+	SpotOrders(ctx context.Context, callback func(resp *common.WsResp[*common.Order])) error
+	USpotOrders() error
+}
+
+var (
+	_ RestTrader           = (*RestClient)(nil)
+	_ CancelAllAfterTrader = (*RestClient)(nil)
+	_ PrivateTrader        = (*PrivateClient)(nil)
+	_ Streamer             = (*ExchangeClient)(nil)
+)
@@ -26,6 +26,15 @@ func (w *BusinessClient) UCandle(channel, instId string) error {
 	return w.Unsubscribe(common.MakeArg("candle"+channel, instId))
 }
 
+// AllTrades 全部交易频道 每条推送仅包含一笔成交 使用快速解码
+// resp及其Data在回调返回后会被复用 需保留时自行拷贝
+func (w *BusinessClient) AllTrades(ctx context.Context, instId string, callback func(resp *common.WsResp[common.Trade])) error {
+	return common.SubscribeDecode(&w.WsClient, ctx, common.MakeArg("trades-all", instId), common.DecodeTrades, callback)
+}
+func (w *BusinessClient) UAllTrades(instId string) error {
+	return w.Unsubscribe(common.MakeArg("trades-all", instId))
+}
+
 type PublicClient struct {
 	common.WsClient
 }
@@ -37,6 +46,76 @@ func (w *PublicClient) UMarkPrice(instId string) error {
 	return w.Unsubscribe(common.MakeArg("mark-price", instId))
 }
 
+// FundingRate 资金费率频道 仅永续合约
+func (w *PublicClient) FundingRate(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.FundingRate])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("funding-rate", instId), callback)
+}
+func (w *PublicClient) UFundingRate(instId string) error {
+	return w.Unsubscribe(common.MakeArg("funding-rate", instId))
+}
+
+// IndexTickers 指数行情频道 instId为指数 如BTC-USDT
+func (w *PublicClient) IndexTickers(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.IndexTicker])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("index-tickers", instId), callback)
+}
+func (w *PublicClient) UIndexTickers(instId string) error {
+	return w.Unsubscribe(common.MakeArg("index-tickers", instId))
+}
+
+// OpenInterest 持仓总量频道
+func (w *PublicClient) OpenInterest(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.OpenInterest])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("open-interest", instId), callback)
+}
+func (w *PublicClient) UOpenInterest(instId string) error {
+	return w.Unsubscribe(common.MakeArg("open-interest", instId))
+}
+
+// OptSummary 期权定价频道 推送instFamily下全部期权
+func (w *PublicClient) OptSummary(ctx context.Context, instFamily string, callback func(resp *common.WsResp[*common.OptSummary])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeInstFamilyArg("opt-summary", instFamily), callback)
+}
+func (w *PublicClient) UOptSummary(instFamily string) error {
+	return w.Unsubscribe(common.MakeInstFamilyArg("opt-summary", instFamily))
+}
+
+// Tickers 行情频道
+func (w *PublicClient) Tickers(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.Ticker])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("tickers", instId), callback)
+}
+func (w *PublicClient) UTickers(instId string) error {
+	return w.Unsubscribe(common.MakeArg("tickers", instId))
+}
+
+// MarketTrades 交易频道
+func (w *PublicClient) MarketTrades(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.Trade])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("trades", instId), callback)
+}
+func (w *PublicClient) UMarketTrades(instId string) error {
+	return w.Unsubscribe(common.MakeArg("trades", instId))
+}
+
+// Books 深度频道 channel可选books、books5、bbo-tbt、books50-l2-tbt、books-l2-tbt
+func (w *PublicClient) Books(ctx context.Context, channel, instId string, callback func(resp *common.WsResp[*common.OrderBook])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeArg(channel, instId), callback)
+}
+func (w *PublicClient) UBooks(channel, instId string) error {
+	return w.Unsubscribe(common.MakeArg(channel, instId))
+}
+
+// BooksDecode 深度频道的快速订阅 单次扫描解码不经过反射 适用于books-l2-tbt
+// resp及其Data在回调返回后会被复用 需保留时自行拷贝
+func (w *PublicClient) BooksDecode(ctx context.Context, channel, instId string, callback func(resp *common.WsResp[common.OrderBook])) error {
+	return common.SubscribeDecode(&w.WsClient, ctx, common.MakeArg(channel, instId), common.DecodeOrderBooks, callback)
+}
+
+// PriceLimit 限价频道
+func (w *PublicClient) PriceLimit(ctx context.Context, instId string, callback func(resp *common.WsResp[*common.PriceLimit])) error {
+	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("price-limit", instId), callback)
+}
+func (w *PublicClient) UPriceLimit(instId string) error {
+	return w.Unsubscribe(common.MakeArg("price-limit", instId))
+}
+
 func (w *BusinessClient) OrderBook(ctx context.Context, channel, sprdId string, callback func(resp *common.WsResp[*common.OrderBook])) error {
 	return common.Subscribe(&w.WsClient, ctx, common.MakeSprdArg(channel, sprdId), callback)
 }
@@ -48,6 +127,7 @@ func (w *BusinessClient) UOrderBook(channel, instId string) error {
 
 type PrivateClient struct {
 	common.WsClient
+	brokerCode string
 }
 
 // Account 资金频道
@@ -62,6 +142,8 @@ func (w *PrivateClient) UAccount() error {
 }
 
 // Positions 持仓频道
+//
+// Deprecated: 未指定instType且推送类型不是持仓 使用AccountPositions
 func (w *PrivateClient) Positions(ctx context.Context, callback func(resp *common.WsResp[*common.Balances])) error {
 	if err := w.Login(ctx); err != nil {
 		return err
@@ -69,6 +151,30 @@ func (w *PrivateClient) Positions(ctx context.Context, callback func(resp *commo
 	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("positions", ""), callback)
 }
 
+// AccountPositions 持仓频道 instType可选MARGIN、SWAP、FUTURES、OPTION、ANY
+func (w *PrivateClient) AccountPositions(ctx context.Context, instType string, callback func(resp *common.WsResp[*common.Position])) error {
+	if err := w.Login(ctx); err != nil {
+		return err
+	}
+	return common.Subscribe(&w.WsClient, ctx, common.MakeInstTypeArg("positions", instType), callback)
+}
+
+// UAccountPositions 取消订阅持仓频道
+func (w *PrivateClient) UAccountPositions(instType string) error {
+	return w.Unsubscribe(common.MakeInstTypeArg("positions", instType))
+}
+
+// AccountGreeks 账户希腊字母频道 ccy为空时推送全部币种
+func (w *PrivateClient) AccountGreeks(ctx context.Context, ccy string, callback func(resp *common.WsResp[*common.AccountGreeks])) error {
+	if err := w.Login(ctx); err != nil {
+		return err
+	}
+	return common.Subscribe(&w.WsClient, ctx, common.MakeCcyArg("account-greeks", ccy), callback)
+}
+func (w *PrivateClient) UAccountGreeks(ccy string) error {
+	return w.Unsubscribe(common.MakeCcyArg("account-greeks", ccy))
+}
+
 // Trades 成交订单频道
 func (w *BusinessClient) Trades(ctx context.Context, sprdId string, callback func(resp *common.WsResp[*common.Trades])) error {
 	if err := w.Login(ctx); err != nil {
@@ -87,12 +193,12 @@ func (w *PrivateClient) Orders(ctx context.Context, instType string, callback fu
 	if err := w.Login(ctx); err != nil {
 		return err
 	}
-	return common.Subscribe(&w.WsClient, ctx, common.MakeArg("orders", instType), callback)
+	return common.Subscribe(&w.WsClient, ctx, common.MakeInstTypeArg("orders", instType), callback)
 }
 
 // UOrders 取消订阅撮合交易订单频道
 func (w *PrivateClient) UOrders(instType string) error {
-	return w.Unsubscribe(common.MakeArg("orders", instType))
+	return w.Unsubscribe(common.MakeInstTypeArg("orders", instType))
 }
 
 // SpotOrders 撮合交易订单频道
@@ -100,13 +206,37 @@ func (w *PrivateClient) SpotOrders(ctx context.Context, callback func(resp *comm
 	if err := w.Login(ctx); err != nil {
 		return err
 	}
-	return common.Subscribe(&w.WsClient, ctx, &common.Arg{
-		Channel:  "orders",
-		InstType: "SPOT",
-	}, callback)
+	return common.Subscribe(&w.WsClient, ctx, common.MakeInstTypeArg("orders", "SPOT"), callback)
 }
 
 // USpotOrders 取消订阅撮合交易订单频道
 func (w *PrivateClient) USpotOrders() error {
-	return w.Unsubscribe(common.MakeArg("orders", "SPOT"))
+	return w.Unsubscribe(common.MakeInstTypeArg("orders", "SPOT"))
+}
+
+// PlaceOrder 下单
+func (w *PrivateClient) PlaceOrder(ctx context.Context, req common.PlaceOrderReq) (*common.WsResp[common.PlaceOrder], error) {
+	if err := w.Login(ctx); err != nil {
+		return nil, err
+	}
+	if req.Tag == "" {
+		req.Tag = w.brokerCode
+	}
+	return common.Request[common.PlaceOrder](&w.WsClient, ctx, "order", []common.PlaceOrderReq{req})
+}
+
+// CancelOrder 撤单
+func (w *PrivateClient) CancelOrder(ctx context.Context, req common.CancelOrderReq) (*common.WsResp[common.PlaceOrder], error) {
+	if err := w.Login(ctx); err != nil {
+		return nil, err
+	}
+	return common.Request[common.PlaceOrder](&w.WsClient, ctx, "cancel-order", []common.CancelOrderReq{req})
+}
+
+// MassCancel 批量撤销期权订单
+func (w *PrivateClient) MassCancel(ctx context.Context, req common.MassCancelReq) (*common.WsResp[common.MassCancel], error) {
+	if err := w.Login(ctx); err != nil {
+		return nil, err
+	}
+	return common.Request[common.MassCancel](&w.WsClient, ctx, "mass-cancel", []common.MassCancelReq{req})
 }
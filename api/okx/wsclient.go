@@ -2,39 +2,91 @@ package okx
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
-	"net/url"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/ws"
 )
 
 type ExchangeClient struct {
 	*PublicClient
 	*BusinessClient
 	*PrivateClient
+	Rest *RestClient // 同一组Option创建 随Close一起关闭
+}
+
+// NewWsClientWithOptions 按Option创建WS与REST客户端 参数错误时返回error 连接在首次使用时建立
+func NewWsClientWithOptions(ctx context.Context, opts ...Option) (*ExchangeClient, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := NewRestClientWithOptions(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := o.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	dialOpts := o.dialOpts
+	if o.userAgent != "" {
+		dialOpts = append([]ws.Option{ws.WithHeader(http.Header{"User-Agent": {o.userAgent}})}, dialOpts...)
+	}
+	if o.proxy != "" {
+		// socks5h需由ws包转换 否则拨号时不识别
+		dialOpts = append([]ws.Option{ws.WithProxyURL(o.proxy)}, dialOpts...)
+	}
+	urls := map[common.SvcType]common.BaseURL{}
+	for _, typ := range []common.SvcType{common.Public, common.Business, common.Private} {
+		if urls[typ], err = o.wsBaseURL(typ); err != nil {
+			return nil, err
+		}
+	}
+	w := &ExchangeClient{
+		PublicClient:   &PublicClient{WsClient: common.NewBaseWsClient(ctx, common.Public, urls[common.Public], o.signer, proxy)},
+		BusinessClient: &BusinessClient{WsClient: common.NewBaseWsClient(ctx, common.Business, urls[common.Business], o.signer, proxy)},
+		PrivateClient:  &PrivateClient{WsClient: common.NewBaseWsClient(ctx, common.Private, urls[common.Private], o.signer, proxy), brokerCode: o.brokerCode},
+		Rest:           rest,
+	}
+	w.PublicClient.AddDialOptions(dialOpts...)
+	w.BusinessClient.AddDialOptions(dialOpts...)
+	w.PrivateClient.AddDialOptions(dialOpts...)
+	if o.logger != nil {
+		w.SetLogger(o.logger)
+	}
+	if o.metrics != nil {
+		w.SetMetrics(o.metrics)
+	}
+	w.SetTracer(o.tracer)
+	return w, nil
 }
 
+// Deprecated: 参数错误时panic 使用NewWsClientWithOptions
 func NewWsClient(ctx context.Context, keyConfig common.IKeyConfig, env common.Destination, proxy ...string) *ExchangeClient {
 	return NewWsClientWithCustom(ctx, keyConfig, env, common.DefaultWsUrls, proxy...)
 }
 
+// Deprecated: 参数错误时panic 使用NewWsClientWithOptions
 func NewWsClientWithCustom(ctx context.Context, keyConfig common.IKeyConfig, env common.Destination, urls map[common.Destination]map[common.SvcType]common.BaseURL, proxy ...string) *ExchangeClient {
-	proxyURL := http.ProxyFromEnvironment
-	if len(proxy) != 0 && proxy[0] != "" {
-		parse, err := url.Parse(proxy[0])
-		if err != nil {
-			panic(err.Error())
-		}
-		proxyURL = http.ProxyURL(parse)
+	opts := []Option{WithSigner(keyConfig), WithEnv(env)}
+	for typ, u := range urls[env] {
+		opts = append(opts, WithWsURL(typ, u))
+	}
+	if len(proxy) != 0 {
+		opts = append(opts, WithProxy(proxy[0]))
 	}
-	pc := &PublicClient{WsClient: common.NewBaseWsClient(ctx, common.Public, urls[env][common.Public], keyConfig, proxyURL)}
-	bc := &BusinessClient{WsClient: common.NewBaseWsClient(ctx, common.Business, urls[env][common.Business], keyConfig, proxyURL)}
-	pvc := &PrivateClient{WsClient: common.NewBaseWsClient(ctx, common.Private, urls[env][common.Private], keyConfig, proxyURL)}
-	return &ExchangeClient{
-		pc,
-		bc,
-		pvc,
+	w, err := NewWsClientWithOptions(ctx, opts...)
+	if err != nil {
+		panic(err.Error())
 	}
+	return w
 }
 
 func (w *ExchangeClient) SetReadMonitor(readMonitor func(arg common.Arg)) {
@@ -42,11 +94,60 @@ func (w *ExchangeClient) SetReadMonitor(readMonitor func(arg common.Arg)) {
 	w.BusinessClient.ReadMonitor = readMonitor
 	w.PrivateClient.ReadMonitor = readMonitor
 }
+
+// SetLog 兼容ILogger 使用SetLogger输出结构化日志
 func (w *ExchangeClient) SetLog(log common.ILogger) {
-	w.PublicClient.Log = log
-	w.BusinessClient.Log = log
-	w.PrivateClient.Log = log
+	w.SetLogger(common.NewSlogLogger(log))
+}
+func (w *ExchangeClient) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		logger = slog.New(common.NewRedactHandler(logger.Handler()))
+	}
+	w.PublicClient.Logger = logger
+	w.BusinessClient.Logger = logger
+	w.PrivateClient.Logger = logger
+}
+func (w *ExchangeClient) SetMetrics(metrics common.Metrics) {
+	w.PublicClient.Metrics = metrics
+	w.BusinessClient.Metrics = metrics
+	w.PrivateClient.Metrics = metrics
+}
+func (w *ExchangeClient) SetTracer(tracer trace.Tracer) {
+	w.PublicClient.Tracer = tracer
+	w.BusinessClient.Tracer = tracer
+	w.PrivateClient.Tracer = tracer
 }
+func (w *ExchangeClient) SetRecorder(recorder ws.Recorder) {
+	w.PublicClient.SetRecorder(recorder)
+	w.BusinessClient.SetRecorder(recorder)
+	w.PrivateClient.SetRecorder(recorder)
+}
+
+// closeTimeout Close等待优雅关闭的最长时间
+const closeTimeout = 5 * time.Second
+
+// Close 关闭三条连接与REST客户端 最多等待closeTimeout
 func (w *ExchangeClient) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	_ = w.Shutdown(ctx)
+}
 
+// Shutdown 并发地优雅关闭三条连接 取消订阅并等待连接协程退出 之后不再重新拨号 同时关闭REST客户端
+func (w *ExchangeClient) Shutdown(ctx context.Context) error {
+	if w.Rest != nil {
+		w.Rest.Close()
+	}
+	clients := []*common.WsClient{&w.PublicClient.WsClient, &w.BusinessClient.WsClient, &w.PrivateClient.WsClient}
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func(i int, c *common.WsClient) {
+			defer wg.Done()
+			errs[i] = c.Close(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/model"
+)
+
+var (
+	ErrUnknownVenue    = errors.New("unknown venue")
+	ErrDuplicateVenue  = errors.New("venue already registered")
+	ErrInvalidRegister = errors.New("invalid venue registration")
+)
+
+// Channel Client中的推送类型
+type Channel string
+
+const (
+	ChannelTickers   Channel = "tickers"
+	ChannelCandles   Channel = "candles"
+	ChannelMarkPrice Channel = "mark-price"
+	ChannelBooks     Channel = "books"
+	ChannelOrders    Channel = "orders"
+	ChannelFills     Channel = "fills"
+	ChannelAccount   Channel = "account"
+	ChannelPositions Channel = "positions"
+)
+
+var AllChannels = []Channel{ChannelTickers, ChannelCandles, ChannelMarkPrice, ChannelBooks, ChannelOrders, ChannelFills, ChannelAccount, ChannelPositions}
+
+// Capabilities 交易所支持的功能 不支持的调用返回model.ErrNotSupported
+type Capabilities struct {
+	InstTypes  []model.InstType
+	OrderTypes []model.OrderType
+	Channels   []Channel
+	// ChannelInstTypes 仅支持部分产品类型的推送 未列出的推送支持InstTypes中的全部类型
+	ChannelInstTypes map[Channel][]model.InstType
+}
+
+func (c Capabilities) SupportsInstType(typ model.InstType) bool {
+	return contains(c.InstTypes, typ)
+}
+
+func (c Capabilities) SupportsOrderType(typ model.OrderType) bool {
+	return contains(c.OrderTypes, typ)
+}
+
+// SupportsChannel 是否支持该推送 可能仅支持部分产品类型 见SupportsChannelFor
+func (c Capabilities) SupportsChannel(channel Channel) bool {
+	return contains(c.Channels, channel)
+}
+
+// SupportsChannelFor 该产品类型是否支持该推送
+func (c Capabilities) SupportsChannelFor(channel Channel, typ model.InstType) bool {
+	if !c.SupportsChannel(channel) || !c.SupportsInstType(typ) {
+		return false
+	}
+	if types, ok := c.ChannelInstTypes[channel]; ok {
+		return contains(types, typ)
+	}
+	return true
+}
+
+func contains[T comparable](items []T, item T) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// Options 创建客户端的通用参数
+type Options struct {
+	Credentials Credentials
+	Env         common.Destination
+	Proxy       string               // 代理地址 为空时使用环境变量
+	Logger      common.ILogger       // 兼容ILogger 同时设置Slog时忽略
+	Slog        *slog.Logger         // 结构化日志 均为空时使用slog.Default()
+	Tracer      trace.TracerProvider // 为REST与WS请求创建span 为空时不记录
+}
+
+type Option func(o *Options)
+
+func WithCredentials(cred Credentials) Option {
+	return func(o *Options) {
+		o.Credentials = cred
+	}
+}
+
+func WithEnv(env common.Destination) Option {
+	return func(o *Options) {
+		o.Env = env
+	}
+}
+
+func WithProxy(proxy string) Option {
+	return func(o *Options) {
+		o.Proxy = proxy
+	}
+}
+
+// WithLogger 兼容ILogger 默认只转发Info及以上级别
+func WithLogger(logger common.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithSlog(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Slog = logger
+	}
+}
+
+// WithTracerProvider 为REST与WS请求创建span
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Options) {
+		o.Tracer = provider
+	}
+}
+
+// Factory 交易所适配器的注册信息
+type Factory struct {
+	New          func(ctx context.Context, opts Options) (Client, error)
+	Capabilities Capabilities
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Factory{}
+)
+
+// Register 注册交易所适配器 名称重复时返回ErrDuplicateVenue 通常在适配器包的init中调用
+func Register(name string, factory Factory) error {
+	if name == "" || factory.New == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidRegister, name)
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateVenue, name)
+	}
+	registry[name] = factory
+	return nil
+}
+
+// MustRegister 注册失败时panic 用于init
+func MustRegister(name string, factory Factory) {
+	if err := Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister 移除已注册的交易所
+func Unregister(name string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	delete(registry, name)
+}
+
+// Venues 已注册的交易所名称 按字母排序
+func Venues() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CapabilitiesOf 查询交易所支持的功能
+func CapabilitiesOf(name string) (Capabilities, error) {
+	factory, err := lookup(name)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return factory.Capabilities, nil
+}
+
+func lookup(name string) (Factory, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	factory, ok := registry[name]
+	if !ok {
+		return Factory{}, fmt.Errorf("%w: %s", ErrUnknownVenue, name)
+	}
+	return factory, nil
+}
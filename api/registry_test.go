@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kurosann/aqt-sdk/api/common"
+	"github.com/kurosann/aqt-sdk/api/model"
+	"github.com/kurosann/aqt-sdk/api/okx"
+)
+
+type fakeClient struct {
+	Client
+	opts Options
+	log  common.ILogger
+}
+
+func (c *fakeClient) Name() string                 { return "fake" }
+func (c *fakeClient) SetLog(logger common.ILogger) { c.log = logger }
+
+func TestRegister(t *testing.T) {
+	factory := Factory{
+		New: func(ctx context.Context, opts Options) (Client, error) {
+			return &fakeClient{opts: opts}, nil
+		},
+		Capabilities: Capabilities{
+			InstTypes:  []model.InstType{model.Spot},
+			OrderTypes: []model.OrderType{model.Limit},
+			Channels:   []Channel{ChannelTickers},
+		},
+	}
+	assert.NoError(t, Register("fake", factory))
+	defer Unregister("fake")
+	assert.ErrorIs(t, Register("fake", factory), ErrDuplicateVenue)
+	assert.ErrorIs(t, Register("", factory), ErrInvalidRegister)
+	assert.ErrorIs(t, Register("nil", Factory{}), ErrInvalidRegister)
+
+	assert.Equal(t, []string{"binance", "fake", "okx"}, Venues())
+
+	caps, err := CapabilitiesOf("fake")
+	assert.NoError(t, err)
+	assert.True(t, caps.SupportsOrderType(model.Limit))
+	assert.False(t, caps.SupportsOrderType(model.PostOnly))
+	assert.False(t, caps.SupportsChannel(ChannelFills))
+	assert.True(t, caps.SupportsInstType(model.Spot))
+
+	logger := common.DefaultLogger{}
+	client, err := New(context.Background(), "fake",
+		WithCredentials(Credentials{Apikey: "k"}),
+		WithEnv(common.TestServer),
+		WithProxy("http://127.0.0.1:1080"),
+		WithLogger(logger))
+	assert.NoError(t, err)
+	fake := client.(*fakeClient)
+	assert.Equal(t, Options{Credentials: Credentials{Apikey: "k"}, Env: common.TestServer, Proxy: "http://127.0.0.1:1080", Logger: logger}, fake.opts)
+	assert.Equal(t, logger, fake.log)
+
+	sl := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client, err = New(context.Background(), "fake", WithSlog(sl))
+	assert.NoError(t, err)
+	assert.Equal(t, common.SlogLogger{Logger: sl}, client.(*fakeClient).log)
+
+	_, err = New(context.Background(), "nope")
+	assert.ErrorIs(t, err, ErrUnknownVenue)
+	_, err = CapabilitiesOf("nope")
+	assert.ErrorIs(t, err, ErrUnknownVenue)
+}
+
+func TestRegisterConcurrent(t *testing.T) {
+	factory := Factory{New: func(ctx context.Context, opts Options) (Client, error) { return &fakeClient{}, nil }}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		name := fmt.Sprintf("venue-%d", i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, Register(name, factory))
+		}()
+		go func() {
+			defer wg.Done()
+			_ = Venues()
+			_, _ = CapabilitiesOf("okx")
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 16; i++ {
+		Unregister(fmt.Sprintf("venue-%d", i))
+	}
+	assert.Equal(t, []string{"binance", "okx"}, Venues())
+}
+
+func TestBuiltinCapabilities(t *testing.T) {
+	caps, err := CapabilitiesOf("binance")
+	assert.NoError(t, err)
+	assert.False(t, caps.SupportsInstType(model.Option))
+	for _, channel := range AllChannels {
+		assert.True(t, caps.SupportsChannel(channel))
+		assert.True(t, caps.SupportsChannelFor(channel, model.Swap))
+	}
+	assert.False(t, caps.SupportsChannelFor(ChannelMarkPrice, model.Spot))
+	assert.True(t, caps.SupportsChannelFor(ChannelTickers, model.Spot))
+	assert.False(t, caps.SupportsChannelFor(ChannelTickers, model.Option))
+
+	caps, err = CapabilitiesOf("okx")
+	assert.NoError(t, err)
+	assert.True(t, caps.SupportsChannelFor(ChannelMarkPrice, model.Spot))
+}
+
+func TestBuiltinInvalidProxy(t *testing.T) {
+	for _, venue := range []string{"okx", "binance"} {
+		client, err := New(context.Background(), venue, WithProxy("://bad"))
+		assert.Error(t, err, venue)
+		assert.Nil(t, client, venue)
+	}
+}
+
+func TestDeprecatedNewClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := NewClient(ctx, "okx", okx.KeyConfig{}, common.TestServer)
+	assert.NoError(t, err)
+	assert.IsType(t, &okx.ExchangeClient{}, client)
+
+	_, err = NewClient(ctx, "binance", okx.KeyConfig{}, common.TestServer)
+	assert.ErrorIs(t, err, ErrUnknownVenue)
+}
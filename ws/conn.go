@@ -1,17 +1,21 @@
 package ws
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// Status 兼容旧的存活判断 使用State获取完整状态
 type Status string
 
 const (
@@ -29,95 +33,217 @@ const (
 	PongMessage   MsgType = 10
 )
 
+var connSeq atomic.Uint64
+
 // Conn ws连接
 type Conn struct {
-	ctx               context.Context
-	cancel            context.CancelCauseFunc
-	Status            Status
-	conn              *websocket.Conn
-	dataCh            map[string]chan Data
-	lock              sync.RWMutex
-	proxy             func(req *http.Request) (*url.URL, error)
-	writeTimeout      time.Duration // 写入超时时间
-	mt                MsgType       // 连接使用的消息类型
-	keepaliveFn       func(*Conn) error
-	keepaliveListenFn func([]byte) bool
+	// Deprecated: 非并发安全 使用Alive或State
+	Status Status
+
+	id           string
+	ctx          context.Context
+	cancel       context.CancelCauseFunc
+	state        atomic.Int32
+	onState      func(conn *Conn, from, to State)
+	conn         *websocket.Conn
+	watchers     map[string]*watcher
+	snapshot     atomic.Pointer[[]*watcher] // 分发时无锁读取
+	lock         sync.RWMutex               // 保护watchers
+	writeLock    sync.Mutex                 // 串行写入 与分发互不阻塞
+	queueSize    int
+	overflow     Overflow
+	proxy        func(req *http.Request) (*url.URL, error)
+	header       http.Header // 握手请求头
+	dial         dialConfig
+	optErr       error         // 参数错误 拨号时返回
+	writeTimeout time.Duration // 写入超时时间
+	mt           MsgType       // 连接使用的消息类型
+	keepaliveFns atomic.Pointer[keepaliveFuncs]
+	recorder     Recorder
+	wg           sync.WaitGroup // 读取与心跳协程
+	readDone     chan struct{}
+	closeOnce    sync.Once
+	closeSent    atomic.Bool
+	pingSent     atomic.Int64 // 最近一次ping的发送时间 纳秒
+	lastPong     atomic.Int64 // 最近一次收到pong的时间 纳秒
+	lastRead     atomic.Int64 // 最近一次收到任意帧的时间 纳秒
+	rtt          atomic.Int64
+	onPingRTT    func(rtt time.Duration)
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+	readIdle     time.Duration
 }
 
 // DialContext 拨号 使用context控制
 func DialContext(ctx context.Context, address string, opts ...Option) (*Conn, error) {
 	ctx, cancel := context.WithCancelCause(ctx)
 	c := &Conn{
-		ctx:               ctx,
-		cancel:            cancel,
-		Status:            Alive,
-		dataCh:            map[string]chan Data{},
-		writeTimeout:      time.Second * 3,
-		mt:                TextMessage,
-		keepaliveFn:       func(conn *Conn) error { return nil },
-		keepaliveListenFn: func(bytes []byte) bool { return true },
+		Status:       Dead,
+		id:           newConnId(),
+		ctx:          ctx,
+		cancel:       cancel,
+		watchers:     map[string]*watcher{},
+		queueSize:    DefaultQueueSize,
+		overflow:     DefaultOverflow,
+		writeTimeout: time.Second * 3,
+		mt:           TextMessage,
+		readDone:     make(chan struct{}),
+		pingInterval: DefaultPingInterval,
+		pongTimeout:  DefaultPongTimeout,
 	}
+	c.SetKeepAlive(func(conn *Conn) error { return nil }, func(bytes []byte) bool { return false })
 	for _, opt := range opts {
 		opt(c)
 	}
-	dialer := websocket.DefaultDialer
-	if c.proxy != nil {
-		dialer.Proxy = c.proxy
-	}
-	dialer.TLSClientConfig = &tls.Config{
-		MinVersion: tls.VersionTLS13,
+	if c.optErr != nil {
+		cancel(c.optErr)
+		return nil, c.optErr
 	}
+	c.clampPongTimeout()
+	dialer := c.newDialer()
 
-	header := http.Header{}
-	header.Add("User-Agent", "Go websockets/13.0")
+	header := c.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", "Go websockets/13.0")
+	}
 	conn, rp, err := dialer.DialContext(ctx, address, header)
 	if err != nil {
+		cancel(err)
+		c.setState(StateClosed)
 		return nil, err
 	}
 	if rp.StatusCode != 101 {
-		return nil, fmt.Errorf("connect response err: %v", rp)
+		err = fmt.Errorf("connect response err: %v", rp)
+		cancel(err)
+		_ = conn.Close()
+		c.setState(StateClosed)
+		return nil, err
 	}
 	c.conn = conn
+	c.dial.apply(conn)
+	c.record(Opened, TextMessage, []byte(address))
+	conn.SetPongHandler(c.onPong)
+	conn.SetPingHandler(c.onPing)
+	c.lastRead.Store(time.Now().UnixNano())
+	c.setState(StateOpen)
+	// 上层ctx结束时关闭连接 使阻塞中的读取返回
+	context.AfterFunc(ctx, func() { c.Close(context.Cause(ctx)) })
+	c.wg.Add(2)
 	go c.keepalive()
 	go c.read()
+	if c.readIdle > 0 {
+		c.wg.Add(1)
+		go c.watchdog()
+	}
 	return c, nil
 }
 
-func (c *Conn) Context() context.Context {
-	return c.ctx
+// NewReplayConn 不依赖网络的连接 写入被丢弃 数据通过Inject注入 用于回放录制
+func NewReplayConn(ctx context.Context, opts ...Option) *Conn {
+	ctx, cancel := context.WithCancelCause(ctx)
+	c := &Conn{
+		Status:    Dead,
+		id:        newConnId(),
+		ctx:       ctx,
+		cancel:    cancel,
+		watchers:  map[string]*watcher{},
+		queueSize: DefaultQueueSize,
+		overflow:  DefaultOverflow,
+		mt:        TextMessage,
+		readDone:  make(chan struct{}),
+	}
+	c.SetKeepAlive(func(conn *Conn) error { return nil }, func(bytes []byte) bool { return false })
+	for _, opt := range opts {
+		opt(c)
+	}
+	close(c.readDone)
+	c.setState(StateOpen)
+	context.AfterFunc(ctx, func() { c.Close(context.Cause(ctx)) })
+	return c
 }
-func (c *Conn) Close(err error) {
-	c.cancel(err)
-	c.Status = Dead
-	_ = c.conn.Close()
+
+func newConnId() string {
+	return strconv.FormatInt(time.Now().UnixMilli(), 36) + "-" + strconv.FormatUint(connSeq.Add(1), 10)
 }
-func (c *Conn) SetKeepAlive(keepaliveFn func(conn *Conn) error, keepaliveListenFn func(data []byte) bool) {
-	c.keepaliveFn = keepaliveFn
-	c.keepaliveListenFn = keepaliveListenFn
+
+// Id 连接标识 用于区分录制中的不同连接
+func (c *Conn) Id() string {
+	return c.id
 }
 
-// keepalive
-func (c *Conn) keepalive() {
-	c.conn.SetPongHandler(func(appData string) error {
-		c.Status = Alive
-		return nil
-	})
-	for {
-		if c.Status == Dead {
-			return
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Close 立即关闭连接 尽力发送关闭帧 可重复调用 不等待协程退出
+// err为关闭原因 可通过context.Cause(c.Context())获取 为nil或正在优雅关闭时使用ErrClosed
+func (c *Conn) Close(err error) {
+	c.closeOnce.Do(func() {
+		if err == nil || c.State() >= StateClosing {
+			err = ErrClosed
 		}
-		err := c.conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(time.Second*3))
-		if err != nil {
-			c.Close(err)
-			return
+		c.setState(StateClosing)
+		c.cancel(err)
+		var data []byte
+		if c.conn != nil {
+			c.writeClose()
+			_ = c.conn.Close()
 		}
-		err = c.keepaliveFn(c)
-		if err != nil {
-			c.Close(err)
-			return
+		if !errors.Is(err, ErrClosed) {
+			data = []byte(err.Error())
+		}
+		c.record(Closed, CloseMessage, data)
+		c.setState(StateClosed)
+	})
+}
+
+// Shutdown 优雅关闭 发送关闭帧并等待对端确认 再等待读取与心跳协程退出
+// ctx结束时强制关闭并返回ctx的错误
+func (c *Conn) Shutdown(ctx context.Context) error {
+	c.setState(StateClosing)
+	if c.conn != nil && c.ctx.Err() == nil {
+		c.writeClose()
+		select {
+		case <-c.readDone:
+		case <-ctx.Done():
 		}
-		time.Sleep(time.Second * 20)
 	}
+	c.Close(ErrClosed)
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeClose 发送一次正常关闭帧
+func (c *Conn) writeClose() {
+	if !c.closeSent.CompareAndSwap(false, true) {
+		return
+	}
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.writeTimeout))
+}
+
+// record 写入录制器 在收发时打上时间戳 发出的帧中登录签名等字段脱敏后再录制
+func (c *Conn) record(dir Direction, mt MsgType, data []byte) {
+	if c.recorder == nil {
+		return
+	}
+	if dir == Outbound {
+		data = Redact(data)
+	}
+	now := time.Now()
+	c.recorder.Record(Frame{ConnId: c.id, Dir: dir, Typ: mt, Wall: now.UnixNano(), Mono: monoNow(now), Data: data})
 }
 
 type Data struct {
@@ -125,14 +251,23 @@ type Data struct {
 	Data []byte
 }
 
-// RegisterWatch 注册监听
-func (c *Conn) RegisterWatch(id string) <-chan Data {
+// RegisterWatch 注册监听 每个监听有独立的有界队列 id重复时关闭之前的监听
+func (c *Conn) RegisterWatch(id string, opts ...WatchOption) <-chan Data {
+	w := &watcher{id: id, size: c.queueSize, overflow: c.overflow, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.ch = make(chan Data, w.size)
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	data := make(chan Data, 1)
-	c.dataCh[id] = data
-	return data
+	if old, ok := c.watchers[id]; ok {
+		old.close()
+	}
+	c.watchers[id] = w
+	c.refreshSnapshot()
+	return w.ch
 }
 
 // UnregisterWatch 注销监听
@@ -141,16 +276,45 @@ func (c *Conn) UnregisterWatch(id string) {
 	defer c.lock.Unlock()
 
 	// 关闭、通知并回收资源
-	if ch, ok := c.dataCh[id]; ok {
-		close(ch)
+	if w, ok := c.watchers[id]; ok {
+		w.close()
+		delete(c.watchers, id)
+		c.refreshSnapshot()
+	}
+}
+
+// refreshSnapshot 需持有c.lock
+func (c *Conn) refreshSnapshot() {
+	watchers := make([]*watcher, 0, len(c.watchers))
+	for _, w := range c.watchers {
+		watchers = append(watchers, w)
+	}
+	c.snapshot.Store(&watchers)
+}
+
+// WatchStats 各监听队列的积压情况
+func (c *Conn) WatchStats() map[string]WatchStats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	stats := make(map[string]WatchStats, len(c.watchers))
+	for id, w := range c.watchers {
+		stats[id] = w.stats()
 	}
-	delete(c.dataCh, id)
+	return stats
 }
 
-// Write 写入数据
+// Write 写入数据 与分发使用不同的锁 慢消费者不会阻塞写入
 func (c *Conn) Write(data []byte) error {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.State() >= StateClosing {
+		return context.Cause(c.ctx)
+	}
+	c.record(Outbound, c.mt, data)
+	if c.conn == nil {
+		return nil
+	}
 	_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
 	err := c.conn.WriteMessage(int(c.mt), data)
 	if err != nil {
@@ -165,32 +329,69 @@ func (c *Conn) Write(data []byte) error {
 
 // 读取数据流
 func (c *Conn) read() {
+	defer c.wg.Done()
+	defer close(c.readDone)
 	for {
-		// 并发控制
-		select {
-		case <-c.ctx.Done():
-			return
-		default:
-		}
-		mt, data, err := c.conn.ReadMessage()
+		mt, data, err := c.readMessage()
 		if err != nil {
-			c.Status = Dead
-			c.cancel(err)
-			_ = c.conn.Close()
+			c.Close(err)
 			return
 		}
-		if c.keepaliveListenFn(data) {
-			c.Status = Alive
+		now := time.Now().UnixNano()
+		c.lastRead.Store(now)
+		c.record(Inbound, MsgType(mt), data)
+		if c.keepaliveFns.Load().listenFn(data) {
+			c.lastPong.Store(now)
 			continue
 		}
-		// 写入已注册的监听中
-		c.lock.RLock()
-		for _, ch := range c.dataCh {
-			ch <- Data{
-				Typ:  MsgType(mt),
-				Data: data,
-			}
+		c.dispatch(MsgType(mt), data)
+	}
+}
+
+// readBufPool 读取缓冲 避免大帧在读取时多次扩容
+var readBufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// maxPooledBuf 超过该容量的缓冲不放回池中
+const maxPooledBuf = 1 << 20
+
+// readMessage 读取一帧到池化缓冲 再拷贝为独立的切片 分发后的数据不会被修改
+func (c *Conn) readMessage() (int, []byte, error) {
+	mt, r, err := c.conn.NextReader()
+	if err != nil {
+		return mt, nil, err
+	}
+	buf := readBufPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuf {
+			readBufPool.Put(buf)
+		}
+	}()
+	buf.Reset()
+	if _, err = buf.ReadFrom(r); err != nil {
+		return mt, nil, err
+	}
+	return mt, bytes.Clone(buf.Bytes()), nil
+}
+
+// Inject 注入一帧数据 与从网络读取到的数据一样分发给监听者
+func (c *Conn) Inject(mt MsgType, data []byte) {
+	c.record(Inbound, mt, data)
+	c.dispatch(mt, data)
+}
+
+// dispatch 按各自的溢出策略写入已注册的监听中 不持有锁
+func (c *Conn) dispatch(mt MsgType, data []byte) {
+	watchers := c.snapshot.Load()
+	if watchers == nil {
+		return
+	}
+	for _, w := range *watchers {
+		if !w.push(c, Data{Typ: mt, Data: data}) {
+			c.Close(fmt.Errorf("%w: %s", ErrSlowConsumer, w.id))
+			return
+		}
+		if c.ctx.Err() != nil {
+			return
 		}
-		c.lock.RUnlock()
 	}
 }
@@ -0,0 +1,121 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type memRecorder struct {
+	lock   sync.Mutex
+	frames []Frame
+}
+
+func (r *memRecorder) Record(frame Frame) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.frames = append(r.frames, frame)
+}
+
+func (r *memRecorder) count(dir Direction) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	n := 0
+	for _, f := range r.frames {
+		if f.Dir == dir {
+			n++
+		}
+	}
+	return n
+}
+
+// newServer 回显文本帧 closed在收到关闭帧时写入关闭码
+func newServer(t *testing.T, closed chan<- int) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				if ce, ok := err.(*websocket.CloseError); ok && closed != nil {
+					closed <- ce.Code
+				}
+				return
+			}
+			_ = conn.WriteMessage(mt, data)
+		}
+	}))
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestConnShutdown(t *testing.T) {
+	closed := make(chan int, 1)
+	srv := newServer(t, closed)
+	defer srv.Close()
+
+	var lock sync.Mutex
+	var states []State
+	rec := &memRecorder{}
+	conn, err := DialContext(context.Background(), wsURL(srv), WithRecorder(rec), WithStateHook(func(conn *Conn, from, to State) {
+		lock.Lock()
+		defer lock.Unlock()
+		states = append(states, to)
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, StateOpen, conn.State())
+	assert.Equal(t, Status(Alive), conn.Status)
+
+	assert.True(t, conn.Transition(StateOpen, StateAuthenticating))
+	assert.False(t, conn.Transition(StateOpen, StateAuthenticating))
+	assert.True(t, conn.Alive())
+	assert.True(t, conn.Transition(StateAuthenticating, StateOpen))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	assert.NoError(t, conn.Shutdown(ctx))
+	assert.Equal(t, Status(Dead), conn.Status)
+	assert.Equal(t, websocket.CloseNormalClosure, <-closed)
+	assert.Equal(t, StateClosed, conn.State())
+	assert.ErrorIs(t, context.Cause(conn.Context()), ErrClosed)
+	assert.ErrorIs(t, conn.Write([]byte("late")), ErrClosed)
+
+	// 重复关闭只记录一次
+	conn.Close(nil)
+	assert.Equal(t, 1, rec.count(Closed))
+	assert.False(t, conn.Transition(StateClosed, StateOpen))
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []State{StateOpen, StateAuthenticating, StateOpen, StateClosing, StateClosed}, states)
+}
+
+func TestConnParentCancel(t *testing.T) {
+	srv := newServer(t, nil)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err := DialContext(ctx, wsURL(srv))
+	assert.NoError(t, err)
+	cancel()
+	// 上层ctx结束后读取与心跳协程退出
+	shutdown, stop := context.WithTimeout(context.Background(), 3*time.Second)
+	defer stop()
+	assert.NoError(t, conn.Shutdown(shutdown))
+	assert.Equal(t, StateClosed, conn.State())
+	assert.ErrorIs(t, context.Cause(conn.Context()), context.Canceled)
+}
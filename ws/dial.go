@@ -0,0 +1,58 @@
+package ws
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialConfig 每个连接独立的拨号参数
+type dialConfig struct {
+	tlsConfig        *tls.Config
+	compression      bool
+	compressionLevel int
+	handshakeTimeout time.Duration
+	readLimit        int64
+	readBufferSize   int
+	writeBufferSize  int
+	localAddr        net.Addr
+}
+
+// newDialer 每次拨号使用独立的拨号器 不修改websocket.DefaultDialer
+func (c *Conn) newDialer() *websocket.Dialer {
+	dialer := *websocket.DefaultDialer
+	if c.proxy != nil {
+		dialer.Proxy = c.proxy
+	}
+	if c.dial.tlsConfig != nil {
+		dialer.TLSClientConfig = c.dial.tlsConfig.Clone()
+	} else {
+		dialer.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if c.dial.handshakeTimeout > 0 {
+		dialer.HandshakeTimeout = c.dial.handshakeTimeout
+	}
+	dialer.ReadBufferSize = c.dial.readBufferSize
+	dialer.WriteBufferSize = c.dial.writeBufferSize
+	dialer.EnableCompression = c.dial.compression
+	if c.dial.localAddr != nil {
+		netDialer := &net.Dialer{LocalAddr: c.dial.localAddr}
+		dialer.NetDialContext = netDialer.DialContext
+	}
+	return &dialer
+}
+
+// apply 握手成功后的连接参数
+func (d dialConfig) apply(conn *websocket.Conn) {
+	if d.compression {
+		conn.EnableWriteCompression(true)
+		if d.compressionLevel != 0 {
+			_ = conn.SetCompressionLevel(d.compressionLevel)
+		}
+	}
+	if d.readLimit > 0 {
+		conn.SetReadLimit(d.readLimit)
+	}
+}
@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialOptions(t *testing.T) {
+	type handshake struct {
+		header     http.Header
+		remote     string
+		extensions string
+	}
+	got := make(chan handshake, 1)
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := handshake{header: r.Header.Clone(), remote: r.RemoteAddr, extensions: r.Header.Get("Sec-Websocket-Extensions")}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		got <- h
+		for {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteMessage(mt, append(data, data...))
+		}
+	}))
+	defer srv.Close()
+
+	// 默认只信任系统根证书 测试证书握手失败
+	_, err := DialContext(context.Background(), "wss"+strings.TrimPrefix(srv.URL, "https"))
+	assert.Error(t, err)
+
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+	conn, err := DialContext(context.Background(), "wss"+strings.TrimPrefix(srv.URL, "https"),
+		WithTLSConfig(tlsConfig),
+		WithCompression(0),
+		WithHeader(http.Header{"X-A": {"1"}}),
+		WithHeader(http.Header{"X-B": {"2"}}),
+		WithHandshakeTimeout(time.Second),
+		WithBufferSizes(1024, 1024),
+		WithReadLimit(32),
+		WithLocalAddr(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}),
+	)
+	assert.NoError(t, err)
+	h := <-got
+	assert.Equal(t, "1", h.header.Get("X-A"))
+	assert.Equal(t, "2", h.header.Get("X-B"))
+	assert.Equal(t, "Go websockets/13.0", h.header.Get("User-Agent"))
+	assert.Contains(t, h.extensions, "permessage-deflate")
+	assert.True(t, strings.HasPrefix(h.remote, "127.0.0.1:"))
+
+	ch := conn.RegisterWatch("test")
+	assert.NoError(t, conn.Write([]byte("abcd")))
+	select {
+	case d := <-ch:
+		assert.Equal(t, "abcdabcd", string(d.Data))
+	case <-conn.Context().Done():
+		t.Fatal(context.Cause(conn.Context()))
+	}
+	// 超过读取上限时关闭连接 上限按压缩后的长度计算
+	payload := make([]byte, 128)
+	_, _ = rand.Read(payload)
+	assert.NoError(t, conn.Write([]byte(hex.EncodeToString(payload))))
+	select {
+	case <-conn.Context().Done():
+		assert.ErrorIs(t, context.Cause(conn.Context()), websocket.ErrReadLimit)
+	case <-time.After(3 * time.Second):
+		t.Fatal("read limit not enforced")
+	}
+}
+
+func TestDialProxyURL(t *testing.T) {
+	for _, proxy := range []string{"ftp://127.0.0.1:21", "socks5://", "://bad"} {
+		_, err := DialContext(context.Background(), "ws://127.0.0.1:1", WithProxyURL(proxy))
+		assert.ErrorIs(t, err, ErrInvalidProxy, proxy)
+	}
+	// socks5h按socks5处理 代理不可达时拨号失败而非参数错误
+	_, err := DialContext(context.Background(), "ws://127.0.0.1:1", WithProxyURL("socks5h://127.0.0.1:1"), WithHandshakeTimeout(time.Second))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidProxy)
+}
@@ -0,0 +1,143 @@
+package ws
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// ErrPongTimeout 发送ping后未在超时时间内收到pong
+	ErrPongTimeout = errors.New("ws: pong timeout")
+	// ErrReadIdle 超过空闲时间未收到任何帧
+	ErrReadIdle = errors.New("ws: read idle timeout")
+)
+
+const (
+	DefaultPingInterval = 20 * time.Second
+	DefaultPongTimeout  = 10 * time.Second
+)
+
+type keepaliveFuncs struct {
+	fn       func(conn *Conn) error
+	listenFn func(data []byte) bool
+}
+
+// SetKeepAlive 应用层心跳 keepaliveFn随每次ping调用 keepaliveListenFn识别应用层的pong 识别的帧不分发
+// 拨号后调用时 之前到达的帧按默认规则处理 需要时使用WithKeepAlive
+func (c *Conn) SetKeepAlive(keepaliveFn func(conn *Conn) error, keepaliveListenFn func(data []byte) bool) {
+	c.keepaliveFns.Store(&keepaliveFuncs{fn: keepaliveFn, listenFn: keepaliveListenFn})
+}
+
+// RTT 最近一次ping的往返时间 尚未测得时为0
+func (c *Conn) RTT() time.Duration {
+	return time.Duration(c.rtt.Load())
+}
+
+// LastRead 最近一次收到任意帧(含控制帧)的时间
+func (c *Conn) LastRead() time.Time {
+	return time.Unix(0, c.lastRead.Load())
+}
+
+func (c *Conn) onPong(appData string) error {
+	now := time.Now()
+	c.lastRead.Store(now.UnixNano())
+	c.lastPong.Store(now.UnixNano())
+	if sent := c.pingSent.Load(); sent != 0 {
+		rtt := now.Sub(time.Unix(0, sent))
+		c.rtt.Store(int64(rtt))
+		if c.onPingRTT != nil {
+			c.onPingRTT(rtt)
+		}
+	}
+	return nil
+}
+
+// onPing 与默认处理一致地回复pong 同时记录读取时间
+func (c *Conn) onPing(appData string) error {
+	c.lastRead.Store(time.Now().UnixNano())
+	err := c.conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(c.writeTimeout))
+	if errors.Is(err, websocket.ErrCloseSent) {
+		return nil
+	}
+	return err
+}
+
+// clampPongTimeout pongTimeout需小于pingInterval 否则waitPong总是先等到下一次心跳
+func (c *Conn) clampPongTimeout() {
+	if c.pongTimeout >= c.pingInterval {
+		c.pongTimeout = c.pingInterval / 2
+	}
+}
+
+// keepalive 每隔pingInterval发送心跳 pongTimeout内未收到pong则关闭连接
+func (c *Conn) keepalive() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+	for {
+		sent := time.Now()
+		c.record(Outbound, PingMessage, []byte("ping"))
+		c.pingSent.Store(sent.UnixNano())
+		err := c.conn.WriteControl(websocket.PingMessage, []byte("ping"), sent.Add(c.writeTimeout))
+		if err != nil {
+			c.Close(err)
+			return
+		}
+		err = c.keepaliveFns.Load().fn(c)
+		if err != nil {
+			c.Close(err)
+			return
+		}
+		if !c.waitPong(sent, ticker.C) {
+			return
+		}
+	}
+}
+
+// waitPong 等待下一次心跳 期间检查pong超时 连接关闭时返回false
+func (c *Conn) waitPong(sent time.Time, next <-chan time.Time) bool {
+	var timeout <-chan time.Time
+	if c.pongTimeout > 0 {
+		timer := time.NewTimer(c.pongTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		select {
+		case <-c.ctx.Done():
+			return false
+		case <-timeout:
+			if c.lastPong.Load() < sent.UnixNano() {
+				c.Close(ErrPongTimeout)
+				return false
+			}
+			timeout = nil
+		case <-next:
+			return true
+		}
+	}
+}
+
+// watchdog 超过readIdle未收到任何帧时关闭连接
+func (c *Conn) watchdog() {
+	defer c.wg.Done()
+	interval := c.readIdle / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if time.Since(c.LastRead()) > c.readIdle {
+				c.Close(ErrReadIdle)
+				return
+			}
+		}
+	}
+}
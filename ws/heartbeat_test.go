@@ -0,0 +1,116 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSilentServer 不回复ping也不发送数据
+func newSilentServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		conn.SetPingHandler(func(string) error { return nil })
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestHeartbeatRTT(t *testing.T) {
+	srv := newServer(t, nil)
+	defer srv.Close()
+
+	rtts := make(chan time.Duration, 1)
+	conn, err := DialContext(context.Background(), wsURL(srv), WithPingInterval(time.Hour), WithPingRTT(func(rtt time.Duration) {
+		rtts <- rtt
+	}))
+	assert.NoError(t, err)
+	defer conn.Close(nil)
+	select {
+	case rtt := <-rtts:
+		assert.Greater(t, rtt, time.Duration(0))
+		assert.Equal(t, rtt, conn.RTT())
+	case <-time.After(3 * time.Second):
+		t.Fatal("no pong")
+	}
+	assert.WithinDuration(t, time.Now(), conn.LastRead(), time.Second)
+}
+
+func TestHeartbeatPongTimeout(t *testing.T) {
+	srv := newSilentServer(t)
+	defer srv.Close()
+
+	conn, err := DialContext(context.Background(), wsURL(srv), WithPingInterval(time.Hour), WithPongTimeout(50*time.Millisecond))
+	assert.NoError(t, err)
+	select {
+	case <-conn.Context().Done():
+		assert.ErrorIs(t, context.Cause(conn.Context()), ErrPongTimeout)
+	case <-time.After(3 * time.Second):
+		t.Fatal("pong timeout not detected")
+	}
+	assert.Eventually(t, func() bool { return conn.State() == StateClosed }, time.Second, time.Millisecond)
+}
+
+func TestHeartbeatPongTimeoutClamp(t *testing.T) {
+	srv := newSilentServer(t)
+	defer srv.Close()
+
+	conn, err := DialContext(context.Background(), wsURL(srv), WithPingInterval(50*time.Millisecond), WithPongTimeout(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 25*time.Millisecond, conn.pongTimeout)
+	select {
+	case <-conn.Context().Done():
+		assert.ErrorIs(t, context.Cause(conn.Context()), ErrPongTimeout)
+	case <-time.After(3 * time.Second):
+		t.Fatal("pong timeout not detected")
+	}
+}
+
+func TestHeartbeatReadIdle(t *testing.T) {
+	srv := newSilentServer(t)
+	defer srv.Close()
+
+	conn, err := DialContext(context.Background(), wsURL(srv),
+		WithPingInterval(time.Hour), WithPongTimeout(0), WithReadIdleTimeout(100*time.Millisecond))
+	assert.NoError(t, err)
+	select {
+	case <-conn.Context().Done():
+		assert.ErrorIs(t, context.Cause(conn.Context()), ErrReadIdle)
+	case <-time.After(3 * time.Second):
+		t.Fatal("idle connection not detected")
+	}
+}
+
+func TestHeartbeatAppPong(t *testing.T) {
+	srv := newServer(t, nil)
+	defer srv.Close()
+
+	// 应用层pong经回显返回 不分发给监听者
+	conn, err := DialContext(context.Background(), wsURL(srv), WithPingInterval(time.Hour), WithKeepAlive(
+		func(conn *Conn) error { return conn.Write([]byte("pong")) },
+		func(data []byte) bool { return string(data) == "pong" }))
+	assert.NoError(t, err)
+	defer conn.Close(nil)
+	ch := conn.RegisterWatch("test")
+	assert.NoError(t, conn.Write([]byte("data")))
+	select {
+	case d := <-ch:
+		assert.Equal(t, "data", string(d.Data))
+	case <-time.After(3 * time.Second):
+		t.Fatal("no data")
+	}
+}
@@ -1,11 +1,18 @@
 package ws
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
 )
 
+// ErrInvalidProxy 代理地址无效
+var ErrInvalidProxy = errors.New("ws: invalid proxy")
+
 type Option func(conn *Conn)
 
 func WithProxy(proxy func(req *http.Request) (*url.URL, error)) Option {
@@ -23,3 +30,147 @@ func WithMsgType(mt MsgType) Option {
 		conn.mt = mt
 	}
 }
+
+// WithRecorder 录制收发的全部帧
+func WithRecorder(recorder Recorder) Option {
+	return func(conn *Conn) {
+		conn.recorder = recorder
+	}
+}
+
+// WithHeader 握手时附加的请求头 多次调用时按键合并 同名键以后者为准
+func WithHeader(header http.Header) Option {
+	return func(conn *Conn) {
+		if conn.header == nil {
+			conn.header = http.Header{}
+		}
+		for k, v := range header {
+			conn.header[k] = v
+		}
+	}
+}
+
+// WithPingRTT 收到pong时回调心跳往返时间
+func WithPingRTT(f func(rtt time.Duration)) Option {
+	return func(conn *Conn) {
+		conn.onPingRTT = f
+	}
+}
+
+// WithWatchQueue 监听队列的默认长度与溢出策略 默认DefaultQueueSize与DefaultOverflow
+// 可通过RegisterWatch的WithQueue按监听覆盖
+func WithWatchQueue(size int, overflow Overflow) Option {
+	return func(conn *Conn) {
+		if size > 0 {
+			conn.queueSize = size
+		}
+		conn.overflow = overflow
+	}
+}
+
+// WithStateHook 状态变化时回调 回调中不应阻塞
+func WithStateHook(f func(conn *Conn, from, to State)) Option {
+	return func(conn *Conn) {
+		conn.onState = f
+	}
+}
+
+// WithKeepAlive 同SetKeepAlive 在读取开始前生效
+func WithKeepAlive(keepaliveFn func(conn *Conn) error, keepaliveListenFn func(data []byte) bool) Option {
+	return func(conn *Conn) {
+		conn.SetKeepAlive(keepaliveFn, keepaliveListenFn)
+	}
+}
+
+// WithPingInterval 发送心跳的间隔 默认DefaultPingInterval
+func WithPingInterval(interval time.Duration) Option {
+	return func(conn *Conn) {
+		if interval > 0 {
+			conn.pingInterval = interval
+		}
+	}
+}
+
+// WithPongTimeout 发送ping后等待pong(控制帧或SetKeepAlive识别的应用层pong)的时间 默认DefaultPongTimeout 0表示不检测
+// 不小于心跳间隔时下一次ping前来不及检测 拨号时缩短为间隔的一半
+func WithPongTimeout(timeout time.Duration) Option {
+	return func(conn *Conn) {
+		conn.pongTimeout = timeout
+	}
+}
+
+// WithReadIdleTimeout 超过该时间未收到任何帧时以ErrReadIdle关闭连接 默认不检测
+func WithReadIdleTimeout(timeout time.Duration) Option {
+	return func(conn *Conn) {
+		conn.readIdle = timeout
+	}
+}
+
+// WithProxyURL 代理地址 支持http/https/socks5/socks5h 解析失败时拨号返回ErrInvalidProxy
+func WithProxyURL(proxy string) Option {
+	return func(conn *Conn) {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			conn.optErr = fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+			return
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		case "socks5h":
+			// 代理端解析域名 与socks5的处理方式相同
+			u.Scheme = "socks5"
+		default:
+			conn.optErr = fmt.Errorf("%w: unsupported scheme %q", ErrInvalidProxy, u.Scheme)
+			return
+		}
+		if u.Host == "" {
+			conn.optErr = fmt.Errorf("%w: missing host", ErrInvalidProxy)
+			return
+		}
+		conn.proxy = http.ProxyURL(u)
+	}
+}
+
+// WithCompression 协商permessage-deflate压缩 level为flate压缩级别 0使用默认级别
+func WithCompression(level int) Option {
+	return func(conn *Conn) {
+		conn.dial.compression = true
+		conn.dial.compressionLevel = level
+	}
+}
+
+// WithTLSConfig 自定义TLS参数 默认最低TLS 1.2
+func WithTLSConfig(config *tls.Config) Option {
+	return func(conn *Conn) {
+		conn.dial.tlsConfig = config
+	}
+}
+
+// WithHandshakeTimeout 握手超时 默认45秒
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return func(conn *Conn) {
+		conn.dial.handshakeTimeout = timeout
+	}
+}
+
+// WithReadLimit 单条消息的最大字节数 超出时连接以websocket.ErrReadLimit关闭
+func WithReadLimit(limit int64) Option {
+	return func(conn *Conn) {
+		conn.dial.readLimit = limit
+	}
+}
+
+// WithBufferSizes 读写缓冲区大小 0使用默认的4096
+func WithBufferSizes(read, write int) Option {
+	return func(conn *Conn) {
+		conn.dial.readBufferSize = read
+		conn.dial.writeBufferSize = write
+	}
+}
+
+// WithLocalAddr 绑定本地地址 用于多网卡或多出口IP
+func WithLocalAddr(addr net.Addr) Option {
+	return func(conn *Conn) {
+		conn.dial.localAddr = addr
+	}
+}
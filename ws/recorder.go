@@ -0,0 +1,320 @@
+package ws
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Direction string
+
+const (
+	Inbound  Direction = "in"
+	Outbound Direction = "out"
+	Opened   Direction = "open" // 建立连接 Data为连接地址
+	Closed   Direction = "close"
+)
+
+// Frame 一条收发的帧
+type Frame struct {
+	ConnId string    `json:"connId"`
+	Dir    Direction `json:"dir"`
+	Typ    MsgType   `json:"typ"`
+	Wall   int64     `json:"wall"` // 收发时的墙上时间 unix纳秒
+	Mono   int64     `json:"mono"` // 收发时的单调时间 纳秒 仅用于计算帧间隔
+	Data   []byte    `json:"data"`
+}
+
+// SensitiveJSON 登录等帧中的密钥与签名字段 common.RedactJSON共用该定义
+var SensitiveJSON = regexp.MustCompile(`(?i)"(apiKey|secretKey|passphrase|sign|signature|listenKey)"\s*:\s*"[^"]*"`)
+
+// Redact 将密钥与签名字段替换为*** 不修改原数据 不含敏感字段时原样返回
+func Redact(data []byte) []byte {
+	if !SensitiveJSON.Match(data) {
+		return data
+	}
+	return SensitiveJSON.ReplaceAll(data, []byte(`"$1":"***"`))
+}
+
+// monoStart 单调时间的起点
+var monoStart = time.Now()
+
+// monoNow t相对monoStart的单调时间
+func monoNow(t time.Time) int64 {
+	return int64(t.Sub(monoStart))
+}
+
+// Recorder 帧录制器 实现需并发安全
+type Recorder interface {
+	Record(frame Frame)
+}
+
+// FileRecorder 将帧以gzip压缩的JSON行追加写入文件 超过大小后滚动
+// Record只把帧放入缓冲 由单独的协程编码写入 不阻塞读协程
+type FileRecorder struct {
+	dir      string
+	prefix   string
+	maxBytes int64
+	start    time.Time
+	frames   chan Frame
+	done     chan struct{}
+	lock     sync.RWMutex
+	closed   bool
+	dropped  atomic.Int64
+
+	// 以下字段只在写协程中访问
+	file    *os.File
+	gz      *gzip.Writer
+	written int64
+	index   int
+
+	errLock sync.Mutex
+	err     error
+}
+
+const (
+	// RecorderBuffer 等待写入的帧数 缓冲满时丢弃新帧
+	RecorderBuffer = 4096
+	// RecorderFlushInterval 定时刷新压缩流 进程异常退出时最多丢失该时间内的数据
+	RecorderFlushInterval = time.Second
+)
+
+// NewFileRecorder maxBytes为单个文件未压缩的最大字节数 0表示不滚动
+func NewFileRecorder(dir, prefix string, maxBytes int64) (*FileRecorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	r := &FileRecorder{
+		dir:      dir,
+		prefix:   prefix,
+		maxBytes: maxBytes,
+		start:    time.Now(),
+		frames:   make(chan Frame, RecorderBuffer),
+		done:     make(chan struct{}),
+	}
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	go r.run()
+	return r, nil
+}
+
+// Record 放入写入缓冲 缓冲满或已关闭时丢弃 出错后停止录制 错误通过Err获取
+func (r *FileRecorder) Record(frame Frame) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.closed {
+		return
+	}
+	if frame.Wall == 0 {
+		now := time.Now()
+		frame.Wall, frame.Mono = now.UnixNano(), monoNow(now)
+	}
+	select {
+	case r.frames <- frame:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped 因缓冲已满丢弃的帧数
+func (r *FileRecorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Err 录制过程中的错误
+func (r *FileRecorder) Err() error {
+	r.errLock.Lock()
+	defer r.errLock.Unlock()
+
+	return r.err
+}
+
+// Close 写完缓冲中的帧后关闭当前文件
+func (r *FileRecorder) Close() error {
+	r.lock.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.frames)
+	}
+	r.lock.Unlock()
+
+	<-r.done
+	return r.Err()
+}
+
+func (r *FileRecorder) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(RecorderFlushInterval)
+	defer ticker.Stop()
+	var failed, dirty bool
+	for {
+		select {
+		case frame, ok := <-r.frames:
+			if !ok {
+				r.setErr(r.closeFile())
+				return
+			}
+			if failed {
+				continue
+			}
+			if err := r.write(frame); err != nil {
+				failed = r.setErr(err)
+				continue
+			}
+			dirty = true
+		case <-ticker.C:
+			if failed || !dirty {
+				continue
+			}
+			failed = r.setErr(r.gz.Flush())
+			dirty = false
+		}
+	}
+}
+
+func (r *FileRecorder) write(frame Frame) error {
+	bs, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	bs = append(bs, '\n')
+	if r.maxBytes > 0 && r.written > 0 && r.written+int64(len(bs)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := r.gz.Write(bs); err != nil {
+		return err
+	}
+	r.written += int64(len(bs))
+	return nil
+}
+
+// setErr 记录第一个错误 返回是否出错
+func (r *FileRecorder) setErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	r.errLock.Lock()
+	defer r.errLock.Unlock()
+
+	if r.err == nil {
+		r.err = err
+	}
+	return true
+}
+
+func (r *FileRecorder) rotate() error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+	r.index++
+	name := fmt.Sprintf("%s-%s-%04d.jsonl.gz", r.prefix, r.start.UTC().Format("20060102T150405"), r.index)
+	f, err := os.OpenFile(filepath.Join(r.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.gz = gzip.NewWriter(f)
+	r.written = 0
+	return nil
+}
+
+func (r *FileRecorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := errors.Join(r.gz.Close(), r.file.Close())
+	r.file = nil
+	return err
+}
+
+// FrameReader 顺序读取录制文件
+type FrameReader struct {
+	paths   []string
+	file    *os.File
+	scanner *bufio.Scanner
+}
+
+// OpenRecording 按文件名顺序打开同一前缀的录制文件 pattern如dir/prefix-*.jsonl.gz
+func OpenRecording(pattern string) (*FrameReader, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no recording matches %s", pattern)
+	}
+	sort.Strings(paths)
+	return &FrameReader{paths: paths}, nil
+}
+
+// Next 读取下一帧 读完返回io.EOF
+func (r *FrameReader) Next() (Frame, error) {
+	for {
+		if r.scanner == nil {
+			if len(r.paths) == 0 {
+				return Frame{}, io.EOF
+			}
+			if err := r.open(r.paths[0]); err != nil {
+				return Frame{}, err
+			}
+			r.paths = r.paths[1:]
+		}
+		if r.scanner.Scan() {
+			line := r.scanner.Bytes()
+			if len(strings.TrimSpace(string(line))) == 0 {
+				continue
+			}
+			var f Frame
+			if err := json.Unmarshal(line, &f); err != nil {
+				return Frame{}, err
+			}
+			return f, nil
+		}
+		err := r.scanner.Err()
+		_ = r.file.Close()
+		r.scanner = nil
+		// 未正常关闭的文件末尾可能不完整 已刷新的部分仍可读取
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return Frame{}, err
+		}
+	}
+}
+
+// Close 关闭正在读取的文件
+func (r *FrameReader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
+
+func (r *FrameReader) open(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.file = f
+	r.scanner = bufio.NewScanner(gz)
+	r.scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return nil
+}
@@ -0,0 +1,109 @@
+package ws
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewFileRecorder(dir, "test", 200)
+	assert.NoError(t, err)
+
+	conn := NewReplayConn(context.Background(), WithRecorder(rec))
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		conn.Inject(TextMessage, []byte(msg))
+		assert.NoError(t, conn.Write([]byte("out-"+msg)))
+	}
+	assert.NoError(t, rec.Close())
+	assert.NoError(t, rec.Err())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test-*.jsonl.gz"))
+	assert.Greater(t, len(files), 1, "expect rotation")
+
+	reader, err := OpenRecording(filepath.Join(dir, "test-*.jsonl.gz"))
+	assert.NoError(t, err)
+	target := NewReplayConn(context.Background())
+	ch := target.RegisterWatch("test")
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for d := range ch {
+			got = append(got, string(d.Data))
+		}
+	}()
+	start := time.Now()
+	assert.NoError(t, Replay(context.Background(), reader, target, 0, ConnFilter(conn.Id())))
+	assert.Less(t, time.Since(start), time.Second)
+	target.UnregisterWatch("test")
+	<-done
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, got)
+}
+
+func TestRecordRedactsLogin(t *testing.T) {
+	rec := &memRecorder{}
+	conn := NewReplayConn(context.Background(), WithRecorder(rec))
+	login := []byte(`{"op":"login","args":[{"apiKey":"key","passphrase":"pass","timestamp":"1538054050","sign":"c2lnbg=="}]}`)
+	order := []byte(`{"id":"1","op":"order","args":[{"instId":"BTC-USDT","side":"buy"}]}`)
+	assert.NoError(t, conn.Write(login))
+	assert.NoError(t, conn.Write(order))
+	conn.Inject(TextMessage, []byte(`{"event":"login","code":"0"}`))
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	var out []string
+	for _, f := range rec.frames {
+		if f.Dir == Outbound {
+			out = append(out, string(f.Data))
+		}
+	}
+	assert.Equal(t, []string{
+		`{"op":"login","args":[{"apiKey":"***","passphrase":"***","timestamp":"1538054050","sign":"***"}]}`,
+		string(order),
+	}, out)
+	// 写出的数据不被修改
+	assert.Contains(t, string(login), `"sign":"c2lnbg=="`)
+}
+
+func TestRecordStampsAtConn(t *testing.T) {
+	rec := &memRecorder{}
+	conn := NewReplayConn(context.Background(), WithRecorder(rec))
+	before := time.Now().UnixNano()
+	conn.Inject(TextMessage, []byte("a"))
+	conn.Inject(TextMessage, []byte("b"))
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	assert.Len(t, rec.frames, 2)
+	assert.GreaterOrEqual(t, rec.frames[0].Wall, before)
+	assert.Positive(t, rec.frames[0].Mono)
+	assert.GreaterOrEqual(t, rec.frames[1].Mono, rec.frames[0].Mono)
+}
+
+func TestFileRecorderKeepsStamps(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewFileRecorder(dir, "stamp", 0)
+	assert.NoError(t, err)
+	rec.Record(Frame{ConnId: "1", Dir: Inbound, Typ: TextMessage, Wall: 42, Mono: 7, Data: []byte("a")})
+	assert.NoError(t, rec.Close())
+	// 关闭后不再录制
+	rec.Record(Frame{ConnId: "1", Dir: Inbound, Typ: TextMessage, Data: []byte("b")})
+	assert.NoError(t, rec.Close())
+
+	reader, err := OpenRecording(filepath.Join(dir, "stamp-*.jsonl.gz"))
+	assert.NoError(t, err)
+	defer reader.Close()
+	f, err := reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), f.Wall)
+	assert.Equal(t, int64(7), f.Mono)
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Zero(t, rec.Dropped())
+}
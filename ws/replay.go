@@ -0,0 +1,59 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Replay 将录制中的入站帧注入conn
+// speed为回放倍速 1为原始节奏 <=0表示不等待 filter为空时回放全部入站帧
+func Replay(ctx context.Context, r *FrameReader, conn *Conn, speed float64, filter func(frame Frame) bool) error {
+	var last int64
+	started := false
+	for {
+		frame, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if frame.Dir != Inbound || (filter != nil && !filter(frame)) {
+			continue
+		}
+		if started && speed > 0 {
+			if wait := time.Duration(float64(frame.Mono-last) / speed); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-conn.Context().Done():
+					timer.Stop()
+					return context.Cause(conn.Context())
+				case <-timer.C:
+				}
+			}
+		}
+		started = true
+		last = frame.Mono
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		conn.Inject(frame.Typ, frame.Data)
+	}
+}
+
+// ConnFilter 只回放指定连接的帧
+func ConnFilter(connIds ...string) func(frame Frame) bool {
+	ids := map[string]struct{}{}
+	for _, id := range connIds {
+		ids[id] = struct{}{}
+	}
+	return func(frame Frame) bool {
+		_, ok := ids[frame.ConnId]
+		return ok
+	}
+}
@@ -0,0 +1,89 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrClosed 连接已被主动关闭
+var ErrClosed = errors.New("ws: connection closed")
+
+// State 连接状态 只按Connecting→Open⇄Authenticating→Closing→Closed的方向迁移
+type State int32
+
+const (
+	StateConnecting State = iota
+	StateOpen
+	StateAuthenticating
+	StateClosing
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateOpen:
+		return "open"
+	case StateAuthenticating:
+		return "authenticating"
+	case StateClosing:
+		return "closing"
+	case StateClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("state(%d)", int32(s))
+}
+
+// State 当前状态
+func (c *Conn) State() State {
+	return State(c.state.Load())
+}
+
+// Alive 连接是否可用
+func (c *Conn) Alive() bool {
+	return alive(c.State())
+}
+
+func alive(s State) bool {
+	return s == StateOpen || s == StateAuthenticating
+}
+
+// Transition 状态为from时迁移到to 用于上层标记登录中等状态
+func (c *Conn) Transition(from, to State) bool {
+	if from >= StateClosing && to < from {
+		return false
+	}
+	if !c.state.CompareAndSwap(int32(from), int32(to)) {
+		return false
+	}
+	c.notify(from, to)
+	return true
+}
+
+// setState 无条件迁移 不会从关闭中或已关闭回到更早的状态
+func (c *Conn) setState(to State) {
+	for {
+		from := c.State()
+		if from == to || (from >= StateClosing && to < from) {
+			return
+		}
+		if c.state.CompareAndSwap(int32(from), int32(to)) {
+			c.notify(from, to)
+			return
+		}
+	}
+}
+
+// notify 存活与否变化时同步Status字段 该变化每个连接各只发生一次
+func (c *Conn) notify(from, to State) {
+	if alive(from) != alive(to) {
+		c.Status = Dead
+		if alive(to) {
+			c.Status = Alive
+		}
+	}
+	if c.onState != nil {
+		c.onState(c, from, to)
+	}
+}
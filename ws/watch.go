@@ -0,0 +1,159 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrSlowConsumer 监听队列已满且溢出策略为OverflowDisconnect
+var ErrSlowConsumer = errors.New("ws: slow consumer")
+
+// Overflow 监听队列已满时的处理方式
+type Overflow int
+
+const (
+	// OverflowBlock 等待监听者取走数据 期间读协程暂停 pong等控制帧也无法处理
+	// 阻塞超过pongTimeout时连接以ErrPongTimeout断开 仅用于能及时消费的监听
+	OverflowBlock Overflow = iota
+	// OverflowDropNewest 丢弃新到的数据
+	OverflowDropNewest
+	// OverflowDropOldest 丢弃队列中最早的数据 默认策略 丢弃数量见WatchStats
+	OverflowDropOldest
+	// OverflowDisconnect 以ErrSlowConsumer关闭连接 由上层重连并重新订阅
+	OverflowDisconnect
+)
+
+func (o Overflow) String() string {
+	switch o {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowDisconnect:
+		return "disconnect"
+	}
+	return fmt.Sprintf("overflow(%d)", int(o))
+}
+
+const (
+	// DefaultQueueSize 监听队列的默认长度
+	DefaultQueueSize = 1024
+	// DefaultOverflow 默认的溢出策略 不阻塞读协程
+	DefaultOverflow = OverflowDropOldest
+)
+
+// WatchOption 单个监听的参数 覆盖连接上WithWatchQueue的设置
+type WatchOption func(w *watcher)
+
+// WithQueue 监听队列长度与溢出策略
+func WithQueue(size int, overflow Overflow) WatchOption {
+	return func(w *watcher) {
+		if size > 0 {
+			w.size = size
+		}
+		w.overflow = overflow
+	}
+}
+
+// WatchStats 监听队列的积压情况
+type WatchStats struct {
+	Queued    int      // 当前积压
+	MaxQueued int      // 历史最大积压
+	Capacity  int      // 队列长度
+	Delivered uint64   // 已放入队列
+	Dropped   uint64   // 因队列已满丢弃
+	Overflow  Overflow // 溢出策略
+}
+
+// watcher 每个监听独立的有界队列
+type watcher struct {
+	id        string
+	size      int
+	overflow  Overflow
+	ch        chan Data
+	done      chan struct{} // 注销时关闭 唤醒阻塞中的分发
+	mu        sync.RWMutex  // 分发持读锁 注销持写锁后关闭ch
+	closed    bool
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	maxQueued atomic.Int64
+}
+
+func (w *watcher) stats() WatchStats {
+	return WatchStats{
+		Queued:    len(w.ch),
+		MaxQueued: int(w.maxQueued.Load()),
+		Capacity:  cap(w.ch),
+		Delivered: w.delivered.Load(),
+		Dropped:   w.dropped.Load(),
+		Overflow:  w.overflow,
+	}
+}
+
+func (w *watcher) close() {
+	close(w.done)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	close(w.ch)
+}
+
+// push 按溢出策略放入队列 返回false表示应断开连接
+func (w *watcher) push(c *Conn, d Data) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return true
+	}
+	select {
+	case w.ch <- d:
+		w.sent()
+		return true
+	default:
+	}
+	switch w.overflow {
+	case OverflowDropNewest:
+		w.dropped.Add(1)
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.ch <- d:
+				w.sent()
+				return true
+			default:
+			}
+			select {
+			case <-w.ch:
+				w.dropped.Add(1)
+			default:
+			}
+		}
+	case OverflowDisconnect:
+		w.dropped.Add(1)
+		return false
+	default:
+		select {
+		case w.ch <- d:
+			w.sent()
+		case <-w.done:
+		case <-c.ctx.Done():
+		}
+	}
+	return true
+}
+
+func (w *watcher) sent() {
+	w.delivered.Add(1)
+	n := int64(len(w.ch))
+	for {
+		max := w.maxQueued.Load()
+		if n <= max || w.maxQueued.CompareAndSwap(max, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchOverflow(t *testing.T) {
+	conn := NewReplayConn(context.Background())
+	fast := conn.RegisterWatch("fast")
+	newest := conn.RegisterWatch("newest", WithQueue(2, OverflowDropNewest))
+	oldest := conn.RegisterWatch("oldest", WithQueue(2, OverflowDropOldest))
+
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range fast {
+			n++
+		}
+		done <- n
+	}()
+	for i := 0; i < 5; i++ {
+		conn.Inject(TextMessage, []byte(strconv.Itoa(i)))
+	}
+	conn.UnregisterWatch("fast")
+	assert.Equal(t, 5, <-done)
+
+	assert.Equal(t, "0", string((<-newest).Data))
+	assert.Equal(t, "1", string((<-newest).Data))
+	assert.Equal(t, "3", string((<-oldest).Data))
+	assert.Equal(t, "4", string((<-oldest).Data))
+
+	stats := conn.WatchStats()
+	assert.Equal(t, WatchStats{MaxQueued: 2, Capacity: 2, Delivered: 2, Dropped: 3, Overflow: OverflowDropNewest}, stats["newest"])
+	assert.Equal(t, uint64(3), stats["oldest"].Dropped)
+	assert.Equal(t, 0, stats["oldest"].Queued)
+}
+
+func TestWatchDisconnect(t *testing.T) {
+	conn := NewReplayConn(context.Background(), WithWatchQueue(1, OverflowDisconnect))
+	conn.RegisterWatch("slow")
+	conn.Inject(TextMessage, []byte("a"))
+	assert.NoError(t, conn.Context().Err())
+	conn.Inject(TextMessage, []byte("b"))
+	assert.ErrorIs(t, context.Cause(conn.Context()), ErrSlowConsumer)
+}
+
+func TestWatchBlockDoesNotStallWrite(t *testing.T) {
+	conn := NewReplayConn(context.Background(), WithWatchQueue(1, OverflowBlock))
+	ch := conn.RegisterWatch("slow")
+	conn.Inject(TextMessage, []byte("a"))
+
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		conn.Inject(TextMessage, []byte("b"))
+	}()
+	select {
+	case <-blocked:
+		t.Fatal("dispatch should block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// 分发阻塞时写入不受影响
+	written := make(chan error, 1)
+	go func() { written <- conn.Write([]byte("out")) }()
+	select {
+	case err := <-written:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("write blocked behind slow consumer")
+	}
+	// 注销唤醒阻塞中的分发
+	conn.UnregisterWatch("slow")
+	<-blocked
+	assert.Equal(t, "a", string((<-ch).Data))
+}
+
+// newFloodServer 收到第一条数据后连续推送n条数据 同时正常回复ping
+func newFloodServer(t *testing.T, n int) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		var once sync.Once
+		for {
+			mt, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if mt != websocket.TextMessage {
+				continue
+			}
+			once.Do(func() {
+				go func() {
+					for i := 0; i < n; i++ {
+						if conn.WriteMessage(websocket.TextMessage, []byte(strconv.Itoa(i))) != nil {
+							return
+						}
+					}
+				}()
+			})
+		}
+	}))
+}
+
+func TestWatchSlowConsumerKeepsHeartbeat(t *testing.T) {
+	srv := newFloodServer(t, 2*DefaultQueueSize)
+	defer srv.Close()
+
+	opts := []Option{WithPingInterval(150 * time.Millisecond), WithPongTimeout(75 * time.Millisecond)}
+	conn, err := DialContext(context.Background(), wsURL(srv), opts...)
+	assert.NoError(t, err)
+	defer conn.Close(nil)
+	// 从不读取的监听不阻塞读协程 pong照常处理
+	conn.RegisterWatch("slow")
+	assert.NoError(t, conn.Write([]byte("start")))
+	assert.Eventually(t, func() bool { return conn.WatchStats()["slow"].Dropped > 0 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(400 * time.Millisecond)
+	assert.NoError(t, context.Cause(conn.Context()))
+	assert.Equal(t, DefaultQueueSize, conn.WatchStats()["slow"].Queued)
+
+	// 阻塞策略下读协程停顿 心跳超时断开
+	blocking, err := DialContext(context.Background(), wsURL(srv), append(opts, WithWatchQueue(1, OverflowBlock))...)
+	assert.NoError(t, err)
+	defer blocking.Close(nil)
+	blocking.RegisterWatch("slow")
+	assert.NoError(t, blocking.Write([]byte("start")))
+	select {
+	case <-blocking.Context().Done():
+		assert.ErrorIs(t, context.Cause(blocking.Context()), ErrPongTimeout)
+	case <-time.After(3 * time.Second):
+		t.Fatal("blocked reader should miss pongs")
+	}
+}